@@ -3,6 +3,7 @@ package analytics
 import (
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
 	"time"
 )
 
@@ -24,8 +25,8 @@ func NewAnalyticsService(
 }
 
 // GetIncomeExpenseDifference рассчитывает разницу между доходами и расходами за период
-func (s *AnalyticsServiceImpl) GetIncomeExpenseDifference(start, end time.Time) (float64, error) {
-	operations, err := s.operationRepo.GetByDateRange(start, end)
+func (s *AnalyticsServiceImpl) GetIncomeExpenseDifference(ctx context.Context, start, end time.Time) (float64, error) {
+	operations, err := s.operationRepo.GetByDateRange(ctx, start, end)
 	if err != nil {
 		return 0, err
 	}
@@ -43,14 +44,14 @@ func (s *AnalyticsServiceImpl) GetIncomeExpenseDifference(start, end time.Time)
 }
 
 // GetCategorySummary получает сумму операций по каждой категории за период
-func (s *AnalyticsServiceImpl) GetCategorySummary(start, end time.Time) (map[*models.Category]float64, error) {
-	operations, err := s.operationRepo.GetByDateRange(start, end)
+func (s *AnalyticsServiceImpl) GetCategorySummary(ctx context.Context, start, end time.Time) (map[*models.Category]float64, error) {
+	operations, err := s.operationRepo.GetByDateRange(ctx, start, end)
 	if err != nil {
 		return nil, err
 	}
 
 	categoryMap := make(map[int]*models.Category)
-	categories, err := s.categoryRepo.GetAll()
+	categories, err := s.categoryRepo.GetAll(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -76,11 +77,11 @@ func (s *AnalyticsServiceImpl) GetCategorySummary(start, end time.Time) (map[*mo
 }
 
 // GetMonthlyDynamics получает месячную динамику доходов и расходов за год
-func (s *AnalyticsServiceImpl) GetMonthlyDynamics(year int) (map[time.Month]map[models.OperationType]float64, error) {
+func (s *AnalyticsServiceImpl) GetMonthlyDynamics(ctx context.Context, year int) (map[time.Month]map[models.OperationType]float64, error) {
 	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
 	end := time.Date(year, 12, 31, 23, 59, 59, 999999999, time.Local)
 
-	operations, err := s.operationRepo.GetByDateRange(start, end)
+	operations, err := s.operationRepo.GetByDateRange(ctx, start, end)
 	if err != nil {
 		return nil, err
 	}
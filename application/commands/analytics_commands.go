@@ -4,6 +4,7 @@ import (
 	"KPO1/application/facade"
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
 	"time"
 )
 
@@ -36,8 +37,8 @@ func NewBalanceByPeriodCommand(
 }
 
 // Execute выполняет команду получения баланса за период
-func (c *BalanceByPeriodCommand) Execute() error {
-	balance, err := c.facade.GetIncomeExpenseDifference(c.startDate, c.endDate)
+func (c *BalanceByPeriodCommand) Execute(ctx context.Context) error {
+	balance, err := c.facade.GetIncomeExpenseDifference(ctx, c.startDate, c.endDate)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -80,8 +81,8 @@ func NewExpensesByCategoryCommand(
 }
 
 // Execute выполняет команду получения расходов по категориям
-func (c *ExpensesByCategoryCommand) Execute() error {
-	categoryMap, err := c.facade.GetCategorySummary(c.startDate, c.endDate)
+func (c *ExpensesByCategoryCommand) Execute(ctx context.Context) error {
+	categoryMap, err := c.facade.GetCategorySummary(ctx, c.startDate, c.endDate)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -132,8 +133,8 @@ func NewIncomesByCategoryCommand(
 }
 
 // Execute выполняет команду получения доходов по категориям
-func (c *IncomesByCategoryCommand) Execute() error {
-	categoryMap, err := c.facade.GetCategorySummary(c.startDate, c.endDate)
+func (c *IncomesByCategoryCommand) Execute(ctx context.Context) error {
+	categoryMap, err := c.facade.GetCategorySummary(ctx, c.startDate, c.endDate)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -184,9 +185,9 @@ func NewStatisticsCommand(
 }
 
 // Execute выполняет команду получения общей статистики
-func (c *StatisticsCommand) Execute() error {
+func (c *StatisticsCommand) Execute(ctx context.Context) error {
 	// Получаем разницу между доходами и расходами
-	difference, err := c.facade.GetIncomeExpenseDifference(c.startDate, c.endDate)
+	difference, err := c.facade.GetIncomeExpenseDifference(ctx, c.startDate, c.endDate)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -195,7 +196,7 @@ func (c *StatisticsCommand) Execute() error {
 	}
 
 	// Получаем суммарные доходы и расходы по категориям
-	categorySummary, err := c.facade.GetCategorySummary(c.startDate, c.endDate)
+	categorySummary, err := c.facade.GetCategorySummary(ctx, c.startDate, c.endDate)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
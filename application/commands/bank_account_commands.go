@@ -4,6 +4,7 @@ import (
 	"KPO1/application/facade"
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
 )
 
 // CreateBankAccountCommand представляет команду для создания банковского счёта
@@ -32,8 +33,8 @@ func NewCreateBankAccountCommand(
 }
 
 // Execute выполняет команду
-func (c *CreateBankAccountCommand) Execute() error {
-	account, err := c.facade.CreateBankAccount(c.name)
+func (c *CreateBankAccountCommand) Execute(ctx context.Context) error {
+	account, err := c.facade.CreateBankAccount(ctx, c.name)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -74,8 +75,8 @@ func NewGetBankAccountCommand(
 }
 
 // Execute выполняет команду
-func (c *GetBankAccountCommand) Execute() error {
-	account, err := c.facade.GetBankAccount(c.id)
+func (c *GetBankAccountCommand) Execute(ctx context.Context) error {
+	account, err := c.facade.GetBankAccount(ctx, c.id)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -113,8 +114,8 @@ func NewListBankAccountsCommand(
 }
 
 // Execute выполняет команду
-func (c *ListBankAccountsCommand) Execute() error {
-	accounts, err := c.facade.GetAllBankAccounts()
+func (c *ListBankAccountsCommand) Execute(ctx context.Context) error {
+	accounts, err := c.facade.GetAllBankAccounts(ctx)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -134,6 +135,7 @@ type UpdateBankAccountCommand struct {
 	CommandBase
 	facade   *facade.BankAccountFacade
 	id       int
+	version  int
 	name     string
 	resultCh chan *models.BankAccount
 	errorCh  chan error
@@ -143,6 +145,7 @@ type UpdateBankAccountCommand struct {
 func NewUpdateBankAccountCommand(
 	facade *facade.BankAccountFacade,
 	id int,
+	version int,
 	name string,
 	resultCh chan *models.BankAccount,
 	errorCh chan error,
@@ -151,6 +154,7 @@ func NewUpdateBankAccountCommand(
 		CommandBase: NewCommandBase("UpdateBankAccount"),
 		facade:      facade,
 		id:          id,
+		version:     version,
 		name:        name,
 		resultCh:    resultCh,
 		errorCh:     errorCh,
@@ -158,8 +162,8 @@ func NewUpdateBankAccountCommand(
 }
 
 // Execute выполняет команду
-func (c *UpdateBankAccountCommand) Execute() error {
-	account, err := c.facade.UpdateBankAccount(c.id, c.name)
+func (c *UpdateBankAccountCommand) Execute(ctx context.Context) error {
+	account, err := c.facade.UpdateBankAccount(ctx, c.id, c.version, c.name)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -197,8 +201,8 @@ func NewDeleteBankAccountCommand(
 }
 
 // Execute выполняет команду
-func (c *DeleteBankAccountCommand) Execute() error {
-	err := c.facade.DeleteBankAccount(c.id)
+func (c *DeleteBankAccountCommand) Execute(ctx context.Context) error {
+	err := c.facade.DeleteBankAccount(ctx, c.id)
 	if err != nil && c.errorCh != nil {
 		c.errorCh <- err
 	}
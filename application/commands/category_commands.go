@@ -4,6 +4,7 @@ import (
 	"KPO1/application/facade"
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
 )
 
 // CreateCategoryCommand представляет команду для создания категории
@@ -35,8 +36,8 @@ func NewCreateCategoryCommand(
 }
 
 // Execute выполняет команду создания категории
-func (c *CreateCategoryCommand) Execute() error {
-	category, err := c.facade.CreateCategory(c.name, c.categoryType)
+func (c *CreateCategoryCommand) Execute(ctx context.Context) error {
+	category, err := c.facade.CreateCategory(ctx, c.name, c.categoryType)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -76,8 +77,8 @@ func NewGetCategoryCommand(
 }
 
 // Execute выполняет команду получения категории
-func (c *GetCategoryCommand) Execute() error {
-	category, err := c.facade.GetCategory(c.id)
+func (c *GetCategoryCommand) Execute(ctx context.Context) error {
+	category, err := c.facade.GetCategory(ctx, c.id)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -114,8 +115,8 @@ func NewListCategoriesCommand(
 }
 
 // Execute выполняет команду получения списка категорий
-func (c *ListCategoriesCommand) Execute() error {
-	categories, err := c.facade.GetAllCategories()
+func (c *ListCategoriesCommand) Execute(ctx context.Context) error {
+	categories, err := c.facade.GetAllCategories(ctx)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -155,8 +156,8 @@ func NewListCategoriesByTypeCommand(
 }
 
 // Execute выполняет команду получения списка категорий по типу
-func (c *ListCategoriesByTypeCommand) Execute() error {
-	categories, err := c.facade.GetCategoriesByType(c.categoryType)
+func (c *ListCategoriesByTypeCommand) Execute(ctx context.Context) error {
+	categories, err := c.facade.GetCategoriesByType(ctx, c.categoryType)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -175,6 +176,7 @@ type UpdateCategoryCommand struct {
 	CommandBase
 	facade       *facade.CategoryFacade
 	id           int
+	version      int
 	name         string
 	categoryType models.OperationType
 	resultCh     chan *models.Category
@@ -185,6 +187,7 @@ type UpdateCategoryCommand struct {
 func NewUpdateCategoryCommand(
 	facade *facade.CategoryFacade,
 	id int,
+	version int,
 	name string,
 	categoryType models.OperationType,
 	resultCh chan *models.Category,
@@ -194,6 +197,7 @@ func NewUpdateCategoryCommand(
 		CommandBase:  NewCommandBase("UpdateCategory"),
 		facade:       facade,
 		id:           id,
+		version:      version,
 		name:         name,
 		categoryType: categoryType,
 		resultCh:     resultCh,
@@ -202,8 +206,8 @@ func NewUpdateCategoryCommand(
 }
 
 // Execute выполняет команду обновления категории
-func (c *UpdateCategoryCommand) Execute() error {
-	category, err := c.facade.UpdateCategory(c.id, c.name, c.categoryType)
+func (c *UpdateCategoryCommand) Execute(ctx context.Context) error {
+	category, err := c.facade.UpdateCategory(ctx, c.id, c.version, c.name, c.categoryType)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -240,8 +244,8 @@ func NewDeleteCategoryCommand(
 }
 
 // Execute выполняет команду удаления категории
-func (c *DeleteCategoryCommand) Execute() error {
-	err := c.facade.DeleteCategory(c.id)
+func (c *DeleteCategoryCommand) Execute(ctx context.Context) error {
+	err := c.facade.DeleteCategory(ctx, c.id)
 	if err != nil && c.errorCh != nil {
 		c.errorCh <- err
 	}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"KPO1/domain/interfaces"
+	"context"
 	"time"
 )
 
@@ -35,9 +36,9 @@ func NewTimeLoggerDecorator(command interfaces.Command) interfaces.Command {
 }
 
 // Execute выполняет команду и логирует время выполнения
-func (d *TimeLoggerDecorator) Execute() error {
+func (d *TimeLoggerDecorator) Execute(ctx context.Context) error {
 	start := time.Now()
-	err := d.command.Execute()
+	err := d.command.Execute(ctx)
 	elapsed := time.Since(start)
 
 	// Здесь можно добавить логирование времени выполнения
@@ -1,21 +1,26 @@
 package commands
 
 import (
+	"KPO1/application/facade"
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
 	"KPO1/infrastructure/importexport"
+	"context"
+	"io"
+	"strings"
+	"time"
 )
 
-// ExportCSVCommand представляет команду для экспорта данных в CSV
-type ExportCSVCommand struct {
+// ExportCommand представляет команду для экспорта данных в любом зарегистрированном формате
+type ExportCommand struct {
 	CommandBase
 	exporter *importexport.FileExporter
-	path     string
 	errorCh  chan error
 }
 
-// NewExportCSVCommand создаёт новую команду для экспорта данных в CSV
-func NewExportCSVCommand(
+// NewExportCommand создаёт новую команду для экспорта данных в файлы директории path
+func NewExportCommand(
+	format importexport.FileFormat,
 	bankAccountRepo interfaces.BankAccountRepository,
 	categoryRepo interfaces.CategoryRepository,
 	operationRepo interfaces.OperationRepository,
@@ -28,236 +33,608 @@ func NewExportCSVCommand(
 		categoryRepo:    categoryRepo,
 		operationRepo:   operationRepo,
 	}
-	return &ExportCSVCommand{
-		CommandBase: NewCommandBase("ExportCSV"),
-		exporter:    importexport.NewFileExporter(importexport.CSV, path, repository),
-		path:        path,
+	return &ExportCommand{
+		CommandBase: NewCommandBase("Export" + strings.ToUpper(string(format))),
+		exporter:    importexport.NewFileExporter(format, path, repository),
 		errorCh:     errorCh,
 	}
 }
 
-// Execute выполняет команду экспорта данных в CSV
-func (c *ExportCSVCommand) Execute() error {
-	err := c.exporter.ExportAll()
+// NewExportStreamCommand создаёт новую команду для экспорта данных в потоки streams
+func NewExportStreamCommand(
+	format importexport.FileFormat,
+	bankAccountRepo interfaces.BankAccountRepository,
+	categoryRepo interfaces.CategoryRepository,
+	operationRepo interfaces.OperationRepository,
+	streams importexport.ExportStreams,
+	errorCh chan error,
+) interfaces.Command {
+	repository := &CompositeRepository{
+		bankAccountRepo: bankAccountRepo,
+		categoryRepo:    categoryRepo,
+		operationRepo:   operationRepo,
+	}
+	return &ExportCommand{
+		CommandBase: NewCommandBase("Export" + strings.ToUpper(string(format))),
+		exporter:    importexport.NewStreamExporter(format, streams, repository),
+		errorCh:     errorCh,
+	}
+}
+
+// Execute выполняет команду экспорта данных
+func (c *ExportCommand) Execute(ctx context.Context) error {
+	err := c.exporter.ExportAll(ctx)
 	if err != nil && c.errorCh != nil {
 		c.errorCh <- err
 	}
 	return err
 }
 
-// ExportJSONCommand представляет команду для экспорта данных в JSON
-type ExportJSONCommand struct {
+// CompositeRepository объединяет все репозитории для импорта/экспорта
+type CompositeRepository struct {
+	bankAccountRepo interfaces.BankAccountRepository
+	categoryRepo    interfaces.CategoryRepository
+	operationRepo   interfaces.OperationRepository
+}
+
+// GetBankAccounts возвращает все банковские счета
+func (r *CompositeRepository) GetBankAccounts(ctx context.Context) ([]*models.BankAccount, error) {
+	return r.bankAccountRepo.GetAll(ctx)
+}
+
+// GetCategories возвращает все категории
+func (r *CompositeRepository) GetCategories(ctx context.Context) ([]*models.Category, error) {
+	return r.categoryRepo.GetAll(ctx)
+}
+
+// GetOperations возвращает все операции
+func (r *CompositeRepository) GetOperations(ctx context.Context) ([]*models.Operation, error) {
+	return r.operationRepo.GetAll(ctx)
+}
+
+// ImportCommand представляет команду для импорта данных в любом зарегистрированном формате
+type ImportCommand struct {
 	CommandBase
-	exporter *importexport.FileExporter
-	path     string
+	importer *importexport.FileImporter
+	resultCh chan *importexport.ImportSummary
 	errorCh  chan error
 }
 
-// NewExportJSONCommand создаёт новую команду для экспорта данных в JSON
-func NewExportJSONCommand(
-	bankAccountRepo interfaces.BankAccountRepository,
-	categoryRepo interfaces.CategoryRepository,
-	operationRepo interfaces.OperationRepository,
+// NewImportCommand создаёт новую команду для импорта данных из файлов директории path
+func NewImportCommand(
+	format importexport.FileFormat,
+	txManager interfaces.TransactionManager,
 	path string,
+	strategy importexport.MergeStrategy,
+	resultCh chan *importexport.ImportSummary,
 	errorCh chan error,
 ) interfaces.Command {
-	// Создаем композитный репозиторий для экспорта
-	repository := &CompositeRepository{
-		bankAccountRepo: bankAccountRepo,
-		categoryRepo:    categoryRepo,
-		operationRepo:   operationRepo,
+	return &ImportCommand{
+		CommandBase: NewCommandBase("Import" + strings.ToUpper(string(format))),
+		importer: importexport.NewFileImporter(
+			format,
+			path,
+			strategy,
+			txManager,
+		),
+		resultCh: resultCh,
+		errorCh:  errorCh,
+	}
+}
+
+// NewImportStreamCommand создаёт новую команду для импорта данных из потоков streams
+func NewImportStreamCommand(
+	format importexport.FileFormat,
+	txManager interfaces.TransactionManager,
+	streams importexport.ImportStreams,
+	strategy importexport.MergeStrategy,
+	resultCh chan *importexport.ImportSummary,
+	errorCh chan error,
+) interfaces.Command {
+	return &ImportCommand{
+		CommandBase: NewCommandBase("Import" + strings.ToUpper(string(format))),
+		importer: importexport.NewStreamImporter(
+			format,
+			streams,
+			strategy,
+			txManager,
+		),
+		resultCh: resultCh,
+		errorCh:  errorCh,
+	}
+}
+
+// Execute выполняет команду импорта данных и передает итог слияния
+func (c *ImportCommand) Execute(ctx context.Context) error {
+	err := c.importer.ImportAll(ctx)
+	if err != nil {
+		if c.errorCh != nil {
+			c.errorCh <- err
+		}
+		return err
+	}
+
+	if c.resultCh != nil {
+		c.resultCh <- c.importer.Summary()
+	}
+	return nil
+}
+
+// ValidateImportCommand представляет команду для проверки файлов импорта без сохранения данных
+type ValidateImportCommand struct {
+	CommandBase
+	importer *importexport.FileImporter
+	resultCh chan *importexport.ValidationReport
+	errorCh  chan error
+}
+
+// NewValidateImportCommand создаёт новую команду для проверки файлов импорта
+func NewValidateImportCommand(
+	format importexport.FileFormat,
+	txManager interfaces.TransactionManager,
+	path string,
+	strategy importexport.MergeStrategy,
+	resultCh chan *importexport.ValidationReport,
+	errorCh chan error,
+) interfaces.Command {
+	return &ValidateImportCommand{
+		CommandBase: NewCommandBase("ValidateImport"),
+		importer: importexport.NewFileImporter(
+			format,
+			path,
+			strategy,
+			txManager,
+		),
+		resultCh: resultCh,
+		errorCh:  errorCh,
 	}
-	return &ExportJSONCommand{
-		CommandBase: NewCommandBase("ExportJSON"),
-		exporter:    importexport.NewFileExporter(importexport.JSON, path, repository),
-		path:        path,
+}
+
+// Execute выполняет проверку файлов импорта и передает отчет о найденных проблемах
+func (c *ValidateImportCommand) Execute(ctx context.Context) error {
+	report, err := c.importer.Validate(ctx)
+	if err != nil {
+		if c.errorCh != nil {
+			c.errorCh <- err
+		}
+		return err
+	}
+
+	if c.resultCh != nil {
+		c.resultCh <- report
+	}
+	return nil
+}
+
+// ImportStatementCommand представляет команду для импорта выписки банка в операции счета
+type ImportStatementCommand struct {
+	CommandBase
+	importer *importexport.StatementImporter
+	resultCh chan *importexport.StatementImportSummary
+	errorCh  chan error
+}
+
+// NewImportStatementCommand создаёт новую команду для импорта выписки банка из файла path
+func NewImportStatementCommand(
+	format importexport.FileFormat,
+	txManager interfaces.TransactionManager,
+	path string,
+	options importexport.StatementImportOptions,
+	resultCh chan *importexport.StatementImportSummary,
+	errorCh chan error,
+) interfaces.Command {
+	return &ImportStatementCommand{
+		CommandBase: NewCommandBase("ImportStatement" + strings.ToUpper(string(format))),
+		importer:    importexport.NewStatementImporter(format, path, options, txManager),
+		resultCh:    resultCh,
 		errorCh:     errorCh,
 	}
 }
 
-// Execute выполняет команду экспорта данных в JSON
-func (c *ExportJSONCommand) Execute() error {
-	err := c.exporter.ExportAll()
-	if err != nil && c.errorCh != nil {
-		c.errorCh <- err
+// NewImportStatementStreamCommand создаёт новую команду для импорта выписки банка из потока r
+func NewImportStatementStreamCommand(
+	format importexport.FileFormat,
+	txManager interfaces.TransactionManager,
+	r io.Reader,
+	options importexport.StatementImportOptions,
+	resultCh chan *importexport.StatementImportSummary,
+	errorCh chan error,
+) interfaces.Command {
+	return &ImportStatementCommand{
+		CommandBase: NewCommandBase("ImportStatement" + strings.ToUpper(string(format))),
+		importer:    importexport.NewStreamStatementImporter(format, r, options, txManager),
+		resultCh:    resultCh,
+		errorCh:     errorCh,
+	}
+}
+
+// NewImportProfileStatementCommand создаёт новую команду для импорта CSV-выписки из файла path по профилю
+func NewImportProfileStatementCommand(
+	profile *importexport.CSVProfile,
+	txManager interfaces.TransactionManager,
+	path string,
+	options importexport.StatementImportOptions,
+	resultCh chan *importexport.StatementImportSummary,
+	errorCh chan error,
+) interfaces.Command {
+	return &ImportStatementCommand{
+		CommandBase: NewCommandBase("ImportStatementProfile"),
+		importer:    importexport.NewProfileStatementImporter(profile, path, options, txManager),
+		resultCh:    resultCh,
+		errorCh:     errorCh,
+	}
+}
+
+// NewImportProfileStatementStreamCommand создаёт новую команду для импорта CSV-выписки из потока r по профилю
+func NewImportProfileStatementStreamCommand(
+	profile *importexport.CSVProfile,
+	txManager interfaces.TransactionManager,
+	r io.Reader,
+	options importexport.StatementImportOptions,
+	resultCh chan *importexport.StatementImportSummary,
+	errorCh chan error,
+) interfaces.Command {
+	return &ImportStatementCommand{
+		CommandBase: NewCommandBase("ImportStatementProfile"),
+		importer:    importexport.NewProfileStreamStatementImporter(profile, r, options, txManager),
+		resultCh:    resultCh,
+		errorCh:     errorCh,
 	}
-	return err
 }
 
-// ExportYAMLCommand представляет команду для экспорта данных в YAML
-type ExportYAMLCommand struct {
+// Execute выполняет команду импорта выписки и передает итог
+func (c *ImportStatementCommand) Execute(ctx context.Context) error {
+	err := c.importer.Import(ctx)
+	if err != nil {
+		if c.errorCh != nil {
+			c.errorCh <- err
+		}
+		return err
+	}
+
+	if c.resultCh != nil {
+		c.resultCh <- c.importer.Summary()
+	}
+	return nil
+}
+
+// ExportStatementCommand представляет команду для экспорта счетов с операциями выпиской
+type ExportStatementCommand struct {
 	CommandBase
-	exporter *importexport.FileExporter
-	path     string
+	exporter *importexport.StatementExporter
 	errorCh  chan error
 }
 
-// NewExportYAMLCommand создаёт новую команду для экспорта данных в YAML
-func NewExportYAMLCommand(
+// NewExportStatementCommand создаёт новую команду для экспорта выписки в файл path
+func NewExportStatementCommand(
+	format importexport.FileFormat,
 	bankAccountRepo interfaces.BankAccountRepository,
 	categoryRepo interfaces.CategoryRepository,
 	operationRepo interfaces.OperationRepository,
 	path string,
 	errorCh chan error,
 ) interfaces.Command {
-	// Создаем композитный репозиторий для экспорта
 	repository := &CompositeRepository{
 		bankAccountRepo: bankAccountRepo,
 		categoryRepo:    categoryRepo,
 		operationRepo:   operationRepo,
 	}
-	return &ExportYAMLCommand{
-		CommandBase: NewCommandBase("ExportYAML"),
-		exporter:    importexport.NewFileExporter(importexport.YAML, path, repository),
-		path:        path,
+	return &ExportStatementCommand{
+		CommandBase: NewCommandBase("ExportStatement" + strings.ToUpper(string(format))),
+		exporter:    importexport.NewStatementExporter(format, path, repository),
+		errorCh:     errorCh,
+	}
+}
+
+// NewExportStatementStreamCommand создаёт новую команду для экспорта выписки в поток w
+func NewExportStatementStreamCommand(
+	format importexport.FileFormat,
+	bankAccountRepo interfaces.BankAccountRepository,
+	categoryRepo interfaces.CategoryRepository,
+	operationRepo interfaces.OperationRepository,
+	w io.Writer,
+	errorCh chan error,
+) interfaces.Command {
+	repository := &CompositeRepository{
+		bankAccountRepo: bankAccountRepo,
+		categoryRepo:    categoryRepo,
+		operationRepo:   operationRepo,
+	}
+	return &ExportStatementCommand{
+		CommandBase: NewCommandBase("ExportStatement" + strings.ToUpper(string(format))),
+		exporter:    importexport.NewStreamStatementExporter(format, w, repository),
 		errorCh:     errorCh,
 	}
 }
 
-// Execute выполняет команду экспорта данных в YAML
-func (c *ExportYAMLCommand) Execute() error {
-	err := c.exporter.ExportAll()
+// Execute выполняет команду экспорта выписки
+func (c *ExportStatementCommand) Execute(ctx context.Context) error {
+	err := c.exporter.Export(ctx)
 	if err != nil && c.errorCh != nil {
 		c.errorCh <- err
 	}
 	return err
 }
 
-// CompositeRepository объединяет все репозитории для импорта/экспорта
-type CompositeRepository struct {
-	bankAccountRepo interfaces.BankAccountRepository
-	categoryRepo    interfaces.CategoryRepository
-	operationRepo   interfaces.OperationRepository
+// WorkbookReports отчеты аналитики, добавляемые листами в книгу XLSX
+type WorkbookReports struct {
+	// Year год месячной динамики; 0 - без листа динамики
+	Year int
+	// Start и End период сводки по категориям; нулевое начало - без листа сводки
+	Start time.Time
+	End   time.Time
 }
 
-// GetBankAccounts возвращает все банковские счета
-func (r *CompositeRepository) GetBankAccounts() ([]*models.BankAccount, error) {
-	return r.bankAccountRepo.GetAll()
-}
-
-// GetCategories возвращает все категории
-func (r *CompositeRepository) GetCategories() ([]*models.Category, error) {
-	return r.categoryRepo.GetAll()
-}
-
-// GetOperations возвращает все операции
-func (r *CompositeRepository) GetOperations() ([]*models.Operation, error) {
-	return r.operationRepo.GetAll()
+// sheets возвращает построители листов выбранных отчетов
+func (r WorkbookReports) sheets(analyticsFacade *facade.AnalyticsFacade) []importexport.ReportSheet {
+	var reports []importexport.ReportSheet
+	if r.Year != 0 {
+		reports = append(reports, func(ctx context.Context) (*importexport.Sheet, error) {
+			dynamics, err := analyticsFacade.GetMonthlyDynamics(ctx, r.Year)
+			if err != nil {
+				return nil, err
+			}
+			return importexport.MonthlyDynamicsSheet(r.Year, dynamics), nil
+		})
+	}
+	if !r.Start.IsZero() {
+		reports = append(reports, func(ctx context.Context) (*importexport.Sheet, error) {
+			summary, err := analyticsFacade.GetCategorySummary(ctx, r.Start, r.End)
+			if err != nil {
+				return nil, err
+			}
+			return importexport.CategorySummarySheet(r.Start, r.End, summary), nil
+		})
+	}
+	return reports
 }
 
-// ImportCSVCommand представляет команду для импорта данных из CSV
-type ImportCSVCommand struct {
+// ExportWorkbookCommand представляет команду для экспорта данных и отчетов в книгу XLSX
+type ExportWorkbookCommand struct {
 	CommandBase
-	importer *importexport.FileImporter
-	path     string
+	exporter *importexport.WorkbookExporter
 	errorCh  chan error
 }
 
-// NewImportCSVCommand создаёт новую команду для импорта данных из CSV
-func NewImportCSVCommand(
+// NewExportWorkbookCommand создаёт новую команду для экспорта книги XLSX в файл path
+func NewExportWorkbookCommand(
 	bankAccountRepo interfaces.BankAccountRepository,
 	categoryRepo interfaces.CategoryRepository,
 	operationRepo interfaces.OperationRepository,
+	analyticsFacade *facade.AnalyticsFacade,
+	reports WorkbookReports,
 	path string,
 	errorCh chan error,
 ) interfaces.Command {
-	return &ImportCSVCommand{
-		CommandBase: NewCommandBase("ImportCSV"),
-		importer: importexport.NewFileImporter(
-			importexport.CSV,
-			path,
-			bankAccountRepo,
-			categoryRepo,
-			operationRepo,
-		),
-		path:    path,
-		errorCh: errorCh,
+	repository := &CompositeRepository{
+		bankAccountRepo: bankAccountRepo,
+		categoryRepo:    categoryRepo,
+		operationRepo:   operationRepo,
+	}
+	return &ExportWorkbookCommand{
+		CommandBase: NewCommandBase("ExportXLSX"),
+		exporter:    importexport.NewWorkbookExporter(path, repository, reports.sheets(analyticsFacade)...),
+		errorCh:     errorCh,
+	}
+}
+
+// NewExportWorkbookStreamCommand создаёт новую команду для экспорта книги XLSX в поток w
+func NewExportWorkbookStreamCommand(
+	bankAccountRepo interfaces.BankAccountRepository,
+	categoryRepo interfaces.CategoryRepository,
+	operationRepo interfaces.OperationRepository,
+	analyticsFacade *facade.AnalyticsFacade,
+	reports WorkbookReports,
+	w io.Writer,
+	errorCh chan error,
+) interfaces.Command {
+	repository := &CompositeRepository{
+		bankAccountRepo: bankAccountRepo,
+		categoryRepo:    categoryRepo,
+		operationRepo:   operationRepo,
+	}
+	return &ExportWorkbookCommand{
+		CommandBase: NewCommandBase("ExportXLSX"),
+		exporter:    importexport.NewStreamWorkbookExporter(w, repository, reports.sheets(analyticsFacade)...),
+		errorCh:     errorCh,
 	}
 }
 
-// Execute выполняет команду импорта данных из CSV
-func (c *ImportCSVCommand) Execute() error {
-	err := c.importer.ImportAll()
+// Execute выполняет команду экспорта книги
+func (c *ExportWorkbookCommand) Execute(ctx context.Context) error {
+	err := c.exporter.Export(ctx)
 	if err != nil && c.errorCh != nil {
 		c.errorCh <- err
 	}
 	return err
 }
 
-// ImportJSONCommand представляет команду для импорта данных из JSON
-type ImportJSONCommand struct {
+// BackupCommand представляет команду для резервного копирования всех данных в один архив
+type BackupCommand struct {
 	CommandBase
-	importer *importexport.FileImporter
-	path     string
+	exporter *importexport.BundleExporter
+	resultCh chan *importexport.BundleManifest
 	errorCh  chan error
 }
 
-// NewImportJSONCommand создаёт новую команду для импорта данных из JSON
-func NewImportJSONCommand(
+// NewBackupCommand создаёт новую команду для резервного копирования в архив path;
+// вид архива определяется расширением, файлы сущностей пишутся в формате format
+func NewBackupCommand(
+	format importexport.FileFormat,
 	bankAccountRepo interfaces.BankAccountRepository,
 	categoryRepo interfaces.CategoryRepository,
 	operationRepo interfaces.OperationRepository,
 	path string,
+	resultCh chan *importexport.BundleManifest,
 	errorCh chan error,
 ) interfaces.Command {
-	return &ImportJSONCommand{
-		CommandBase: NewCommandBase("ImportJSON"),
-		importer: importexport.NewFileImporter(
-			importexport.JSON,
-			path,
-			bankAccountRepo,
-			categoryRepo,
-			operationRepo,
-		),
-		path:    path,
-		errorCh: errorCh,
+	repository := &CompositeRepository{
+		bankAccountRepo: bankAccountRepo,
+		categoryRepo:    categoryRepo,
+		operationRepo:   operationRepo,
+	}
+	return &BackupCommand{
+		CommandBase: NewCommandBase("Backup"),
+		exporter:    importexport.NewBundleExporter(path, format, repository),
+		resultCh:    resultCh,
+		errorCh:     errorCh,
 	}
 }
 
-// Execute выполняет команду импорта данных из JSON
-func (c *ImportJSONCommand) Execute() error {
-	err := c.importer.ImportAll()
-	if err != nil && c.errorCh != nil {
-		c.errorCh <- err
+// NewBackupStreamCommand создаёт новую команду для резервного копирования в поток w
+func NewBackupStreamCommand(
+	format importexport.FileFormat,
+	bankAccountRepo interfaces.BankAccountRepository,
+	categoryRepo interfaces.CategoryRepository,
+	operationRepo interfaces.OperationRepository,
+	w io.Writer,
+	kind importexport.BundleKind,
+	resultCh chan *importexport.BundleManifest,
+	errorCh chan error,
+) interfaces.Command {
+	repository := &CompositeRepository{
+		bankAccountRepo: bankAccountRepo,
+		categoryRepo:    categoryRepo,
+		operationRepo:   operationRepo,
+	}
+	return &BackupCommand{
+		CommandBase: NewCommandBase("Backup"),
+		exporter:    importexport.NewStreamBundleExporter(w, kind, format, repository),
+		resultCh:    resultCh,
+		errorCh:     errorCh,
 	}
-	return err
 }
 
-// ImportYAMLCommand представляет команду для импорта данных из YAML
-type ImportYAMLCommand struct {
+// Execute выполняет команду резервного копирования и передает манифест архива
+func (c *BackupCommand) Execute(ctx context.Context) error {
+	manifest, err := c.exporter.Export(ctx)
+	if err != nil {
+		if c.errorCh != nil {
+			c.errorCh <- err
+		}
+		return err
+	}
+
+	if c.resultCh != nil {
+		c.resultCh <- manifest
+	}
+	return nil
+}
+
+// VerifyBackupCommand представляет команду для проверки резервной копии без восстановления
+type VerifyBackupCommand struct {
 	CommandBase
-	importer *importexport.FileImporter
-	path     string
+	verify   func(ctx context.Context) (*importexport.BundleManifest, error)
+	resultCh chan *importexport.BundleManifest
 	errorCh  chan error
 }
 
-// NewImportYAMLCommand создаёт новую команду для импорта данных из YAML
-func NewImportYAMLCommand(
-	bankAccountRepo interfaces.BankAccountRepository,
-	categoryRepo interfaces.CategoryRepository,
-	operationRepo interfaces.OperationRepository,
+// NewVerifyBackupCommand создаёт новую команду для проверки резервной копии из файла path
+func NewVerifyBackupCommand(
 	path string,
+	resultCh chan *importexport.BundleManifest,
 	errorCh chan error,
 ) interfaces.Command {
-	return &ImportYAMLCommand{
-		CommandBase: NewCommandBase("ImportYAML"),
-		importer: importexport.NewFileImporter(
-			importexport.YAML,
-			path,
-			bankAccountRepo,
-			categoryRepo,
-			operationRepo,
-		),
-		path:    path,
-		errorCh: errorCh,
+	return &VerifyBackupCommand{
+		CommandBase: NewCommandBase("VerifyBackup"),
+		verify: func(ctx context.Context) (*importexport.BundleManifest, error) {
+			return importexport.VerifyBundle(ctx, path)
+		},
+		resultCh: resultCh,
+		errorCh:  errorCh,
 	}
 }
 
-// Execute выполняет команду импорта данных из YAML
-func (c *ImportYAMLCommand) Execute() error {
-	err := c.importer.ImportAll()
-	if err != nil && c.errorCh != nil {
-		c.errorCh <- err
+// NewVerifyBackupStreamCommand создаёт новую команду для проверки резервной копии из потока r
+func NewVerifyBackupStreamCommand(
+	r io.Reader,
+	kind importexport.BundleKind,
+	resultCh chan *importexport.BundleManifest,
+	errorCh chan error,
+) interfaces.Command {
+	return &VerifyBackupCommand{
+		CommandBase: NewCommandBase("VerifyBackup"),
+		verify: func(ctx context.Context) (*importexport.BundleManifest, error) {
+			bundle, err := importexport.ReadBundle(ctx, r, kind)
+			if err != nil {
+				return nil, err
+			}
+			return bundle.Manifest, nil
+		},
+		resultCh: resultCh,
+		errorCh:  errorCh,
 	}
-	return err
+}
+
+// Execute выполняет проверку резервной копии и передает ее манифест
+func (c *VerifyBackupCommand) Execute(ctx context.Context) error {
+	manifest, err := c.verify(ctx)
+	if err != nil {
+		if c.errorCh != nil {
+			c.errorCh <- err
+		}
+		return err
+	}
+
+	if c.resultCh != nil {
+		c.resultCh <- manifest
+	}
+	return nil
+}
+
+// RestoreBackupCommand представляет команду для восстановления данных из резервной копии
+type RestoreBackupCommand struct {
+	CommandBase
+	importer *importexport.BundleImporter
+	resultCh chan *importexport.ImportSummary
+	errorCh  chan error
+}
+
+// NewRestoreBackupCommand создаёт новую команду для восстановления из архива path
+func NewRestoreBackupCommand(
+	txManager interfaces.TransactionManager,
+	path string,
+	strategy importexport.MergeStrategy,
+	resultCh chan *importexport.ImportSummary,
+	errorCh chan error,
+) interfaces.Command {
+	return &RestoreBackupCommand{
+		CommandBase: NewCommandBase("RestoreBackup"),
+		importer:    importexport.NewBundleImporter(path, strategy, txManager),
+		resultCh:    resultCh,
+		errorCh:     errorCh,
+	}
+}
+
+// NewRestoreBackupStreamCommand создаёт новую команду для восстановления из потока r
+func NewRestoreBackupStreamCommand(
+	txManager interfaces.TransactionManager,
+	r io.Reader,
+	kind importexport.BundleKind,
+	strategy importexport.MergeStrategy,
+	resultCh chan *importexport.ImportSummary,
+	errorCh chan error,
+) interfaces.Command {
+	return &RestoreBackupCommand{
+		CommandBase: NewCommandBase("RestoreBackup"),
+		importer:    importexport.NewStreamBundleImporter(r, kind, strategy, txManager),
+		resultCh:    resultCh,
+		errorCh:     errorCh,
+	}
+}
+
+// Execute выполняет восстановление из резервной копии и передает итог слияния
+func (c *RestoreBackupCommand) Execute(ctx context.Context) error {
+	err := c.importer.ImportAll(ctx)
+	if err != nil {
+		if c.errorCh != nil {
+			c.errorCh <- err
+		}
+		return err
+	}
+
+	if c.resultCh != nil {
+		c.resultCh <- c.importer.Summary()
+	}
+	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"KPO1/application/facade"
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
 	"time"
 )
 
@@ -33,8 +34,8 @@ func NewMonthlyDynamicsCommand(
 }
 
 // Execute выполняет команду получения месячной динамики
-func (c *MonthlyDynamicsCommand) Execute() error {
-	dynamics, err := c.facade.GetMonthlyDynamics(c.year)
+func (c *MonthlyDynamicsCommand) Execute(ctx context.Context) error {
+	dynamics, err := c.facade.GetMonthlyDynamics(ctx, c.year)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -4,6 +4,7 @@ import (
 	"KPO1/application/facade"
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
 	"time"
 )
 
@@ -48,8 +49,9 @@ func NewCreateOperationCommand(
 }
 
 // Execute выполняет команду создания операции
-func (c *CreateOperationCommand) Execute() error {
+func (c *CreateOperationCommand) Execute(ctx context.Context) error {
 	operation, err := c.facade.CreateOperation(
+		ctx,
 		c.bankAccountID,
 		c.categoryID,
 		c.amount,
@@ -95,8 +97,8 @@ func NewGetOperationCommand(
 }
 
 // Execute выполняет команду получения операции
-func (c *GetOperationCommand) Execute() error {
-	operation, err := c.facade.GetOperationDetails(c.id)
+func (c *GetOperationCommand) Execute(ctx context.Context) error {
+	operation, err := c.facade.GetOperationDetails(ctx, c.id)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -133,8 +135,8 @@ func NewListOperationsCommand(
 }
 
 // Execute выполняет команду получения списка операций
-func (c *ListOperationsCommand) Execute() error {
-	operations, err := c.facade.GetAllOperations()
+func (c *ListOperationsCommand) Execute(ctx context.Context) error {
+	operations, err := c.facade.GetAllOperations(ctx)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -148,6 +150,47 @@ func (c *ListOperationsCommand) Execute() error {
 	return nil
 }
 
+// FindOperationsCommand представляет команду для поиска операций по запросу
+type FindOperationsCommand struct {
+	CommandBase
+	facade   *facade.OperationFacade
+	query    models.OperationQuery
+	resultCh chan *models.OperationPage
+	errorCh  chan error
+}
+
+// NewFindOperationsCommand создаёт новую команду для поиска операций по запросу
+func NewFindOperationsCommand(
+	facade *facade.OperationFacade,
+	query models.OperationQuery,
+	resultCh chan *models.OperationPage,
+	errorCh chan error,
+) interfaces.Command {
+	return &FindOperationsCommand{
+		CommandBase: NewCommandBase("FindOperations"),
+		facade:      facade,
+		query:       query,
+		resultCh:    resultCh,
+		errorCh:     errorCh,
+	}
+}
+
+// Execute выполняет команду поиска операций
+func (c *FindOperationsCommand) Execute(ctx context.Context) error {
+	page, err := c.facade.FindOperations(ctx, c.query)
+	if err != nil {
+		if c.errorCh != nil {
+			c.errorCh <- err
+		}
+		return err
+	}
+
+	if c.resultCh != nil {
+		c.resultCh <- page
+	}
+	return nil
+}
+
 // ListOperationsByAccountCommand представляет команду для получения списка операций по счету
 type ListOperationsByAccountCommand struct {
 	CommandBase
@@ -174,8 +217,8 @@ func NewListOperationsByAccountCommand(
 }
 
 // Execute выполняет команду получения списка операций по счету
-func (c *ListOperationsByAccountCommand) Execute() error {
-	operations, err := c.facade.GetOperationsByBankAccount(c.bankAccountID)
+func (c *ListOperationsByAccountCommand) Execute(ctx context.Context) error {
+	operations, err := c.facade.GetOperationsByBankAccount(ctx, c.bankAccountID)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -215,8 +258,8 @@ func NewListOperationsByCategoryCommand(
 }
 
 // Execute выполняет команду получения списка операций по категории
-func (c *ListOperationsByCategoryCommand) Execute() error {
-	operations, err := c.facade.GetOperationsByCategory(c.categoryID)
+func (c *ListOperationsByCategoryCommand) Execute(ctx context.Context) error {
+	operations, err := c.facade.GetOperationsByCategory(ctx, c.categoryID)
 	if err != nil {
 		if c.errorCh != nil {
 			c.errorCh <- err
@@ -253,8 +296,8 @@ func NewDeleteOperationCommand(
 }
 
 // Execute выполняет команду удаления операции
-func (c *DeleteOperationCommand) Execute() error {
-	err := c.facade.DeleteOperation(c.id)
+func (c *DeleteOperationCommand) Execute(ctx context.Context) error {
+	err := c.facade.DeleteOperation(ctx, c.id)
 	if err != nil && c.errorCh != nil {
 		c.errorCh <- err
 	}
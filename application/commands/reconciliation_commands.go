@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"KPO1/application/facade"
+	"KPO1/domain/interfaces"
+	"KPO1/domain/models"
+	"context"
+)
+
+// ReconcileBalancesCommand представляет команду для сверки балансов счетов с их операциями
+type ReconcileBalancesCommand struct {
+	CommandBase
+	facade   *facade.ReconciliationFacade
+	resultCh chan []*models.BalanceDiscrepancy
+	errorCh  chan error
+}
+
+// NewReconcileBalancesCommand создаёт новую команду для сверки балансов счетов
+func NewReconcileBalancesCommand(
+	facade *facade.ReconciliationFacade,
+	resultCh chan []*models.BalanceDiscrepancy,
+	errorCh chan error,
+) interfaces.Command {
+	return &ReconcileBalancesCommand{
+		CommandBase: NewCommandBase("ReconcileBalances"),
+		facade:      facade,
+		resultCh:    resultCh,
+		errorCh:     errorCh,
+	}
+}
+
+// Execute выполняет команду сверки балансов счетов
+func (c *ReconcileBalancesCommand) Execute(ctx context.Context) error {
+	discrepancies, err := c.facade.FindDiscrepancies(ctx)
+	if err != nil {
+		if c.errorCh != nil {
+			c.errorCh <- err
+		}
+		return err
+	}
+
+	if c.resultCh != nil {
+		c.resultCh <- discrepancies
+	}
+	return nil
+}
+
+// TrustStoredBalanceCommand представляет команду для записи начального остатка счета
+type TrustStoredBalanceCommand struct {
+	CommandBase
+	facade        *facade.ReconciliationFacade
+	bankAccountID int
+	resultCh      chan *models.Operation
+	errorCh       chan error
+}
+
+// NewTrustStoredBalanceCommand создаёт новую команду для записи начального остатка счета
+func NewTrustStoredBalanceCommand(
+	facade *facade.ReconciliationFacade,
+	bankAccountID int,
+	resultCh chan *models.Operation,
+	errorCh chan error,
+) interfaces.Command {
+	return &TrustStoredBalanceCommand{
+		CommandBase:   NewCommandBase("TrustStoredBalance"),
+		facade:        facade,
+		bankAccountID: bankAccountID,
+		resultCh:      resultCh,
+		errorCh:       errorCh,
+	}
+}
+
+// Execute выполняет команду записи начального остатка счета
+func (c *TrustStoredBalanceCommand) Execute(ctx context.Context) error {
+	operation, err := c.facade.TrustStoredBalance(ctx, c.bankAccountID)
+	if err != nil {
+		if c.errorCh != nil {
+			c.errorCh <- err
+		}
+		return err
+	}
+
+	if c.resultCh != nil {
+		c.resultCh <- operation
+	}
+	return nil
+}
+
+// TrustOperationsCommand представляет команду для пересчета баланса счета по операциям
+type TrustOperationsCommand struct {
+	CommandBase
+	facade        *facade.ReconciliationFacade
+	bankAccountID int
+	resultCh      chan *models.BankAccount
+	errorCh       chan error
+}
+
+// NewTrustOperationsCommand создаёт новую команду для пересчета баланса счета по операциям
+func NewTrustOperationsCommand(
+	facade *facade.ReconciliationFacade,
+	bankAccountID int,
+	resultCh chan *models.BankAccount,
+	errorCh chan error,
+) interfaces.Command {
+	return &TrustOperationsCommand{
+		CommandBase:   NewCommandBase("TrustOperations"),
+		facade:        facade,
+		bankAccountID: bankAccountID,
+		resultCh:      resultCh,
+		errorCh:       errorCh,
+	}
+}
+
+// Execute выполняет команду пересчета баланса счета по операциям
+func (c *TrustOperationsCommand) Execute(ctx context.Context) error {
+	account, err := c.facade.TrustOperations(ctx, c.bankAccountID)
+	if err != nil {
+		if c.errorCh != nil {
+			c.errorCh <- err
+		}
+		return err
+	}
+
+	if c.resultCh != nil {
+		c.resultCh <- account
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"KPO1/domain/interfaces"
+	"context"
 	"fmt"
 	"time"
 )
@@ -19,11 +20,11 @@ func NewTimeMeasurementDecorator(cmd interfaces.Command) *TimeMeasurementDecorat
 }
 
 // Execute выполняет команду и измеряет время выполнения
-func (d *TimeMeasurementDecorator) Execute() error {
+func (d *TimeMeasurementDecorator) Execute(ctx context.Context) error {
 	start := time.Now()
 
 	// Выполняем оригинальную команду
-	err := d.wrappedCommand.Execute()
+	err := d.wrappedCommand.Execute(ctx)
 
 	// Вычисляем затраченное время
 	duration := time.Since(start)
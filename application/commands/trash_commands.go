@@ -0,0 +1,209 @@
+package commands
+
+import (
+	"KPO1/application/facade"
+	"KPO1/domain/interfaces"
+	"KPO1/domain/models"
+	"context"
+	"time"
+)
+
+// TrashContents содержимое корзины
+type TrashContents struct {
+	BankAccounts []*models.BankAccount
+	Categories   []*models.Category
+	Operations   []*models.Operation
+}
+
+// TrashEntity тип сущности в корзине
+type TrashEntity string
+
+const (
+	TrashBankAccount TrashEntity = "bank_account"
+	TrashCategory    TrashEntity = "category"
+	TrashOperation   TrashEntity = "operation"
+)
+
+// ListTrashCommand представляет команду для получения содержимого корзины
+type ListTrashCommand struct {
+	CommandBase
+	bankAccountFacade *facade.BankAccountFacade
+	categoryFacade    *facade.CategoryFacade
+	operationFacade   *facade.OperationFacade
+	resultCh          chan *TrashContents
+	errorCh           chan error
+}
+
+// NewListTrashCommand создаёт новую команду для получения содержимого корзины
+func NewListTrashCommand(
+	bankAccountFacade *facade.BankAccountFacade,
+	categoryFacade *facade.CategoryFacade,
+	operationFacade *facade.OperationFacade,
+	resultCh chan *TrashContents,
+	errorCh chan error,
+) interfaces.Command {
+	return &ListTrashCommand{
+		CommandBase:       NewCommandBase("ListTrash"),
+		bankAccountFacade: bankAccountFacade,
+		categoryFacade:    categoryFacade,
+		operationFacade:   operationFacade,
+		resultCh:          resultCh,
+		errorCh:           errorCh,
+	}
+}
+
+// Execute выполняет команду
+func (c *ListTrashCommand) Execute(ctx context.Context) error {
+	contents, err := c.list(ctx)
+	if err != nil {
+		if c.errorCh != nil {
+			c.errorCh <- err
+		}
+		return err
+	}
+
+	if c.resultCh != nil {
+		c.resultCh <- contents
+	}
+
+	return nil
+}
+
+// list собирает удаленные сущности всех типов
+func (c *ListTrashCommand) list(ctx context.Context) (*TrashContents, error) {
+	var contents TrashContents
+	var err error
+
+	if contents.BankAccounts, err = c.bankAccountFacade.GetDeletedBankAccounts(ctx); err != nil {
+		return nil, err
+	}
+	if contents.Categories, err = c.categoryFacade.GetDeletedCategories(ctx); err != nil {
+		return nil, err
+	}
+	if contents.Operations, err = c.operationFacade.GetDeletedOperations(ctx); err != nil {
+		return nil, err
+	}
+
+	return &contents, nil
+}
+
+// RestoreFromTrashCommand представляет команду для восстановления сущности из корзины
+type RestoreFromTrashCommand struct {
+	CommandBase
+	bankAccountFacade *facade.BankAccountFacade
+	categoryFacade    *facade.CategoryFacade
+	operationFacade   *facade.OperationFacade
+	entity            TrashEntity
+	id                int
+	errorCh           chan error
+}
+
+// NewRestoreFromTrashCommand создаёт новую команду для восстановления сущности из корзины
+func NewRestoreFromTrashCommand(
+	bankAccountFacade *facade.BankAccountFacade,
+	categoryFacade *facade.CategoryFacade,
+	operationFacade *facade.OperationFacade,
+	entity TrashEntity,
+	id int,
+	errorCh chan error,
+) interfaces.Command {
+	return &RestoreFromTrashCommand{
+		CommandBase:       NewCommandBase("RestoreFromTrash"),
+		bankAccountFacade: bankAccountFacade,
+		categoryFacade:    categoryFacade,
+		operationFacade:   operationFacade,
+		entity:            entity,
+		id:                id,
+		errorCh:           errorCh,
+	}
+}
+
+// Execute выполняет команду
+func (c *RestoreFromTrashCommand) Execute(ctx context.Context) error {
+	var err error
+	switch c.entity {
+	case TrashBankAccount:
+		err = c.bankAccountFacade.RestoreBankAccount(ctx, c.id)
+	case TrashCategory:
+		err = c.categoryFacade.RestoreCategory(ctx, c.id)
+	case TrashOperation:
+		err = c.operationFacade.RestoreOperation(ctx, c.id)
+	default:
+		err = &models.ValidationError{Field: "entity", Message: "Неизвестный тип сущности в корзине"}
+	}
+
+	if err != nil && c.errorCh != nil {
+		c.errorCh <- err
+	}
+
+	return err
+}
+
+// PurgeTrashCommand представляет команду для очистки корзины от старых записей
+type PurgeTrashCommand struct {
+	CommandBase
+	bankAccountFacade *facade.BankAccountFacade
+	categoryFacade    *facade.CategoryFacade
+	operationFacade   *facade.OperationFacade
+	olderThan         time.Duration
+	resultCh          chan int
+	errorCh           chan error
+}
+
+// NewPurgeTrashCommand создаёт новую команду для окончательного удаления записей,
+// пролежавших в корзине дольше olderThan
+func NewPurgeTrashCommand(
+	bankAccountFacade *facade.BankAccountFacade,
+	categoryFacade *facade.CategoryFacade,
+	operationFacade *facade.OperationFacade,
+	olderThan time.Duration,
+	resultCh chan int,
+	errorCh chan error,
+) interfaces.Command {
+	return &PurgeTrashCommand{
+		CommandBase:       NewCommandBase("PurgeTrash"),
+		bankAccountFacade: bankAccountFacade,
+		categoryFacade:    categoryFacade,
+		operationFacade:   operationFacade,
+		olderThan:         olderThan,
+		resultCh:          resultCh,
+		errorCh:           errorCh,
+	}
+}
+
+// Execute выполняет команду
+func (c *PurgeTrashCommand) Execute(ctx context.Context) error {
+	purged, err := c.purge(ctx)
+	if err != nil {
+		if c.errorCh != nil {
+			c.errorCh <- err
+		}
+		return err
+	}
+
+	if c.resultCh != nil {
+		c.resultCh <- purged
+	}
+
+	return nil
+}
+
+// purge очищает корзину от старых операций, категорий и счетов и возвращает число удаленных записей
+func (c *PurgeTrashCommand) purge(ctx context.Context) (int, error) {
+	operations, err := c.operationFacade.PurgeOperations(ctx, c.olderThan)
+	if err != nil {
+		return 0, err
+	}
+
+	categories, err := c.categoryFacade.PurgeCategories(ctx, c.olderThan)
+	if err != nil {
+		return operations, err
+	}
+
+	bankAccounts, err := c.bankAccountFacade.PurgeBankAccounts(ctx, c.olderThan)
+	if err != nil {
+		return operations + categories, err
+	}
+
+	return operations + categories + bankAccounts, nil
+}
@@ -4,6 +4,7 @@ import (
 	"KPO1/application/facade"
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
 	"time"
 )
 
@@ -12,6 +13,7 @@ type UpdateOperationCommand struct {
 	CommandBase
 	facade        *facade.OperationFacade
 	id            int
+	version       int
 	bankAccountID int
 	categoryID    int
 	amount        float64
@@ -26,6 +28,7 @@ type UpdateOperationCommand struct {
 func NewUpdateOperationCommand(
 	facade *facade.OperationFacade,
 	id int,
+	version int,
 	bankAccountID int,
 	categoryID int,
 	amount float64,
@@ -39,6 +42,7 @@ func NewUpdateOperationCommand(
 		CommandBase:   NewCommandBase("UpdateOperation"),
 		facade:        facade,
 		id:            id,
+		version:       version,
 		bankAccountID: bankAccountID,
 		categoryID:    categoryID,
 		amount:        amount,
@@ -51,9 +55,11 @@ func NewUpdateOperationCommand(
 }
 
 // Execute выполняет команду обновления операции
-func (c *UpdateOperationCommand) Execute() error {
+func (c *UpdateOperationCommand) Execute(ctx context.Context) error {
 	operation, err := c.facade.UpdateOperation(
+		ctx,
 		c.id,
+		c.version,
 		c.bankAccountID,
 		c.categoryID,
 		c.amount,
@@ -3,6 +3,7 @@ package facade
 import (
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
 	"fmt"
 	"time"
 )
@@ -20,29 +21,32 @@ func NewAnalyticsFacade(analyticsService interfaces.AnalyticsService) *Analytics
 }
 
 // GetIncomeExpenseDifference получает разницу между доходами и расходами за период
-func (f *AnalyticsFacade) GetIncomeExpenseDifference(start, end time.Time) (float64, error) {
+func (f *AnalyticsFacade) GetIncomeExpenseDifference(ctx context.Context, start, end time.Time) (float64, error) {
 	if start.After(end) {
-		return 0, fmt.Errorf("дата начала не может быть позже даты окончания")
+		return 0, &models.ValidationError{Field: "start", Message: "Дата начала не может быть позже даты окончания"}
 	}
 
-	return f.analyticsService.GetIncomeExpenseDifference(start, end)
+	return f.analyticsService.GetIncomeExpenseDifference(ctx, start, end)
 }
 
 // GetCategorySummary получает суммарные доходы/расходы по категориям за период
-func (f *AnalyticsFacade) GetCategorySummary(start, end time.Time) (map[*models.Category]float64, error) {
+func (f *AnalyticsFacade) GetCategorySummary(ctx context.Context, start, end time.Time) (map[*models.Category]float64, error) {
 	if start.After(end) {
-		return nil, fmt.Errorf("дата начала не может быть позже даты окончания")
+		return nil, &models.ValidationError{Field: "start", Message: "Дата начала не может быть позже даты окончания"}
 	}
 
-	return f.analyticsService.GetCategorySummary(start, end)
+	return f.analyticsService.GetCategorySummary(ctx, start, end)
 }
 
 // GetMonthlyDynamics получает месячную динамику доходов и расходов за год
-func (f *AnalyticsFacade) GetMonthlyDynamics(year int) (map[time.Month]map[models.OperationType]float64, error) {
+func (f *AnalyticsFacade) GetMonthlyDynamics(ctx context.Context, year int) (map[time.Month]map[models.OperationType]float64, error) {
 	currentYear := time.Now().Year()
 	if year < 2000 || year > currentYear+1 {
-		return nil, fmt.Errorf("некорректный год (должен быть в диапазоне от 2000 до %d)", currentYear+1)
+		return nil, &models.ValidationError{
+			Field:   "year",
+			Message: fmt.Sprintf("Некорректный год (должен быть в диапазоне от 2000 до %d)", currentYear+1),
+		}
 	}
 
-	return f.analyticsService.GetMonthlyDynamics(year)
+	return f.analyticsService.GetMonthlyDynamics(ctx, year)
 }
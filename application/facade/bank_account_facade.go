@@ -3,6 +3,8 @@ package facade
 import (
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
+	"time"
 )
 
 // BankAccountFacade представляет фасад для работы с банковскими счетами
@@ -18,56 +20,84 @@ func NewBankAccountFacade(bankAccountService interfaces.BankAccountService) *Ban
 }
 
 // CreateBankAccount создает новый банковский счёт
-func (f *BankAccountFacade) CreateBankAccount(name string) (*models.BankAccount, error) {
+func (f *BankAccountFacade) CreateBankAccount(ctx context.Context, name string) (*models.BankAccount, error) {
 	// Валидация входных данных
 	if name == "" {
-		return nil, &models.ValidationError{Message: "Название счета не может быть пустым"}
+		return nil, &models.ValidationError{Field: "name", Message: "Название счета не может быть пустым"}
 	}
 
-	return f.bankAccountService.CreateBankAccount(name)
+	return f.bankAccountService.CreateBankAccount(ctx, name)
 }
 
 // GetBankAccount получает банковский счёт по ID
-func (f *BankAccountFacade) GetBankAccount(id int) (*models.BankAccount, error) {
+func (f *BankAccountFacade) GetBankAccount(ctx context.Context, id int) (*models.BankAccount, error) {
 	if id <= 0 {
-		return nil, &models.ValidationError{Message: "ID счета должен быть положительным числом"}
+		return nil, &models.ValidationError{Field: "id", Message: "ID счета должен быть положительным числом"}
 	}
 
-	return f.bankAccountService.GetBankAccount(id)
+	return f.bankAccountService.GetBankAccount(ctx, id)
 }
 
 // GetAllBankAccounts получает все банковские счета
-func (f *BankAccountFacade) GetAllBankAccounts() ([]*models.BankAccount, error) {
-	return f.bankAccountService.GetAllBankAccounts()
+func (f *BankAccountFacade) GetAllBankAccounts(ctx context.Context) ([]*models.BankAccount, error) {
+	return f.bankAccountService.GetAllBankAccounts(ctx)
 }
 
-// UpdateBankAccount обновляет банковский счёт
-func (f *BankAccountFacade) UpdateBankAccount(id int, name string) (*models.BankAccount, error) {
+// UpdateBankAccount обновляет банковский счёт, полученный ранее в версии version.
+// Если счет успели изменить, возвращается *models.ConflictError (см. IsConflict).
+func (f *BankAccountFacade) UpdateBankAccount(ctx context.Context, id, version int, name string) (*models.BankAccount, error) {
 	if id <= 0 {
-		return nil, &models.ValidationError{Message: "ID счета должен быть положительным числом"}
+		return nil, &models.ValidationError{Field: "id", Message: "ID счета должен быть положительным числом"}
+	}
+
+	if version < 0 {
+		return nil, &models.ValidationError{Field: "version", Message: "Версия счета не может быть отрицательной"}
 	}
 
 	if name == "" {
-		return nil, &models.ValidationError{Message: "Название счета не может быть пустым"}
+		return nil, &models.ValidationError{Field: "name", Message: "Название счета не может быть пустым"}
 	}
 
-	return f.bankAccountService.UpdateBankAccount(id, name)
+	return f.bankAccountService.UpdateBankAccount(ctx, id, version, name)
 }
 
-// DeleteBankAccount удаляет банковский счёт
-func (f *BankAccountFacade) DeleteBankAccount(id int) error {
+// DeleteBankAccount перемещает банковский счёт в корзину
+func (f *BankAccountFacade) DeleteBankAccount(ctx context.Context, id int) error {
 	if id <= 0 {
-		return &models.ValidationError{Message: "ID счета должен быть положительным числом"}
+		return &models.ValidationError{Field: "id", Message: "ID счета должен быть положительным числом"}
 	}
 
-	return f.bankAccountService.DeleteBankAccount(id)
+	return f.bankAccountService.DeleteBankAccount(ctx, id)
 }
 
 // RecalculateBalance пересчитывает баланс счёта
-func (f *BankAccountFacade) RecalculateBalance(id int) (*models.BankAccount, error) {
+func (f *BankAccountFacade) RecalculateBalance(ctx context.Context, id int) (*models.BankAccount, error) {
 	if id <= 0 {
-		return nil, &models.ValidationError{Message: "ID счета должен быть положительным числом"}
+		return nil, &models.ValidationError{Field: "id", Message: "ID счета должен быть положительным числом"}
+	}
+
+	return f.bankAccountService.RecalculateBalance(ctx, id)
+}
+
+// GetDeletedBankAccounts получает банковские счета из корзины
+func (f *BankAccountFacade) GetDeletedBankAccounts(ctx context.Context) ([]*models.BankAccount, error) {
+	return f.bankAccountService.GetDeletedBankAccounts(ctx)
+}
+
+// RestoreBankAccount восстанавливает банковский счёт из корзины
+func (f *BankAccountFacade) RestoreBankAccount(ctx context.Context, id int) error {
+	if id <= 0 {
+		return &models.ValidationError{Field: "id", Message: "ID счета должен быть положительным числом"}
+	}
+
+	return f.bankAccountService.RestoreBankAccount(ctx, id)
+}
+
+// PurgeBankAccounts окончательно удаляет банковские счета, пролежавшие в корзине дольше olderThan
+func (f *BankAccountFacade) PurgeBankAccounts(ctx context.Context, olderThan time.Duration) (int, error) {
+	if olderThan < 0 {
+		return 0, &models.ValidationError{Field: "older_than", Message: "Срок хранения в корзине не может быть отрицательным"}
 	}
 
-	return f.bankAccountService.RecalculateBalance(id)
+	return f.bankAccountService.PurgeBankAccounts(ctx, time.Now().Add(-olderThan))
 }
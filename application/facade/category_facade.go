@@ -3,6 +3,8 @@ package facade
 import (
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
+	"time"
 )
 
 // CategoryFacade представляет фасад для работы с категориями
@@ -18,64 +20,92 @@ func NewCategoryFacade(categoryService interfaces.CategoryService) *CategoryFaca
 }
 
 // CreateCategory создает новую категорию
-func (f *CategoryFacade) CreateCategory(name string, opType models.OperationType) (*models.Category, error) {
+func (f *CategoryFacade) CreateCategory(ctx context.Context, name string, opType models.OperationType) (*models.Category, error) {
 	// Валидация входных данных
 	if name == "" {
-		return nil, &models.ValidationError{Message: "Название категории не может быть пустым"}
+		return nil, &models.ValidationError{Field: "name", Message: "Название категории не может быть пустым"}
 	}
 
 	if opType != models.Income && opType != models.Expense {
-		return nil, &models.ValidationError{Message: "Тип категории должен быть INCOME или EXPENSE"}
+		return nil, &models.ValidationError{Field: "type", Message: "Тип категории должен быть INCOME или EXPENSE"}
 	}
 
-	return f.categoryService.CreateCategory(name, opType)
+	return f.categoryService.CreateCategory(ctx, name, opType)
 }
 
 // GetCategory получает категорию по ID
-func (f *CategoryFacade) GetCategory(id int) (*models.Category, error) {
+func (f *CategoryFacade) GetCategory(ctx context.Context, id int) (*models.Category, error) {
 	if id <= 0 {
-		return nil, &models.ValidationError{Message: "ID категории должен быть положительным числом"}
+		return nil, &models.ValidationError{Field: "id", Message: "ID категории должен быть положительным числом"}
 	}
 
-	return f.categoryService.GetCategory(id)
+	return f.categoryService.GetCategory(ctx, id)
 }
 
 // GetAllCategories получает все категории
-func (f *CategoryFacade) GetAllCategories() ([]*models.Category, error) {
-	return f.categoryService.GetAllCategories()
+func (f *CategoryFacade) GetAllCategories(ctx context.Context) ([]*models.Category, error) {
+	return f.categoryService.GetAllCategories(ctx)
 }
 
 // GetCategoriesByType получает категории по типу операции
-func (f *CategoryFacade) GetCategoriesByType(opType models.OperationType) ([]*models.Category, error) {
+func (f *CategoryFacade) GetCategoriesByType(ctx context.Context, opType models.OperationType) ([]*models.Category, error) {
 	if opType != models.Income && opType != models.Expense {
-		return nil, &models.ValidationError{Message: "Тип категории должен быть INCOME или EXPENSE"}
+		return nil, &models.ValidationError{Field: "type", Message: "Тип категории должен быть INCOME или EXPENSE"}
 	}
 
-	return f.categoryService.GetCategoriesByType(opType)
+	return f.categoryService.GetCategoriesByType(ctx, opType)
 }
 
-// UpdateCategory обновляет категорию
-func (f *CategoryFacade) UpdateCategory(id int, name string, opType models.OperationType) (*models.Category, error) {
+// UpdateCategory обновляет категорию, полученную ранее в версии version.
+// Если категорию успели изменить, возвращается *models.ConflictError (см. IsConflict).
+func (f *CategoryFacade) UpdateCategory(ctx context.Context, id, version int, name string, opType models.OperationType) (*models.Category, error) {
 	if id <= 0 {
-		return nil, &models.ValidationError{Message: "ID категории должен быть положительным числом"}
+		return nil, &models.ValidationError{Field: "id", Message: "ID категории должен быть положительным числом"}
+	}
+
+	if version < 0 {
+		return nil, &models.ValidationError{Field: "version", Message: "Версия категории не может быть отрицательной"}
 	}
 
 	if name == "" {
-		return nil, &models.ValidationError{Message: "Название категории не может быть пустым"}
+		return nil, &models.ValidationError{Field: "name", Message: "Название категории не может быть пустым"}
 	}
 
 	if opType != models.Income && opType != models.Expense {
-		return nil, &models.ValidationError{Message: "Тип категории должен быть INCOME или EXPENSE"}
+		return nil, &models.ValidationError{Field: "type", Message: "Тип категории должен быть INCOME или EXPENSE"}
 	}
 
-	return f.categoryService.UpdateCategory(id, name, opType)
+	return f.categoryService.UpdateCategory(ctx, id, version, name, opType)
 }
 
-// DeleteCategory удаляет категорию
-func (f *CategoryFacade) DeleteCategory(id int) error {
+// DeleteCategory перемещает категорию в корзину
+func (f *CategoryFacade) DeleteCategory(ctx context.Context, id int) error {
 	if id <= 0 {
-		return &models.ValidationError{Message: "ID категории должен быть положительным числом"}
+		return &models.ValidationError{Field: "id", Message: "ID категории должен быть положительным числом"}
+	}
+
+	return f.categoryService.DeleteCategory(ctx, id)
+}
+
+// GetDeletedCategories получает категории из корзины
+func (f *CategoryFacade) GetDeletedCategories(ctx context.Context) ([]*models.Category, error) {
+	return f.categoryService.GetDeletedCategories(ctx)
+}
+
+// RestoreCategory восстанавливает категорию из корзины
+func (f *CategoryFacade) RestoreCategory(ctx context.Context, id int) error {
+	if id <= 0 {
+		return &models.ValidationError{Field: "id", Message: "ID категории должен быть положительным числом"}
+	}
+
+	return f.categoryService.RestoreCategory(ctx, id)
+}
+
+// PurgeCategories окончательно удаляет категории, пролежавшие в корзине дольше olderThan
+func (f *CategoryFacade) PurgeCategories(ctx context.Context, olderThan time.Duration) (int, error) {
+	if olderThan < 0 {
+		return 0, &models.ValidationError{Field: "older_than", Message: "Срок хранения в корзине не может быть отрицательным"}
 	}
 
-	return f.categoryService.DeleteCategory(id)
+	return f.categoryService.PurgeCategories(ctx, time.Now().Add(-olderThan))
 }
@@ -0,0 +1,31 @@
+package facade
+
+import (
+	"KPO1/domain/models"
+	"errors"
+)
+
+// IsConflict сообщает, что изменение отклонено, так как данные успели изменить.
+// В этом случае клиенту следует заново получить сущность и повторить изменение.
+func IsConflict(err error) bool {
+	return errors.Is(err, models.ErrConflict)
+}
+
+// IsNotFound сообщает, что сущность не найдена или находится в корзине
+func IsNotFound(err error) bool {
+	return errors.Is(err, models.ErrNotFound)
+}
+
+// IsInUse сообщает, что сущность нельзя удалить, пока на нее ссылаются другие сущности
+func IsInUse(err error) bool {
+	return errors.Is(err, models.ErrInUse)
+}
+
+// InvalidField возвращает поле, не прошедшее валидацию, если err - ошибка валидации
+func InvalidField(err error) (string, bool) {
+	var validation *models.ValidationError
+	if !errors.As(err, &validation) {
+		return "", false
+	}
+	return validation.Field, true
+}
@@ -3,6 +3,7 @@ package facade
 import (
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
 	"time"
 )
 
@@ -25,6 +26,7 @@ func NewOperationFacade(
 
 // CreateOperation создает новую операцию
 func (f *OperationFacade) CreateOperation(
+	ctx context.Context,
 	bankAccountID, categoryID int,
 	amount float64,
 	date time.Time,
@@ -32,25 +34,26 @@ func (f *OperationFacade) CreateOperation(
 ) (*models.Operation, error) {
 	// Валидация входных данных
 	if bankAccountID <= 0 {
-		return nil, &models.ValidationError{Message: "ID счета должен быть положительным числом"}
+		return nil, &models.ValidationError{Field: "bank_account_id", Message: "ID счета должен быть положительным числом"}
 	}
 
 	if categoryID <= 0 {
-		return nil, &models.ValidationError{Message: "ID категории должен быть положительным числом"}
+		return nil, &models.ValidationError{Field: "category_id", Message: "ID категории должен быть положительным числом"}
 	}
 
 	if amount <= 0 {
-		return nil, &models.ValidationError{Message: "Сумма операции должна быть положительным числом"}
+		return nil, &models.ValidationError{Field: "amount", Message: "Сумма операции должна быть положительным числом"}
 	}
 
 	// Определяем тип операции на основе категории
-	category, err := f.categoryService.GetCategory(categoryID)
+	category, err := f.categoryService.GetCategory(ctx, categoryID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Создаем операцию с типом, соответствующим категории
 	return f.operationService.CreateOperation(
+		ctx,
 		bankAccountID,
 		categoryID,
 		amount,
@@ -61,79 +64,97 @@ func (f *OperationFacade) CreateOperation(
 }
 
 // GetOperationDetails получает детальную информацию об операции
-func (f *OperationFacade) GetOperationDetails(id int) (*models.Operation, error) {
+func (f *OperationFacade) GetOperationDetails(ctx context.Context, id int) (*models.Operation, error) {
 	if id <= 0 {
-		return nil, &models.ValidationError{Message: "ID операции должен быть положительным числом"}
+		return nil, &models.ValidationError{Field: "id", Message: "ID операции должен быть положительным числом"}
 	}
 
-	return f.operationService.GetOperation(id)
+	return f.operationService.GetOperation(ctx, id)
 }
 
 // GetAllOperations получает список всех операций
-func (f *OperationFacade) GetAllOperations() ([]*models.Operation, error) {
-	return f.operationService.GetAllOperations()
+func (f *OperationFacade) GetAllOperations(ctx context.Context) ([]*models.Operation, error) {
+	return f.operationService.GetAllOperations(ctx)
 }
 
 // GetOperationsByBankAccount получает список операций по банковскому счёту
-func (f *OperationFacade) GetOperationsByBankAccount(bankAccountID int) ([]*models.Operation, error) {
+func (f *OperationFacade) GetOperationsByBankAccount(ctx context.Context, bankAccountID int) ([]*models.Operation, error) {
 	if bankAccountID <= 0 {
-		return nil, &models.ValidationError{Message: "ID счета должен быть положительным числом"}
+		return nil, &models.ValidationError{Field: "bank_account_id", Message: "ID счета должен быть положительным числом"}
 	}
 
-	return f.operationService.GetOperationsByBankAccount(bankAccountID)
+	return f.operationService.GetOperationsByBankAccount(ctx, bankAccountID)
 }
 
 // GetOperationsByCategory получает список операций по категории
-func (f *OperationFacade) GetOperationsByCategory(categoryID int) ([]*models.Operation, error) {
+func (f *OperationFacade) GetOperationsByCategory(ctx context.Context, categoryID int) ([]*models.Operation, error) {
 	if categoryID <= 0 {
-		return nil, &models.ValidationError{Message: "ID категории должен быть положительным числом"}
+		return nil, &models.ValidationError{Field: "category_id", Message: "ID категории должен быть положительным числом"}
 	}
 
-	return f.operationService.GetOperationsByCategory(categoryID)
+	return f.operationService.GetOperationsByCategory(ctx, categoryID)
 }
 
 // GetOperationsByDateRange получает список операций за период
-func (f *OperationFacade) GetOperationsByDateRange(start, end time.Time) ([]*models.Operation, error) {
+func (f *OperationFacade) GetOperationsByDateRange(ctx context.Context, start, end time.Time) ([]*models.Operation, error) {
 	if start.After(end) {
-		return nil, &models.ValidationError{Message: "Дата начала не может быть позже даты окончания"}
+		return nil, &models.ValidationError{Field: "start", Message: "Дата начала не может быть позже даты окончания"}
 	}
 
-	return f.operationService.GetOperationsByDateRange(start, end)
+	return f.operationService.GetOperationsByDateRange(ctx, start, end)
 }
 
-// UpdateOperation обновляет информацию об операции
+// FindOperations получает страницу операций, отобранных и упорядоченных по запросу.
+// Следующую страницу можно получить, передав в запросе NextCursor текущей.
+func (f *OperationFacade) FindOperations(ctx context.Context, query models.OperationQuery) (*models.OperationPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	return f.operationService.FindOperations(ctx, query)
+}
+
+// UpdateOperation обновляет информацию об операции, полученной ранее в версии version.
+// Если операцию успели изменить, возвращается *models.ConflictError (см. IsConflict).
 func (f *OperationFacade) UpdateOperation(
-	id, bankAccountID, categoryID int,
+	ctx context.Context,
+	id, version, bankAccountID, categoryID int,
 	amount float64,
 	date time.Time,
 	description string,
 ) (*models.Operation, error) {
 	// Валидация входных данных
 	if id <= 0 {
-		return nil, &models.ValidationError{Message: "ID операции должен быть положительным числом"}
+		return nil, &models.ValidationError{Field: "id", Message: "ID операции должен быть положительным числом"}
+	}
+
+	if version < 0 {
+		return nil, &models.ValidationError{Field: "version", Message: "Версия операции не может быть отрицательной"}
 	}
 
 	if bankAccountID <= 0 {
-		return nil, &models.ValidationError{Message: "ID счета должен быть положительным числом"}
+		return nil, &models.ValidationError{Field: "bank_account_id", Message: "ID счета должен быть положительным числом"}
 	}
 
 	if categoryID <= 0 {
-		return nil, &models.ValidationError{Message: "ID категории должен быть положительным числом"}
+		return nil, &models.ValidationError{Field: "category_id", Message: "ID категории должен быть положительным числом"}
 	}
 
 	if amount <= 0 {
-		return nil, &models.ValidationError{Message: "Сумма операции должна быть положительным числом"}
+		return nil, &models.ValidationError{Field: "amount", Message: "Сумма операции должна быть положительным числом"}
 	}
 
 	// Определяем тип операции на основе категории
-	category, err := f.categoryService.GetCategory(categoryID)
+	category, err := f.categoryService.GetCategory(ctx, categoryID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Обновляем операцию с типом, соответствующим категории
 	return f.operationService.UpdateOperation(
+		ctx,
 		id,
+		version,
 		bankAccountID,
 		categoryID,
 		amount,
@@ -143,11 +164,34 @@ func (f *OperationFacade) UpdateOperation(
 	)
 }
 
-// DeleteOperation удаляет операцию
-func (f *OperationFacade) DeleteOperation(id int) error {
+// DeleteOperation перемещает операцию в корзину
+func (f *OperationFacade) DeleteOperation(ctx context.Context, id int) error {
+	if id <= 0 {
+		return &models.ValidationError{Field: "id", Message: "ID операции должен быть положительным числом"}
+	}
+
+	return f.operationService.DeleteOperation(ctx, id)
+}
+
+// GetDeletedOperations получает операции из корзины
+func (f *OperationFacade) GetDeletedOperations(ctx context.Context) ([]*models.Operation, error) {
+	return f.operationService.GetDeletedOperations(ctx)
+}
+
+// RestoreOperation восстанавливает операцию из корзины и снова учитывает ее в балансе счета
+func (f *OperationFacade) RestoreOperation(ctx context.Context, id int) error {
 	if id <= 0 {
-		return &models.ValidationError{Message: "ID операции должен быть положительным числом"}
+		return &models.ValidationError{Field: "id", Message: "ID операции должен быть положительным числом"}
+	}
+
+	return f.operationService.RestoreOperation(ctx, id)
+}
+
+// PurgeOperations окончательно удаляет операции, пролежавшие в корзине дольше olderThan
+func (f *OperationFacade) PurgeOperations(ctx context.Context, olderThan time.Duration) (int, error) {
+	if olderThan < 0 {
+		return 0, &models.ValidationError{Field: "older_than", Message: "Срок хранения в корзине не может быть отрицательным"}
 	}
 
-	return f.operationService.DeleteOperation(id)
+	return f.operationService.PurgeOperations(ctx, time.Now().Add(-olderThan))
 }
@@ -0,0 +1,47 @@
+package facade
+
+import (
+	"KPO1/domain/interfaces"
+	"KPO1/domain/models"
+	"context"
+)
+
+// ReconciliationFacade представляет фасад для сверки балансов счетов с их операциями
+type ReconciliationFacade struct {
+	reconciliationService interfaces.ReconciliationService
+	bankAccountService    interfaces.BankAccountService
+}
+
+// NewReconciliationFacade создаёт новый фасад для сверки балансов счетов
+func NewReconciliationFacade(
+	reconciliationService interfaces.ReconciliationService,
+	bankAccountService interfaces.BankAccountService,
+) *ReconciliationFacade {
+	return &ReconciliationFacade{
+		reconciliationService: reconciliationService,
+		bankAccountService:    bankAccountService,
+	}
+}
+
+// FindDiscrepancies получает счета, баланс которых не совпадает с суммой операций
+func (f *ReconciliationFacade) FindDiscrepancies(ctx context.Context) ([]*models.BalanceDiscrepancy, error) {
+	return f.reconciliationService.FindDiscrepancies(ctx)
+}
+
+// TrustStoredBalance оставляет сохраненный баланс счета, записывая разницу операцией начального остатка
+func (f *ReconciliationFacade) TrustStoredBalance(ctx context.Context, bankAccountID int) (*models.Operation, error) {
+	if bankAccountID <= 0 {
+		return nil, &models.ValidationError{Field: "bank_account_id", Message: "ID счета должен быть положительным числом"}
+	}
+
+	return f.reconciliationService.RecordOpeningBalance(ctx, bankAccountID)
+}
+
+// TrustOperations пересчитывает баланс счета по его операциям
+func (f *ReconciliationFacade) TrustOperations(ctx context.Context, bankAccountID int) (*models.BankAccount, error) {
+	if bankAccountID <= 0 {
+		return nil, &models.ValidationError{Field: "bank_account_id", Message: "ID счета должен быть положительным числом"}
+	}
+
+	return f.bankAccountService.RecalculateBalance(ctx, bankAccountID)
+}
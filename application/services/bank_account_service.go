@@ -4,7 +4,7 @@ import (
 	"KPO1/domain/factory"
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
-	"errors"
+	"context"
 	"time"
 )
 
@@ -12,6 +12,7 @@ import (
 type BankAccountServiceImpl struct {
 	bankAccountRepo interfaces.BankAccountRepository
 	operationRepo   interfaces.OperationRepository
+	txManager       interfaces.TransactionManager
 	factory         *factory.BankAccountFactory
 }
 
@@ -19,23 +20,27 @@ type BankAccountServiceImpl struct {
 func NewBankAccountService(
 	bankAccountRepo interfaces.BankAccountRepository,
 	operationRepo interfaces.OperationRepository,
+	txManager interfaces.TransactionManager,
 	factory *factory.BankAccountFactory,
 ) interfaces.BankAccountService {
 	return &BankAccountServiceImpl{
 		bankAccountRepo: bankAccountRepo,
 		operationRepo:   operationRepo,
+		txManager:       txManager,
 		factory:         factory,
 	}
 }
 
 // CreateBankAccount создает новый банковский счёт
-func (s *BankAccountServiceImpl) CreateBankAccount(name string) (*models.BankAccount, error) {
-	account, err := s.factory.CreateBankAccount(name)
+func (s *BankAccountServiceImpl) CreateBankAccount(ctx context.Context, name string) (*models.BankAccount, error) {
+	account, err := s.factory.CreateBankAccount(ctx, name)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.bankAccountRepo.Save(account)
+	err = withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		return uow.BankAccounts().Save(ctx, account)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -44,8 +49,8 @@ func (s *BankAccountServiceImpl) CreateBankAccount(name string) (*models.BankAcc
 }
 
 // GetBankAccount получает банковский счёт по ID
-func (s *BankAccountServiceImpl) GetBankAccount(id int) (*models.BankAccount, error) {
-	account, err := s.bankAccountRepo.GetByID(id)
+func (s *BankAccountServiceImpl) GetBankAccount(ctx context.Context, id int) (*models.BankAccount, error) {
+	account, err := s.bankAccountRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -53,25 +58,31 @@ func (s *BankAccountServiceImpl) GetBankAccount(id int) (*models.BankAccount, er
 }
 
 // GetAllBankAccounts получает все банковские счета
-func (s *BankAccountServiceImpl) GetAllBankAccounts() ([]*models.BankAccount, error) {
-	return s.bankAccountRepo.GetAll()
+func (s *BankAccountServiceImpl) GetAllBankAccounts(ctx context.Context) ([]*models.BankAccount, error) {
+	return s.bankAccountRepo.GetAll(ctx)
 }
 
-// UpdateBankAccount обновляет банковский счёт
-func (s *BankAccountServiceImpl) UpdateBankAccount(id int, name string) (*models.BankAccount, error) {
-	account, err := s.bankAccountRepo.GetByID(id)
-	if err != nil {
-		return nil, err
-	}
+// UpdateBankAccount обновляет банковский счёт, прочитанный клиентом в версии version
+func (s *BankAccountServiceImpl) UpdateBankAccount(ctx context.Context, id, version int, name string) (*models.BankAccount, error) {
+	var account *models.BankAccount
+	err := withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		var err error
+		account, err = uow.BankAccounts().GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
 
-	account.Name = name
-	account.UpdatedAt = time.Now()
+		// Репозиторий отклонит обновление, если счет изменился после чтения клиентом
+		account.Version = version
+		account.Name = name
+		account.UpdatedAt = time.Now()
 
-	if err := account.Validate(); err != nil {
-		return nil, err
-	}
+		if err := account.Validate(); err != nil {
+			return err
+		}
 
-	err = s.bankAccountRepo.Update(account)
+		return uow.BankAccounts().Update(ctx, account)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -79,47 +90,73 @@ func (s *BankAccountServiceImpl) UpdateBankAccount(id int, name string) (*models
 	return account, nil
 }
 
-// DeleteBankAccount удаляет банковский счёт
-func (s *BankAccountServiceImpl) DeleteBankAccount(id int) error {
-	// Проверяем наличие операций по этому счету
-	operations, err := s.operationRepo.GetByBankAccountID(id)
-	if err != nil {
-		return err
-	}
+// DeleteBankAccount перемещает банковский счёт в корзину
+func (s *BankAccountServiceImpl) DeleteBankAccount(ctx context.Context, id int) error {
+	return withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		// Проверяем наличие операций по этому счету
+		operations, err := uow.Operations().GetByBankAccountID(ctx, id)
+		if err != nil {
+			return err
+		}
 
-	if len(operations) > 0 {
-		return errors.New("нельзя удалить счет, по которому есть операции")
-	}
+		if len(operations) > 0 {
+			return &models.InUseError{Entity: models.EntityBankAccount, ID: id}
+		}
+
+		return uow.BankAccounts().Delete(ctx, id)
+	})
+}
 
-	return s.bankAccountRepo.Delete(id)
+// GetDeletedBankAccounts получает банковские счета из корзины
+func (s *BankAccountServiceImpl) GetDeletedBankAccounts(ctx context.Context) ([]*models.BankAccount, error) {
+	return s.bankAccountRepo.GetDeleted(ctx)
+}
+
+// RestoreBankAccount восстанавливает банковский счёт из корзины
+func (s *BankAccountServiceImpl) RestoreBankAccount(ctx context.Context, id int) error {
+	return withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		return uow.BankAccounts().Restore(ctx, id)
+	})
+}
+
+// PurgeBankAccounts окончательно удаляет счета, перемещенные в корзину раньше deletedBefore.
+// Операции этих счетов, оставшиеся в корзине, больше нельзя будет восстановить.
+func (s *BankAccountServiceImpl) PurgeBankAccounts(ctx context.Context, deletedBefore time.Time) (int, error) {
+	var purged int
+	err := withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		var err error
+		purged, err = uow.BankAccounts().Purge(ctx, deletedBefore)
+		return err
+	})
+	return purged, err
 }
 
 // RecalculateBalance пересчитывает баланс счёта
-func (s *BankAccountServiceImpl) RecalculateBalance(id int) (*models.BankAccount, error) {
-	account, err := s.bankAccountRepo.GetByID(id)
-	if err != nil {
-		return nil, err
-	}
+func (s *BankAccountServiceImpl) RecalculateBalance(ctx context.Context, id int) (*models.BankAccount, error) {
+	var account *models.BankAccount
+	err := withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		var err error
+		account, err = uow.BankAccounts().GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
 
-	operations, err := s.operationRepo.GetByBankAccountID(id)
-	if err != nil {
-		return nil, err
-	}
+		operations, err := uow.Operations().GetByBankAccountID(ctx, id)
+		if err != nil {
+			return err
+		}
 
-	// Сбрасываем баланс и пересчитываем
-	account.Balance = 0
+		// Сбрасываем баланс и пересчитываем
+		account.Balance = 0
 
-	for _, op := range operations {
-		if op.Type == models.Income {
-			account.Balance += op.Amount
-		} else {
-			account.Balance -= op.Amount
+		for _, op := range operations {
+			account.Balance += balanceDelta(op.Type, op.Amount)
 		}
-	}
 
-	account.UpdatedAt = time.Now()
+		account.UpdatedAt = time.Now()
 
-	err = s.bankAccountRepo.Update(account)
+		return uow.BankAccounts().Update(ctx, account)
+	})
 	if err != nil {
 		return nil, err
 	}
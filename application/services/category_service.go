@@ -4,7 +4,7 @@ import (
 	"KPO1/domain/factory"
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
-	"errors"
+	"context"
 	"time"
 )
 
@@ -12,6 +12,7 @@ import (
 type CategoryServiceImpl struct {
 	categoryRepo  interfaces.CategoryRepository
 	operationRepo interfaces.OperationRepository
+	txManager     interfaces.TransactionManager
 	factory       *factory.CategoryFactory
 }
 
@@ -19,23 +20,27 @@ type CategoryServiceImpl struct {
 func NewCategoryService(
 	categoryRepo interfaces.CategoryRepository,
 	operationRepo interfaces.OperationRepository,
+	txManager interfaces.TransactionManager,
 	factory *factory.CategoryFactory,
 ) interfaces.CategoryService {
 	return &CategoryServiceImpl{
 		categoryRepo:  categoryRepo,
 		operationRepo: operationRepo,
+		txManager:     txManager,
 		factory:       factory,
 	}
 }
 
 // CreateCategory создает новую категорию
-func (s *CategoryServiceImpl) CreateCategory(name string, opType models.OperationType) (*models.Category, error) {
-	category, err := s.factory.CreateCategory(name, opType)
+func (s *CategoryServiceImpl) CreateCategory(ctx context.Context, name string, opType models.OperationType) (*models.Category, error) {
+	category, err := s.factory.CreateCategory(ctx, name, opType)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.categoryRepo.Save(category)
+	err = withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		return uow.Categories().Save(ctx, category)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -44,8 +49,8 @@ func (s *CategoryServiceImpl) CreateCategory(name string, opType models.Operatio
 }
 
 // GetCategory получает категорию по ID
-func (s *CategoryServiceImpl) GetCategory(id int) (*models.Category, error) {
-	category, err := s.categoryRepo.GetByID(id)
+func (s *CategoryServiceImpl) GetCategory(ctx context.Context, id int) (*models.Category, error) {
+	category, err := s.categoryRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -53,49 +58,80 @@ func (s *CategoryServiceImpl) GetCategory(id int) (*models.Category, error) {
 }
 
 // GetAllCategories получает все категории
-func (s *CategoryServiceImpl) GetAllCategories() ([]*models.Category, error) {
-	return s.categoryRepo.GetAll()
+func (s *CategoryServiceImpl) GetAllCategories(ctx context.Context) ([]*models.Category, error) {
+	return s.categoryRepo.GetAll(ctx)
 }
 
 // GetCategoriesByType получает категории по типу операции
-func (s *CategoryServiceImpl) GetCategoriesByType(opType models.OperationType) ([]*models.Category, error) {
-	return s.categoryRepo.GetByType(opType)
+func (s *CategoryServiceImpl) GetCategoriesByType(ctx context.Context, opType models.OperationType) ([]*models.Category, error) {
+	return s.categoryRepo.GetByType(ctx, opType)
 }
 
-// UpdateCategory обновляет категорию
-func (s *CategoryServiceImpl) UpdateCategory(id int, name string, opType models.OperationType) (*models.Category, error) {
-	category, err := s.categoryRepo.GetByID(id)
+// UpdateCategory обновляет категорию, прочитанную клиентом в версии version
+func (s *CategoryServiceImpl) UpdateCategory(ctx context.Context, id, version int, name string, opType models.OperationType) (*models.Category, error) {
+	var category *models.Category
+	err := withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		var err error
+		category, err = uow.Categories().GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// Репозиторий отклонит обновление, если категория изменилась после чтения клиентом
+		category.Version = version
+		category.Name = name
+		category.Type = opType
+		category.UpdatedAt = time.Now()
+
+		if err := category.Validate(); err != nil {
+			return err
+		}
+
+		return uow.Categories().Update(ctx, category)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	category.Name = name
-	category.Type = opType
-	category.UpdatedAt = time.Now()
+	return category, nil
+}
 
-	if err := category.Validate(); err != nil {
-		return nil, err
-	}
+// DeleteCategory перемещает категорию в корзину
+func (s *CategoryServiceImpl) DeleteCategory(ctx context.Context, id int) error {
+	return withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		// Проверяем наличие операций с этой категорией
+		operations, err := uow.Operations().GetByCategoryID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if len(operations) > 0 {
+			return &models.InUseError{Entity: models.EntityCategory, ID: id}
+		}
+
+		return uow.Categories().Delete(ctx, id)
+	})
+}
 
-	err = s.categoryRepo.Update(category)
-	if err != nil {
-		return nil, err
-	}
+// GetDeletedCategories получает категории из корзины
+func (s *CategoryServiceImpl) GetDeletedCategories(ctx context.Context) ([]*models.Category, error) {
+	return s.categoryRepo.GetDeleted(ctx)
+}
 
-	return category, nil
+// RestoreCategory восстанавливает категорию из корзины
+func (s *CategoryServiceImpl) RestoreCategory(ctx context.Context, id int) error {
+	return withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		return uow.Categories().Restore(ctx, id)
+	})
 }
 
-// DeleteCategory удаляет категорию
-func (s *CategoryServiceImpl) DeleteCategory(id int) error {
-	// Проверяем наличие операций с этой категорией
-	operations, err := s.operationRepo.GetByCategoryID(id)
-	if err != nil {
+// PurgeCategories окончательно удаляет категории, перемещенные в корзину раньше deletedBefore
+func (s *CategoryServiceImpl) PurgeCategories(ctx context.Context, deletedBefore time.Time) (int, error) {
+	var purged int
+	err := withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		var err error
+		purged, err = uow.Categories().Purge(ctx, deletedBefore)
 		return err
-	}
-
-	if len(operations) > 0 {
-		return errors.New("нельзя удалить категорию, по которой есть операции")
-	}
-
-	return s.categoryRepo.Delete(id)
+	})
+	return purged, err
 }
@@ -4,6 +4,7 @@ import (
 	"KPO1/domain/factory"
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
 	"time"
 )
 
@@ -12,6 +13,7 @@ type OperationServiceImpl struct {
 	operationRepo   interfaces.OperationRepository
 	bankAccountRepo interfaces.BankAccountRepository
 	categoryRepo    interfaces.CategoryRepository
+	txManager       interfaces.TransactionManager
 	factory         *factory.OperationFactory
 }
 
@@ -20,62 +22,62 @@ func NewOperationService(
 	operationRepo interfaces.OperationRepository,
 	bankAccountRepo interfaces.BankAccountRepository,
 	categoryRepo interfaces.CategoryRepository,
+	txManager interfaces.TransactionManager,
 	factory *factory.OperationFactory,
 ) interfaces.OperationService {
 	return &OperationServiceImpl{
 		operationRepo:   operationRepo,
 		bankAccountRepo: bankAccountRepo,
 		categoryRepo:    categoryRepo,
+		txManager:       txManager,
 		factory:         factory,
 	}
 }
 
-// CreateOperation создает новую операцию
+// CreateOperation создает новую операцию и изменяет баланс счета в одной транзакции
 func (s *OperationServiceImpl) CreateOperation(
+	ctx context.Context,
 	bankAccountID, categoryID int,
 	amount float64,
 	opType models.OperationType,
 	date time.Time,
 	description string,
 ) (*models.Operation, error) {
-	// Проверяем наличие счета
-	account, err := s.bankAccountRepo.GetByID(bankAccountID)
+	// Создаем операцию до начала транзакции: ID выдается из общего счетчика хранилища
+	operation, err := s.factory.CreateOperation(ctx, bankAccountID, categoryID, amount, opType, date, description)
 	if err != nil {
 		return nil, err
 	}
 
-	// Проверяем наличие категории
-	category, err := s.categoryRepo.GetByID(categoryID)
-	if err != nil {
-		return nil, err
-	}
+	err = withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		// Проверяем наличие счета
+		account, err := uow.BankAccounts().GetByID(ctx, bankAccountID)
+		if err != nil {
+			return err
+		}
 
-	// Проверяем соответствие типа категории и типа операции
-	if category.Type != opType {
-		return nil, &models.ValidationError{Message: "Тип категории не соответствует типу операции"}
-	}
+		// Проверяем наличие категории
+		category, err := uow.Categories().GetByID(ctx, categoryID)
+		if err != nil {
+			return err
+		}
 
-	// Создаем операцию
-	operation, err := s.factory.CreateOperation(bankAccountID, categoryID, amount, opType, date, description)
-	if err != nil {
-		return nil, err
-	}
+		// Проверяем соответствие типа категории и типа операции
+		if category.Type != opType {
+			return &models.ValidationError{Field: "category_id", Message: "Тип категории не соответствует типу операции"}
+		}
 
-	// Сохраняем операцию
-	err = s.operationRepo.Save(operation)
-	if err != nil {
-		return nil, err
-	}
+		// Сохраняем операцию
+		if err := uow.Operations().Save(ctx, operation); err != nil {
+			return err
+		}
 
-	// Обновляем баланс счета
-	if opType == models.Income {
-		account.Balance += amount
-	} else {
-		account.Balance -= amount
-	}
-	account.UpdatedAt = time.Now()
+		// Обновляем баланс счета
+		account.Balance += balanceDelta(opType, amount)
+		account.UpdatedAt = time.Now()
 
-	err = s.bankAccountRepo.Update(account)
+		return uow.BankAccounts().Update(ctx, account)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -84,8 +86,8 @@ func (s *OperationServiceImpl) CreateOperation(
 }
 
 // GetOperation получает операцию по ID
-func (s *OperationServiceImpl) GetOperation(id int) (*models.Operation, error) {
-	operation, err := s.operationRepo.GetByID(id)
+func (s *OperationServiceImpl) GetOperation(ctx context.Context, id int) (*models.Operation, error) {
+	operation, err := s.operationRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -93,140 +95,190 @@ func (s *OperationServiceImpl) GetOperation(id int) (*models.Operation, error) {
 }
 
 // GetAllOperations получает все операции
-func (s *OperationServiceImpl) GetAllOperations() ([]*models.Operation, error) {
-	return s.operationRepo.GetAll()
+func (s *OperationServiceImpl) GetAllOperations(ctx context.Context) ([]*models.Operation, error) {
+	return s.operationRepo.GetAll(ctx)
 }
 
 // GetOperationsByBankAccount получает операции по счету
-func (s *OperationServiceImpl) GetOperationsByBankAccount(bankAccountID int) ([]*models.Operation, error) {
-	return s.operationRepo.GetByBankAccountID(bankAccountID)
+func (s *OperationServiceImpl) GetOperationsByBankAccount(ctx context.Context, bankAccountID int) ([]*models.Operation, error) {
+	return s.operationRepo.GetByBankAccountID(ctx, bankAccountID)
 }
 
 // GetOperationsByCategory получает операции по категории
-func (s *OperationServiceImpl) GetOperationsByCategory(categoryID int) ([]*models.Operation, error) {
-	return s.operationRepo.GetByCategoryID(categoryID)
+func (s *OperationServiceImpl) GetOperationsByCategory(ctx context.Context, categoryID int) ([]*models.Operation, error) {
+	return s.operationRepo.GetByCategoryID(ctx, categoryID)
 }
 
 // GetOperationsByDateRange получает операции в указанном диапазоне дат
-func (s *OperationServiceImpl) GetOperationsByDateRange(start, end time.Time) ([]*models.Operation, error) {
-	return s.operationRepo.GetByDateRange(start, end)
+func (s *OperationServiceImpl) GetOperationsByDateRange(ctx context.Context, start, end time.Time) ([]*models.Operation, error) {
+	return s.operationRepo.GetByDateRange(ctx, start, end)
 }
 
-// UpdateOperation обновляет операцию
+// FindOperations получает страницу операций по запросу с фильтром, сортировкой и пагинацией
+func (s *OperationServiceImpl) FindOperations(ctx context.Context, query models.OperationQuery) (*models.OperationPage, error) {
+	return s.operationRepo.Find(ctx, query)
+}
+
+// UpdateOperation обновляет операцию, прочитанную клиентом в версии version,
+// и балансы затронутых счетов в одной транзакции.
+// Изменения применяются к копиям и сохраняются только после всех проверок.
 func (s *OperationServiceImpl) UpdateOperation(
-	id, bankAccountID, categoryID int,
+	ctx context.Context,
+	id, version, bankAccountID, categoryID int,
 	amount float64,
 	opType models.OperationType,
 	date time.Time,
 	description string,
 ) (*models.Operation, error) {
-	// Получаем старую операцию
-	oldOperation, err := s.operationRepo.GetByID(id)
-	if err != nil {
-		return nil, err
-	}
+	var updated models.Operation
+	err := withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		// Получаем старую операцию
+		oldOperation, err := uow.Operations().GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
 
-	// Получаем старый банковский счет и откатываем баланс
-	oldAccount, err := s.bankAccountRepo.GetByID(oldOperation.BankAccountID)
-	if err != nil {
-		return nil, err
-	}
+		// Проверяем новую категорию
+		category, err := uow.Categories().GetByID(ctx, categoryID)
+		if err != nil {
+			return err
+		}
 
-	if oldOperation.Type == models.Income {
-		oldAccount.Balance -= oldOperation.Amount
-	} else {
-		oldAccount.Balance += oldOperation.Amount
-	}
+		// Проверяем соответствие типа категории и типа операции
+		if category.Type != opType {
+			return &models.ValidationError{Field: "category_id", Message: "Тип категории не соответствует типу операции"}
+		}
+
+		// Готовим новую версию операции
+		updated = *oldOperation
+		updated.Version = version
+		updated.BankAccountID = bankAccountID
+		updated.CategoryID = categoryID
+		updated.Amount = amount
+		updated.Type = opType
+		updated.Date = date
+		updated.Description = description
+
+		if err := updated.Validate(); err != nil {
+			return err
+		}
 
-	// Проверяем новый счет
-	var newAccount *models.BankAccount
-	if oldOperation.BankAccountID != bankAccountID {
-		newAccount, err = s.bankAccountRepo.GetByID(bankAccountID)
+		// Получаем старый и новый счета
+		oldAccount, err := uow.BankAccounts().GetByID(ctx, oldOperation.BankAccountID)
 		if err != nil {
-			return nil, err
+			return err
 		}
-	} else {
-		newAccount = oldAccount
-	}
 
-	// Проверяем новую категорию
-	category, err := s.categoryRepo.GetByID(categoryID)
-	if err != nil {
-		return nil, err
-	}
+		newAccount := oldAccount
+		if oldOperation.BankAccountID != bankAccountID {
+			newAccount, err = uow.BankAccounts().GetByID(ctx, bankAccountID)
+			if err != nil {
+				return err
+			}
+		}
 
-	// Проверяем соответствие типа категории и типа операции
-	if category.Type != opType {
-		return nil, &models.ValidationError{Message: "Тип категории не соответствует типу операции"}
-	}
+		// Откатываем старую операцию и применяем новую
+		now := time.Now()
+		oldAccount.Balance -= balanceDelta(oldOperation.Type, oldOperation.Amount)
+		oldAccount.UpdatedAt = now
+		newAccount.Balance += balanceDelta(opType, amount)
+		newAccount.UpdatedAt = now
 
-	// Обновляем операцию
-	oldOperation.BankAccountID = bankAccountID
-	oldOperation.CategoryID = categoryID
-	oldOperation.Amount = amount
-	oldOperation.Type = opType
-	oldOperation.Date = date
-	oldOperation.Description = description
-
-	// Обновляем новый баланс счета
-	if opType == models.Income {
-		newAccount.Balance += amount
-	} else {
-		newAccount.Balance -= amount
-	}
-	newAccount.UpdatedAt = time.Now()
+		// Сохраняем изменения
+		if err := uow.Operations().Update(ctx, &updated); err != nil {
+			return err
+		}
+
+		if err := uow.BankAccounts().Update(ctx, oldAccount); err != nil {
+			return err
+		}
+
+		if newAccount != oldAccount {
+			return uow.BankAccounts().Update(ctx, newAccount)
+		}
 
-	// Сохраняем изменения
-	err = s.operationRepo.Update(oldOperation)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Обновляем старый счет, если нужно
-	if oldOperation.BankAccountID != bankAccountID {
-		err = s.bankAccountRepo.Update(oldAccount)
+	return &updated, nil
+}
+
+// DeleteOperation перемещает операцию в корзину и откатывает ее влияние на баланс в одной транзакции
+func (s *OperationServiceImpl) DeleteOperation(ctx context.Context, id int) error {
+	return withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		// Получаем операцию
+		operation, err := uow.Operations().GetByID(ctx, id)
 		if err != nil {
-			return nil, err
+			return err
 		}
-	}
 
-	// Обновляем новый счет
-	err = s.bankAccountRepo.Update(newAccount)
-	if err != nil {
-		return nil, err
-	}
+		// Получаем банковский счет
+		account, err := uow.BankAccounts().GetByID(ctx, operation.BankAccountID)
+		if err != nil {
+			return err
+		}
+
+		// Обновляем баланс счета
+		account.Balance -= balanceDelta(operation.Type, operation.Amount)
+		account.UpdatedAt = time.Now()
 
-	return oldOperation, nil
+		// Перемещаем операцию в корзину
+		if err := uow.Operations().Delete(ctx, id); err != nil {
+			return err
+		}
+
+		// Обновляем счет
+		return uow.BankAccounts().Update(ctx, account)
+	})
 }
 
-// DeleteOperation удаляет операцию
-func (s *OperationServiceImpl) DeleteOperation(id int) error {
-	// Получаем операцию
-	operation, err := s.operationRepo.GetByID(id)
-	if err != nil {
-		return err
-	}
+// GetDeletedOperations получает операции из корзины
+func (s *OperationServiceImpl) GetDeletedOperations(ctx context.Context) ([]*models.Operation, error) {
+	return s.operationRepo.GetDeleted(ctx)
+}
 
-	// Получаем банковский счет
-	account, err := s.bankAccountRepo.GetByID(operation.BankAccountID)
-	if err != nil {
-		return err
-	}
+// RestoreOperation восстанавливает операцию из корзины и снова применяет ее к балансу счета
+// в одной транзакции. Счет и категория операции не должны находиться в корзине.
+func (s *OperationServiceImpl) RestoreOperation(ctx context.Context, id int) error {
+	return withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		if err := uow.Operations().Restore(ctx, id); err != nil {
+			return err
+		}
 
-	// Обновляем баланс счета
-	if operation.Type == models.Income {
-		account.Balance -= operation.Amount
-	} else {
-		account.Balance += operation.Amount
-	}
-	account.UpdatedAt = time.Now()
+		operation, err := uow.Operations().GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
 
-	// Удаляем операцию
-	err = s.operationRepo.Delete(id)
-	if err != nil {
-		return err
-	}
+		// Проверяем, что категория операции не удалена
+		if _, err := uow.Categories().GetByID(ctx, operation.CategoryID); err != nil {
+			return err
+		}
+
+		account, err := uow.BankAccounts().GetByID(ctx, operation.BankAccountID)
+		if err != nil {
+			return err
+		}
 
-	// Обновляем счет
-	return s.bankAccountRepo.Update(account)
+		// Возвращаем влияние операции на баланс счета
+		account.Balance += balanceDelta(operation.Type, operation.Amount)
+		account.UpdatedAt = time.Now()
+
+		return uow.BankAccounts().Update(ctx, account)
+	})
+}
+
+// PurgeOperations окончательно удаляет операции, перемещенные в корзину раньше deletedBefore.
+// Баланс счетов не меняется: он уже не учитывает операции из корзины.
+func (s *OperationServiceImpl) PurgeOperations(ctx context.Context, deletedBefore time.Time) (int, error) {
+	var purged int
+	err := withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		var err error
+		purged, err = uow.Operations().Purge(ctx, deletedBefore)
+		return err
+	})
+	return purged, err
 }
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"KPO1/domain/factory"
+	"KPO1/domain/interfaces"
+	"KPO1/domain/models"
+	"context"
+	"math"
+	"time"
+)
+
+const (
+	// openingBalanceCategory название категории для операций начального остатка
+	openingBalanceCategory = "Начальный остаток"
+	// openingBalanceDescription описание операции начального остатка
+	openingBalanceDescription = "Начальный остаток счета"
+)
+
+// ReconciliationServiceImpl реализация сервиса сверки балансов счетов
+type ReconciliationServiceImpl struct {
+	bankAccountRepo  interfaces.BankAccountRepository
+	categoryRepo     interfaces.CategoryRepository
+	operationRepo    interfaces.OperationRepository
+	txManager        interfaces.TransactionManager
+	categoryFactory  *factory.CategoryFactory
+	operationFactory *factory.OperationFactory
+}
+
+// NewReconciliationService создаёт новый сервис сверки балансов счетов
+func NewReconciliationService(
+	bankAccountRepo interfaces.BankAccountRepository,
+	categoryRepo interfaces.CategoryRepository,
+	operationRepo interfaces.OperationRepository,
+	txManager interfaces.TransactionManager,
+	categoryFactory *factory.CategoryFactory,
+	operationFactory *factory.OperationFactory,
+) interfaces.ReconciliationService {
+	return &ReconciliationServiceImpl{
+		bankAccountRepo:  bankAccountRepo,
+		categoryRepo:     categoryRepo,
+		operationRepo:    operationRepo,
+		txManager:        txManager,
+		categoryFactory:  categoryFactory,
+		operationFactory: operationFactory,
+	}
+}
+
+// FindDiscrepancies возвращает счета, баланс которых не совпадает с суммой операций
+func (s *ReconciliationServiceImpl) FindDiscrepancies(ctx context.Context) ([]*models.BalanceDiscrepancy, error) {
+	accounts, err := s.bankAccountRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var discrepancies []*models.BalanceDiscrepancy
+	for _, account := range accounts {
+		operations, err := s.operationRepo.GetByBankAccountID(ctx, account.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		discrepancy := discrepancyOf(account, operations)
+		if models.BalancesDiffer(discrepancy.StoredBalance, discrepancy.OperationsBalance) {
+			discrepancies = append(discrepancies, discrepancy)
+		}
+	}
+
+	return discrepancies, nil
+}
+
+// RecordOpeningBalance принимает сохраненный баланс счета за верный и записывает
+// разницу с операциями операцией начального остатка на дату первой операции.
+// Баланс счета не меняется, после записи он совпадает с суммой операций.
+func (s *ReconciliationServiceImpl) RecordOpeningBalance(ctx context.Context, bankAccountID int) (*models.Operation, error) {
+	account, err := s.bankAccountRepo.GetByID(ctx, bankAccountID)
+	if err != nil {
+		return nil, err
+	}
+	operations, err := s.operationRepo.GetByBankAccountID(ctx, bankAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	discrepancy := discrepancyOf(account, operations)
+	difference := discrepancy.Difference()
+	if !models.BalancesDiffer(discrepancy.StoredBalance, discrepancy.OperationsBalance) {
+		return nil, &models.ValidationError{Field: "bank_account_id", Message: "Баланс счета совпадает с суммой операций"}
+	}
+
+	opType := models.Income
+	if difference < 0 {
+		opType = models.Expense
+	}
+
+	// Категорию и операцию создаем до начала транзакции: ID выдается из общего счетчика хранилища
+	category, created, err := s.openingBalanceCategory(ctx, opType)
+	if err != nil {
+		return nil, err
+	}
+	operation, err := s.operationFactory.CreateOperation(
+		ctx,
+		bankAccountID,
+		category.ID,
+		math.Abs(difference),
+		opType,
+		openingBalanceDate(account, operations),
+		openingBalanceDescription,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = withTransaction(ctx, s.txManager, func(uow interfaces.UnitOfWork) error {
+		// Проверяем, что за время подготовки баланс и операции счета не изменились
+		account, err := uow.BankAccounts().GetByID(ctx, bankAccountID)
+		if err != nil {
+			return err
+		}
+		operations, err := uow.Operations().GetByBankAccountID(ctx, bankAccountID)
+		if err != nil {
+			return err
+		}
+		if models.BalancesDiffer(discrepancyOf(account, operations).Difference(), difference) {
+			return &models.ValidationError{Message: "Баланс или операции счета изменились во время сверки, повторите сверку"}
+		}
+
+		if created {
+			if err := uow.Categories().Save(ctx, category); err != nil {
+				return err
+			}
+		}
+		return uow.Operations().Save(ctx, operation)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return operation, nil
+}
+
+// openingBalanceCategory находит категорию начального остатка нужного типа или создает новую.
+// Флаг created сообщает, что категорию еще нужно сохранить.
+func (s *ReconciliationServiceImpl) openingBalanceCategory(ctx context.Context, opType models.OperationType) (*models.Category, bool, error) {
+	categories, err := s.categoryRepo.GetByType(ctx, opType)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, category := range categories {
+		if category.Name == openingBalanceCategory {
+			return category, false, nil
+		}
+	}
+
+	category, err := s.categoryFactory.CreateCategory(ctx, openingBalanceCategory, opType)
+	if err != nil {
+		return nil, false, err
+	}
+	return category, true, nil
+}
+
+// discrepancyOf сравнивает сохраненный баланс счета с суммой его операций
+func discrepancyOf(account *models.BankAccount, operations []*models.Operation) *models.BalanceDiscrepancy {
+	discrepancy := &models.BalanceDiscrepancy{
+		BankAccountID: account.ID,
+		AccountName:   account.Name,
+		StoredBalance: account.Balance,
+	}
+	for _, op := range operations {
+		discrepancy.OperationsBalance += balanceDelta(op.Type, op.Amount)
+	}
+	return discrepancy
+}
+
+// openingBalanceDate возвращает дату первой операции счета, а без операций - дату создания счета
+func openingBalanceDate(account *models.BankAccount, operations []*models.Operation) time.Time {
+	var date time.Time
+	for _, op := range operations {
+		if date.IsZero() || op.Date.Before(date) {
+			date = op.Date
+		}
+	}
+	if date.IsZero() {
+		date = account.CreatedAt
+	}
+	if date.IsZero() {
+		date = time.Now()
+	}
+	return date
+}
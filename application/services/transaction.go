@@ -0,0 +1,34 @@
+package services
+
+import (
+	"KPO1/domain/interfaces"
+	"KPO1/domain/models"
+	"context"
+	"fmt"
+)
+
+// withTransaction выполняет fn в транзакции: фиксирует ее при успехе и откатывает при ошибке.
+// Все изменения, сделанные через репозитории uow, применяются вместе или не применяются вовсе.
+func withTransaction(ctx context.Context, txManager interfaces.TransactionManager, fn func(uow interfaces.UnitOfWork) error) error {
+	tx, err := txManager.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (ошибка отката транзакции: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// balanceDelta возвращает изменение баланса счета от операции
+func balanceDelta(opType models.OperationType, amount float64) float64 {
+	if opType == models.Income {
+		return amount
+	}
+	return -amount
+}
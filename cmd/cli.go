@@ -0,0 +1,576 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"KPO1/application/commands"
+	"KPO1/di"
+	"KPO1/domain/interfaces"
+	"KPO1/infrastructure/importexport"
+)
+
+// runCLI выполняет команду командной строки: formats, export, import, statement, statement-export, profiles, xlsx,
+// backup или verify.
+// Форматы берутся из реестра кодеков, поэтому новый формат появляется здесь без изменений.
+func runCLI(ctx context.Context, container *di.Container, args []string) error {
+	switch args[0] {
+	case "formats":
+		printFormats(os.Stdout)
+		return nil
+	case "export":
+		return runExport(ctx, container, args[1:])
+	case "import":
+		return runImport(ctx, container, args[1:])
+	case "statement":
+		return runStatement(ctx, container, args[1:])
+	case "statement-export":
+		return runStatementExport(ctx, container, args[1:])
+	case "profiles":
+		return runProfiles(container, args[1:])
+	case "xlsx":
+		return runWorkbook(ctx, container, args[1:])
+	case "backup":
+		return runBackup(ctx, container, args[1:])
+	case "verify":
+		return runVerify(ctx, args[1:])
+	default:
+		return fmt.Errorf("неизвестная команда %q, ожидается formats, export, import, statement, statement-export, profiles, xlsx, backup или verify", args[0])
+	}
+}
+
+// runExport экспортирует данные в директорию или одну сущность в stdout
+func runExport(ctx context.Context, container *di.Container, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", string(importexport.CSV), "формат экспорта: "+formatList((*importexport.Codec).CanExport))
+	dir := flags.String("dir", "", "директория для файлов экспорта")
+	entity := flags.String("entity", "", "сущность для записи в stdout вместо директории: accounts, categories или operations")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if _, err := importexport.LookupCodec(importexport.FileFormat(*format)); err != nil {
+		return err
+	}
+
+	errorCh := make(chan error, 1)
+	var cmd interfaces.Command
+	switch {
+	case *entity != "":
+		streams, err := exportStreams(*entity, os.Stdout)
+		if err != nil {
+			return err
+		}
+		cmd = commands.NewExportStreamCommand(
+			importexport.FileFormat(*format),
+			container.GetBankAccountRepository(),
+			container.GetCategoryRepository(),
+			container.GetOperationRepository(),
+			streams,
+			errorCh,
+		)
+	case *dir != "":
+		cmd = commands.NewExportCommand(
+			importexport.FileFormat(*format),
+			container.GetBankAccountRepository(),
+			container.GetCategoryRepository(),
+			container.GetOperationRepository(),
+			*dir,
+			errorCh,
+		)
+	default:
+		return fmt.Errorf("укажите -dir или -entity")
+	}
+
+	return cmd.Execute(ctx)
+}
+
+// runImport импортирует данные из директории, резервной копии или одну сущность из stdin
+func runImport(ctx context.Context, container *di.Container, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", string(importexport.CSV), "формат импорта: "+formatList((*importexport.Codec).CanImport))
+	dir := flags.String("dir", "", "директория с файлами импорта")
+	entity := flags.String("entity", "", "сущность для чтения из stdin вместо директории: accounts, categories или operations")
+	bundle := flags.String("bundle", "", "архив резервной копии вместо директории, - для чтения из stdin; формат берется из манифеста")
+	kindName := flags.String("kind", string(importexport.BundleZip), "вид архива из stdin: zip или tar.gz")
+	strategyName := flags.String("strategy", string(importexport.MergeSkip), "при совпадении ID: overwrite, skip или remap")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if _, err := importexport.LookupCodec(importexport.FileFormat(*format)); err != nil {
+		return err
+	}
+	strategy, err := importexport.ParseMergeStrategy(*strategyName)
+	if err != nil {
+		return err
+	}
+
+	resultCh := make(chan *importexport.ImportSummary, 1)
+	errorCh := make(chan error, 1)
+	var cmd interfaces.Command
+	switch {
+	case *bundle == "-":
+		kind, err := importexport.ParseBundleKind(*kindName)
+		if err != nil {
+			return err
+		}
+		cmd = commands.NewRestoreBackupStreamCommand(
+			container.GetTransactionManager(),
+			os.Stdin,
+			kind,
+			strategy,
+			resultCh,
+			errorCh,
+		)
+	case *bundle != "":
+		cmd = commands.NewRestoreBackupCommand(
+			container.GetTransactionManager(),
+			*bundle,
+			strategy,
+			resultCh,
+			errorCh,
+		)
+	case *entity != "":
+		streams, err := importStreams(*entity, os.Stdin)
+		if err != nil {
+			return err
+		}
+		cmd = commands.NewImportStreamCommand(
+			importexport.FileFormat(*format),
+			container.GetTransactionManager(),
+			streams,
+			strategy,
+			resultCh,
+			errorCh,
+		)
+	case *dir != "":
+		cmd = commands.NewImportCommand(
+			importexport.FileFormat(*format),
+			container.GetTransactionManager(),
+			*dir,
+			strategy,
+			resultCh,
+			errorCh,
+		)
+	default:
+		return fmt.Errorf("укажите -dir, -bundle или -entity")
+	}
+
+	if err := cmd.Execute(ctx); err != nil {
+		printProblems(err)
+		return err
+	}
+
+	summary := <-resultCh
+	for _, entity := range []struct {
+		title   string
+		summary importexport.EntityImportSummary
+	}{
+		{"Счета", summary.BankAccounts},
+		{"Категории", summary.Categories},
+		{"Операции", summary.Operations},
+	} {
+		fmt.Fprintf(os.Stderr, "%s: создано %d, заменено %d, пропущено %d, перенесено на новые ID %d\n",
+			entity.title, entity.summary.Created, entity.summary.Overwritten, entity.summary.Skipped, len(entity.summary.Remapped))
+	}
+	return nil
+}
+
+// runStatement импортирует выписку банка из файла или stdin в операции счета или счетов из выписки
+func runStatement(ctx context.Context, container *di.Container, args []string) error {
+	flags := flag.NewFlagSet("statement", flag.ContinueOnError)
+	format := flags.String("format", string(importexport.OFX), "формат выписки: "+formatList((*importexport.Codec).CanImportStatement))
+	file := flags.String("file", "-", "файл выписки, - для чтения из stdin")
+	account := flags.Int("account", 0, "ID счета для операций выписки, 0 - счета из выписки по названию")
+	income := flags.Int("income", 0, "ID категории поступлений по умолчанию")
+	expense := flags.Int("expense", 0, "ID категории списаний по умолчанию")
+	rulesPath := flags.String("rules", "", "YAML-файл правил категорий")
+	profileName := flags.String("profile", "", "профиль CSV банка вместо формата выписки")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var profile *importexport.CSVProfile
+	if *profileName != "" {
+		var err error
+		if profile, err = container.GetCSVProfileStore().Get(*profileName); err != nil {
+			return err
+		}
+	}
+
+	options := importexport.StatementImportOptions{
+		BankAccountID:     *account,
+		IncomeCategoryID:  *income,
+		ExpenseCategoryID: *expense,
+	}
+	if *rulesPath != "" {
+		rules, err := os.Open(*rulesPath)
+		if err != nil {
+			return err
+		}
+		options.Rules, err = importexport.LoadCategoryRules(rules)
+		rules.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	resultCh := make(chan *importexport.StatementImportSummary, 1)
+	errorCh := make(chan error, 1)
+	var cmd interfaces.Command
+	switch {
+	case profile != nil && *file == "-":
+		cmd = commands.NewImportProfileStatementStreamCommand(
+			profile,
+			container.GetTransactionManager(),
+			os.Stdin,
+			options,
+			resultCh,
+			errorCh,
+		)
+	case profile != nil:
+		cmd = commands.NewImportProfileStatementCommand(
+			profile,
+			container.GetTransactionManager(),
+			*file,
+			options,
+			resultCh,
+			errorCh,
+		)
+	case *file == "-":
+		cmd = commands.NewImportStatementStreamCommand(
+			importexport.FileFormat(*format),
+			container.GetTransactionManager(),
+			os.Stdin,
+			options,
+			resultCh,
+			errorCh,
+		)
+	default:
+		cmd = commands.NewImportStatementCommand(
+			importexport.FileFormat(*format),
+			container.GetTransactionManager(),
+			*file,
+			options,
+			resultCh,
+			errorCh,
+		)
+	}
+
+	if err := cmd.Execute(ctx); err != nil {
+		printProblems(err)
+		return err
+	}
+
+	summary := <-resultCh
+	fmt.Fprintf(os.Stderr, "Операций импортировано: %d (категория по правилам: %d), повторов пропущено: %d, с нулевой суммой: %d\n",
+		summary.Imported, summary.ByRule, summary.Duplicates, summary.Skipped)
+	if summary.CreatedAccounts > 0 || summary.CreatedCategories > 0 {
+		fmt.Fprintf(os.Stderr, "Создано счетов: %d, категорий: %d\n", summary.CreatedAccounts, summary.CreatedCategories)
+	}
+	fmt.Fprintf(os.Stderr, "Баланс изменен на %+.2f руб.\n", summary.BalanceChange)
+	for _, check := range summary.BalanceChecks {
+		status := "совпадает"
+		if !check.Matches() {
+			status = "РАСХОЖДЕНИЕ"
+		}
+		fmt.Fprintf(os.Stderr, "%s - %s\n", check, status)
+	}
+	return nil
+}
+
+// runWorkbook экспортирует данные и отчеты аналитики книгой XLSX в файл или stdout
+func runWorkbook(ctx context.Context, container *di.Container, args []string) error {
+	flags := flag.NewFlagSet("xlsx", flag.ContinueOnError)
+	file := flags.String("file", "-", "файл книги, - для записи в stdout")
+	year := flags.Int("year", 0, "год листа месячной динамики, 0 - без листа")
+	from := flags.String("from", "", "начало периода листа сумм по категориям, ГГГГ-ММ-ДД")
+	to := flags.String("to", "", "конец периода листа сумм по категориям, ГГГГ-ММ-ДД")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	reports := commands.WorkbookReports{Year: *year}
+	if *from != "" || *to != "" {
+		var err error
+		if reports.Start, err = time.Parse("2006-01-02", *from); err != nil {
+			return fmt.Errorf("начало периода %q не в формате ГГГГ-ММ-ДД", *from)
+		}
+		if reports.End, err = time.Parse("2006-01-02", *to); err != nil {
+			return fmt.Errorf("конец периода %q не в формате ГГГГ-ММ-ДД", *to)
+		}
+	}
+
+	errorCh := make(chan error, 1)
+	if *file == "-" {
+		return commands.NewExportWorkbookStreamCommand(
+			container.GetBankAccountRepository(),
+			container.GetCategoryRepository(),
+			container.GetOperationRepository(),
+			container.GetAnalyticsFacade(),
+			reports,
+			os.Stdout,
+			errorCh,
+		).Execute(ctx)
+	}
+	return commands.NewExportWorkbookCommand(
+		container.GetBankAccountRepository(),
+		container.GetCategoryRepository(),
+		container.GetOperationRepository(),
+		container.GetAnalyticsFacade(),
+		reports,
+		*file,
+		errorCh,
+	).Execute(ctx)
+}
+
+// runProfiles выводит профили CSV банков, сохраняет профили из YAML-файла или удаляет профиль
+func runProfiles(container *di.Container, args []string) error {
+	flags := flag.NewFlagSet("profiles", flag.ContinueOnError)
+	load := flags.String("load", "", "YAML-файл со списком профилей для сохранения")
+	remove := flags.String("delete", "", "название профиля для удаления")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	store := container.GetCSVProfileStore()
+	switch {
+	case *load != "":
+		file, err := os.Open(*load)
+		if err != nil {
+			return err
+		}
+		profiles, err := importexport.LoadCSVProfiles(file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+		for _, profile := range profiles {
+			if err := store.Save(profile); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Профиль %q сохранен\n", profile.Name)
+		}
+		return nil
+	case *remove != "":
+		return store.Delete(*remove)
+	default:
+		profiles, err := store.List()
+		if err != nil {
+			return err
+		}
+		for _, profile := range profiles {
+			fmt.Println(profile.Name)
+		}
+		return nil
+	}
+}
+
+// runStatementExport экспортирует счета с операциями выпиской в файл или stdout
+func runStatementExport(ctx context.Context, container *di.Container, args []string) error {
+	flags := flag.NewFlagSet("statement-export", flag.ContinueOnError)
+	format := flags.String("format", string(importexport.QIF), "формат выписки: "+formatList((*importexport.Codec).CanExportStatement))
+	file := flags.String("file", "-", "файл выписки, - для записи в stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	errorCh := make(chan error, 1)
+	if *file == "-" {
+		return commands.NewExportStatementStreamCommand(
+			importexport.FileFormat(*format),
+			container.GetBankAccountRepository(),
+			container.GetCategoryRepository(),
+			container.GetOperationRepository(),
+			os.Stdout,
+			errorCh,
+		).Execute(ctx)
+	}
+	return commands.NewExportStatementCommand(
+		importexport.FileFormat(*format),
+		container.GetBankAccountRepository(),
+		container.GetCategoryRepository(),
+		container.GetOperationRepository(),
+		*file,
+		errorCh,
+	).Execute(ctx)
+}
+
+// runBackup сохраняет все данные в архив резервной копии или пишет архив в stdout
+func runBackup(ctx context.Context, container *di.Container, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	format := flags.String("format", string(importexport.JSON), "формат файлов в архиве: "+formatList((*importexport.Codec).CanExport))
+	file := flags.String("file", "-", "файл архива .zip, .tar.gz или .tgz, - для записи в stdout")
+	kindName := flags.String("kind", string(importexport.BundleZip), "вид архива для stdout: zip или tar.gz")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	resultCh := make(chan *importexport.BundleManifest, 1)
+	errorCh := make(chan error, 1)
+	var cmd interfaces.Command
+	if *file == "-" {
+		kind, err := importexport.ParseBundleKind(*kindName)
+		if err != nil {
+			return err
+		}
+		cmd = commands.NewBackupStreamCommand(
+			importexport.FileFormat(*format),
+			container.GetBankAccountRepository(),
+			container.GetCategoryRepository(),
+			container.GetOperationRepository(),
+			os.Stdout,
+			kind,
+			resultCh,
+			errorCh,
+		)
+	} else {
+		cmd = commands.NewBackupCommand(
+			importexport.FileFormat(*format),
+			container.GetBankAccountRepository(),
+			container.GetCategoryRepository(),
+			container.GetOperationRepository(),
+			*file,
+			resultCh,
+			errorCh,
+		)
+	}
+	if err := cmd.Execute(ctx); err != nil {
+		return err
+	}
+	printManifest(<-resultCh)
+	return nil
+}
+
+// runVerify проверяет архив резервной копии из файла или stdin и выводит его манифест
+func runVerify(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	file := flags.String("file", "-", "файл архива, - для чтения из stdin")
+	kindName := flags.String("kind", string(importexport.BundleZip), "вид архива из stdin: zip или tar.gz")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	resultCh := make(chan *importexport.BundleManifest, 1)
+	errorCh := make(chan error, 1)
+	var cmd interfaces.Command
+	if *file == "-" {
+		kind, err := importexport.ParseBundleKind(*kindName)
+		if err != nil {
+			return err
+		}
+		cmd = commands.NewVerifyBackupStreamCommand(os.Stdin, kind, resultCh, errorCh)
+	} else {
+		cmd = commands.NewVerifyBackupCommand(*file, resultCh, errorCh)
+	}
+	if err := cmd.Execute(ctx); err != nil {
+		printProblems(err)
+		return err
+	}
+	printManifest(<-resultCh)
+	return nil
+}
+
+// printManifest выводит в stderr манифест резервной копии
+func printManifest(manifest *importexport.BundleManifest) {
+	fmt.Fprintf(os.Stderr, "Версия схемы %d, формат %s, создана %s\n",
+		manifest.Version, manifest.Format, manifest.ExportedAt.Format(time.RFC3339))
+	for _, file := range manifest.Files {
+		fmt.Fprintf(os.Stderr, "%s: записей %d, %d байт, SHA-256 %s\n", file.Name, file.Count, file.Size, file.SHA256)
+	}
+}
+
+// printProblems выводит в stderr проблемы из отчета о проверке, если ошибка его содержит
+func printProblems(err error) {
+	var report *importexport.ValidationReport
+	if errors.As(err, &report) {
+		for _, problem := range report.Problems {
+			fmt.Fprintf(os.Stderr, "  %s\n", problem)
+		}
+	}
+}
+
+// printFormats выводит зарегистрированные форматы и сущности, которые они читают и пишут
+func printFormats(w io.Writer) {
+	for _, codec := range importexport.Codecs() {
+		var directions []string
+		if codec.CanImportStatement() {
+			directions = append(directions, "импорт")
+		}
+		if codec.CanExportStatement() {
+			directions = append(directions, "экспорт")
+		}
+		statement := "-"
+		if len(directions) > 0 {
+			statement = strings.Join(directions, ", ")
+		}
+		fmt.Fprintf(w, "%-6s %-20s импорт: %-34s экспорт: %-34s выписка: %s\n", codec.Format, codec.Title,
+			entityList(codec.BankAccounts.Decode != nil, codec.Categories.Decode != nil, codec.Operations.Decode != nil),
+			entityList(codec.BankAccounts.Encode != nil, codec.Categories.Encode != nil, codec.Operations.Encode != nil),
+			statement)
+	}
+}
+
+// entityList перечисляет поддерживаемые сущности
+func entityList(accounts, categories, operations bool) string {
+	var names []string
+	if accounts {
+		names = append(names, "accounts")
+	}
+	if categories {
+		names = append(names, "categories")
+	}
+	if operations {
+		names = append(names, "operations")
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ", ")
+}
+
+// formatList перечисляет форматы, подходящие по supports
+func formatList(supports func(*importexport.Codec) bool) string {
+	var formats []string
+	for _, codec := range importexport.Codecs() {
+		if supports(codec) {
+			formats = append(formats, string(codec.Format))
+		}
+	}
+	return strings.Join(formats, ", ")
+}
+
+// importStreams направляет поток r в указанную сущность
+func importStreams(entity string, r io.Reader) (importexport.ImportStreams, error) {
+	switch entity {
+	case "accounts":
+		return importexport.ImportStreams{BankAccounts: r}, nil
+	case "categories":
+		return importexport.ImportStreams{Categories: r}, nil
+	case "operations":
+		return importexport.ImportStreams{Operations: r}, nil
+	default:
+		return importexport.ImportStreams{}, fmt.Errorf("неизвестная сущность %q", entity)
+	}
+}
+
+// exportStreams направляет указанную сущность в поток w
+func exportStreams(entity string, w io.Writer) (importexport.ExportStreams, error) {
+	switch entity {
+	case "accounts":
+		return importexport.ExportStreams{BankAccounts: w}, nil
+	case "categories":
+		return importexport.ExportStreams{Categories: w}, nil
+	case "operations":
+		return importexport.ExportStreams{Operations: w}, nil
+	default:
+		return importexport.ExportStreams{}, fmt.Errorf("неизвестная сущность %q", entity)
+	}
+}
@@ -1,23 +1,62 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 
 	"KPO1/di"
+	"KPO1/infrastructure/persistence"
 	"KPO1/infrastructure/ui"
+
+	// Драйвер SQLite для хранилища sql
+	_ "modernc.org/sqlite"
 )
 
 func main() {
+	storage := flag.String("storage", string(di.FileStorage), "тип хранилища: memory, file или sql")
+	dataDir := flag.String("data", "./data", "директория для данных")
+	dsn := flag.String("dsn", "", "строка подключения к базе данных для хранилища sql")
+	cacheSize := flag.Int("cache", 1024, "размер кэша репозиториев в записях, 0 отключает кэш")
+	flag.Usage = usage
+	flag.Parse()
+
 	// Создаём консольный интерфейс
 	console := ui.NewConsoleUI()
 
 	// Подготавливаем директорию для данных
-	dataDir := "./data"
-	ensureDir(dataDir)
+	ensureDir(*dataDir)
+
+	// Создаём DI-контейнер с выбранным хранилищем
+	container, err := di.NewContainerWithConfig(di.Config{
+		Storage:   di.StorageType(*storage),
+		DataDir:   *dataDir,
+		SQLDSN:    *dsn,
+		CacheSize: *cacheSize,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка загрузки данных: %v\n", err)
+		os.Exit(1)
+	}
+	defer container.Close()
 
-	// Создаём DI-контейнер
-	container := di.NewContainer()
+	// Команда в аргументах выполняется без интерактивного меню
+	if flag.NArg() > 0 {
+		reportRecovery(os.Stderr, container)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		err := runCLI(ctx, container, flag.Args())
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+			container.Close()
+			os.Exit(1)
+		}
+		return
+	}
+	reportRecovery(os.Stdout, container)
 
 	// Создаём главное меню с доступом к DI-контейнеру
 	menu := ui.NewMainMenu(console, container)
@@ -26,13 +65,52 @@ func main() {
 	// Выводим приветствие и запускаем основной цикл
 	fmt.Println("Добро пожаловать в систему учета финансов ВШЭ-банка!")
 	fmt.Println("=================================================")
-	if err := console.Run(); err != nil {
+	if err := console.Run(context.Background()); err != nil {
 		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		container.Close()
 		os.Exit(1)
 	}
 	fmt.Println("До свидания! Спасибо за использование системы учета финансов ВШЭ-банка!")
 }
 
+// reportRecovery сообщает о восстановлении данных из журнала после аварийного завершения
+func reportRecovery(w io.Writer, container *di.Container) {
+	store, ok := container.GetDataStore().(*persistence.FileRepository)
+	if !ok {
+		return
+	}
+
+	report := store.Recovery()
+	if report.DiscardedBytes > 0 {
+		fmt.Fprintf(w, "Внимание: последняя запись журнала была оборвана и отброшена (%d байт)\n", report.DiscardedBytes)
+	}
+	if report.ReplayedEntries > 0 {
+		fmt.Fprintf(w, "Восстановлено изменений из журнала: %d\n", report.ReplayedEntries)
+	}
+}
+
+// usage выводит справку по флагам и командам с форматами из реестра кодеков
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Использование: %s [флаги] [formats | export ... | import ... | statement ... | statement-export ... | profiles ... | xlsx ... | backup ... | verify ...]\n\nФлаги:\n", os.Args[0])
+	flag.PrintDefaults()
+	fmt.Fprintln(out, "\nКоманды:")
+	fmt.Fprintln(out, "  formats                                              список форматов импорта и экспорта")
+	fmt.Fprintln(out, "  export -format F (-dir D | -entity E)                экспорт в директорию или одной сущности в stdout")
+	fmt.Fprintln(out, "  import -format F (-dir D | -entity E) [-strategy S]  импорт из директории или одной сущности из stdin")
+	fmt.Fprintln(out, "  import -bundle P [-kind K] [-strategy S]             восстановление из проверенной резервной копии")
+	fmt.Fprintln(out, "  statement -format F [-account A] [-file P] [-income C] [-expense C] [-rules R]")
+	fmt.Fprintln(out, "                                                       импорт выписки банка в операции счета или счетов из выписки")
+	fmt.Fprintln(out, "  statement -profile N [-account A] [-file P] ...      импорт CSV-выписки банка по сохраненному профилю")
+	fmt.Fprintln(out, "  statement-export -format F [-file P]                 экспорт счетов с операциями выпиской в файл или stdout")
+	fmt.Fprintln(out, "  profiles [-load Y | -delete N]                       список, сохранение из YAML или удаление профилей CSV")
+	fmt.Fprintln(out, "  xlsx [-file P] [-year Y] [-from D -to D]             книга Excel со счетами, категориями, операциями и отчетами")
+	fmt.Fprintln(out, "  backup [-file P] [-format F] [-kind K]               резервная копия всех данных в архиве .zip или .tar.gz")
+	fmt.Fprintln(out, "  verify [-file P] [-kind K]                           проверка манифеста и контрольных сумм резервной копии")
+	fmt.Fprintln(out, "\nФорматы:")
+	printFormats(out)
+}
+
 // ensureDir создает директорию, если она не существует
 func ensureDir(dirPath string) {
 	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
@@ -0,0 +1,57 @@
+package di
+
+import (
+	"KPO1/infrastructure/persistence"
+	"fmt"
+	"path/filepath"
+)
+
+// StorageType представляет тип хранилища данных
+type StorageType string
+
+const (
+	// MemoryStorage хранилище в памяти, данные теряются при выходе
+	MemoryStorage StorageType = "memory"
+	// FileStorage файловое хранилище в директории данных
+	FileStorage StorageType = "file"
+	// SQLStorage хранилище в базе данных через database/sql
+	SQLStorage StorageType = "sql"
+)
+
+// defaultSQLDriver драйвер базы данных по умолчанию
+const defaultSQLDriver = "sqlite"
+
+// Config представляет конфигурацию контейнера зависимостей
+type Config struct {
+	Storage StorageType
+	DataDir string
+	// SQLDriver имя зарегистрированного драйвера database/sql, по умолчанию "sqlite"
+	SQLDriver string
+	// SQLDSN строка подключения, по умолчанию файл ledger.db в директории данных
+	SQLDSN string
+	// CacheSize размер кэша репозиториев в записях, 0 отключает кэширование
+	CacheSize int
+}
+
+// openDataStore открывает хранилище данных, выбранное в конфигурации
+func openDataStore(config Config) (persistence.TransactionalStore, error) {
+	switch config.Storage {
+	case MemoryStorage, "":
+		return persistence.NewMemoryRepository(), nil
+	case FileStorage:
+		return persistence.NewFileRepository(config.DataDir)
+	case SQLStorage:
+		driver := config.SQLDriver
+		if driver == "" {
+			driver = defaultSQLDriver
+		}
+		dsn := config.SQLDSN
+		if dsn == "" {
+			// Транзакции сразу берут блокировку записи, а конкурирующие ждут ее, а не падают с SQLITE_BUSY
+			dsn = "file:" + filepath.Join(config.DataDir, "ledger.db") + "?_pragma=busy_timeout(5000)&_txlock=immediate"
+		}
+		return persistence.NewSQLRepository(driver, dsn)
+	default:
+		return nil, fmt.Errorf("неизвестный тип хранилища: %s", config.Storage)
+	}
+}
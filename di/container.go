@@ -6,17 +6,24 @@ import (
 	"KPO1/application/services"
 	"KPO1/domain/factory"
 	"KPO1/domain/interfaces"
+	"KPO1/infrastructure/importexport"
 	"KPO1/infrastructure/persistence"
+	"io"
+	"path/filepath"
 	"sync"
 )
 
 // Container представляет контейнер для внедрения зависимостей
 type Container struct {
+	// Конфигурация и хранилище данных
+	config    Config
+	dataStore persistence.TransactionalStore
+
 	// Репозитории
-	memoryRepository      *persistence.MemoryRepository
 	bankAccountRepository interfaces.BankAccountRepository
 	categoryRepository    interfaces.CategoryRepository
 	operationRepository   interfaces.OperationRepository
+	txManager             interfaces.TransactionManager
 
 	// Фабрики
 	bankAccountFactory *factory.BankAccountFactory
@@ -24,39 +31,79 @@ type Container struct {
 	operationFactory   *factory.OperationFactory
 
 	// Сервисы
-	bankAccountService interfaces.BankAccountService
-	categoryService    interfaces.CategoryService
-	operationService   interfaces.OperationService
-	analyticsService   interfaces.AnalyticsService
+	bankAccountService    interfaces.BankAccountService
+	categoryService       interfaces.CategoryService
+	operationService      interfaces.OperationService
+	analyticsService      interfaces.AnalyticsService
+	reconciliationService interfaces.ReconciliationService
 
 	// Фасады
-	bankAccountFacade *facade.BankAccountFacade
-	categoryFacade    *facade.CategoryFacade
-	operationFacade   *facade.OperationFacade
-	analyticsFacade   *facade.AnalyticsFacade
+	bankAccountFacade    *facade.BankAccountFacade
+	categoryFacade       *facade.CategoryFacade
+	operationFacade      *facade.OperationFacade
+	analyticsFacade      *facade.AnalyticsFacade
+	reconciliationFacade *facade.ReconciliationFacade
+
+	// Профили CSV банков
+	csvProfileStore *importexport.CSVProfileStore
 
 	// мьютексы для потокобезопасности
 	repoMu    sync.Mutex
 	factoryMu sync.Mutex
 	serviceMu sync.Mutex
 	facadeMu  sync.Mutex
+	profileMu sync.Mutex
 }
 
-// NewContainer создает новый контейнер для внедрения зависимостей
+// NewContainer создает новый контейнер для внедрения зависимостей с хранилищем в памяти
 func NewContainer() *Container {
-	return &Container{}
+	return &Container{
+		config:    Config{Storage: MemoryStorage},
+		dataStore: persistence.NewMemoryRepository(),
+	}
 }
 
-// GetMemoryRepository возвращает репозиторий в памяти
-func (c *Container) GetMemoryRepository() *persistence.MemoryRepository {
-	c.repoMu.Lock()
-	defer c.repoMu.Unlock()
+// NewContainerWithConfig создает контейнер и открывает хранилище согласно конфигурации
+func NewContainerWithConfig(config Config) (*Container, error) {
+	dataStore, err := openDataStore(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Container{
+		config:    config,
+		dataStore: dataStore,
+	}, nil
+}
+
+// Close освобождает ресурсы хранилища данных
+func (c *Container) Close() error {
+	if closer, ok := c.dataStore.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// GetDataStore возвращает хранилище данных, с которым работают репозитории
+func (c *Container) GetDataStore() persistence.TransactionalStore {
+	return c.dataStore
+}
+
+// GetCSVProfileStore возвращает хранилище профилей CSV банков в файле csv_profiles.yaml директории данных
+func (c *Container) GetCSVProfileStore() *importexport.CSVProfileStore {
+	c.profileMu.Lock()
+	defer c.profileMu.Unlock()
 
-	if c.memoryRepository == nil {
-		c.memoryRepository = persistence.NewMemoryRepository()
+	if c.csvProfileStore == nil {
+		c.csvProfileStore = importexport.NewCSVProfileStore(filepath.Join(c.config.DataDir, "csv_profiles.yaml"))
 	}
 
-	return c.memoryRepository
+	return c.csvProfileStore
+}
+
+// GetIDAllocator возвращает общий источник ID для фабрик, репозиториев и импорта
+func (c *Container) GetIDAllocator() interfaces.IDAllocator {
+	return c.dataStore
 }
 
 // GetBankAccountRepository возвращает репозиторий банковских счетов
@@ -65,13 +112,7 @@ func (c *Container) GetBankAccountRepository() interfaces.BankAccountRepository
 	defer c.repoMu.Unlock()
 
 	if c.bankAccountRepository == nil {
-		// Используем напрямую memoryRepository вместо вызова GetMemoryRepository()
-		if c.memoryRepository == nil {
-			c.memoryRepository = persistence.NewMemoryRepository()
-		}
-		memRepo := c.memoryRepository
-
-		c.bankAccountRepository = persistence.NewBankAccountRepository(memRepo)
+		c.bankAccountRepository = c.newBankAccountRepository()
 	}
 
 	return c.bankAccountRepository
@@ -83,13 +124,7 @@ func (c *Container) GetCategoryRepository() interfaces.CategoryRepository {
 	defer c.repoMu.Unlock()
 
 	if c.categoryRepository == nil {
-		// Используем напрямую memoryRepository вместо вызова GetMemoryRepository()
-		if c.memoryRepository == nil {
-			c.memoryRepository = persistence.NewMemoryRepository()
-		}
-		memRepo := c.memoryRepository
-
-		c.categoryRepository = persistence.NewCategoryRepository(memRepo)
+		c.categoryRepository = c.newCategoryRepository()
 	}
 
 	return c.categoryRepository
@@ -101,25 +136,31 @@ func (c *Container) GetOperationRepository() interfaces.OperationRepository {
 	defer c.repoMu.Unlock()
 
 	if c.operationRepository == nil {
-		// Используем напрямую memoryRepository вместо вызова GetMemoryRepository()
-		if c.memoryRepository == nil {
-			c.memoryRepository = persistence.NewMemoryRepository()
-		}
-		memRepo := c.memoryRepository
-
-		c.operationRepository = persistence.NewOperationRepository(memRepo)
+		c.operationRepository = c.newOperationRepository()
 	}
 
 	return c.operationRepository
 }
 
+// GetTransactionManager возвращает менеджер транзакций над репозиториями
+func (c *Container) GetTransactionManager() interfaces.TransactionManager {
+	c.repoMu.Lock()
+	defer c.repoMu.Unlock()
+
+	if c.txManager == nil {
+		c.txManager = c.newTransactionManager()
+	}
+
+	return c.txManager
+}
+
 // GetBankAccountFactory возвращает фабрику банковских счетов
 func (c *Container) GetBankAccountFactory() *factory.BankAccountFactory {
 	c.factoryMu.Lock()
 	defer c.factoryMu.Unlock()
 
 	if c.bankAccountFactory == nil {
-		c.bankAccountFactory = factory.NewBankAccountFactory()
+		c.bankAccountFactory = c.newBankAccountFactory()
 	}
 
 	return c.bankAccountFactory
@@ -131,7 +172,7 @@ func (c *Container) GetCategoryFactory() *factory.CategoryFactory {
 	defer c.factoryMu.Unlock()
 
 	if c.categoryFactory == nil {
-		c.categoryFactory = factory.NewCategoryFactory()
+		c.categoryFactory = c.newCategoryFactory()
 	}
 
 	return c.categoryFactory
@@ -143,12 +184,80 @@ func (c *Container) GetOperationFactory() *factory.OperationFactory {
 	defer c.factoryMu.Unlock()
 
 	if c.operationFactory == nil {
-		c.operationFactory = factory.NewOperationFactory()
+		c.operationFactory = c.newOperationFactory()
 	}
 
 	return c.operationFactory
 }
 
+// newBankAccountRepository создает репозиторий счетов, при необходимости с кэширующим прокси
+func (c *Container) newBankAccountRepository() interfaces.BankAccountRepository {
+	repo := persistence.NewBankAccountRepository(c.dataStore)
+	if c.config.CacheSize > 0 {
+		return persistence.NewCachingBankAccountRepository(repo, c.config.CacheSize)
+	}
+	return repo
+}
+
+// newCategoryRepository создает репозиторий категорий, при необходимости с кэширующим прокси
+func (c *Container) newCategoryRepository() interfaces.CategoryRepository {
+	repo := persistence.NewCategoryRepository(c.dataStore)
+	if c.config.CacheSize > 0 {
+		return persistence.NewCachingCategoryRepository(repo, c.config.CacheSize)
+	}
+	return repo
+}
+
+// newOperationRepository создает репозиторий операций, при необходимости с кэширующим прокси
+func (c *Container) newOperationRepository() interfaces.OperationRepository {
+	repo := persistence.NewOperationRepository(c.dataStore)
+	if c.config.CacheSize > 0 {
+		return persistence.NewCachingOperationRepository(repo, c.config.CacheSize)
+	}
+	return repo
+}
+
+// newTransactionManager создает менеджер транзакций хранилища. Если репозитории кэшируются,
+// менеджер сбрасывает из их кэшей записи, измененные транзакцией. Вызывается под repoMu.
+func (c *Container) newTransactionManager() interfaces.TransactionManager {
+	txManager := persistence.NewTransactionManager(c.dataStore)
+	if c.config.CacheSize <= 0 {
+		return txManager
+	}
+
+	if c.bankAccountRepository == nil {
+		c.bankAccountRepository = c.newBankAccountRepository()
+	}
+	if c.categoryRepository == nil {
+		c.categoryRepository = c.newCategoryRepository()
+	}
+	if c.operationRepository == nil {
+		c.operationRepository = c.newOperationRepository()
+	}
+
+	return persistence.NewCachingTransactionManager(
+		txManager,
+		c.bankAccountRepository.(*persistence.CachingBankAccountRepository),
+		c.categoryRepository.(*persistence.CachingCategoryRepository),
+		c.operationRepository.(*persistence.CachingOperationRepository),
+	)
+}
+
+// newBankAccountFactory создает фабрику счетов, получающую ID из счетчиков хранилища
+func (c *Container) newBankAccountFactory() *factory.BankAccountFactory {
+	return factory.NewBankAccountFactory(c.dataStore)
+}
+
+// newCategoryFactory создает фабрику категорий, получающую ID из счетчиков хранилища
+func (c *Container) newCategoryFactory() *factory.CategoryFactory {
+	return factory.NewCategoryFactory(c.dataStore)
+}
+
+// newOperationFactory создает фабрику операций, получающую ID из счетчиков хранилища
+func (c *Container) newOperationFactory() *factory.OperationFactory {
+	return factory.NewOperationFactory(c.dataStore)
+}
+
 // GetBankAccountService возвращает сервис для управления банковскими счетами
 func (c *Container) GetBankAccountService() interfaces.BankAccountService {
 	c.serviceMu.Lock()
@@ -158,26 +267,27 @@ func (c *Container) GetBankAccountService() interfaces.BankAccountService {
 		// Получаем все зависимости вне мьютекса serviceMu
 		c.repoMu.Lock()
 
-		if c.memoryRepository == nil {
-			c.memoryRepository = persistence.NewMemoryRepository()
-		}
-
 		if c.bankAccountRepository == nil {
-			c.bankAccountRepository = persistence.NewBankAccountRepository(c.memoryRepository)
+			c.bankAccountRepository = c.newBankAccountRepository()
 		}
 		bankRepo := c.bankAccountRepository
 
 		if c.operationRepository == nil {
-			c.operationRepository = persistence.NewOperationRepository(c.memoryRepository)
+			c.operationRepository = c.newOperationRepository()
 		}
 		opRepo := c.operationRepository
 
+		if c.txManager == nil {
+			c.txManager = c.newTransactionManager()
+		}
+		txManager := c.txManager
+
 		c.repoMu.Unlock()
 
 		// Инициализируем фабрику напрямую
 		c.factoryMu.Lock()
 		if c.bankAccountFactory == nil {
-			c.bankAccountFactory = factory.NewBankAccountFactory()
+			c.bankAccountFactory = c.newBankAccountFactory()
 		}
 		bankFactory := c.bankAccountFactory
 		c.factoryMu.Unlock()
@@ -185,6 +295,7 @@ func (c *Container) GetBankAccountService() interfaces.BankAccountService {
 		c.bankAccountService = services.NewBankAccountService(
 			bankRepo,
 			opRepo,
+			txManager,
 			bankFactory,
 		)
 	}
@@ -201,11 +312,13 @@ func (c *Container) GetCategoryService() interfaces.CategoryService {
 		// Получаем все зависимости до инициализации сервиса
 		catRepo := c.GetCategoryRepository()
 		opRepo := c.GetOperationRepository()
+		txManager := c.GetTransactionManager()
 		factory := c.GetCategoryFactory()
 
 		c.categoryService = services.NewCategoryService(
 			catRepo,
 			opRepo,
+			txManager,
 			factory,
 		)
 	}
@@ -223,12 +336,14 @@ func (c *Container) GetOperationService() interfaces.OperationService {
 		opRepo := c.GetOperationRepository()
 		bankRepo := c.GetBankAccountRepository()
 		catRepo := c.GetCategoryRepository()
+		txManager := c.GetTransactionManager()
 		factory := c.GetOperationFactory()
 
 		c.operationService = services.NewOperationService(
 			opRepo,
 			bankRepo,
 			catRepo,
+			txManager,
 			factory,
 		)
 	}
@@ -255,6 +370,33 @@ func (c *Container) GetAnalyticsService() interfaces.AnalyticsService {
 	return c.analyticsService
 }
 
+// GetReconciliationService возвращает сервис сверки балансов счетов
+func (c *Container) GetReconciliationService() interfaces.ReconciliationService {
+	c.serviceMu.Lock()
+	defer c.serviceMu.Unlock()
+
+	if c.reconciliationService == nil {
+		// Получаем все зависимости до инициализации сервиса
+		bankRepo := c.GetBankAccountRepository()
+		catRepo := c.GetCategoryRepository()
+		opRepo := c.GetOperationRepository()
+		txManager := c.GetTransactionManager()
+		catFactory := c.GetCategoryFactory()
+		opFactory := c.GetOperationFactory()
+
+		c.reconciliationService = services.NewReconciliationService(
+			bankRepo,
+			catRepo,
+			opRepo,
+			txManager,
+			catFactory,
+			opFactory,
+		)
+	}
+
+	return c.reconciliationService
+}
+
 // GetBankAccountFacade возвращает фасад для управления банковскими счетами
 func (c *Container) GetBankAccountFacade() *facade.BankAccountFacade {
 	c.facadeMu.Lock()
@@ -269,26 +411,27 @@ func (c *Container) GetBankAccountFacade() *facade.BankAccountFacade {
 
 			c.repoMu.Lock()
 
-			if c.memoryRepository == nil {
-				c.memoryRepository = persistence.NewMemoryRepository()
-			}
-
 			if c.bankAccountRepository == nil {
-				c.bankAccountRepository = persistence.NewBankAccountRepository(c.memoryRepository)
+				c.bankAccountRepository = c.newBankAccountRepository()
 			}
 			bankRepo := c.bankAccountRepository
 
 			if c.operationRepository == nil {
-				c.operationRepository = persistence.NewOperationRepository(c.memoryRepository)
+				c.operationRepository = c.newOperationRepository()
 			}
 			opRepo := c.operationRepository
 
+			if c.txManager == nil {
+				c.txManager = c.newTransactionManager()
+			}
+			txManager := c.txManager
+
 			c.repoMu.Unlock()
 
 			// Инициализируем фабрику напрямую
 			c.factoryMu.Lock()
 			if c.bankAccountFactory == nil {
-				c.bankAccountFactory = factory.NewBankAccountFactory()
+				c.bankAccountFactory = c.newBankAccountFactory()
 			}
 			bankFactory := c.bankAccountFactory
 			c.factoryMu.Unlock()
@@ -301,6 +444,7 @@ func (c *Container) GetBankAccountFacade() *facade.BankAccountFacade {
 				c.bankAccountService = services.NewBankAccountService(
 					bankRepo,
 					opRepo,
+					txManager,
 					bankFactory,
 				)
 			}
@@ -343,31 +487,32 @@ func (c *Container) GetOperationFacade() *facade.OperationFacade {
 			// Получаем все зависимости для сервиса операций
 			c.repoMu.Lock()
 
-			if c.memoryRepository == nil {
-				c.memoryRepository = persistence.NewMemoryRepository()
-			}
-
 			if c.operationRepository == nil {
-				c.operationRepository = persistence.NewOperationRepository(c.memoryRepository)
+				c.operationRepository = c.newOperationRepository()
 			}
 			opRepo := c.operationRepository
 
 			if c.bankAccountRepository == nil {
-				c.bankAccountRepository = persistence.NewBankAccountRepository(c.memoryRepository)
+				c.bankAccountRepository = c.newBankAccountRepository()
 			}
 			bankRepo := c.bankAccountRepository
 
 			if c.categoryRepository == nil {
-				c.categoryRepository = persistence.NewCategoryRepository(c.memoryRepository)
+				c.categoryRepository = c.newCategoryRepository()
 			}
 			catRepo := c.categoryRepository
 
+			if c.txManager == nil {
+				c.txManager = c.newTransactionManager()
+			}
+			txManager := c.txManager
+
 			c.repoMu.Unlock()
 
 			// Инициализируем фабрику напрямую
 			c.factoryMu.Lock()
 			if c.operationFactory == nil {
-				c.operationFactory = factory.NewOperationFactory()
+				c.operationFactory = c.newOperationFactory()
 			}
 			opFactory := c.operationFactory
 			c.factoryMu.Unlock()
@@ -376,6 +521,7 @@ func (c *Container) GetOperationFacade() *facade.OperationFacade {
 				opRepo,
 				bankRepo,
 				catRepo,
+				txManager,
 				opFactory,
 			)
 		}
@@ -386,26 +532,27 @@ func (c *Container) GetOperationFacade() *facade.OperationFacade {
 			// Получаем все зависимости для сервиса категорий
 			c.repoMu.Lock()
 
-			if c.memoryRepository == nil {
-				c.memoryRepository = persistence.NewMemoryRepository()
-			}
-
 			if c.categoryRepository == nil {
-				c.categoryRepository = persistence.NewCategoryRepository(c.memoryRepository)
+				c.categoryRepository = c.newCategoryRepository()
 			}
 			catRepo := c.categoryRepository
 
 			if c.operationRepository == nil {
-				c.operationRepository = persistence.NewOperationRepository(c.memoryRepository)
+				c.operationRepository = c.newOperationRepository()
 			}
 			opRepo := c.operationRepository
 
+			if c.txManager == nil {
+				c.txManager = c.newTransactionManager()
+			}
+			txManager := c.txManager
+
 			c.repoMu.Unlock()
 
 			// Инициализируем фабрику напрямую
 			c.factoryMu.Lock()
 			if c.categoryFactory == nil {
-				c.categoryFactory = factory.NewCategoryFactory()
+				c.categoryFactory = c.newCategoryFactory()
 			}
 			catFactory := c.categoryFactory
 			c.factoryMu.Unlock()
@@ -413,6 +560,7 @@ func (c *Container) GetOperationFacade() *facade.OperationFacade {
 			c.categoryService = services.NewCategoryService(
 				catRepo,
 				opRepo,
+				txManager,
 				catFactory,
 			)
 		}
@@ -441,3 +589,22 @@ func (c *Container) GetAnalyticsFacade() *facade.AnalyticsFacade {
 
 	return c.analyticsFacade
 }
+
+// GetReconciliationFacade возвращает фасад для сверки балансов счетов
+func (c *Container) GetReconciliationFacade() *facade.ReconciliationFacade {
+	c.facadeMu.Lock()
+	defer c.facadeMu.Unlock()
+
+	if c.reconciliationFacade == nil {
+		// Получаем сервисы до инициализации фасада
+		reconciliationService := c.GetReconciliationService()
+		bankAccountService := c.GetBankAccountService()
+
+		c.reconciliationFacade = facade.NewReconciliationFacade(
+			reconciliationService,
+			bankAccountService,
+		)
+	}
+
+	return c.reconciliationFacade
+}
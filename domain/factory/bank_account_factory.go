@@ -1,27 +1,34 @@
 package factory
 
 import (
+	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
 	"time"
 )
 
 // BankAccountFactory представляет фабрику для создания банковских счетов
 type BankAccountFactory struct {
-	nextID int
+	ids interfaces.IDAllocator
 }
 
-// NewBankAccountFactory создаёт новую фабрику счетов
-func NewBankAccountFactory() *BankAccountFactory {
+// NewBankAccountFactory создаёт новую фабрику счетов, получающую ID из общего счетчика
+func NewBankAccountFactory(ids interfaces.IDAllocator) *BankAccountFactory {
 	return &BankAccountFactory{
-		nextID: 1,
+		ids: ids,
 	}
 }
 
 // CreateBankAccount создаёт новый банковский счёт
-func (f *BankAccountFactory) CreateBankAccount(name string) (*models.BankAccount, error) {
+func (f *BankAccountFactory) CreateBankAccount(ctx context.Context, name string) (*models.BankAccount, error) {
+	id, err := f.ids.NextID(ctx, models.EntityBankAccount)
+	if err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 	account := &models.BankAccount{
-		ID:        f.nextID,
+		ID:        id,
 		Name:      name,
 		Balance:   0,
 		CreatedAt: now,
@@ -33,13 +40,5 @@ func (f *BankAccountFactory) CreateBankAccount(name string) (*models.BankAccount
 		return nil, err
 	}
 
-	f.nextID++
 	return account, nil
 }
-
-// SetNextID устанавливает следующий ID для фабрики
-func (f *BankAccountFactory) SetNextID(id int) {
-	if id > f.nextID {
-		f.nextID = id
-	}
-}
@@ -1,27 +1,34 @@
 package factory
 
 import (
+	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
 	"time"
 )
 
 // CategoryFactory представляет фабрику для создания категорий
 type CategoryFactory struct {
-	nextID int
+	ids interfaces.IDAllocator
 }
 
-// NewCategoryFactory создаёт новую фабрику категорий
-func NewCategoryFactory() *CategoryFactory {
+// NewCategoryFactory создаёт новую фабрику категорий, получающую ID из общего счетчика
+func NewCategoryFactory(ids interfaces.IDAllocator) *CategoryFactory {
 	return &CategoryFactory{
-		nextID: 1,
+		ids: ids,
 	}
 }
 
 // CreateCategory создаёт новую категорию
-func (f *CategoryFactory) CreateCategory(name string, opType models.OperationType) (*models.Category, error) {
+func (f *CategoryFactory) CreateCategory(ctx context.Context, name string, opType models.OperationType) (*models.Category, error) {
+	id, err := f.ids.NextID(ctx, models.EntityCategory)
+	if err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 	category := &models.Category{
-		ID:        f.nextID,
+		ID:        id,
 		Type:      opType,
 		Name:      name,
 		CreatedAt: now,
@@ -33,13 +40,5 @@ func (f *CategoryFactory) CreateCategory(name string, opType models.OperationTyp
 		return nil, err
 	}
 
-	f.nextID++
 	return category, nil
 }
-
-// SetNextID устанавливает следующий ID для фабрики
-func (f *CategoryFactory) SetNextID(id int) {
-	if id > f.nextID {
-		f.nextID = id
-	}
-}
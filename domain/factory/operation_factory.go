@@ -1,34 +1,42 @@
 package factory
 
 import (
+	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
 	"time"
 )
 
 // OperationFactory представляет фабрику для создания операций
 type OperationFactory struct {
-	nextID int
+	ids interfaces.IDAllocator
 }
 
-// NewOperationFactory создаёт новую фабрику операций
-func NewOperationFactory() *OperationFactory {
+// NewOperationFactory создаёт новую фабрику операций, получающую ID из общего счетчика
+func NewOperationFactory(ids interfaces.IDAllocator) *OperationFactory {
 	return &OperationFactory{
-		nextID: 1,
+		ids: ids,
 	}
 }
 
 // CreateOperation создаёт новую операцию
 func (f *OperationFactory) CreateOperation(
+	ctx context.Context,
 	bankAccountID, categoryID int,
 	amount float64,
 	opType models.OperationType,
 	date time.Time,
 	description string,
 ) (*models.Operation, error) {
+	id, err := f.ids.NextID(ctx, models.EntityOperation)
+	if err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 
 	operation := &models.Operation{
-		ID:            f.nextID,
+		ID:            id,
 		Type:          opType,
 		BankAccountID: bankAccountID,
 		CategoryID:    categoryID,
@@ -43,13 +51,5 @@ func (f *OperationFactory) CreateOperation(
 		return nil, err
 	}
 
-	f.nextID++
 	return operation, nil
 }
-
-// SetNextID устанавливает следующий ID для фабрики
-func (f *OperationFactory) SetNextID(id int) {
-	if id > f.nextID {
-		f.nextID = id
-	}
-}
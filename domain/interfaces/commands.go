@@ -1,8 +1,11 @@
 package interfaces
 
-// Command представляет команду для выполнения
+import "context"
+
+// Command представляет команду для выполнения.
+// Отмена контекста прерывает долгие команды, например импорт.
 type Command interface {
-	Execute() error
+	Execute(ctx context.Context) error
 	GetName() string
 }
 
@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"KPO1/domain/models"
+	"context"
 )
 
 // ExportVisitor интерфейс для экспорта данных с использованием паттерна Посетитель
@@ -13,7 +14,7 @@ type ExportVisitor interface {
 
 // CompositeRepository интерфейс композитного репозитория для экспорта/импорта
 type CompositeRepository interface {
-	GetBankAccounts() ([]*models.BankAccount, error)
-	GetCategories() ([]*models.Category, error)
-	GetOperations() ([]*models.Operation, error)
+	GetBankAccounts(ctx context.Context) ([]*models.BankAccount, error)
+	GetCategories(ctx context.Context) ([]*models.Category, error)
+	GetOperations(ctx context.Context) ([]*models.Operation, error)
 }
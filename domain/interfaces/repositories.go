@@ -2,34 +2,58 @@ package interfaces
 
 import (
 	"KPO1/domain/models"
+	"context"
 	"time"
 )
 
-// Repository представляет общий интерфейс для репозиториев
+// Repository представляет общий интерфейс для репозиториев.
+// Все методы принимают контекст и прекращают работу, если он отменен.
+// Delete перемещает сущность в корзину: она пропадает из GetByID, GetAll и запросов,
+// но может быть восстановлена через TrashRepository.
 type Repository[T any] interface {
-	GetByID(id int) (*T, error)
-	GetAll() ([]*T, error)
-	Save(item *T) error
-	Update(item *T) error
-	Delete(id int) error
+	GetByID(ctx context.Context, id int) (*T, error)
+	GetAll(ctx context.Context) ([]*T, error)
+	Save(ctx context.Context, item *T) error
+	Update(ctx context.Context, item *T) error
+	Delete(ctx context.Context, id int) error
+}
+
+// TrashRepository представляет корзину удаленных сущностей
+type TrashRepository[T any] interface {
+	GetDeleted(ctx context.Context) ([]*T, error)
+	Restore(ctx context.Context, id int) error
+	// Purge окончательно удаляет сущности, перемещенные в корзину раньше deletedBefore
+	Purge(ctx context.Context, deletedBefore time.Time) (int, error)
 }
 
 // BankAccountRepository представляет репозиторий для работы с банковскими счетами
 type BankAccountRepository interface {
 	Repository[models.BankAccount]
+	TrashRepository[models.BankAccount]
 }
 
 // CategoryRepository представляет репозиторий для работы с категориями
 type CategoryRepository interface {
 	Repository[models.Category]
-	GetByType(opType models.OperationType) ([]*models.Category, error)
+	TrashRepository[models.Category]
+	GetByType(ctx context.Context, opType models.OperationType) ([]*models.Category, error)
 }
 
 // OperationRepository представляет репозиторий для работы с операциями
 type OperationRepository interface {
 	Repository[models.Operation]
-	GetByBankAccountID(bankAccountID int) ([]*models.Operation, error)
-	GetByCategoryID(categoryID int) ([]*models.Operation, error)
-	GetByDateRange(start, end time.Time) ([]*models.Operation, error)
-	GetByTypeAndDateRange(opType models.OperationType, start, end time.Time) ([]*models.Operation, error)
+	TrashRepository[models.Operation]
+	GetByBankAccountID(ctx context.Context, bankAccountID int) ([]*models.Operation, error)
+	GetByCategoryID(ctx context.Context, categoryID int) ([]*models.Operation, error)
+	GetByDateRange(ctx context.Context, start, end time.Time) ([]*models.Operation, error)
+	GetByTypeAndDateRange(ctx context.Context, opType models.OperationType, start, end time.Time) ([]*models.Operation, error)
+	// Find возвращает страницу операций, отобранных и упорядоченных по запросу
+	Find(ctx context.Context, query models.OperationQuery) (*models.OperationPage, error)
+}
+
+// IDAllocator выдает ID новым сущностям из счетчиков хранилища.
+// Счетчики общие для фабрик, репозиториев и импорта: сохранение записи с явным ID
+// сдвигает счетчик за него, поэтому выданный ID не совпадает с уже существующим.
+type IDAllocator interface {
+	NextID(ctx context.Context, entity models.EntityKind) (int, error)
 }
@@ -2,44 +2,63 @@ package interfaces
 
 import (
 	"KPO1/domain/models"
+	"context"
 	"time"
 )
 
 // BankAccountService представляет сервис для управления банковскими счетами
 type BankAccountService interface {
-	CreateBankAccount(name string) (*models.BankAccount, error)
-	GetBankAccount(id int) (*models.BankAccount, error)
-	GetAllBankAccounts() ([]*models.BankAccount, error)
-	UpdateBankAccount(id int, name string) (*models.BankAccount, error)
-	DeleteBankAccount(id int) error
-	RecalculateBalance(id int) (*models.BankAccount, error)
+	CreateBankAccount(ctx context.Context, name string) (*models.BankAccount, error)
+	GetBankAccount(ctx context.Context, id int) (*models.BankAccount, error)
+	GetAllBankAccounts(ctx context.Context) ([]*models.BankAccount, error)
+	UpdateBankAccount(ctx context.Context, id, version int, name string) (*models.BankAccount, error)
+	DeleteBankAccount(ctx context.Context, id int) error
+	GetDeletedBankAccounts(ctx context.Context) ([]*models.BankAccount, error)
+	RestoreBankAccount(ctx context.Context, id int) error
+	PurgeBankAccounts(ctx context.Context, deletedBefore time.Time) (int, error)
+	RecalculateBalance(ctx context.Context, id int) (*models.BankAccount, error)
+}
+
+// ReconciliationService представляет сервис сверки балансов счетов с их операциями
+type ReconciliationService interface {
+	// FindDiscrepancies возвращает счета, баланс которых не совпадает с суммой операций
+	FindDiscrepancies(ctx context.Context) ([]*models.BalanceDiscrepancy, error)
+	// RecordOpeningBalance сохраняет баланс счета, записывая разницу операцией начального остатка
+	RecordOpeningBalance(ctx context.Context, bankAccountID int) (*models.Operation, error)
 }
 
 // CategoryService представляет сервис для управления категориями
 type CategoryService interface {
-	CreateCategory(name string, opType models.OperationType) (*models.Category, error)
-	GetCategory(id int) (*models.Category, error)
-	GetAllCategories() ([]*models.Category, error)
-	GetCategoriesByType(opType models.OperationType) ([]*models.Category, error)
-	UpdateCategory(id int, name string, opType models.OperationType) (*models.Category, error)
-	DeleteCategory(id int) error
+	CreateCategory(ctx context.Context, name string, opType models.OperationType) (*models.Category, error)
+	GetCategory(ctx context.Context, id int) (*models.Category, error)
+	GetAllCategories(ctx context.Context) ([]*models.Category, error)
+	GetCategoriesByType(ctx context.Context, opType models.OperationType) ([]*models.Category, error)
+	UpdateCategory(ctx context.Context, id, version int, name string, opType models.OperationType) (*models.Category, error)
+	DeleteCategory(ctx context.Context, id int) error
+	GetDeletedCategories(ctx context.Context) ([]*models.Category, error)
+	RestoreCategory(ctx context.Context, id int) error
+	PurgeCategories(ctx context.Context, deletedBefore time.Time) (int, error)
 }
 
 // OperationService представляет сервис для управления операциями
 type OperationService interface {
-	CreateOperation(bankAccountID, categoryID int, amount float64, opType models.OperationType, date time.Time, description string) (*models.Operation, error)
-	GetOperation(id int) (*models.Operation, error)
-	GetAllOperations() ([]*models.Operation, error)
-	GetOperationsByBankAccount(bankAccountID int) ([]*models.Operation, error)
-	GetOperationsByCategory(categoryID int) ([]*models.Operation, error)
-	GetOperationsByDateRange(start, end time.Time) ([]*models.Operation, error)
-	UpdateOperation(id, bankAccountID, categoryID int, amount float64, opType models.OperationType, date time.Time, description string) (*models.Operation, error)
-	DeleteOperation(id int) error
+	CreateOperation(ctx context.Context, bankAccountID, categoryID int, amount float64, opType models.OperationType, date time.Time, description string) (*models.Operation, error)
+	GetOperation(ctx context.Context, id int) (*models.Operation, error)
+	GetAllOperations(ctx context.Context) ([]*models.Operation, error)
+	GetOperationsByBankAccount(ctx context.Context, bankAccountID int) ([]*models.Operation, error)
+	GetOperationsByCategory(ctx context.Context, categoryID int) ([]*models.Operation, error)
+	GetOperationsByDateRange(ctx context.Context, start, end time.Time) ([]*models.Operation, error)
+	FindOperations(ctx context.Context, query models.OperationQuery) (*models.OperationPage, error)
+	UpdateOperation(ctx context.Context, id, version, bankAccountID, categoryID int, amount float64, opType models.OperationType, date time.Time, description string) (*models.Operation, error)
+	DeleteOperation(ctx context.Context, id int) error
+	GetDeletedOperations(ctx context.Context) ([]*models.Operation, error)
+	RestoreOperation(ctx context.Context, id int) error
+	PurgeOperations(ctx context.Context, deletedBefore time.Time) (int, error)
 }
 
 // AnalyticsService представляет сервис для аналитики финансов
 type AnalyticsService interface {
-	GetIncomeExpenseDifference(start, end time.Time) (float64, error)
-	GetCategorySummary(start, end time.Time) (map[*models.Category]float64, error)
-	GetMonthlyDynamics(year int) (map[time.Month]map[models.OperationType]float64, error)
+	GetIncomeExpenseDifference(ctx context.Context, start, end time.Time) (float64, error)
+	GetCategorySummary(ctx context.Context, start, end time.Time) (map[*models.Category]float64, error)
+	GetMonthlyDynamics(ctx context.Context, year int) (map[time.Month]map[models.OperationType]float64, error)
 }
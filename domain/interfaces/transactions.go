@@ -0,0 +1,25 @@
+package interfaces
+
+import "context"
+
+// UnitOfWork представляет набор репозиториев, изменения в которых выполняются как единое целое
+type UnitOfWork interface {
+	BankAccounts() BankAccountRepository
+	Categories() CategoryRepository
+	Operations() OperationRepository
+}
+
+// Transaction представляет открытую транзакцию над репозиториями.
+// Изменения становятся видны другим только после Commit,
+// Rollback после Commit ничего не делает.
+type Transaction interface {
+	UnitOfWork
+	Commit() error
+	Rollback() error
+}
+
+// TransactionManager представляет источник транзакций.
+// Контекст ограничивает ожидание начала транзакции.
+type TransactionManager interface {
+	Begin(ctx context.Context) (Transaction, error)
+}
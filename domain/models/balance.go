@@ -0,0 +1,35 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// balanceTolerance допустимое расхождение баланса из-за округления, меньше копейки
+const balanceTolerance = 0.005
+
+// BalanceDiscrepancy представляет расхождение сохраненного баланса счета с суммой его операций
+type BalanceDiscrepancy struct {
+	BankAccountID int
+	AccountName   string
+	// StoredBalance баланс, сохраненный в счете
+	StoredBalance float64
+	// OperationsBalance баланс, рассчитанный по операциям счета
+	OperationsBalance float64
+}
+
+// Difference возвращает часть сохраненного баланса, не подтвержденную операциями
+func (d *BalanceDiscrepancy) Difference() float64 {
+	return d.StoredBalance - d.OperationsBalance
+}
+
+// String возвращает строковое представление расхождения
+func (d *BalanceDiscrepancy) String() string {
+	return fmt.Sprintf("Счет #%d: %s (Баланс: %.2f руб., по операциям: %.2f руб., разница: %+.2f руб.)",
+		d.BankAccountID, d.AccountName, d.StoredBalance, d.OperationsBalance, d.Difference())
+}
+
+// BalancesDiffer сообщает, что балансы различаются больше чем на ошибку округления
+func BalancesDiffer(a, b float64) bool {
+	return math.Abs(a-b) >= balanceTolerance
+}
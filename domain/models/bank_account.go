@@ -12,21 +12,28 @@ type BankAccount struct {
 	Balance   float64
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Version   int
+	DeletedAt *time.Time
 }
 
 // Validate проверяет валидность банковского счёта
 func (b *BankAccount) Validate() error {
 	if b.Name == "" {
-		return &ValidationError{Message: "Название счета не может быть пустым"}
+		return &ValidationError{Field: "name", Message: "Название счета не может быть пустым"}
 	}
 
 	if b.ID <= 0 {
-		return &ValidationError{Message: "ID счета должен быть положительным числом"}
+		return &ValidationError{Field: "id", Message: "ID счета должен быть положительным числом"}
 	}
 
 	return nil
 }
 
+// IsDeleted сообщает, что банковский счёт перемещен в корзину
+func (b *BankAccount) IsDeleted() bool {
+	return b.DeletedAt != nil
+}
+
 // String возвращает строковое представление банковского счёта
 func (b *BankAccount) String() string {
 	return fmt.Sprintf("Счет #%d: %s (Баланс: %.2f руб.)", b.ID, b.Name, b.Balance)
@@ -12,25 +12,32 @@ type Category struct {
 	Name      string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Version   int
+	DeletedAt *time.Time
 }
 
 // Validate проверяет валидность категории
 func (c *Category) Validate() error {
 	if c.Name == "" {
-		return &ValidationError{Message: "Название категории не может быть пустым"}
+		return &ValidationError{Field: "name", Message: "Название категории не может быть пустым"}
 	}
 
 	if c.ID <= 0 {
-		return &ValidationError{Message: "ID категории должен быть положительным числом"}
+		return &ValidationError{Field: "id", Message: "ID категории должен быть положительным числом"}
 	}
 
 	if c.Type != Income && c.Type != Expense {
-		return &ValidationError{Message: "Тип категории должен быть INCOME или EXPENSE"}
+		return &ValidationError{Field: "type", Message: "Тип категории должен быть INCOME или EXPENSE"}
 	}
 
 	return nil
 }
 
+// IsDeleted сообщает, что категория перемещена в корзину
+func (c *Category) IsDeleted() bool {
+	return c.DeletedAt != nil
+}
+
 // String возвращает строковое представление категории
 func (c *Category) String() string {
 	typeStr := "Расход"
@@ -7,12 +7,3 @@ const (
 	Income  OperationType = "INCOME"
 	Expense OperationType = "EXPENSE"
 )
-
-// ValidationError представляет ошибку валидации
-type ValidationError struct {
-	Message string
-}
-
-func (e ValidationError) Error() string {
-	return e.Message
-}
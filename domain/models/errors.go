@@ -0,0 +1,150 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode стабильный код ошибки, по которому клиенты (консоль, HTTP, тесты)
+// различают ошибки, не разбирая текст сообщения
+type ErrorCode string
+
+const (
+	CodeNotFound   ErrorCode = "not_found"
+	CodeConflict   ErrorCode = "conflict"
+	CodeInUse      ErrorCode = "in_use"
+	CodeValidation ErrorCode = "validation"
+	CodeInternal   ErrorCode = "internal"
+)
+
+// Категории доменных ошибок. Типизированные ошибки ниже сопоставляются с ними через errors.Is,
+// подробности можно получить через errors.As.
+var (
+	// ErrNotFound сущность не найдена или находится в корзине
+	ErrNotFound = errors.New("не найдено")
+	// ErrConflict сущность изменена после того, как клиент ее прочитал
+	ErrConflict = errors.New("конфликт версий")
+	// ErrInUse сущность нельзя удалить, пока на нее ссылаются другие
+	ErrInUse = errors.New("используется")
+	// ErrValidation входные данные не прошли проверку
+	ErrValidation = errors.New("ошибка валидации")
+)
+
+// EntityKind тип доменной сущности в ошибках
+type EntityKind string
+
+const (
+	EntityBankAccount EntityKind = "bank_account"
+	EntityCategory    EntityKind = "category"
+	EntityOperation   EntityKind = "operation"
+)
+
+// entityNames формы названий сущностей для сообщений об ошибках
+var entityNames = map[EntityKind]struct {
+	notFound string
+	genitive string
+	inUse    string
+}{
+	EntityBankAccount: {"банковский счет %s не найден", "счета", "нельзя удалить счет%s, по которому есть операции"},
+	EntityCategory:    {"категория %s не найдена", "категории", "нельзя удалить категорию%s, по которой есть операции"},
+	EntityOperation:   {"операция %s не найдена", "операции", "нельзя удалить операцию%s, на которую есть ссылки"},
+}
+
+// NotFoundError сущность с заданным ID не найдена
+type NotFoundError struct {
+	Entity EntityKind
+	ID     int
+	// InTrash ошибка восстановления: сущности нет в корзине
+	InTrash bool
+}
+
+func (e *NotFoundError) Error() string {
+	message := fmt.Sprintf(entityNames[e.Entity].notFound, fmt.Sprintf("#%d", e.ID))
+	if e.InTrash {
+		message += " в корзине"
+	}
+	return message
+}
+
+// Is сопоставляет ошибку с ErrNotFound
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// NewNotFoundError создает ошибку отсутствия сущности
+func NewNotFoundError(entity EntityKind, id int) error {
+	return &NotFoundError{Entity: entity, ID: id}
+}
+
+// NewNotInTrashError создает ошибку отсутствия сущности в корзине
+func NewNotInTrashError(entity EntityKind, id int) error {
+	return &NotFoundError{Entity: entity, ID: id, InTrash: true}
+}
+
+// ConflictError представляет ошибку конкурентного изменения: сущность была
+// изменена с момента ее чтения, и обновление по устаревшей версии отклонено
+type ConflictError struct {
+	Entity          EntityKind
+	ID              int
+	ExpectedVersion int
+	ActualVersion   int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("данные %s #%d устарели (версия %d, текущая %d): получите актуальные данные и повторите",
+		entityNames[e.Entity].genitive, e.ID, e.ExpectedVersion, e.ActualVersion)
+}
+
+// Is сопоставляет ошибку с ErrConflict
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// InUseError сущность нельзя удалить, так как на нее ссылаются другие сущности
+type InUseError struct {
+	Entity EntityKind
+	ID     int
+}
+
+func (e *InUseError) Error() string {
+	return fmt.Sprintf(entityNames[e.Entity].inUse, fmt.Sprintf(" #%d", e.ID))
+}
+
+// Is сопоставляет ошибку с ErrInUse
+func (e *InUseError) Is(target error) bool {
+	return target == ErrInUse
+}
+
+// ValidationError представляет ошибку валидации входных данных
+type ValidationError struct {
+	// Field имя поля, не прошедшего проверку; пустое, если ошибка относится к данным в целом
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// Is сопоставляет ошибку с ErrValidation
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// ErrorCodeOf возвращает стабильный код ошибки; ошибки вне доменной таксономии считаются внутренними
+func ErrorCodeOf(err error) ErrorCode {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, ErrConflict):
+		return CodeConflict
+	case errors.Is(err, ErrInUse):
+		return CodeInUse
+	case errors.Is(err, ErrValidation):
+		return CodeValidation
+	default:
+		return CodeInternal
+	}
+}
@@ -14,34 +14,43 @@ type Operation struct {
 	Amount        float64
 	Date          time.Time
 	Description   string
-	CreatedAt     time.Time
+	// ExternalID идентификатор операции в выписке банка (например, FITID в OFX); пустой для операций, введенных вручную
+	ExternalID string
+	CreatedAt  time.Time
+	Version    int
+	DeletedAt  *time.Time
 }
 
 // Validate проверяет валидность операции
 func (o *Operation) Validate() error {
 	if o.ID <= 0 {
-		return &ValidationError{Message: "ID операции должен быть положительным числом"}
+		return &ValidationError{Field: "id", Message: "ID операции должен быть положительным числом"}
 	}
 
 	if o.Type != Income && o.Type != Expense {
-		return &ValidationError{Message: "Тип операции должен быть INCOME или EXPENSE"}
+		return &ValidationError{Field: "type", Message: "Тип операции должен быть INCOME или EXPENSE"}
 	}
 
 	if o.BankAccountID <= 0 {
-		return &ValidationError{Message: "ID счета должен быть положительным числом"}
+		return &ValidationError{Field: "bank_account_id", Message: "ID счета должен быть положительным числом"}
 	}
 
 	if o.CategoryID <= 0 {
-		return &ValidationError{Message: "ID категории должен быть положительным числом"}
+		return &ValidationError{Field: "category_id", Message: "ID категории должен быть положительным числом"}
 	}
 
 	if o.Amount <= 0 {
-		return &ValidationError{Message: "Сумма операции должна быть положительным числом"}
+		return &ValidationError{Field: "amount", Message: "Сумма операции должна быть положительным числом"}
 	}
 
 	return nil
 }
 
+// IsDeleted сообщает, что операция перемещена в корзину
+func (o *Operation) IsDeleted() bool {
+	return o.DeletedAt != nil
+}
+
 // String возвращает строковое представление операции
 func (o *Operation) String() string {
 	typeStr := "Расход"
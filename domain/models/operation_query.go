@@ -0,0 +1,279 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// OperationSortField поле, по которому сортируются операции.
+// Значение совпадает с именем колонки в SQL-хранилище.
+type OperationSortField string
+
+const (
+	SortOperationsByID          OperationSortField = "id"
+	SortOperationsByDate        OperationSortField = "date"
+	SortOperationsByAmount      OperationSortField = "amount"
+	SortOperationsByType        OperationSortField = "type"
+	SortOperationsByBankAccount OperationSortField = "bank_account_id"
+	SortOperationsByCategory    OperationSortField = "category_id"
+	SortOperationsByDescription OperationSortField = "description"
+	SortOperationsByCreatedAt   OperationSortField = "created_at"
+)
+
+// OperationSortFields возвращает все поля, по которым можно сортировать операции
+func OperationSortFields() []OperationSortField {
+	return []OperationSortField{
+		SortOperationsByID,
+		SortOperationsByDate,
+		SortOperationsByAmount,
+		SortOperationsByType,
+		SortOperationsByBankAccount,
+		SortOperationsByCategory,
+		SortOperationsByDescription,
+		SortOperationsByCreatedAt,
+	}
+}
+
+// OperationQuery спецификация запроса операций: фильтр, сортировка и страница.
+// Нулевые значения полей фильтра означают отсутствие условия.
+type OperationQuery struct {
+	BankAccountID int
+	CategoryID    int
+	Type          OperationType
+	MinAmount     *float64
+	MaxAmount     *float64
+	From          time.Time
+	To            time.Time
+	// Description подстрока описания, ищется без учета регистра
+	Description string
+
+	// SortBy поле сортировки, по умолчанию ID. При равных значениях операции упорядочены по ID.
+	SortBy     OperationSortField
+	Descending bool
+
+	// Limit размер страницы, 0 - без ограничения
+	Limit int
+	// Offset количество пропускаемых операций
+	Offset int
+	// Cursor продолжение предыдущей страницы (OperationPage.NextCursor).
+	// Не сдвигается при добавлении и удалении операций, в отличие от Offset.
+	Cursor string
+}
+
+// OperationPage страница результата запроса операций
+type OperationPage struct {
+	Operations []*Operation
+	// Total количество операций, подходящих под фильтр, без учета страницы
+	Total int
+	// NextCursor курсор следующей страницы, пустой для последней страницы
+	NextCursor string
+}
+
+// operationCursor содержимое курсора: сортировка и ключ последней операции страницы
+type operationCursor struct {
+	SortBy     OperationSortField `json:"s"`
+	Descending bool               `json:"d"`
+	Key        Operation          `json:"k"`
+}
+
+// Validate проверяет корректность запроса
+func (q *OperationQuery) Validate() error {
+	if q.BankAccountID < 0 {
+		return &ValidationError{Field: "bank_account_id", Message: "ID счета не может быть отрицательным"}
+	}
+
+	if q.CategoryID < 0 {
+		return &ValidationError{Field: "category_id", Message: "ID категории не может быть отрицательным"}
+	}
+
+	if q.Type != "" && q.Type != Income && q.Type != Expense {
+		return &ValidationError{Field: "type", Message: "Тип операции должен быть INCOME или EXPENSE"}
+	}
+
+	if q.MinAmount != nil && q.MaxAmount != nil && *q.MinAmount > *q.MaxAmount {
+		return &ValidationError{Field: "min_amount", Message: "Минимальная сумма больше максимальной"}
+	}
+
+	if !q.From.IsZero() && !q.To.IsZero() && q.From.After(q.To) {
+		return &ValidationError{Field: "from", Message: "Дата начала периода позже даты окончания"}
+	}
+
+	if !q.validSortField() {
+		return &ValidationError{Field: "sort_by", Message: "Неизвестное поле сортировки: " + string(q.SortBy)}
+	}
+
+	if q.Limit < 0 {
+		return &ValidationError{Field: "limit", Message: "Размер страницы не может быть отрицательным"}
+	}
+
+	if q.Offset < 0 {
+		return &ValidationError{Field: "offset", Message: "Смещение не может быть отрицательным"}
+	}
+
+	if q.Cursor != "" && q.Offset > 0 {
+		return &ValidationError{Field: "cursor", Message: "Нельзя одновременно задавать курсор и смещение"}
+	}
+
+	if _, err := q.CursorKey(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SortField возвращает поле сортировки с учетом значения по умолчанию
+func (q *OperationQuery) SortField() OperationSortField {
+	if q.SortBy == "" {
+		return SortOperationsByID
+	}
+	return q.SortBy
+}
+
+// validSortField сообщает, что поле сортировки известно
+func (q *OperationQuery) validSortField() bool {
+	for _, field := range OperationSortFields() {
+		if q.SortField() == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches проверяет, подходит ли операция под фильтр запроса
+func (q *OperationQuery) Matches(o *Operation) bool {
+	if q.BankAccountID != 0 && o.BankAccountID != q.BankAccountID {
+		return false
+	}
+	if q.CategoryID != 0 && o.CategoryID != q.CategoryID {
+		return false
+	}
+	if q.Type != "" && o.Type != q.Type {
+		return false
+	}
+	if q.MinAmount != nil && o.Amount < *q.MinAmount {
+		return false
+	}
+	if q.MaxAmount != nil && o.Amount > *q.MaxAmount {
+		return false
+	}
+	if !q.From.IsZero() && o.Date.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && o.Date.After(q.To) {
+		return false
+	}
+	if q.Description != "" && !strings.Contains(strings.ToLower(o.Description), strings.ToLower(q.Description)) {
+		return false
+	}
+	return true
+}
+
+// Compare сравнивает операции в порядке сортировки запроса:
+// отрицательное число, если a идет раньше b, положительное - если позже
+func (q *OperationQuery) Compare(a, b *Operation) int {
+	result := compareOperationField(q.SortField(), a, b)
+	if result == 0 {
+		result = compareValues(a.ID, b.ID)
+	}
+	if q.Descending {
+		return -result
+	}
+	return result
+}
+
+// CursorKey возвращает ключ сортировки операции, после которой начинается страница,
+// или nil, если курсор не задан
+func (q *OperationQuery) CursorKey() (*Operation, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, &ValidationError{Field: "cursor", Message: "Неверный курсор страницы"}
+	}
+
+	var cursor operationCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, &ValidationError{Field: "cursor", Message: "Неверный курсор страницы"}
+	}
+
+	if cursor.SortBy != q.SortField() || cursor.Descending != q.Descending {
+		return nil, &ValidationError{Field: "cursor", Message: "Курсор страницы получен для другой сортировки"}
+	}
+
+	return &cursor.Key, nil
+}
+
+// NextCursor возвращает курсор страницы, следующей за операцией last
+func (q *OperationQuery) NextCursor(last *Operation) string {
+	cursor := operationCursor{SortBy: q.SortField(), Descending: q.Descending}
+	cursor.Key.ID = last.ID
+	switch q.SortField() {
+	case SortOperationsByDate:
+		cursor.Key.Date = last.Date
+	case SortOperationsByAmount:
+		cursor.Key.Amount = last.Amount
+	case SortOperationsByType:
+		cursor.Key.Type = last.Type
+	case SortOperationsByBankAccount:
+		cursor.Key.BankAccountID = last.BankAccountID
+	case SortOperationsByCategory:
+		cursor.Key.CategoryID = last.CategoryID
+	case SortOperationsByDescription:
+		cursor.Key.Description = last.Description
+	case SortOperationsByCreatedAt:
+		cursor.Key.CreatedAt = last.CreatedAt
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// compareOperationField сравнивает значения поля сортировки двух операций
+func compareOperationField(field OperationSortField, a, b *Operation) int {
+	switch field {
+	case SortOperationsByDate:
+		return compareTimes(a.Date, b.Date)
+	case SortOperationsByAmount:
+		return compareValues(a.Amount, b.Amount)
+	case SortOperationsByType:
+		return compareValues(a.Type, b.Type)
+	case SortOperationsByBankAccount:
+		return compareValues(a.BankAccountID, b.BankAccountID)
+	case SortOperationsByCategory:
+		return compareValues(a.CategoryID, b.CategoryID)
+	case SortOperationsByDescription:
+		return compareValues(a.Description, b.Description)
+	case SortOperationsByCreatedAt:
+		return compareTimes(a.CreatedAt, b.CreatedAt)
+	default:
+		return 0
+	}
+}
+
+// compareValues сравнивает два упорядочиваемых значения
+func compareValues[T ~int | ~float64 | ~string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compareTimes сравнивает два момента времени
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	default:
+		return 0
+	}
+}
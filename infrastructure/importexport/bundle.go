@@ -0,0 +1,552 @@
+package importexport
+
+import (
+	"KPO1/domain/interfaces"
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BundleVersion версия схемы манифеста резервной копии; копии более новой версии не читаются
+const BundleVersion = 1
+
+// bundleManifestName имя манифеста в архиве
+const bundleManifestName = "manifest.json"
+
+// maxBundleEntrySize предельный размер файла в архиве, защищающий от архивов-бомб
+const maxBundleEntrySize = 1 << 30
+
+// bundleEntities сущности резервной копии в порядке записи
+var bundleEntities = []string{"accounts", "categories", "operations"}
+
+// BundleKind вид архива резервной копии
+type BundleKind string
+
+const (
+	// BundleZip архив ZIP
+	BundleZip BundleKind = "zip"
+	// BundleTarGz архив tar, сжатый gzip
+	BundleTarGz BundleKind = "tar.gz"
+)
+
+// BundleKindOf определяет вид архива по имени файла: .tar.gz и .tgz - tar.gz, остальные - zip
+func BundleKindOf(path string) BundleKind {
+	lower := strings.ToLower(path)
+	if strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") {
+		return BundleTarGz
+	}
+	return BundleZip
+}
+
+// ParseBundleKind разбирает вид архива
+func ParseBundleKind(name string) (BundleKind, error) {
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), ".")) {
+	case "zip", "":
+		return BundleZip, nil
+	case "tar.gz", "tgz":
+		return BundleTarGz, nil
+	default:
+		return "", fmt.Errorf("неизвестный вид архива %q, ожидается zip или tar.gz", name)
+	}
+}
+
+// BundleFile файл сущности в резервной копии
+type BundleFile struct {
+	Name   string `json:"name"`
+	Entity string `json:"entity"`
+	Count  int    `json:"count"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// BundleManifest манифест резервной копии: версия схемы, формат файлов сущностей,
+// время экспорта, число записей и контрольные суммы файлов
+type BundleManifest struct {
+	Version    int          `json:"schema_version"`
+	Format     FileFormat   `json:"format"`
+	ExportedAt time.Time    `json:"exported_at"`
+	Files      []BundleFile `json:"files"`
+}
+
+// Count возвращает число записей сущности по манифесту
+func (m *BundleManifest) Count(entity string) int {
+	for _, file := range m.Files {
+		if file.Entity == entity {
+			return file.Count
+		}
+	}
+	return 0
+}
+
+// BundleExporter экспортирует все данные одним архивом с манифестом
+type BundleExporter struct {
+	kind   BundleKind
+	format FileFormat
+	// path файл архива; пустой, если архив пишется в поток w
+	path       string
+	w          io.Writer
+	repository interfaces.CompositeRepository
+}
+
+// NewBundleExporter создает новый экспортер резервной копии в файл path;
+// вид архива определяется расширением, файлы сущностей пишутся в формате format
+func NewBundleExporter(path string, format FileFormat, repository interfaces.CompositeRepository) *BundleExporter {
+	return &BundleExporter{kind: BundleKindOf(path), format: format, path: path, repository: repository}
+}
+
+// NewStreamBundleExporter создает новый экспортер резервной копии в поток w. Поток экспортер не закрывает.
+func NewStreamBundleExporter(w io.Writer, kind BundleKind, format FileFormat, repository interfaces.CompositeRepository) *BundleExporter {
+	return &BundleExporter{kind: kind, format: format, w: w, repository: repository}
+}
+
+// Export записывает архив и возвращает его манифест. Файл архива появляется только
+// после успешной записи, поэтому прерванный экспорт не оставляет неполную копию.
+func (e *BundleExporter) Export(ctx context.Context) (*BundleManifest, error) {
+	codec, err := LookupCodec(e.format)
+	if err != nil {
+		return nil, err
+	}
+	if codec.BankAccounts.Encode == nil || codec.Categories.Encode == nil || codec.Operations.Encode == nil {
+		return nil, fmt.Errorf("формат %s не поддерживает экспорт всех сущностей", e.format)
+	}
+
+	files, manifest, err := e.encode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.path == "" {
+		return manifest, writeBundle(e.w, e.kind, manifest, files)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(e.path), ".backup-*")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBundle(temp, e.kind, manifest, files); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return nil, err
+	}
+	if err := temp.Close(); err != nil {
+		os.Remove(temp.Name())
+		return nil, err
+	}
+	if err := os.Rename(temp.Name(), e.path); err != nil {
+		os.Remove(temp.Name())
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// encode записывает сущности в файлы формата и составляет манифест
+func (e *BundleExporter) encode(ctx context.Context) (map[string][]byte, *BundleManifest, error) {
+	accounts, err := e.repository.GetBankAccounts(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка получения счетов: %w", err)
+	}
+	categories, err := e.repository.GetCategories(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка получения категорий: %w", err)
+	}
+	operations, err := e.repository.GetOperations(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка получения операций: %w", err)
+	}
+
+	manifest := &BundleManifest{Version: BundleVersion, Format: e.format, ExportedAt: time.Now()}
+	files := make(map[string][]byte, len(bundleEntities))
+	for _, entity := range bundleEntities {
+		var buffer bytes.Buffer
+		var count int
+		switch entity {
+		case "accounts":
+			count, err = len(accounts), WriteBankAccounts(&buffer, e.format, accounts)
+		case "categories":
+			count, err = len(categories), WriteCategories(&buffer, e.format, categories)
+		case "operations":
+			count, err = len(operations), WriteOperations(&buffer, e.format, operations)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		name := fmt.Sprintf("%s.%s", entity, e.format)
+		sum := sha256.Sum256(buffer.Bytes())
+		files[name] = buffer.Bytes()
+		manifest.Files = append(manifest.Files, BundleFile{
+			Name:   name,
+			Entity: entity,
+			Count:  count,
+			Size:   int64(buffer.Len()),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+	return files, manifest, nil
+}
+
+// writeBundle записывает манифест и файлы сущностей архивом вида kind
+func writeBundle(w io.Writer, kind BundleKind, manifest *BundleManifest, files map[string][]byte) error {
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	entries := []struct {
+		name string
+		data []byte
+	}{{bundleManifestName, manifestData}}
+	for _, file := range manifest.Files {
+		entries = append(entries, struct {
+			name string
+			data []byte
+		}{file.Name, files[file.Name]})
+	}
+
+	switch kind {
+	case BundleTarGz:
+		compressed := gzip.NewWriter(w)
+		archive := tar.NewWriter(compressed)
+		for _, entry := range entries {
+			header := &tar.Header{
+				Name:     entry.name,
+				Mode:     0644,
+				Size:     int64(len(entry.data)),
+				ModTime:  manifest.ExportedAt,
+				Typeflag: tar.TypeReg,
+			}
+			if err := archive.WriteHeader(header); err != nil {
+				return err
+			}
+			if _, err := archive.Write(entry.data); err != nil {
+				return err
+			}
+		}
+		if err := archive.Close(); err != nil {
+			return err
+		}
+		return compressed.Close()
+	default:
+		archive := zip.NewWriter(w)
+		for _, entry := range entries {
+			file, err := archive.CreateHeader(&zip.FileHeader{
+				Name:     entry.name,
+				Method:   zip.Deflate,
+				Modified: manifest.ExportedAt,
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := file.Write(entry.data); err != nil {
+				return err
+			}
+		}
+		return archive.Close()
+	}
+}
+
+// Bundle проверенная резервная копия
+type Bundle struct {
+	Manifest *BundleManifest
+	files    map[string][]byte
+}
+
+// Streams возвращает потоки файлов сущностей для импорта
+func (b *Bundle) Streams() ImportStreams {
+	streams := ImportStreams{}
+	for _, file := range b.Manifest.Files {
+		r := bytes.NewReader(b.files[file.Name])
+		switch file.Entity {
+		case "accounts":
+			streams.BankAccounts = r
+		case "categories":
+			streams.Categories = r
+		case "operations":
+			streams.Operations = r
+		}
+	}
+	return streams
+}
+
+// ReadBundle читает резервную копию и проверяет ее: версию схемы и формат в манифесте,
+// состав архива, размеры и SHA-256 файлов, разбор записей и их число.
+// Найденные повреждения возвращаются отчетом *ValidationReport в ошибке, одна проблема на каждое.
+func ReadBundle(ctx context.Context, r io.Reader, kind BundleKind) (*Bundle, error) {
+	report := &ValidationReport{}
+	entries, err := readBundleEntries(r, kind, report)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestData, ok := entries[bundleManifestName]
+	if !ok {
+		return nil, fmt.Errorf("в архиве нет манифеста %s", bundleManifestName)
+	}
+	manifest := &BundleManifest{}
+	if err := json.Unmarshal(manifestData, manifest); err != nil {
+		return nil, fmt.Errorf("манифест %s поврежден: %w", bundleManifestName, err)
+	}
+	if manifest.Version < 1 || manifest.Version > BundleVersion {
+		return nil, fmt.Errorf("версия схемы резервной копии %d не поддерживается, ожидается от 1 до %d", manifest.Version, BundleVersion)
+	}
+	codec, err := LookupCodec(manifest.Format)
+	if err != nil {
+		return nil, err
+	}
+	if !codec.CanImport() {
+		return nil, fmt.Errorf("формат %s не поддерживает импорт", manifest.Format)
+	}
+
+	problem := func(file, message string) {
+		report.Add(ImportProblem{File: file, Message: message})
+	}
+	listed := map[string]bool{bundleManifestName: true}
+	entities := make(map[string]bool, len(bundleEntities))
+	for _, file := range manifest.Files {
+		if listed[file.Name] {
+			problem(file.Name, "файл указан в манифесте дважды")
+			continue
+		}
+		listed[file.Name] = true
+		if entities[file.Entity] {
+			problem(file.Name, fmt.Sprintf("сущность %s указана в манифесте дважды", file.Entity))
+			continue
+		}
+		entities[file.Entity] = true
+
+		data, ok := entries[file.Name]
+		if !ok {
+			problem(file.Name, "файл из манифеста отсутствует в архиве")
+			continue
+		}
+		if int64(len(data)) != file.Size {
+			problem(file.Name, fmt.Sprintf("размер %d байт не совпадает с манифестом (%d байт)", len(data), file.Size))
+			continue
+		}
+		sum := sha256.Sum256(data)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), file.SHA256) {
+			problem(file.Name, "контрольная сумма SHA-256 не совпадает с манифестом")
+			continue
+		}
+
+		count, fileReport, err := countBundleRecords(ctx, file.Entity, data, manifest.Format)
+		if err != nil {
+			problem(file.Name, err.Error())
+			continue
+		}
+		if !fileReport.Valid() {
+			problem(file.Name, fmt.Sprintf("записи не прошли проверку, проблем: %d", len(fileReport.Problems)))
+			continue
+		}
+		if count != file.Count {
+			problem(file.Name, fmt.Sprintf("записей %d, а в манифесте %d", count, file.Count))
+		}
+	}
+	for _, entity := range bundleEntities {
+		if !entities[entity] {
+			problem(bundleManifestName, fmt.Sprintf("в манифесте нет файла сущности %s", entity))
+		}
+	}
+	for name := range entries {
+		if !listed[name] {
+			problem(name, "файл не указан в манифесте")
+		}
+	}
+
+	if !report.Valid() {
+		report.sort()
+		return nil, fmt.Errorf("резервная копия повреждена: %w", report)
+	}
+	return &Bundle{Manifest: manifest, files: entries}, nil
+}
+
+// countBundleRecords разбирает файл сущности и возвращает число записей
+func countBundleRecords(ctx context.Context, entity string, data []byte, format FileFormat) (int, *ValidationReport, error) {
+	r := bytes.NewReader(data)
+	switch entity {
+	case "accounts":
+		items, report, err := ReadBankAccounts(ctx, r, format)
+		return len(items), report, err
+	case "categories":
+		items, report, err := ReadCategories(ctx, r, format)
+		return len(items), report, err
+	case "operations":
+		items, report, err := ReadOperations(ctx, r, format)
+		return len(items), report, err
+	default:
+		return 0, nil, fmt.Errorf("неизвестная сущность %q", entity)
+	}
+}
+
+// readBundleEntries читает все файлы архива. Каталоги пропускаются, а файлы во вложенных
+// путях, повторы и слишком большие файлы попадают в отчет.
+func readBundleEntries(r io.Reader, kind BundleKind, report *ValidationReport) (map[string][]byte, error) {
+	entries := make(map[string][]byte)
+	add := func(name string, content io.Reader) error {
+		if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+			report.Add(ImportProblem{File: name, Message: "файл во вложенном пути"})
+			return nil
+		}
+		if _, ok := entries[name]; ok {
+			report.Add(ImportProblem{File: name, Message: "файл повторяется в архиве"})
+			return nil
+		}
+		data, err := io.ReadAll(io.LimitReader(content, maxBundleEntrySize+1))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if len(data) > maxBundleEntrySize {
+			report.Add(ImportProblem{File: name, Message: "файл слишком большой"})
+			return nil
+		}
+		entries[name] = data
+		return nil
+	}
+
+	switch kind {
+	case BundleTarGz:
+		compressed, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("архив tar.gz поврежден: %w", err)
+		}
+		archive := tar.NewReader(compressed)
+		for {
+			header, err := archive.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("архив tar.gz поврежден: %w", err)
+			}
+			if header.Typeflag == tar.TypeDir {
+				continue
+			}
+			if err := add(header.Name, archive); err != nil {
+				return nil, fmt.Errorf("архив tar.gz поврежден: %w", err)
+			}
+		}
+	default:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("архив zip поврежден: %w", err)
+		}
+		for _, file := range archive.File {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			content, err := file.Open()
+			if err != nil {
+				return nil, fmt.Errorf("архив zip поврежден: %s: %w", file.Name, err)
+			}
+			err = add(file.Name, content)
+			content.Close()
+			if err != nil {
+				return nil, fmt.Errorf("архив zip поврежден: %w", err)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// VerifyBundle проверяет файл резервной копии path и возвращает его манифест
+func VerifyBundle(ctx context.Context, path string) (*BundleManifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	bundle, err := ReadBundle(ctx, file, BundleKindOf(path))
+	if err != nil {
+		return nil, err
+	}
+	return bundle.Manifest, nil
+}
+
+// BundleImporter импортирует резервную копию: архив проверяется целиком до начала транзакции,
+// а поврежденная копия не импортируется вовсе
+type BundleImporter struct {
+	kind      BundleKind
+	open      func() (io.ReadCloser, error)
+	strategy  MergeStrategy
+	txManager interfaces.TransactionManager
+	summary   *ImportSummary
+	manifest  *BundleManifest
+}
+
+// NewBundleImporter создает новый импортер резервной копии из файла path
+func NewBundleImporter(path string, strategy MergeStrategy, txManager interfaces.TransactionManager) *BundleImporter {
+	return &BundleImporter{
+		kind: BundleKindOf(path),
+		open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+		strategy:  strategy,
+		txManager: txManager,
+		summary:   newImportSummary(strategy),
+	}
+}
+
+// NewStreamBundleImporter создает новый импортер резервной копии из потока r. Поток читается один раз.
+func NewStreamBundleImporter(r io.Reader, kind BundleKind, strategy MergeStrategy, txManager interfaces.TransactionManager) *BundleImporter {
+	consumed := false
+	return &BundleImporter{
+		kind: kind,
+		open: func() (io.ReadCloser, error) {
+			if consumed {
+				return nil, fmt.Errorf("поток резервной копии уже прочитан")
+			}
+			consumed = true
+			return io.NopCloser(r), nil
+		},
+		strategy:  strategy,
+		txManager: txManager,
+		summary:   newImportSummary(strategy),
+	}
+}
+
+// Summary возвращает итог последнего успешного импорта
+func (i *BundleImporter) Summary() *ImportSummary {
+	return i.summary
+}
+
+// Manifest возвращает манифест последней прочитанной копии
+func (i *BundleImporter) Manifest() *BundleManifest {
+	return i.manifest
+}
+
+// ImportAll проверяет архив и импортирует его в одной транзакции по стратегии слияния.
+// При повреждении архива возвращается *ImportAbortedError с этапом «проверка архива».
+func (i *BundleImporter) ImportAll(ctx context.Context) error {
+	r, err := i.open()
+	if err != nil {
+		return &ImportAbortedError{Stage: "проверка архива", Err: err}
+	}
+	bundle, err := ReadBundle(ctx, r, i.kind)
+	r.Close()
+	if err != nil {
+		return &ImportAbortedError{Stage: "проверка архива", Err: err}
+	}
+	i.manifest = bundle.Manifest
+
+	importer := NewStreamImporter(bundle.Manifest.Format, bundle.Streams(), i.strategy, i.txManager)
+	if err := importer.ImportAll(ctx); err != nil {
+		return err
+	}
+	i.summary = importer.Summary()
+	return nil
+}
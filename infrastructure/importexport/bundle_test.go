@@ -0,0 +1,262 @@
+package importexport
+
+import (
+	"KPO1/domain/models"
+	"KPO1/infrastructure/persistence"
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ledgerRepository композитный репозиторий над данными Ledger
+type ledgerRepository struct {
+	ledger *Ledger
+}
+
+// GetBankAccounts возвращает счета
+func (r ledgerRepository) GetBankAccounts(context.Context) ([]*models.BankAccount, error) {
+	return r.ledger.BankAccounts, nil
+}
+
+// GetCategories возвращает категории
+func (r ledgerRepository) GetCategories(context.Context) ([]*models.Category, error) {
+	return r.ledger.Categories, nil
+}
+
+// GetOperations возвращает операции
+func (r ledgerRepository) GetOperations(context.Context) ([]*models.Operation, error) {
+	return r.ledger.Operations, nil
+}
+
+// bundleLedger данные для резервной копии
+func bundleLedger() *Ledger {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	return &Ledger{
+		BankAccounts: []*models.BankAccount{{ID: 1, Name: "Основной", Balance: 49750}},
+		Categories: []*models.Category{
+			{ID: 1, Type: models.Income, Name: "Зарплата"},
+			{ID: 2, Type: models.Expense, Name: "Кафе"},
+		},
+		Operations: []*models.Operation{
+			{ID: 1, Type: models.Income, BankAccountID: 1, CategoryID: 1, Amount: 50000, Date: day, Description: "Аванс"},
+			{ID: 2, Type: models.Expense, BankAccountID: 1, CategoryID: 2, Amount: 250, Date: day, Description: "Кофе"},
+		},
+	}
+}
+
+// bundleEntry файл архива в порядке записи
+type bundleEntry struct {
+	name string
+	data []byte
+}
+
+// exportBundleEntries экспортирует bundleLedger и возвращает файлы получившегося архива
+func exportBundleEntries(t *testing.T) []bundleEntry {
+	t.Helper()
+	var out bytes.Buffer
+	exporter := NewStreamBundleExporter(&out, BundleZip, JSON, ledgerRepository{bundleLedger()})
+	if _, err := exporter.Export(context.Background()); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []bundleEntry
+	for _, file := range archive.File {
+		var data bytes.Buffer
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data.ReadFrom(r)
+		r.Close()
+		entries = append(entries, bundleEntry{file.Name, data.Bytes()})
+	}
+	return entries
+}
+
+// packBundle записывает файлы архивом вида kind без проверок writeBundle
+func packBundle(t *testing.T, kind BundleKind, entries []bundleEntry) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	switch kind {
+	case BundleTarGz:
+		compressed := gzip.NewWriter(&out)
+		archive := tar.NewWriter(compressed)
+		for _, entry := range entries {
+			if err := archive.WriteHeader(&tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.data)), Typeflag: tar.TypeReg}); err != nil {
+				t.Fatal(err)
+			}
+			archive.Write(entry.data)
+		}
+		archive.Close()
+		compressed.Close()
+	default:
+		archive := zip.NewWriter(&out)
+		for _, entry := range entries {
+			file, err := archive.Create(entry.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Write(entry.data)
+		}
+		archive.Close()
+	}
+	return out.Bytes()
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	for _, kind := range []BundleKind{BundleZip, BundleTarGz} {
+		t.Run(string(kind), func(t *testing.T) {
+			ctx := context.Background()
+			var out bytes.Buffer
+			manifest, err := NewStreamBundleExporter(&out, kind, JSON, ledgerRepository{bundleLedger()}).Export(ctx)
+			if err != nil {
+				t.Fatalf("Export: %v", err)
+			}
+			if manifest.Count("accounts") != 1 || manifest.Count("categories") != 2 || manifest.Count("operations") != 2 {
+				t.Errorf("manifest files = %+v", manifest.Files)
+			}
+
+			bundle, err := ReadBundle(ctx, bytes.NewReader(out.Bytes()), kind)
+			if err != nil {
+				t.Fatalf("ReadBundle: %v", err)
+			}
+			if bundle.Manifest.Version != BundleVersion || bundle.Manifest.Format != JSON {
+				t.Errorf("manifest = %+v", bundle.Manifest)
+			}
+
+			repo := persistence.NewMemoryRepository()
+			importer := NewStreamBundleImporter(bytes.NewReader(out.Bytes()), kind, MergeOverwrite, persistence.NewTransactionManager(repo))
+			if err := importer.ImportAll(ctx); err != nil {
+				t.Fatalf("ImportAll: %v", err)
+			}
+			operations, err := repo.GetAllOperations(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, operation := range operations {
+				got = append(got, fmt.Sprintf("%d:%s:%.2f", operation.ID, operation.Description, operation.Amount))
+			}
+			if strings.Join(got, ",") != "1:Аванс:50000.00,2:Кофе:250.00" {
+				t.Errorf("imported operations = %v", got)
+			}
+			if accounts, _ := repo.GetAllBankAccounts(ctx); len(accounts) != 1 || accounts[0].Name != "Основной" {
+				t.Errorf("imported accounts = %+v", accounts)
+			}
+		})
+	}
+}
+
+func TestReadBundleReportsDamage(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		// damage изменяет файлы архива, экспортированного из bundleLedger
+		damage   func(entries []bundleEntry) []bundleEntry
+		problems []string
+	}{
+		{
+			name: "flipped byte",
+			damage: func(entries []bundleEntry) []bundleEntry {
+				for n, entry := range entries {
+					if entry.name == "operations.json" {
+						data := append([]byte(nil), entry.data...)
+						data[len(data)/2] ^= 0x01
+						entries[n].data = data
+					}
+				}
+				return entries
+			},
+			problems: []string{"operations.json: контрольная сумма SHA-256 не совпадает с манифестом"},
+		},
+		{
+			name: "missing entry",
+			damage: func(entries []bundleEntry) []bundleEntry {
+				var kept []bundleEntry
+				for _, entry := range entries {
+					if entry.name != "categories.json" {
+						kept = append(kept, entry)
+					}
+				}
+				return kept
+			},
+			problems: []string{"categories.json: файл из манифеста отсутствует в архиве"},
+		},
+		{
+			name: "extra entry",
+			damage: func(entries []bundleEntry) []bundleEntry {
+				return append(entries, bundleEntry{"notes.txt", []byte("заметки")})
+			},
+			problems: []string{"notes.txt: файл не указан в манифесте"},
+		},
+		{
+			name: "nested path",
+			damage: func(entries []bundleEntry) []bundleEntry {
+				for n, entry := range entries {
+					if entry.name == "accounts.json" {
+						entries[n].name = "backup/accounts.json"
+					}
+				}
+				return entries
+			},
+			problems: []string{
+				"accounts.json: файл из манифеста отсутствует в архиве",
+				"backup/accounts.json: файл во вложенном пути",
+			},
+		},
+		{
+			name: "duplicate entry",
+			damage: func(entries []bundleEntry) []bundleEntry {
+				for _, entry := range entries {
+					if entry.name == "accounts.json" {
+						return append(entries, entry)
+					}
+				}
+				return entries
+			},
+			problems: []string{"accounts.json: файл повторяется в архиве"},
+		},
+	} {
+		for _, kind := range []BundleKind{BundleZip, BundleTarGz} {
+			t.Run(test.name+"/"+string(kind), func(t *testing.T) {
+				data := packBundle(t, kind, test.damage(exportBundleEntries(t)))
+
+				_, err := ReadBundle(context.Background(), bytes.NewReader(data), kind)
+				var report *ValidationReport
+				if !errors.As(err, &report) {
+					t.Fatalf("ReadBundle error = %v, want *ValidationReport", err)
+				}
+				var got []string
+				for _, problem := range report.Problems {
+					got = append(got, problem.String())
+				}
+				sort.Strings(got)
+				if strings.Join(got, "\n") != strings.Join(test.problems, "\n") {
+					t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(test.problems, "\n"))
+				}
+
+				// Поврежденная копия не импортируется
+				repo := persistence.NewMemoryRepository()
+				err = NewStreamBundleImporter(bytes.NewReader(data), kind, MergeOverwrite, persistence.NewTransactionManager(repo)).ImportAll(context.Background())
+				var aborted *ImportAbortedError
+				if !errors.As(err, &aborted) || aborted.Stage != "проверка архива" {
+					t.Errorf("ImportAll error = %v, want abort at archive check", err)
+				}
+				if operations, _ := repo.GetAllOperations(context.Background()); len(operations) != 0 {
+					t.Errorf("store has %d operations after rejected import", len(operations))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package importexport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CAMT053 выписка банка ISO 20022 camt.053 (BankToCustomerStatement)
+const CAMT053 FileFormat = "camt053"
+
+func init() {
+	RegisterCodec(&Codec{Format: CAMT053, Title: "camt.053 (выписка ISO 20022)", Statement: StatementCodec{Decode: decodeCAMT053}})
+}
+
+// camtAmount сумма с валютой
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+// camtDate дата или дата со временем
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+// camtAccount счет выписки
+type camtAccount struct {
+	IBAN     string `xml:"Id>IBAN"`
+	Other    string `xml:"Id>Othr>Id"`
+	Currency string `xml:"Ccy"`
+}
+
+// camtBalance остаток по счету
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	Indicator string     `xml:"CdtDbtInd"`
+	Date      camtDate   `xml:"Dt"`
+}
+
+// camtParty участник платежа: в ранних версиях схемы имя лежит прямо в элементе, в поздних - в Pty
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+// camtTransaction подробности платежа внутри записи
+type camtTransaction struct {
+	ServicerRef  string    `xml:"Refs>AcctSvcrRef"`
+	Debtor       camtParty `xml:"RltdPties>Dbtr"`
+	Creditor     camtParty `xml:"RltdPties>Cdtr"`
+	Unstructured []string  `xml:"RmtInf>Ustrd"`
+	Reference    string    `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	Info         string    `xml:"AddtlTxInf"`
+}
+
+// camtStatus статус записи: в версиях до 08 - код текстом, начиная с 08 - в элементе Cd
+type camtStatus struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+// camtEntry запись Ntry выписки
+type camtEntry struct {
+	Amount       camtAmount        `xml:"Amt"`
+	Indicator    string            `xml:"CdtDbtInd"`
+	Status       camtStatus        `xml:"Sts"`
+	BookingDate  camtDate          `xml:"BookgDt"`
+	ValueDate    camtDate          `xml:"ValDt"`
+	ServicerRef  string            `xml:"AcctSvcrRef"`
+	Transactions []camtTransaction `xml:"NtryDtls>TxDtls"`
+	Info         string            `xml:"AddtlNtryInf"`
+}
+
+// decodeCAMT053 читает выписку camt.053. Операции берутся из записей Ntry со статусом BOOK:
+// CdtDbtInd задает знак суммы, BookgDt (или ValDt) - дату, контрагент из RltdPties - получателя,
+// RmtInf - назначение платежа, AcctSvcrRef - ключ для поиска повторов. Отложенные записи
+// пропускаются. Остатки OPBD/PRCD и CLBD становятся начальным и конечным остатком выписки.
+// Для сторнирующих записей (RvslInd) CdtDbtInd уже указывает фактическое направление.
+func decodeCAMT053(r io.Reader, file string, report *ValidationReport) (*Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	charset := UTF8
+	if match := xmlEncoding.FindSubmatch(data[:min(len(data), 1024)]); match != nil {
+		if charset, err = ParseCharset(string(match[1])); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	text, err := decodeCharset(data, charset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	parser := &camtParser{file: file, text: text, report: report, statement: &Statement{}}
+	if err := parser.parse(); err != nil {
+		return nil, err
+	}
+	if !parser.found {
+		return nil, fmt.Errorf("%s: не найден элемент BkToCstmrStmt", file)
+	}
+	return parser.statement, nil
+}
+
+// camtParser читает элементы выписки потоком, чтобы знать строки записей
+type camtParser struct {
+	file      string
+	text      string
+	report    *ValidationReport
+	statement *Statement
+	// found выписка содержит элемент BkToCstmrStmt
+	found bool
+	// offset и line позиция, до которой посчитаны строки
+	offset int
+	line   int
+	index  int
+}
+
+// parse обходит документ и разбирает элементы Acct, Bal и Ntry выписок Stmt
+func (p *camtParser) parse() error {
+	decoder := xml.NewDecoder(strings.NewReader(p.text))
+	// Текст уже перекодирован в UTF-8, объявление кодировки можно не учитывать
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	p.line = 1
+
+	var path []string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s, строка %d: %w", p.file, p.lineAt(int(decoder.InputOffset())), err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			name := element.Name.Local
+			parent := ""
+			if len(path) > 0 {
+				parent = path[len(path)-1]
+			}
+			line := p.lineAt(int(decoder.InputOffset()))
+
+			switch {
+			case name == "BkToCstmrStmt":
+				p.found = true
+			case parent == "Stmt" && name == "Acct":
+				var account camtAccount
+				if err := decoder.DecodeElement(&account, &element); err != nil {
+					return fmt.Errorf("%s, строка %d: %w", p.file, line, err)
+				}
+				p.account(account, line)
+				continue
+			case parent == "Stmt" && name == "Bal":
+				var balance camtBalance
+				if err := decoder.DecodeElement(&balance, &element); err != nil {
+					return fmt.Errorf("%s, строка %d: %w", p.file, line, err)
+				}
+				p.balance(balance, line)
+				continue
+			case parent == "Stmt" && name == "Ntry":
+				var entry camtEntry
+				if err := decoder.DecodeElement(&entry, &element); err != nil {
+					return fmt.Errorf("%s, строка %d: %w", p.file, line, err)
+				}
+				p.entry(entry, line)
+				continue
+			}
+			path = append(path, name)
+		case xml.EndElement:
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+		}
+	}
+}
+
+// lineAt возвращает номер строки для смещения offset, не меньшего предыдущего
+func (p *camtParser) lineAt(offset int) int {
+	if offset > len(p.text) {
+		offset = len(p.text)
+	}
+	if offset > p.offset {
+		p.line += strings.Count(p.text[p.offset:offset], "\n")
+		p.offset = offset
+	}
+	return p.line
+}
+
+// account запоминает счет выписки; второй счет в одном файле - проблема
+func (p *camtParser) account(account camtAccount, line int) {
+	id := strings.TrimSpace(account.IBAN)
+	if id == "" {
+		id = strings.TrimSpace(account.Other)
+	}
+	switch {
+	case p.statement.AccountID == "":
+		p.statement.AccountID = id
+	case id != p.statement.AccountID:
+		p.report.Add(ImportProblem{
+			File:    p.file,
+			Line:    line,
+			Field:   "Acct",
+			Message: fmt.Sprintf("выписка содержит несколько счетов: %s и %s", p.statement.AccountID, id),
+		})
+	}
+	if p.statement.Currency == "" {
+		p.statement.Currency = strings.TrimSpace(account.Currency)
+	}
+}
+
+// balance запоминает самый ранний начальный и самый поздний конечный остаток
+func (p *camtParser) balance(balance camtBalance, line int) {
+	code := strings.ToUpper(strings.TrimSpace(balance.Code))
+	if code != "OPBD" && code != "PRCD" && code != "CLBD" {
+		return
+	}
+	problem := func(field, message string) {
+		p.report.Add(ImportProblem{File: p.file, Line: line, Field: field, Message: message})
+	}
+
+	amount, err := parseCAMTAmount(balance.Amount, balance.Indicator)
+	if err != nil {
+		problem("Bal/Amt", err.Error())
+		return
+	}
+	date, err := parseCAMTDate(balance.Date)
+	if err != nil {
+		problem("Bal/Dt", err.Error())
+		return
+	}
+
+	// Предыдущий конечный остаток PRCD датирован прошлым днем, он же начальный остаток следующего
+	if code == "PRCD" {
+		date = date.AddDate(0, 0, 1)
+	}
+	current := &StatementBalance{Date: date, Amount: amount}
+	if code == "CLBD" {
+		if p.statement.Closing == nil || !date.Before(p.statement.Closing.Date) {
+			p.statement.Closing = current
+		}
+		return
+	}
+	if p.statement.Opening == nil || date.Before(p.statement.Opening.Date) {
+		p.statement.Opening = current
+	}
+}
+
+// entry добавляет проведенную запись в операции выписки
+func (p *camtParser) entry(entry camtEntry, line int) {
+	status := strings.ToUpper(strings.TrimSpace(entry.Status.Code))
+	if status == "" {
+		status = strings.ToUpper(strings.TrimSpace(entry.Status.Value))
+	}
+	if status != "" && status != "BOOK" {
+		return
+	}
+
+	p.index++
+	problem := func(field, message string) {
+		p.report.Add(ImportProblem{File: p.file, Line: line, Record: p.index, Field: field, Message: message})
+	}
+
+	amount, err := parseCAMTAmount(entry.Amount, entry.Indicator)
+	if err != nil {
+		problem("Amt", err.Error())
+		return
+	}
+	dateValue := entry.BookingDate
+	if dateValue.Date == "" && dateValue.DateTime == "" {
+		dateValue = entry.ValueDate
+	}
+	date, err := parseCAMTDate(dateValue)
+	if err != nil {
+		problem("BookgDt", err.Error())
+		return
+	}
+
+	result := StatementEntry{
+		ID:     strings.TrimSpace(entry.ServicerRef),
+		Date:   date,
+		Amount: amount,
+		Memo:   strings.TrimSpace(entry.Info),
+		Line:   line,
+	}
+	if len(entry.Transactions) > 0 {
+		transaction := entry.Transactions[0]
+		if result.ID == "" {
+			result.ID = strings.TrimSpace(transaction.ServicerRef)
+		}
+		// Контрагент поступления - плательщик, списания - получатель
+		party := transaction.Creditor
+		if amount > 0 {
+			party = transaction.Debtor
+		}
+		result.Name = strings.TrimSpace(party.Name)
+		if result.Name == "" {
+			result.Name = strings.TrimSpace(party.PartyName)
+		}
+		if memo := camtRemittance(transaction); memo != "" {
+			result.Memo = memo
+		}
+	}
+	p.statement.Entries = append(p.statement.Entries, result)
+}
+
+// camtRemittance возвращает назначение платежа: неструктурированный текст, ссылку или доп. информацию
+func camtRemittance(transaction camtTransaction) string {
+	var parts []string
+	for _, part := range transaction.Unstructured {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	switch {
+	case len(parts) > 0:
+		return strings.Join(parts, " ")
+	case strings.TrimSpace(transaction.Reference) != "":
+		return strings.TrimSpace(transaction.Reference)
+	default:
+		return strings.TrimSpace(transaction.Info)
+	}
+}
+
+// parseCAMTAmount возвращает сумму со знаком по признаку CRDT/DBIT
+func parseCAMTAmount(amount camtAmount, indicator string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(amount.Value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("сумма %q не является числом", amount.Value)
+	}
+	switch strings.ToUpper(strings.TrimSpace(indicator)) {
+	case "CRDT":
+		return value, nil
+	case "DBIT":
+		return -value, nil
+	default:
+		return 0, fmt.Errorf("признак %q не является CRDT или DBIT", indicator)
+	}
+}
+
+// parseCAMTDate разбирает дату ISO 8601 из Dt или DtTm
+func parseCAMTDate(date camtDate) (time.Time, error) {
+	if value := strings.TrimSpace(date.Date); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("дата %q не в формате ГГГГ-ММ-ДД", value)
+		}
+		return parsed, nil
+	}
+	value := strings.TrimSpace(date.DateTime)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("дата %q не в формате ISO 8601", value)
+}
@@ -0,0 +1,106 @@
+package importexport
+
+import (
+	"KPO1/domain/models"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Decoder читает записи сущности из потока. Записи, которые не удалось разобрать,
+// добавляются в report с именем файла file; ошибка прерывает чтение всего потока.
+type Decoder[T any] func(r io.Reader, file string, report *ValidationReport) ([]Record[T], error)
+
+// Encoder записывает сущности в поток
+type Encoder[T any] func(w io.Writer, items []*T) error
+
+// EntityCodec чтение и запись одной сущности в формате кодека.
+// Nil-функция означает, что формат не поддерживает импорт или экспорт этой сущности.
+type EntityCodec[T any] struct {
+	Decode Decoder[T]
+	Encode Encoder[T]
+}
+
+// Codec описывает формат файлов импорта и экспорта.
+// Формат регистрируется один раз через RegisterCodec, после чего становится доступен
+// импортеру, экспортеру, командам, меню и командной строке.
+type Codec struct {
+	Format FileFormat
+	// Title название формата для меню и справки
+	Title        string
+	BankAccounts EntityCodec[models.BankAccount]
+	Categories   EntityCodec[models.Category]
+	Operations   EntityCodec[models.Operation]
+	// Statement чтение и запись выписок банка; nil-функции, если формат не является выпиской
+	Statement StatementCodec
+}
+
+// CanImport сообщает, что формат умеет читать хотя бы одну сущность
+func (c *Codec) CanImport() bool {
+	return c.BankAccounts.Decode != nil || c.Categories.Decode != nil || c.Operations.Decode != nil
+}
+
+// CanImportStatement сообщает, что формат умеет читать выписки банка
+func (c *Codec) CanImportStatement() bool {
+	return c.Statement.Decode != nil
+}
+
+// CanExportStatement сообщает, что формат умеет записывать счета с операциями выпиской
+func (c *Codec) CanExportStatement() bool {
+	return c.Statement.Encode != nil
+}
+
+// CanExport сообщает, что формат умеет записывать хотя бы одну сущность
+func (c *Codec) CanExport() bool {
+	return c.BankAccounts.Encode != nil || c.Categories.Encode != nil || c.Operations.Encode != nil
+}
+
+// codecRegistry реестр кодеков в порядке регистрации
+var codecRegistry = struct {
+	sync.RWMutex
+	codecs map[FileFormat]*Codec
+	order  []FileFormat
+}{codecs: make(map[FileFormat]*Codec)}
+
+// RegisterCodec регистрирует кодек формата. Как и database/sql.Register,
+// вызывается из init и паникует при пустом или повторно зарегистрированном формате.
+func RegisterCodec(codec *Codec) {
+	codecRegistry.Lock()
+	defer codecRegistry.Unlock()
+
+	if codec == nil || codec.Format == "" {
+		panic("importexport: кодек без формата")
+	}
+	if _, ok := codecRegistry.codecs[codec.Format]; ok {
+		panic(fmt.Sprintf("importexport: формат %s уже зарегистрирован", codec.Format))
+	}
+	codecRegistry.codecs[codec.Format] = codec
+	codecRegistry.order = append(codecRegistry.order, codec.Format)
+}
+
+// LookupCodec возвращает кодек зарегистрированного формата
+func LookupCodec(format FileFormat) (*Codec, error) {
+	codecRegistry.RLock()
+	defer codecRegistry.RUnlock()
+
+	codec, ok := codecRegistry.codecs[format]
+	if !ok {
+		return nil, &models.ValidationError{
+			Field:   "format",
+			Message: fmt.Sprintf("неподдерживаемый формат: %s", format),
+		}
+	}
+	return codec, nil
+}
+
+// Codecs возвращает зарегистрированные кодеки в порядке регистрации
+func Codecs() []*Codec {
+	codecRegistry.RLock()
+	defer codecRegistry.RUnlock()
+
+	codecs := make([]*Codec, 0, len(codecRegistry.order))
+	for _, format := range codecRegistry.order {
+		codecs = append(codecs, codecRegistry.codecs[format])
+	}
+	return codecs
+}
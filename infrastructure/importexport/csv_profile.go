@@ -0,0 +1,547 @@
+package importexport
+
+import (
+	"KPO1/domain/models"
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SignConvention способ, которым CSV банка задает направление операции
+type SignConvention string
+
+const (
+	// SignPositiveIncome сумма со знаком: поступления положительные, списания отрицательные
+	SignPositiveIncome SignConvention = "positive_income"
+	// SignPositiveExpense сумма со знаком: списания положительные, как в выписках кредитных карт
+	SignPositiveExpense SignConvention = "positive_expense"
+	// SignDirectionColumn сумма без знака, направление в отдельной колонке
+	SignDirectionColumn SignConvention = "direction_column"
+	// SignDebitCredit суммы списания и поступления в разных колонках
+	SignDebitCredit SignConvention = "debit_credit"
+)
+
+// CSVColumns сопоставление полей операции колонкам CSV. Колонка задается названием
+// из заголовка без учета регистра или номером, начиная с 1; пустая строка - колонки нет.
+type CSVColumns struct {
+	Date   string `yaml:"date"`
+	Amount string `yaml:"amount,omitempty"`
+	// Debit и Credit суммы списания и поступления для SignDebitCredit
+	Debit  string `yaml:"debit,omitempty"`
+	Credit string `yaml:"credit,omitempty"`
+	// Direction направление операции для SignDirectionColumn
+	Direction   string `yaml:"direction,omitempty"`
+	Payee       string `yaml:"payee,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	// Category название категории; отсутствующая категория создается при импорте
+	Category string `yaml:"category,omitempty"`
+	// ID идентификатор операции в банке для поиска повторов
+	ID string `yaml:"id,omitempty"`
+}
+
+// CSVProfile именованный профиль разбора CSV-выписки конкретного банка
+type CSVProfile struct {
+	Name string `yaml:"name"`
+	// Delimiter разделитель полей, по умолчанию «,»; «\t» или tab - табуляция
+	Delimiter string `yaml:"delimiter,omitempty"`
+	// Encoding кодировка файла: utf-8 (по умолчанию), windows-1251 или windows-1252
+	Encoding string `yaml:"encoding,omitempty"`
+	// SkipRows строки перед заголовком или данными, которые нужно пропустить
+	SkipRows int `yaml:"skip_rows,omitempty"`
+	// NoHeader в файле нет строки заголовка, колонки задаются только номерами
+	NoHeader bool       `yaml:"no_header,omitempty"`
+	Columns  CSVColumns `yaml:"columns"`
+	// DateFormat формат даты в нотации Go, по умолчанию 02.01.2006
+	DateFormat string `yaml:"date_format,omitempty"`
+	// DecimalSeparator десятичный разделитель, по умолчанию «.»
+	DecimalSeparator string `yaml:"decimal_separator,omitempty"`
+	// ThousandsSeparator разделитель тысяч; пробелы между разрядами убираются всегда
+	ThousandsSeparator string         `yaml:"thousands_separator,omitempty"`
+	Sign               SignConvention `yaml:"sign,omitempty"`
+	// DebitValues и CreditValues значения колонки направления для списаний и поступлений
+	DebitValues  []string `yaml:"debit_values,omitempty"`
+	CreditValues []string `yaml:"credit_values,omitempty"`
+}
+
+// defaultCSVDateFormat формат даты профиля по умолчанию
+const defaultCSVDateFormat = "02.01.2006"
+
+// delimiter возвращает разделитель полей
+func (p *CSVProfile) delimiter() rune {
+	switch p.Delimiter {
+	case "":
+		return ','
+	case `\t`, "tab":
+		return '\t'
+	}
+	r, _ := utf8.DecodeRuneInString(p.Delimiter)
+	return r
+}
+
+// dateFormat возвращает формат даты
+func (p *CSVProfile) dateFormat() string {
+	if p.DateFormat == "" {
+		return defaultCSVDateFormat
+	}
+	return p.DateFormat
+}
+
+// sign возвращает способ задания направления операции
+func (p *CSVProfile) sign() SignConvention {
+	if p.Sign == "" {
+		return SignPositiveIncome
+	}
+	return p.Sign
+}
+
+// Validate проверяет, что профиль полон и непротиворечив
+func (p *CSVProfile) Validate() error {
+	invalid := func(field, message string) error {
+		return &models.ValidationError{Field: field, Message: message}
+	}
+
+	if strings.TrimSpace(p.Name) == "" {
+		return invalid("name", "Название профиля не может быть пустым")
+	}
+	if p.Delimiter != "" && p.Delimiter != `\t` && p.Delimiter != "tab" && utf8.RuneCountInString(p.Delimiter) != 1 {
+		return invalid("delimiter", "Разделитель должен быть одним символом")
+	}
+	if _, err := ParseCharset(p.Encoding); err != nil {
+		return invalid("encoding", err.Error())
+	}
+	if p.SkipRows < 0 {
+		return invalid("skip_rows", "Число пропускаемых строк не может быть отрицательным")
+	}
+	if sample := time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC); !sameDay(p.dateFormat(), sample) {
+		return invalid("date_format", fmt.Sprintf("Формат даты %q не содержит день, месяц и год", p.dateFormat()))
+	}
+	if p.DecimalSeparator != "" && p.DecimalSeparator != "." && p.DecimalSeparator != "," {
+		return invalid("decimal_separator", "Десятичный разделитель должен быть «.» или «,»")
+	}
+	if p.ThousandsSeparator != "" && p.ThousandsSeparator == p.DecimalSeparator {
+		return invalid("thousands_separator", "Разделитель тысяч совпадает с десятичным")
+	}
+
+	columns := []struct{ field, column string }{
+		{"date", p.Columns.Date}, {"amount", p.Columns.Amount}, {"debit", p.Columns.Debit}, {"credit", p.Columns.Credit},
+		{"direction", p.Columns.Direction}, {"payee", p.Columns.Payee}, {"description", p.Columns.Description},
+		{"category", p.Columns.Category}, {"id", p.Columns.ID},
+	}
+	mapped := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		if column.column == "" {
+			continue
+		}
+		mapped[column.field] = true
+		if number, err := strconv.Atoi(column.column); err == nil {
+			if number < 1 {
+				return invalid("columns."+column.field, "Номер колонки начинается с 1")
+			}
+		} else if p.NoHeader {
+			return invalid("columns."+column.field, "Без заголовка колонка задается только номером")
+		}
+	}
+
+	required := []string{"date"}
+	switch p.sign() {
+	case SignPositiveIncome, SignPositiveExpense:
+		required = append(required, "amount")
+	case SignDirectionColumn:
+		required = append(required, "amount", "direction")
+		if len(p.DebitValues) == 0 && len(p.CreditValues) == 0 {
+			return invalid("debit_values", "Для колонки направления нужны значения списаний или поступлений")
+		}
+	case SignDebitCredit:
+		required = append(required, "debit", "credit")
+	default:
+		return invalid("sign", fmt.Sprintf("Неизвестное правило знака %q: ожидается %s, %s, %s или %s",
+			p.Sign, SignPositiveIncome, SignPositiveExpense, SignDirectionColumn, SignDebitCredit))
+	}
+	for _, field := range required {
+		if !mapped[field] {
+			return invalid("columns."+field, fmt.Sprintf("Для правила знака %s нужна колонка %s", p.sign(), field))
+		}
+	}
+	return nil
+}
+
+// sameDay сообщает, что дата, записанная в формате layout, читается обратно в тот же день
+func sameDay(layout string, date time.Time) bool {
+	parsed, err := time.Parse(layout, date.Format(layout))
+	return err == nil && parsed.Year() == date.Year() && parsed.YearDay() == date.YearDay()
+}
+
+// Decoder возвращает чтение CSV-выписки по профилю
+func (p *CSVProfile) Decoder() StatementDecoder {
+	return func(r io.Reader, file string, report *ValidationReport) (*Statement, error) {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		charset, _ := ParseCharset(p.Encoding)
+		decoded, err := DecodeReader(r, charset)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		buffered := bufio.NewReader(decoded)
+		for skipped := 0; skipped < p.SkipRows; skipped++ {
+			if _, err := buffered.ReadString('\n'); err != nil {
+				return &Statement{}, nil
+			}
+		}
+
+		reader := csv.NewReader(buffered)
+		reader.Comma = p.delimiter()
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+
+		var header []string
+		if !p.NoHeader {
+			if header, err = reader.Read(); err == io.EOF {
+				return &Statement{}, nil
+			} else if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+		}
+		columns, err := p.resolveColumns(header)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		statement := &Statement{}
+		for index := 1; ; index++ {
+			fields, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			line, _ := reader.FieldPos(0)
+			line += p.SkipRows
+			if blankRecord(fields) {
+				index--
+				continue
+			}
+
+			problem := func(field, message string) {
+				report.Add(ImportProblem{File: file, Line: line, Record: index, Field: field, Message: message})
+			}
+			if entry, ok := p.entry(fields, columns, problem); ok {
+				entry.Line = line
+				statement.Entries = append(statement.Entries, entry)
+			}
+		}
+		return statement, nil
+	}
+}
+
+// csvColumnIndexes номера колонок полей операции, начиная с 0; -1 - колонки нет
+type csvColumnIndexes struct {
+	date, amount, debit, credit, direction, payee, description, category, id int
+}
+
+// resolveColumns находит колонки профиля в заголовке
+func (p *CSVProfile) resolveColumns(header []string) (csvColumnIndexes, error) {
+	var resolveErr error
+	resolve := func(column string) int {
+		if column == "" || resolveErr != nil {
+			return -1
+		}
+		if number, err := strconv.Atoi(column); err == nil {
+			return number - 1
+		}
+		for n, name := range header {
+			name = strings.TrimPrefix(strings.TrimSpace(name), "\uFEFF")
+			if strings.EqualFold(name, strings.TrimSpace(column)) {
+				return n
+			}
+		}
+		resolveErr = fmt.Errorf("в заголовке нет колонки %q", column)
+		return -1
+	}
+
+	indexes := csvColumnIndexes{
+		date:        resolve(p.Columns.Date),
+		amount:      resolve(p.Columns.Amount),
+		debit:       resolve(p.Columns.Debit),
+		credit:      resolve(p.Columns.Credit),
+		direction:   resolve(p.Columns.Direction),
+		payee:       resolve(p.Columns.Payee),
+		description: resolve(p.Columns.Description),
+		category:    resolve(p.Columns.Category),
+		id:          resolve(p.Columns.ID),
+	}
+	return indexes, resolveErr
+}
+
+// entry разбирает строку CSV в операцию выписки
+func (p *CSVProfile) entry(fields []string, columns csvColumnIndexes, problem func(field, message string)) (StatementEntry, bool) {
+	value := func(index int) string {
+		if index < 0 || index >= len(fields) {
+			return ""
+		}
+		return strings.TrimSpace(fields[index])
+	}
+
+	entry := StatementEntry{
+		ID:       value(columns.id),
+		Name:     value(columns.payee),
+		Memo:     value(columns.description),
+		Category: value(columns.category),
+	}
+	valid := true
+
+	date, err := time.Parse(p.dateFormat(), value(columns.date))
+	if err != nil {
+		problem(p.Columns.Date, fmt.Sprintf("дата %q не в формате %s", value(columns.date), p.dateFormat()))
+		valid = false
+	}
+	entry.Date = date
+
+	amount, fieldErr := p.amount(value, columns)
+	if fieldErr != nil {
+		problem(fieldErr.field, fieldErr.message)
+		valid = false
+	}
+	entry.Amount = amount
+
+	return entry, valid
+}
+
+// csvFieldError ошибка разбора поля строки CSV
+type csvFieldError struct {
+	field, message string
+}
+
+// amount возвращает сумму со знаком по правилу знака профиля
+func (p *CSVProfile) amount(value func(int) string, columns csvColumnIndexes) (float64, *csvFieldError) {
+	parse := func(column string, index int) (float64, *csvFieldError) {
+		text := value(index)
+		amount, err := p.parseNumber(text)
+		if err != nil {
+			return 0, &csvFieldError{field: column, message: fmt.Sprintf("сумма %q не является числом", text)}
+		}
+		return amount, nil
+	}
+
+	switch p.sign() {
+	case SignPositiveExpense:
+		amount, err := parse(p.Columns.Amount, columns.amount)
+		return -amount, err
+	case SignDirectionColumn:
+		amount, err := parse(p.Columns.Amount, columns.amount)
+		if err != nil {
+			return 0, err
+		}
+		direction := value(columns.direction)
+		switch {
+		case containsFold(p.DebitValues, direction):
+			return -abs(amount), nil
+		case containsFold(p.CreditValues, direction):
+			return abs(amount), nil
+		case len(p.CreditValues) == 0:
+			return abs(amount), nil
+		case len(p.DebitValues) == 0:
+			return -abs(amount), nil
+		default:
+			return 0, &csvFieldError{field: p.Columns.Direction, message: fmt.Sprintf("направление %q не является ни списанием, ни поступлением", direction)}
+		}
+	case SignDebitCredit:
+		var debit, credit float64
+		var err *csvFieldError
+		if value(columns.debit) != "" {
+			if debit, err = parse(p.Columns.Debit, columns.debit); err != nil {
+				return 0, err
+			}
+		}
+		if value(columns.credit) != "" {
+			if credit, err = parse(p.Columns.Credit, columns.credit); err != nil {
+				return 0, err
+			}
+		}
+		return abs(credit) - abs(debit), nil
+	default:
+		return parse(p.Columns.Amount, columns.amount)
+	}
+}
+
+// parseNumber разбирает число с разделителями профиля; скобки означают отрицательное число
+func (p *CSVProfile) parseNumber(text string) (float64, error) {
+	negative := strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")")
+	text = strings.Trim(text, "()")
+	text = strings.NewReplacer(" ", "", "\u00A0", "", "\u202F", "", "'", "").Replace(text)
+	if p.ThousandsSeparator != "" {
+		text = strings.ReplaceAll(text, p.ThousandsSeparator, "")
+	}
+	if p.DecimalSeparator == "," {
+		text = strings.Replace(text, ",", ".", 1)
+	}
+	amount, err := strconv.ParseFloat(text, 64)
+	if negative {
+		amount = -amount
+	}
+	return amount, err
+}
+
+// blankRecord сообщает, что все поля строки пусты
+func blankRecord(fields []string) bool {
+	for _, field := range fields {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// containsFold сообщает, что values содержит value без учета регистра
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(strings.TrimSpace(candidate), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// abs возвращает модуль числа
+func abs(value float64) float64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// LoadCSVProfiles читает профили из YAML-списка
+func LoadCSVProfiles(r io.Reader) ([]*CSVProfile, error) {
+	var profiles []*CSVProfile
+	if err := yaml.NewDecoder(r).Decode(&profiles); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("ошибка чтения профилей CSV: %w", err)
+	}
+	for _, profile := range profiles {
+		if err := profile.Validate(); err != nil {
+			return nil, fmt.Errorf("профиль %q: %w", profile.Name, err)
+		}
+	}
+	return profiles, nil
+}
+
+// CSVProfileStore хранит именованные профили CSV в YAML-файле
+type CSVProfileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewCSVProfileStore создает хранилище профилей в файле path; файл создается при первом сохранении
+func NewCSVProfileStore(path string) *CSVProfileStore {
+	return &CSVProfileStore{path: path}
+}
+
+// List возвращает сохраненные профили в порядке добавления
+func (s *CSVProfileStore) List() ([]*CSVProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// Get возвращает профиль по названию без учета регистра
+func (s *CSVProfileStore) Get(name string) (*CSVProfile, error) {
+	profiles, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, profile := range profiles {
+		if strings.EqualFold(profile.Name, strings.TrimSpace(name)) {
+			return profile, nil
+		}
+	}
+	return nil, &models.ValidationError{Field: "profile", Message: fmt.Sprintf("Профиль CSV %q не найден", name)}
+}
+
+// Save добавляет профиль или заменяет сохраненный профиль с тем же названием
+func (s *CSVProfileStore) Save(profile *CSVProfile) error {
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profiles, err := s.load()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for n, saved := range profiles {
+		if strings.EqualFold(saved.Name, profile.Name) {
+			profiles[n] = profile
+			replaced = true
+		}
+	}
+	if !replaced {
+		profiles = append(profiles, profile)
+	}
+	return s.write(profiles)
+}
+
+// Delete удаляет профиль по названию без учета регистра
+func (s *CSVProfileStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profiles, err := s.load()
+	if err != nil {
+		return err
+	}
+	for n, profile := range profiles {
+		if strings.EqualFold(profile.Name, strings.TrimSpace(name)) {
+			return s.write(append(profiles[:n], profiles[n+1:]...))
+		}
+	}
+	return &models.ValidationError{Field: "profile", Message: fmt.Sprintf("Профиль CSV %q не найден", name)}
+}
+
+// load читает профили из файла; отсутствующий файл означает, что профилей нет
+func (s *CSVProfileStore) load() ([]*CSVProfile, error) {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadCSVProfiles(file)
+}
+
+// write записывает профили во временный файл и заменяет им прежний
+func (s *CSVProfileStore) write(profiles []*CSVProfile) error {
+	data, err := yaml.Marshal(profiles)
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(s.path), ".csv_profiles-*.yaml")
+	if err != nil {
+		return err
+	}
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	return os.Rename(temp.Name(), s.path)
+}
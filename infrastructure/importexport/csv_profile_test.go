@@ -0,0 +1,56 @@
+package importexport
+
+import (
+	"KPO1/infrastructure/persistence"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCSVProfileImportKeepsIdenticalRowsWithoutID(t *testing.T) {
+	ctx := context.Background()
+	repo := newStatementStore(t)
+	options := StatementImportOptions{BankAccountID: 1, IncomeCategoryID: 1, ExpenseCategoryID: 2}
+
+	// Профиль без колонки ID: повторы ищутся по дате, сумме и описанию
+	profile := &CSVProfile{
+		Name:             "bank",
+		Delimiter:        ";",
+		DateFormat:       "02.01.2006",
+		DecimalSeparator: ",",
+		Columns:          CSVColumns{Date: "Дата", Amount: "Сумма", Description: "Описание"},
+	}
+	if err := profile.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	const text = "Дата;Сумма;Описание\n01.03.2024;-250,00;Кофейня\n01.03.2024;-250,00;Кофейня\n"
+
+	importer := NewProfileStreamStatementImporter(profile, strings.NewReader(text), options, persistence.NewTransactionManager(repo))
+	if err := importer.Import(ctx); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if got := importer.Summary(); got.Imported != 2 || got.Duplicates != 0 {
+		t.Errorf("first import: imported %d, duplicates %d; want 2 and 0", got.Imported, got.Duplicates)
+	}
+	operations, err := repo.GetAllOperations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 2 {
+		t.Fatalf("store has %d operations, want 2", len(operations))
+	}
+	for _, operation := range operations {
+		if operation.Amount != 250 || operation.Description != "Кофейня" || operation.Date.Format("2006-01-02") != "2024-03-01" {
+			t.Errorf("operation = %+v", operation)
+		}
+	}
+
+	// Повторный импорт того же файла не добавляет операций
+	importer = NewProfileStreamStatementImporter(profile, strings.NewReader(text), options, persistence.NewTransactionManager(repo))
+	if err := importer.Import(ctx); err != nil {
+		t.Fatalf("second Import: %v", err)
+	}
+	if got := importer.Summary(); got.Imported != 0 || got.Duplicates != 2 {
+		t.Errorf("second import: imported %d, duplicates %d; want 0 and 2", got.Imported, got.Duplicates)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -18,230 +19,183 @@ var _ interfaces.ExportVisitor = (*ExportVisitor)(nil)
 // ExportVisitor реализует паттерн Посетитель для экспорта данных
 type ExportVisitor struct {
 	format FileFormat
-	path   string
+	// create открывает поток для записей сущности; nil-поток означает, что сущность не экспортируется
+	create func(name string) (io.WriteCloser, error)
 }
 
-// NewExportVisitor создает нового посетителя для экспорта
+// NewExportVisitor создает нового посетителя для экспорта в файлы директории path
 func NewExportVisitor(format FileFormat, path string) *ExportVisitor {
 	return &ExportVisitor{
 		format: format,
-		path:   path,
+		create: func(name string) (io.WriteCloser, error) {
+			return os.Create(fmt.Sprintf("%s/%s.%s", path, name, format))
+		},
+	}
+}
+
+// NewStreamExportVisitor создает нового посетителя для экспорта в потоки streams.
+// Сущности без потока пропускаются, потоки посетитель не закрывает.
+func NewStreamExportVisitor(format FileFormat, streams ExportStreams) *ExportVisitor {
+	return &ExportVisitor{
+		format: format,
+		create: streams.create,
 	}
 }
 
 // VisitBankAccounts экспортирует банковские счета
 func (v *ExportVisitor) VisitBankAccounts(accounts []*models.BankAccount) error {
-	switch v.format {
-	case CSV:
-		return v.exportBankAccountsToCSV(accounts)
-	case JSON:
-		return v.exportBankAccountsToJSON(accounts)
-	case YAML:
-		return v.exportBankAccountsToYAML(accounts)
-	default:
-		return fmt.Errorf("неподдерживаемый формат: %s", v.format)
+	codec, err := LookupCodec(v.format)
+	if err != nil {
+		return err
 	}
+	return export(v, "accounts", codec.BankAccounts.Encode, accounts)
 }
 
 // VisitCategories экспортирует категории
 func (v *ExportVisitor) VisitCategories(categories []*models.Category) error {
-	switch v.format {
-	case CSV:
-		return v.exportCategoriesToCSV(categories)
-	case JSON:
-		return v.exportCategoriesToJSON(categories)
-	case YAML:
-		return v.exportCategoriesToYAML(categories)
-	default:
-		return fmt.Errorf("неподдерживаемый формат: %s", v.format)
+	codec, err := LookupCodec(v.format)
+	if err != nil {
+		return err
 	}
+	return export(v, "categories", codec.Categories.Encode, categories)
 }
 
 // VisitOperations экспортирует операции
 func (v *ExportVisitor) VisitOperations(operations []*models.Operation) error {
-	switch v.format {
-	case CSV:
-		return v.exportOperationsToCSV(operations)
-	case JSON:
-		return v.exportOperationsToJSON(operations)
-	case YAML:
-		return v.exportOperationsToYAML(operations)
-	default:
-		return fmt.Errorf("неподдерживаемый формат: %s", v.format)
-	}
-}
-
-// exportBankAccountsToCSV экспортирует банковские счета в CSV
-func (v *ExportVisitor) exportBankAccountsToCSV(accounts []*models.BankAccount) error {
-	file, err := os.Create(fmt.Sprintf("%s/accounts.csv", v.path))
+	codec, err := LookupCodec(v.format)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	return export(v, "operations", codec.Operations.Encode, operations)
+}
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+// export открывает поток сущности и записывает в него данные.
+// Сущности, которые формат не умеет записывать, пропускаются без создания файла.
+func export[T any](v *ExportVisitor, name string, encode Encoder[T], items []*T) error {
+	if encode == nil {
+		return nil
+	}
 
-	// Запись заголовка
-	err = writer.Write([]string{"ID", "Name", "Balance"})
+	w, err := v.create(name)
 	if err != nil {
 		return err
 	}
-
-	// Запись данных
-	for _, account := range accounts {
-		err = writer.Write([]string{
-			fmt.Sprintf("%d", account.ID),
-			account.Name,
-			fmt.Sprintf("%.2f", account.Balance),
-		})
-		if err != nil {
-			return err
-		}
+	if w == nil {
+		return nil
 	}
 
-	return nil
+	if err := encode(w, items); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
 }
 
-// exportBankAccountsToJSON экспортирует банковские счета в JSON
-func (v *ExportVisitor) exportBankAccountsToJSON(accounts []*models.BankAccount) error {
-	file, err := os.Create(fmt.Sprintf("%s/accounts.json", v.path))
+// WriteBankAccounts записывает банковские счета в поток w в формате format
+func WriteBankAccounts(w io.Writer, format FileFormat, accounts []*models.BankAccount) error {
+	codec, err := LookupCodec(format)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(accounts)
+	return encodeEntity(w, codec.BankAccounts.Encode, accounts, format, "счетов")
 }
 
-// exportBankAccountsToYAML экспортирует банковские счета в YAML
-func (v *ExportVisitor) exportBankAccountsToYAML(accounts []*models.BankAccount) error {
-	file, err := os.Create(fmt.Sprintf("%s/accounts.yaml", v.path))
+// WriteCategories записывает категории в поток w в формате format
+func WriteCategories(w io.Writer, format FileFormat, categories []*models.Category) error {
+	codec, err := LookupCodec(format)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	encoder := yaml.NewEncoder(file)
-	return encoder.Encode(accounts)
+	return encodeEntity(w, codec.Categories.Encode, categories, format, "категорий")
 }
 
-// exportCategoriesToCSV экспортирует категории в CSV
-func (v *ExportVisitor) exportCategoriesToCSV(categories []*models.Category) error {
-	file, err := os.Create(fmt.Sprintf("%s/categories.csv", v.path))
+// WriteOperations записывает операции в поток w в формате format
+func WriteOperations(w io.Writer, format FileFormat, operations []*models.Operation) error {
+	codec, err := LookupCodec(format)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	return encodeEntity(w, codec.Operations.Encode, operations, format, "операций")
+}
 
-	// Запись заголовка
-	err = writer.Write([]string{"ID", "Type", "Name"})
-	if err != nil {
-		return err
+// encodeEntity записывает сущности кодером формата, если формат поддерживает их экспорт
+func encodeEntity[T any](w io.Writer, encode Encoder[T], items []*T, format FileFormat, title string) error {
+	if encode == nil {
+		return fmt.Errorf("формат %s не поддерживает экспорт %s", format, title)
 	}
+	return encode(w, items)
+}
 
-	// Запись данных
-	for _, category := range categories {
-		err = writer.Write([]string{
+// encodeBankAccountsCSV записывает банковские счета в CSV
+func encodeBankAccountsCSV(w io.Writer, accounts []*models.BankAccount) error {
+	return writeCSV(w, []string{"ID", "Name", "Balance"}, accounts, func(account *models.BankAccount) []string {
+		return []string{
+			fmt.Sprintf("%d", account.ID),
+			account.Name,
+			fmt.Sprintf("%.2f", account.Balance),
+		}
+	})
+}
+
+// encodeCategoriesCSV записывает категории в CSV
+func encodeCategoriesCSV(w io.Writer, categories []*models.Category) error {
+	return writeCSV(w, []string{"ID", "Type", "Name"}, categories, func(category *models.Category) []string {
+		return []string{
 			fmt.Sprintf("%d", category.ID),
 			string(category.Type),
 			category.Name,
-		})
-		if err != nil {
-			return err
 		}
-	}
-
-	return nil
-}
-
-// exportCategoriesToJSON экспортирует категории в JSON
-func (v *ExportVisitor) exportCategoriesToJSON(categories []*models.Category) error {
-	file, err := os.Create(fmt.Sprintf("%s/categories.json", v.path))
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(categories)
+	})
 }
 
-// exportCategoriesToYAML экспортирует категории в YAML
-func (v *ExportVisitor) exportCategoriesToYAML(categories []*models.Category) error {
-	file, err := os.Create(fmt.Sprintf("%s/categories.yaml", v.path))
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	encoder := yaml.NewEncoder(file)
-	return encoder.Encode(categories)
+// encodeOperationsCSV записывает операции в CSV
+func encodeOperationsCSV(w io.Writer, operations []*models.Operation) error {
+	header := []string{"ID", "Type", "BankAccountID", "Amount", "Date", "Description", "CategoryID"}
+	return writeCSV(w, header, operations, func(op *models.Operation) []string {
+		return []string{
+			fmt.Sprintf("%d", op.ID),
+			string(op.Type),
+			fmt.Sprintf("%d", op.BankAccountID),
+			fmt.Sprintf("%.2f", op.Amount),
+			op.Date.Format(time.RFC3339),
+			op.Description,
+			fmt.Sprintf("%d", op.CategoryID),
+		}
+	})
 }
 
-// exportOperationsToCSV экспортирует операции в CSV
-func (v *ExportVisitor) exportOperationsToCSV(operations []*models.Operation) error {
-	file, err := os.Create(fmt.Sprintf("%s/operations.csv", v.path))
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+// writeCSV записывает заголовок и строки записей в CSV
+func writeCSV[T any](w io.Writer, header []string, records []*T, row func(*T) []string) error {
+	writer := csv.NewWriter(w)
 
 	// Запись заголовка
-	err = writer.Write([]string{"ID", "Type", "BankAccountID", "Amount", "Date", "Description", "CategoryID"})
-	if err != nil {
+	if err := writer.Write(header); err != nil {
 		return err
 	}
 
 	// Запись данных
-	for _, op := range operations {
-		err = writer.Write([]string{
-			fmt.Sprintf("%d", op.ID),
-			string(op.Type),
-			fmt.Sprintf("%d", op.BankAccountID),
-			fmt.Sprintf("%.2f", op.Amount),
-			op.Date.Format(time.RFC3339),
-			op.Description,
-			fmt.Sprintf("%d", op.CategoryID),
-		})
-		if err != nil {
+	for _, record := range records {
+		if err := writer.Write(row(record)); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	writer.Flush()
+	return writer.Error()
 }
 
-// exportOperationsToJSON экспортирует операции в JSON
-func (v *ExportVisitor) exportOperationsToJSON(operations []*models.Operation) error {
-	file, err := os.Create(fmt.Sprintf("%s/operations.json", v.path))
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
+// writeJSON записывает записи массивом JSON с отступами
+func writeJSON[T any](w io.Writer, records []*T) error {
+	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
-	return encoder.Encode(operations)
+	return encoder.Encode(records)
 }
 
-// exportOperationsToYAML экспортирует операции в YAML
-func (v *ExportVisitor) exportOperationsToYAML(operations []*models.Operation) error {
-	file, err := os.Create(fmt.Sprintf("%s/operations.yaml", v.path))
-	if err != nil {
+// writeYAML записывает записи последовательностью YAML
+func writeYAML[T any](w io.Writer, records []*T) error {
+	encoder := yaml.NewEncoder(w)
+	if err := encoder.Encode(records); err != nil {
 		return err
 	}
-	defer file.Close()
-
-	encoder := yaml.NewEncoder(file)
-	return encoder.Encode(operations)
+	return encoder.Close()
 }
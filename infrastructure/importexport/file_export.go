@@ -2,18 +2,18 @@ package importexport
 
 import (
 	"KPO1/domain/interfaces"
+	"context"
 	"fmt"
 	"os"
 )
 
-// FileExporter экспортирует данные в файлы
+// FileExporter экспортирует данные в файлы директории или в потоки
 type FileExporter struct {
 	visitor    interfaces.ExportVisitor
 	repository interfaces.CompositeRepository
-	exportPath string
 }
 
-// NewFileExporter создает новый экспортер файлов
+// NewFileExporter создает новый экспортер в файлы директории path
 func NewFileExporter(format FileFormat, path string, repository interfaces.CompositeRepository) *FileExporter {
 	// Создаем директорию для экспорта, если не существует
 	if _, err := os.Stat(path); os.IsNotExist(err) {
@@ -26,21 +26,29 @@ func NewFileExporter(format FileFormat, path string, repository interfaces.Compo
 	return &FileExporter{
 		visitor:    NewExportVisitor(format, path),
 		repository: repository,
-		exportPath: path,
+	}
+}
+
+// NewStreamExporter создает новый экспортер в потоки streams.
+// Каждая сущность пишется в свой поток, сущности без потока пропускаются.
+func NewStreamExporter(format FileFormat, streams ExportStreams, repository interfaces.CompositeRepository) *FileExporter {
+	return &FileExporter{
+		visitor:    NewStreamExportVisitor(format, streams),
+		repository: repository,
 	}
 }
 
 // ExportAll экспортирует все данные в файлы
-func (e *FileExporter) ExportAll() error {
-	if err := e.ExportBankAccounts(); err != nil {
+func (e *FileExporter) ExportAll(ctx context.Context) error {
+	if err := e.ExportBankAccounts(ctx); err != nil {
 		return err
 	}
 
-	if err := e.ExportCategories(); err != nil {
+	if err := e.ExportCategories(ctx); err != nil {
 		return err
 	}
 
-	if err := e.ExportOperations(); err != nil {
+	if err := e.ExportOperations(ctx); err != nil {
 		return err
 	}
 
@@ -48,8 +56,8 @@ func (e *FileExporter) ExportAll() error {
 }
 
 // ExportBankAccounts экспортирует банковские счета
-func (e *FileExporter) ExportBankAccounts() error {
-	accounts, err := e.repository.GetBankAccounts()
+func (e *FileExporter) ExportBankAccounts(ctx context.Context) error {
+	accounts, err := e.repository.GetBankAccounts(ctx)
 	if err != nil {
 		return fmt.Errorf("ошибка получения счетов: %w", err)
 	}
@@ -63,8 +71,8 @@ func (e *FileExporter) ExportBankAccounts() error {
 }
 
 // ExportCategories экспортирует категории
-func (e *FileExporter) ExportCategories() error {
-	categories, err := e.repository.GetCategories()
+func (e *FileExporter) ExportCategories(ctx context.Context) error {
+	categories, err := e.repository.GetCategories(ctx)
 	if err != nil {
 		return fmt.Errorf("ошибка получения категорий: %w", err)
 	}
@@ -78,8 +86,8 @@ func (e *FileExporter) ExportCategories() error {
 }
 
 // ExportOperations экспортирует операции
-func (e *FileExporter) ExportOperations() error {
-	operations, err := e.repository.GetOperations()
+func (e *FileExporter) ExportOperations(ctx context.Context) error {
+	operations, err := e.repository.GetOperations(ctx)
 	if err != nil {
 		return fmt.Errorf("ошибка получения операций: %w", err)
 	}
@@ -1,5 +1,10 @@
 package importexport
 
+import (
+	"KPO1/domain/models"
+	"io"
+)
+
 // FileFormat представляет формат файла для импорта/экспорта
 type FileFormat string
 
@@ -11,3 +16,63 @@ const (
 	// YAML формат YAML
 	YAML FileFormat = "yaml"
 )
+
+func init() {
+	RegisterCodec(&Codec{
+		Format: CSV,
+		Title:  "CSV",
+		BankAccounts: EntityCodec[models.BankAccount]{
+			Decode: csvDecoder(3, parseBankAccountCSV),
+			Encode: encodeBankAccountsCSV,
+		},
+		Categories: EntityCodec[models.Category]{
+			Decode: csvDecoder(3, parseCategoryCSV),
+			Encode: encodeCategoriesCSV,
+		},
+		Operations: EntityCodec[models.Operation]{
+			Decode: csvDecoder(7, parseOperationCSV),
+			Encode: encodeOperationsCSV,
+		},
+	})
+
+	RegisterCodec(&Codec{
+		Format: JSON,
+		Title:  "JSON",
+		BankAccounts: EntityCodec[models.BankAccount]{
+			Decode: readJSONRecords[models.BankAccount],
+			Encode: writeJSON[models.BankAccount],
+		},
+		Categories: EntityCodec[models.Category]{
+			Decode: readJSONRecords[models.Category],
+			Encode: writeJSON[models.Category],
+		},
+		Operations: EntityCodec[models.Operation]{
+			Decode: readJSONRecords[models.Operation],
+			Encode: writeJSON[models.Operation],
+		},
+	})
+
+	RegisterCodec(&Codec{
+		Format: YAML,
+		Title:  "YAML",
+		BankAccounts: EntityCodec[models.BankAccount]{
+			Decode: readYAMLRecords[models.BankAccount],
+			Encode: writeYAML[models.BankAccount],
+		},
+		Categories: EntityCodec[models.Category]{
+			Decode: readYAMLRecords[models.Category],
+			Encode: writeYAML[models.Category],
+		},
+		Operations: EntityCodec[models.Operation]{
+			Decode: readYAMLRecords[models.Operation],
+			Encode: writeYAML[models.Operation],
+		},
+	})
+}
+
+// csvDecoder возвращает чтение CSV с заданным числом полей и разбором строки parse
+func csvDecoder[T any](columns int, parse func(fields []string, problem func(field, message string)) *T) Decoder[T] {
+	return func(r io.Reader, file string, report *ValidationReport) ([]Record[T], error) {
+		return readCSVRecords(r, file, columns, parse, report)
+	}
+}
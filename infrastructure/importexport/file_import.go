@@ -3,434 +3,319 @@ package importexport
 import (
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
-	"encoding/csv"
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"strconv"
 	"time"
-
-	"gopkg.in/yaml.v3"
 )
 
-// FileImporter импортирует данные из файлов
+// FileImporter импортирует данные из файлов директории или из потоков.
+// Импорт устроен как шаблонный метод: записи читаются в зависимости от формата,
+// проверяются общими правилами и только затем сохраняются по стратегии слияния.
+// Весь импорт выполняется в одной транзакции и применяется целиком или не применяется вовсе.
 type FileImporter struct {
-	format      FileFormat
-	importPath  string
-	bankAccRepo interfaces.BankAccountRepository
-	catRepo     interfaces.CategoryRepository
-	opRepo      interfaces.OperationRepository
+	format FileFormat
+	// open открывает поток с записями сущности; nil-поток означает, что записей нет
+	open      func(name string) (io.ReadCloser, error)
+	strategy  MergeStrategy
+	txManager interfaces.TransactionManager
+	summary   *ImportSummary
 }
 
-// NewFileImporter создает новый импортер файлов
+// NewFileImporter создает новый импортер файлов директории path
 func NewFileImporter(
 	format FileFormat,
 	path string,
-	bankAccRepo interfaces.BankAccountRepository,
-	catRepo interfaces.CategoryRepository,
-	opRepo interfaces.OperationRepository,
+	strategy MergeStrategy,
+	txManager interfaces.TransactionManager,
 ) *FileImporter {
 	return &FileImporter{
-		format:      format,
-		importPath:  path,
-		bankAccRepo: bankAccRepo,
-		catRepo:     catRepo,
-		opRepo:      opRepo,
+		format: format,
+		open: func(name string) (io.ReadCloser, error) {
+			return os.Open(fmt.Sprintf("%s/%s.%s", path, name, format))
+		},
+		strategy:  strategy,
+		txManager: txManager,
+		summary:   newImportSummary(strategy),
 	}
 }
 
-// ImportAll импортирует все данные из файлов
-func (i *FileImporter) ImportAll() error {
-	if err := i.ImportBankAccounts(); err != nil {
-		return err
-	}
-
-	if err := i.ImportCategories(); err != nil {
-		return err
-	}
-
-	if err := i.ImportOperations(); err != nil {
-		return err
+// NewStreamImporter создает новый импортер из потоков streams.
+// Потоки читаются один раз: Validate и ImportAll нельзя вызвать для одних и тех же потоков дважды.
+// Имена файлов в отчете о проверке условные, например operations.csv.
+func NewStreamImporter(
+	format FileFormat,
+	streams ImportStreams,
+	strategy MergeStrategy,
+	txManager interfaces.TransactionManager,
+) *FileImporter {
+	consumed := make(map[string]bool)
+	return &FileImporter{
+		format: format,
+		open: func(name string) (io.ReadCloser, error) {
+			r, err := streams.reader(name)
+			if err != nil || r == nil {
+				return nil, err
+			}
+			if consumed[name] {
+				return nil, fmt.Errorf("поток %s.%s уже прочитан", name, format)
+			}
+			consumed[name] = true
+			return io.NopCloser(r), nil
+		},
+		strategy:  strategy,
+		txManager: txManager,
+		summary:   newImportSummary(strategy),
 	}
+}
 
-	return nil
+// ImportAbortedError сообщает, что импорт отменен и хранилище осталось в прежнем состоянии
+type ImportAbortedError struct {
+	// Stage этап, на котором импорт был прерван
+	Stage string
+	Err   error
 }
 
-// ImportBankAccounts импортирует банковские счета
-func (i *FileImporter) ImportBankAccounts() error {
-	switch i.format {
-	case CSV:
-		return i.importBankAccountsFromCSV()
-	case JSON:
-		return i.importBankAccountsFromJSON()
-	case YAML:
-		return i.importBankAccountsFromYAML()
-	default:
-		return fmt.Errorf("неподдерживаемый формат: %s", i.format)
-	}
+func (e *ImportAbortedError) Error() string {
+	return fmt.Sprintf("импорт отменен на этапе «%s», данные не изменены: %v", e.Stage, e.Err)
 }
 
-// ImportCategories импортирует категории
-func (i *FileImporter) ImportCategories() error {
-	switch i.format {
-	case CSV:
-		return i.importCategoriesFromCSV()
-	case JSON:
-		return i.importCategoriesFromJSON()
-	case YAML:
-		return i.importCategoriesFromYAML()
-	default:
-		return fmt.Errorf("неподдерживаемый формат: %s", i.format)
-	}
+// Unwrap возвращает причину отмены импорта
+func (e *ImportAbortedError) Unwrap() error {
+	return e.Err
 }
 
-// ImportOperations импортирует операции
-func (i *FileImporter) ImportOperations() error {
-	switch i.format {
-	case CSV:
-		return i.importOperationsFromCSV()
-	case JSON:
-		return i.importOperationsFromJSON()
-	case YAML:
-		return i.importOperationsFromYAML()
-	default:
-		return fmt.Errorf("неподдерживаемый формат: %s", i.format)
-	}
+// Summary возвращает итог последнего успешного импорта: сколько записей создано,
+// заменено, пропущено и перенесено на новые ID
+func (i *FileImporter) Summary() *ImportSummary {
+	return i.summary
 }
 
-// importBankAccountsFromCSV импортирует банковские счета из CSV
-func (i *FileImporter) importBankAccountsFromCSV() error {
-	file, err := os.Open(fmt.Sprintf("%s/accounts.csv", i.importPath))
+// Validate читает и проверяет файлы импорта, ничего не сохраняя.
+// Отчет содержит все найденные проблемы с файлом, строкой и полем.
+// Ошибка возвращается, только если файлы не удалось прочитать целиком.
+func (i *FileImporter) Validate(ctx context.Context) (*ValidationReport, error) {
+	tx, err := i.txManager.Begin(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer file.Close()
+	defer tx.Rollback()
 
-	reader := csv.NewReader(file)
+	_, report, err := i.load(ctx, tx)
+	return report, err
+}
 
-	// Пропускаем заголовок
-	_, err = reader.Read()
+// ImportAll проверяет файлы и импортирует все данные в одной транзакции.
+// При любой ошибке, в том числе при отмене контекста, транзакция откатывается,
+// хранилище остается в прежнем состоянии и возвращается *ImportAbortedError;
+// если причина в данных файлов, она содержит *ValidationReport.
+func (i *FileImporter) ImportAll(ctx context.Context) error {
+	tx, err := i.txManager.Begin(ctx)
 	if err != nil {
-		return err
+		return &ImportAbortedError{Stage: "начало транзакции", Err: err}
 	}
 
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-
-		if len(record) < 3 {
-			return fmt.Errorf("неверный формат записи: %v", record)
-		}
-
-		id, err := strconv.Atoi(record[0])
-		if err != nil {
-			return fmt.Errorf("ошибка преобразования ID: %w", err)
-		}
-
-		name := record[1]
-
-		balance, err := strconv.ParseFloat(record[2], 64)
-		if err != nil {
-			return fmt.Errorf("ошибка преобразования баланса: %w", err)
-		}
-
-		account := &models.BankAccount{
-			ID:      id,
-			Name:    name,
-			Balance: balance,
+	summary := newImportSummary(i.strategy)
+	if err := i.importAll(ctx, tx, summary); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (ошибка отката транзакции: %v)", err, rbErr)
 		}
+		return err
+	}
 
-		err = i.bankAccRepo.Save(account)
-		if err != nil {
-			return fmt.Errorf("ошибка создания счета: %w", err)
-		}
+	if err := tx.Commit(); err != nil {
+		return &ImportAbortedError{Stage: "фиксация", Err: err}
 	}
 
+	i.summary = summary
 	return nil
 }
 
-// importBankAccountsFromJSON импортирует банковские счета из JSON
-func (i *FileImporter) importBankAccountsFromJSON() error {
-	file, err := os.Open(fmt.Sprintf("%s/accounts.json", i.importPath))
+// importAll проверяет и сохраняет записи через репозитории транзакции
+func (i *FileImporter) importAll(ctx context.Context, uow interfaces.UnitOfWork, summary *ImportSummary) error {
+	data, report, err := i.load(ctx, uow)
 	if err != nil {
-		return err
+		return &ImportAbortedError{Stage: "чтение файлов", Err: err}
 	}
-	defer file.Close()
-
-	var accounts []*models.BankAccount
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&accounts); err != nil {
-		return err
+	if !report.Valid() {
+		return &ImportAbortedError{Stage: "проверка файлов", Err: report}
 	}
 
-	for _, account := range accounts {
-		err := i.bankAccRepo.Save(account)
-		if err != nil {
-			return fmt.Errorf("ошибка создания счета: %w", err)
-		}
+	err = mergeRecords[models.BankAccount](ctx, i.strategy, uow.BankAccounts(), items(data.accounts), bankAccountID, &summary.BankAccounts)
+	if err != nil {
+		return &ImportAbortedError{Stage: "счета", Err: err}
 	}
 
-	return nil
-}
-
-// importBankAccountsFromYAML импортирует банковские счета из YAML
-func (i *FileImporter) importBankAccountsFromYAML() error {
-	file, err := os.Open(fmt.Sprintf("%s/accounts.yaml", i.importPath))
+	err = mergeRecords[models.Category](ctx, i.strategy, uow.Categories(), items(data.categories), categoryID, &summary.Categories)
 	if err != nil {
-		return err
+		return &ImportAbortedError{Stage: "категории", Err: err}
 	}
-	defer file.Close()
 
-	var accounts []*models.BankAccount
-	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&accounts); err != nil {
-		return err
-	}
+	// Операции ссылаются на счета и категории, которые могли получить новые ID
+	operations := items(data.operations)
+	summary.rewriteReferences(operations)
 
-	for _, account := range accounts {
-		err := i.bankAccRepo.Save(account)
-		if err != nil {
-			return fmt.Errorf("ошибка создания счета: %w", err)
-		}
+	err = mergeRecords[models.Operation](ctx, i.strategy, uow.Operations(), operations, operationID, &summary.Operations)
+	if err != nil {
+		return &ImportAbortedError{Stage: "операции", Err: err}
 	}
 
 	return nil
 }
 
-// importCategoriesFromCSV импортирует категории из CSV
-func (i *FileImporter) importCategoriesFromCSV() error {
-	file, err := os.Open(fmt.Sprintf("%s/categories.csv", i.importPath))
+// load читает все файлы импорта и проверяет записи по данным uow
+func (i *FileImporter) load(ctx context.Context, uow interfaces.UnitOfWork) (*importData, *ValidationReport, error) {
+	codec, err := LookupCodec(i.format)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
+	report := &ValidationReport{}
+	data := &importData{}
 
-	// Пропускаем заголовок
-	_, err = reader.Read()
-	if err != nil {
-		return err
+	if data.accounts, err = readRecords(i, "accounts", codec.BankAccounts.Decode, report); err != nil {
+		return nil, nil, err
+	}
+	if data.categories, err = readRecords(i, "categories", codec.Categories.Decode, report); err != nil {
+		return nil, nil, err
+	}
+	if data.operations, err = readRecords(i, "operations", codec.Operations.Decode, report); err != nil {
+		return nil, nil, err
 	}
 
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-
-		if len(record) < 3 {
-			return fmt.Errorf("неверный формат записи: %v", record)
-		}
-
-		id, err := strconv.Atoi(record[0])
-		if err != nil {
-			return fmt.Errorf("ошибка преобразования ID: %w", err)
-		}
-
-		categoryType := models.OperationType(record[1])
-		if categoryType != models.Income && categoryType != models.Expense {
-			return fmt.Errorf("неверный тип категории: %s", categoryType)
-		}
-
-		name := record[2]
-
-		category := &models.Category{
-			ID:   id,
-			Type: categoryType,
-			Name: name,
-		}
-
-		err = i.catRepo.Save(category)
-		if err != nil {
-			return fmt.Errorf("ошибка создания категории: %w", err)
-		}
+	if err := i.check(ctx, uow, data, report); err != nil {
+		return nil, nil, err
 	}
+	report.sort()
 
-	return nil
+	return data, report, nil
 }
 
-// importCategoriesFromJSON импортирует категории из JSON
-func (i *FileImporter) importCategoriesFromJSON() error {
-	file, err := os.Open(fmt.Sprintf("%s/categories.json", i.importPath))
-	if err != nil {
-		return err
+// readRecords читает записи сущности из ее потока декодером формата импортера.
+// Сущности, которые формат не умеет читать, пропускаются без открытия файла.
+func readRecords[T any](i *FileImporter, name string, decode Decoder[T], report *ValidationReport) ([]Record[T], error) {
+	if decode == nil {
+		return nil, nil
 	}
-	defer file.Close()
 
-	var categories []*models.Category
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&categories); err != nil {
-		return err
+	r, err := i.open(name)
+	if err != nil {
+		return nil, err
 	}
-
-	for _, category := range categories {
-		err := i.catRepo.Save(category)
-		if err != nil {
-			return fmt.Errorf("ошибка создания категории: %w", err)
-		}
+	if r == nil {
+		return nil, nil
 	}
+	defer r.Close()
 
-	return nil
+	return decode(r, i.fileName(name), report)
 }
 
-// importCategoriesFromYAML импортирует категории из YAML
-func (i *FileImporter) importCategoriesFromYAML() error {
-	file, err := os.Open(fmt.Sprintf("%s/categories.yaml", i.importPath))
+// ReadBankAccounts читает банковские счета из потока r в формате format без сохранения.
+// Записи с проблемами попадают в отчет и не возвращаются; ссылки между сущностями не проверяются.
+func ReadBankAccounts(ctx context.Context, r io.Reader, format FileFormat) ([]*models.BankAccount, *ValidationReport, error) {
+	codec, err := LookupCodec(format)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	defer file.Close()
+	return readStream(ctx, r, format, "accounts", "счетов", codec.BankAccounts.Decode, bankAccountID)
+}
 
-	var categories []*models.Category
-	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&categories); err != nil {
-		return err
+// ReadCategories читает категории из потока r в формате format без сохранения
+func ReadCategories(ctx context.Context, r io.Reader, format FileFormat) ([]*models.Category, *ValidationReport, error) {
+	codec, err := LookupCodec(format)
+	if err != nil {
+		return nil, nil, err
 	}
+	return readStream(ctx, r, format, "categories", "категорий", codec.Categories.Decode, categoryID)
+}
 
-	for _, category := range categories {
-		err := i.catRepo.Save(category)
-		if err != nil {
-			return fmt.Errorf("ошибка создания категории: %w", err)
-		}
+// ReadOperations читает операции из потока r в формате format без сохранения.
+// Существование счета и категории операции не проверяется.
+func ReadOperations(ctx context.Context, r io.Reader, format FileFormat) ([]*models.Operation, *ValidationReport, error) {
+	codec, err := LookupCodec(format)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	return nil
+	return readStream(ctx, r, format, "operations", "операций", codec.Operations.Decode, operationID)
 }
 
-// importOperationsFromCSV импортирует операции из CSV
-func (i *FileImporter) importOperationsFromCSV() error {
-	file, err := os.Open(fmt.Sprintf("%s/operations.csv", i.importPath))
-	if err != nil {
-		return err
+// readStream читает и проверяет записи одной сущности из потока
+func readStream[T any](
+	ctx context.Context,
+	r io.Reader,
+	format FileFormat,
+	name, title string,
+	decode Decoder[T],
+	idOf func(*T) *int,
+) ([]*T, *ValidationReport, error) {
+	if decode == nil {
+		return nil, nil, fmt.Errorf("формат %s не поддерживает импорт %s", format, title)
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
+	report := &ValidationReport{}
+	file := fmt.Sprintf("%s.%s", name, format)
 
-	// Пропускаем заголовок
-	_, err = reader.Read()
+	records, err := decode(r, file, report)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
+	if records, err = checkRecords(ctx, records, file, idOf, report, nil); err != nil {
+		return nil, nil, err
+	}
+	report.sort()
 
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-
-		if len(record) < 7 {
-			return fmt.Errorf("неверный формат записи: %v", record)
-		}
-
-		id, err := strconv.Atoi(record[0])
-		if err != nil {
-			return fmt.Errorf("ошибка преобразования ID: %w", err)
-		}
-
-		opType := models.OperationType(record[1])
-		if opType != models.Income && opType != models.Expense {
-			return fmt.Errorf("неверный тип операции: %s", opType)
-		}
-
-		bankAccountID, err := strconv.Atoi(record[2])
-		if err != nil {
-			return fmt.Errorf("ошибка преобразования ID счета: %w", err)
-		}
-
-		amount, err := strconv.ParseFloat(record[3], 64)
-		if err != nil {
-			return fmt.Errorf("ошибка преобразования суммы: %w", err)
-		}
-
-		date, err := time.Parse(time.RFC3339, record[4])
-		if err != nil {
-			return fmt.Errorf("ошибка преобразования даты: %w", err)
-		}
+	return items(records), report, nil
+}
 
-		description := record[5]
+// parseBankAccountCSV разбирает строку CSV со счетом: id, name, balance
+func parseBankAccountCSV(fields []string, problem func(field, message string)) *models.BankAccount {
+	account := &models.BankAccount{Name: fields[1]}
+	account.ID = parseIntField(fields[0], "id", "ID", problem)
+	account.Balance = parseFloatField(fields[2], "balance", "баланс", problem)
+	return account
+}
 
-		categoryID, err := strconv.Atoi(record[6])
-		if err != nil {
-			return fmt.Errorf("ошибка преобразования ID категории: %w", err)
-		}
+// parseCategoryCSV разбирает строку CSV с категорией: id, type, name
+func parseCategoryCSV(fields []string, problem func(field, message string)) *models.Category {
+	category := &models.Category{Type: models.OperationType(fields[1]), Name: fields[2]}
+	category.ID = parseIntField(fields[0], "id", "ID", problem)
+	return category
+}
 
-		operation := &models.Operation{
-			ID:            id,
-			Type:          opType,
-			BankAccountID: bankAccountID,
-			Amount:        amount,
-			Date:          date,
-			Description:   description,
-			CategoryID:    categoryID,
-		}
+// parseOperationCSV разбирает строку CSV с операцией:
+// id, type, bank_account_id, amount, date, description, category_id
+func parseOperationCSV(fields []string, problem func(field, message string)) *models.Operation {
+	operation := &models.Operation{Type: models.OperationType(fields[1]), Description: fields[5]}
+	operation.ID = parseIntField(fields[0], "id", "ID", problem)
+	operation.BankAccountID = parseIntField(fields[2], "bank_account_id", "ID счета", problem)
+	operation.Amount = parseFloatField(fields[3], "amount", "сумма", problem)
+	operation.CategoryID = parseIntField(fields[6], "category_id", "ID категории", problem)
 
-		err = i.opRepo.Save(operation)
-		if err != nil {
-			return fmt.Errorf("ошибка создания операции: %w", err)
-		}
+	date, err := time.Parse(time.RFC3339, fields[4])
+	if err != nil {
+		problem("date", fmt.Sprintf("дата %q не в формате RFC 3339", fields[4]))
 	}
+	operation.Date = date
 
-	return nil
+	return operation
 }
 
-// importOperationsFromJSON импортирует операции из JSON
-func (i *FileImporter) importOperationsFromJSON() error {
-	file, err := os.Open(fmt.Sprintf("%s/operations.json", i.importPath))
+// parseIntField разбирает целое поле и сообщает о проблеме, если это не число
+func parseIntField(value, field, title string, problem func(field, message string)) int {
+	n, err := strconv.Atoi(value)
 	if err != nil {
-		return err
+		problem(field, fmt.Sprintf("%s %q не является целым числом", title, value))
 	}
-	defer file.Close()
-
-	var operations []*models.Operation
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&operations); err != nil {
-		return err
-	}
-
-	for _, operation := range operations {
-		err := i.opRepo.Save(operation)
-		if err != nil {
-			return fmt.Errorf("ошибка создания операции: %w", err)
-		}
-	}
-
-	return nil
+	return n
 }
 
-// importOperationsFromYAML импортирует операции из YAML
-func (i *FileImporter) importOperationsFromYAML() error {
-	file, err := os.Open(fmt.Sprintf("%s/operations.yaml", i.importPath))
+// parseFloatField разбирает дробное поле и сообщает о проблеме, если это не число
+func parseFloatField(value, field, title string, problem func(field, message string)) float64 {
+	n, err := strconv.ParseFloat(value, 64)
 	if err != nil {
-		return err
+		problem(field, fmt.Sprintf("%s %q не является числом", title, value))
 	}
-	defer file.Close()
-
-	var operations []*models.Operation
-	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&operations); err != nil {
-		return err
-	}
-
-	for _, operation := range operations {
-		err := i.opRepo.Save(operation)
-		if err != nil {
-			return fmt.Errorf("ошибка создания операции: %w", err)
-		}
-	}
-
-	return nil
+	return n
 }
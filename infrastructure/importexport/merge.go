@@ -0,0 +1,160 @@
+package importexport
+
+import (
+	"KPO1/domain/interfaces"
+	"KPO1/domain/models"
+	"context"
+	"fmt"
+)
+
+// MergeStrategy определяет, что делать с импортируемой записью, ID которой уже занят в хранилище
+type MergeStrategy string
+
+const (
+	// MergeOverwrite заменяет существующую запись записью из файла
+	MergeOverwrite MergeStrategy = "overwrite"
+	// MergeSkip оставляет существующую запись, запись из файла пропускается
+	MergeSkip MergeStrategy = "skip"
+	// MergeRemap сохраняет запись из файла под новым ID и переписывает ссылки на нее
+	MergeRemap MergeStrategy = "remap"
+)
+
+// ParseMergeStrategy разбирает название стратегии слияния
+func ParseMergeStrategy(name string) (MergeStrategy, error) {
+	switch strategy := MergeStrategy(name); strategy {
+	case MergeOverwrite, MergeSkip, MergeRemap:
+		return strategy, nil
+	default:
+		return "", &models.ValidationError{
+			Field:   "strategy",
+			Message: fmt.Sprintf("неизвестная стратегия слияния %q, ожидается overwrite, skip или remap", name),
+		}
+	}
+}
+
+// EntityImportSummary итог импорта записей одного типа
+type EntityImportSummary struct {
+	// Created записи, сохраненные под свободным ID
+	Created int
+	// Overwritten записи, заменившие существующие
+	Overwritten int
+	// Skipped записи, пропущенные из-за занятого ID
+	Skipped int
+	// Remapped новые ID записей, перенесенных с занятых ID: ID из файла -> новый ID
+	Remapped map[int]int
+}
+
+// ImportSummary итог импорта по всем сущностям
+type ImportSummary struct {
+	Strategy     MergeStrategy
+	BankAccounts EntityImportSummary
+	Categories   EntityImportSummary
+	Operations   EntityImportSummary
+	// RewrittenReferences число ссылок операций на счета и категории, переписанных на новые ID
+	RewrittenReferences int
+}
+
+// newImportSummary создает пустой итог импорта
+func newImportSummary(strategy MergeStrategy) *ImportSummary {
+	return &ImportSummary{
+		Strategy:     strategy,
+		BankAccounts: EntityImportSummary{Remapped: make(map[int]int)},
+		Categories:   EntityImportSummary{Remapped: make(map[int]int)},
+		Operations:   EntityImportSummary{Remapped: make(map[int]int)},
+	}
+}
+
+// rewriteReferences переносит ссылки операций на счета и категории, получившие новые ID
+func (s *ImportSummary) rewriteReferences(operations []*models.Operation) {
+	for _, operation := range operations {
+		if id, ok := s.BankAccounts.Remapped[operation.BankAccountID]; ok {
+			operation.BankAccountID = id
+			s.RewrittenReferences++
+		}
+		if id, ok := s.Categories.Remapped[operation.CategoryID]; ok {
+			operation.CategoryID = id
+			s.RewrittenReferences++
+		}
+	}
+}
+
+// mergeRepository репозиторий, в который сливаются импортируемые записи
+type mergeRepository[T any] interface {
+	interfaces.Repository[T]
+	interfaces.TrashRepository[T]
+}
+
+// mergeRecords сохраняет записи по стратегии слияния и учитывает результат в итоге.
+// Занятыми считаются и ID записей в корзине, чтобы импорт не затирал их незаметно.
+func mergeRecords[T any](
+	ctx context.Context,
+	strategy MergeStrategy,
+	repo mergeRepository[T],
+	records []*T,
+	idOf func(*T) *int,
+	summary *EntityImportSummary,
+) error {
+	occupied, err := occupiedIDs(ctx, repo, idOf)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		id := idOf(record)
+		fileID := *id
+		exists := fileID != 0 && occupied[fileID]
+
+		switch {
+		case !exists:
+			summary.Created++
+		case strategy == MergeSkip:
+			summary.Skipped++
+			continue
+		case strategy == MergeRemap:
+			// Нулевой ID выдается хранилищем из общего счетчика
+			*id = 0
+		default:
+			summary.Overwritten++
+		}
+
+		if err := repo.Save(ctx, record); err != nil {
+			return err
+		}
+		if exists && strategy == MergeRemap {
+			summary.Remapped[fileID] = *id
+		}
+		occupied[*id] = true
+	}
+
+	return nil
+}
+
+// occupiedIDs возвращает ID всех записей хранилища, включая записи в корзине
+func occupiedIDs[T any](ctx context.Context, repo mergeRepository[T], idOf func(*T) *int) (map[int]bool, error) {
+	live, err := repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := repo.GetDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	occupied := make(map[int]bool, len(live)+len(deleted))
+	for _, record := range live {
+		occupied[*idOf(record)] = true
+	}
+	for _, record := range deleted {
+		occupied[*idOf(record)] = true
+	}
+	return occupied, nil
+}
+
+// bankAccountID, categoryID и operationID дают доступ к ID записи для слияния
+func bankAccountID(account *models.BankAccount) *int { return &account.ID }
+func categoryID(category *models.Category) *int      { return &category.ID }
+func operationID(operation *models.Operation) *int   { return &operation.ID }
@@ -0,0 +1,301 @@
+package importexport
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MT940 выписка банка SWIFT MT940
+const MT940 FileFormat = "mt940"
+
+func init() {
+	RegisterCodec(&Codec{Format: MT940, Title: "MT940 (выписка SWIFT)", Statement: StatementCodec{Decode: decodeMT940}})
+}
+
+var (
+	// mt940Tag начало поля :NN: или :NNA: в начале строки
+	mt940Tag = regexp.MustCompile(`^:(\d{2}[A-Z]?):`)
+	// mt940Transaction поле :61:: дата валютирования, дата проводки, признак, код средств, сумма,
+	// вид операции, ссылка клиента и ссылка банка после //
+	mt940Transaction = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d*)([NSF][A-Z0-9]{3})([^/]*)(?://(.*))?$`)
+	// mt940Balance поля :60F:, :60M:, :62F:, :62M:: признак, дата, валюта и сумма
+	mt940Balance = regexp.MustCompile(`^([CD])(\d{6})([A-Z]{3})(\d+,\d*)$`)
+	// mt940Subfield подполе ?NN структурированного поля :86:
+	mt940Subfield = regexp.MustCompile(`\?(\d{2})`)
+)
+
+// mt940Field поле сообщения с номером строки, на которой оно начинается
+type mt940Field struct {
+	tag   string
+	value string
+	line  int
+}
+
+// decodeMT940 читает выписку MT940. Операции берутся из полей :61:, описание - из следующего
+// за ним поля :86:, которое может занимать несколько строк. Признак C - поступление, D - списание,
+// RC и RD - сторно поступления и списания. Ссылка банка после // - ключ для поиска повторов.
+// Поля :60F:/:60M: и :62F:/:62M: дают начальный и конечный остатки. Остатки сверяются
+// для одной выписки, поэтому второе поле :60F: в файле - проблема; :60M: и :62M: -
+// промежуточные остатки страниц той же выписки. Файл не в UTF-8 читается как Windows-1251.
+func decodeMT940(r io.Reader, file string, report *ValidationReport) (*Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	charset := UTF8
+	if !utf8.Valid(data) {
+		charset = Windows1251
+	}
+	text, err := decodeCharset(data, charset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	fields := splitMT940(text)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%s: не найдены поля MT940", file)
+	}
+
+	parser := &mt940Parser{file: file, report: report, statement: &Statement{}}
+	for n, field := range fields {
+		switch field.tag {
+		case "25":
+			parser.account(field)
+		case "60F", "60M":
+			parser.balance(field, false)
+		case "62F", "62M":
+			parser.balance(field, true)
+		case "61":
+			narrative := ""
+			if n+1 < len(fields) && fields[n+1].tag == "86" {
+				narrative = fields[n+1].value
+			}
+			parser.entry(field, narrative)
+		}
+	}
+	parser.finishOpening()
+	return parser.statement, nil
+}
+
+// splitMT940 делит текст на поля. Продолжение поля на следующих строках присоединяется
+// к нему через перевод строки; заголовки блоков {1:...}{2:...}{4: и завершающие -} пропускаются.
+func splitMT940(text string) []mt940Field {
+	var fields []mt940Field
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if block := strings.Index(line, "{4:"); block >= 0 {
+			line = line[block+len("{4:"):]
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "-" || trimmed == "-}" || strings.HasPrefix(trimmed, "{") {
+			continue
+		}
+
+		if match := mt940Tag.FindStringSubmatch(line); match != nil {
+			fields = append(fields, mt940Field{tag: match[1], value: line[len(match[0]):], line: n + 1})
+			continue
+		}
+		if len(fields) > 0 {
+			fields[len(fields)-1].value += "\n" + line
+		}
+	}
+	return fields
+}
+
+// mt940Parser собирает выписку из полей сообщений
+type mt940Parser struct {
+	file      string
+	report    *ValidationReport
+	statement *Statement
+	index     int
+	// openingDate дата поля :60F: до уточнения по датам операций
+	openingDate time.Time
+	// finalOpening строка поля :60F:, с которого начинается выписка; 0, если его еще не было
+	finalOpening int
+}
+
+// problem добавляет проблему поля в отчет
+func (p *mt940Parser) problem(field mt940Field, record int, message string) {
+	p.report.Add(ImportProblem{File: p.file, Line: field.line, Record: record, Field: ":" + field.tag + ":", Message: message})
+}
+
+// account запоминает счет выписки; второй счет в одном файле - проблема
+func (p *mt940Parser) account(field mt940Field) {
+	id := strings.TrimSpace(field.value)
+	switch {
+	case p.statement.AccountID == "":
+		p.statement.AccountID = id
+	case id != p.statement.AccountID:
+		p.problem(field, 0, fmt.Sprintf("выписка содержит несколько счетов: %s и %s", p.statement.AccountID, id))
+	}
+}
+
+// balance запоминает первый начальный и последний конечный остаток.
+// Второе поле :60F: начинает следующую выписку, остатки которой не с чем сверить.
+func (p *mt940Parser) balance(field mt940Field, closing bool) {
+	if field.tag == "60F" {
+		if p.finalOpening != 0 {
+			p.problem(field, 0, fmt.Sprintf("файл содержит несколько выписок, первая начинается в строке %d; импортируйте их по одной", p.finalOpening))
+			return
+		}
+		p.finalOpening = field.line
+	}
+
+	match := mt940Balance.FindStringSubmatch(strings.TrimSpace(field.value))
+	if match == nil {
+		p.problem(field, 0, fmt.Sprintf("остаток %q не в формате MT940", strings.TrimSpace(field.value)))
+		return
+	}
+	date, err := time.Parse("060102", match[2])
+	if err != nil {
+		p.problem(field, 0, fmt.Sprintf("дата %q не в формате ГГММДД", match[2]))
+		return
+	}
+	amount, err := parseMT940Amount(match[4])
+	if err != nil {
+		p.problem(field, 0, err.Error())
+		return
+	}
+	if match[1] == "D" {
+		amount = -amount
+	}
+	if p.statement.Currency == "" {
+		p.statement.Currency = match[3]
+	}
+
+	if closing {
+		p.statement.Closing = &StatementBalance{Date: date, Amount: amount}
+	} else if p.statement.Opening == nil {
+		p.statement.Opening = &StatementBalance{Date: date, Amount: amount}
+		p.openingDate = date
+	}
+}
+
+// finishOpening уточняет дату начального остатка. Банки указывают в :60F: либо день выписки,
+// либо день предыдущего конечного остатка; во втором случае дата раньше первой операции,
+// и остаток относится к началу следующего дня.
+func (p *mt940Parser) finishOpening() {
+	if p.statement.Opening == nil || len(p.statement.Entries) == 0 {
+		return
+	}
+	first := p.statement.Entries[0].Date
+	for _, entry := range p.statement.Entries[1:] {
+		if entry.Date.Before(first) {
+			first = entry.Date
+		}
+	}
+	if p.openingDate.Before(first) {
+		p.statement.Opening.Date = p.openingDate.AddDate(0, 0, 1)
+	}
+}
+
+// entry добавляет операцию из поля :61: и его описания :86:
+func (p *mt940Parser) entry(field mt940Field, narrative string) {
+	p.index++
+	lines := strings.SplitN(field.value, "\n", 2)
+	match := mt940Transaction.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	if match == nil {
+		p.problem(field, p.index, fmt.Sprintf("операция %q не в формате MT940", strings.TrimSpace(lines[0])))
+		return
+	}
+
+	valueDate, err := time.Parse("060102", match[1])
+	if err != nil {
+		p.problem(field, p.index, fmt.Sprintf("дата валютирования %q не в формате ГГММДД", match[1]))
+		return
+	}
+	date := valueDate
+	if match[2] != "" {
+		if date, err = mt940EntryDate(valueDate, match[2]); err != nil {
+			p.problem(field, p.index, err.Error())
+			return
+		}
+	}
+
+	amount, err := parseMT940Amount(match[5])
+	if err != nil {
+		p.problem(field, p.index, err.Error())
+		return
+	}
+	// Сторно поступления уменьшает остаток, сторно списания - увеличивает
+	if match[3] == "D" || match[3] == "RC" {
+		amount = -amount
+	}
+
+	entry := StatementEntry{Date: date, Amount: amount, Line: field.line}
+	if reference := strings.TrimSpace(match[8]); reference != "" && !strings.EqualFold(reference, "NONREF") {
+		entry.ID = reference
+	}
+	entry.Name, entry.Memo = parseMT940Narrative(narrative)
+	if entry.Memo == "" && len(lines) > 1 {
+		entry.Memo = strings.TrimSpace(lines[1])
+	}
+	if entry.Memo == "" {
+		if reference := strings.TrimSpace(match[7]); !strings.EqualFold(reference, "NONREF") {
+			entry.Memo = reference
+		}
+	}
+	p.statement.Entries = append(p.statement.Entries, entry)
+}
+
+// mt940EntryDate возвращает дату проводки ММДД в году даты валютирования; проводка может
+// попасть на соседний год, если валютирование было в конце или начале года
+func mt940EntryDate(valueDate time.Time, monthDay string) (time.Time, error) {
+	date, err := time.Parse("20060102", fmt.Sprintf("%d%s", valueDate.Year(), monthDay))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("дата проводки %q не в формате ММДД", monthDay)
+	}
+	switch {
+	case date.Sub(valueDate) > 180*24*time.Hour:
+		date = date.AddDate(-1, 0, 0)
+	case valueDate.Sub(date) > 180*24*time.Hour:
+		date = date.AddDate(1, 0, 0)
+	}
+	return date, nil
+}
+
+// parseMT940Amount разбирает сумму MT940 с десятичной запятой
+func parseMT940Amount(value string) (float64, error) {
+	amount, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil {
+		return 0, fmt.Errorf("сумма %q не является числом", value)
+	}
+	return amount, nil
+}
+
+// parseMT940Narrative возвращает контрагента и назначение платежа из поля :86:.
+// В структурированном поле (код операции и подполя ?NN) строки переносятся посреди слов,
+// поэтому склеиваются без пробела: назначение - подполя ?20-?29 и ?60-?63, контрагент - ?32 и ?33.
+// Свободный текст склеивается через пробел и целиком становится назначением.
+func parseMT940Narrative(narrative string) (name, memo string) {
+	if !mt940Subfield.MatchString(narrative) {
+		return "", strings.Join(strings.Fields(narrative), " ")
+	}
+
+	joined := strings.NewReplacer("\r", "", "\n", "").Replace(narrative)
+	positions := mt940Subfield.FindAllStringSubmatchIndex(joined, -1)
+	var memoParts, nameParts []string
+	for n, position := range positions {
+		end := len(joined)
+		if n+1 < len(positions) {
+			end = positions[n+1][0]
+		}
+		code, _ := strconv.Atoi(joined[position[2]:position[3]])
+		value := strings.TrimSpace(joined[position[1]:end])
+		if value == "" {
+			continue
+		}
+		switch {
+		case code >= 20 && code <= 29, code >= 60 && code <= 63:
+			memoParts = append(memoParts, value)
+		case code == 32 || code == 33:
+			nameParts = append(nameParts, value)
+		}
+	}
+	return strings.Join(nameParts, ""), strings.Join(memoParts, " ")
+}
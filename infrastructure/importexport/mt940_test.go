@@ -0,0 +1,123 @@
+package importexport
+
+import (
+	"KPO1/infrastructure/persistence"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// mt940SameDayCoffee выписка MT940 с двумя одинаковыми покупками за один день без ссылки банка
+const mt940SameDayCoffee = `:20:STMT1
+:25:40817810000000000001
+:28C:1/1
+:60F:C240229RUB1000,00
+:61:2403010301D250,00NMSCNONREF
+:86:Кофейня
+:61:2403010301D250,00NMSCNONREF
+:86:Кофейня
+:62F:C240301RUB500,00
+`
+
+func TestMT940ImportKeepsIdenticalNonrefOperations(t *testing.T) {
+	ctx := context.Background()
+	repo := newStatementStore(t)
+	options := StatementImportOptions{BankAccountID: 1, IncomeCategoryID: 1, ExpenseCategoryID: 2}
+
+	importer := NewStreamStatementImporter(MT940, strings.NewReader(mt940SameDayCoffee), options, persistence.NewTransactionManager(repo))
+	if err := importer.Import(ctx); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if got := importer.Summary(); got.Imported != 2 || got.Duplicates != 0 {
+		t.Errorf("first import: imported %d, duplicates %d; want 2 and 0", got.Imported, got.Duplicates)
+	}
+	operations, err := repo.GetAllOperations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 2 {
+		t.Fatalf("store has %d operations, want 2", len(operations))
+	}
+	for _, operation := range operations {
+		if operation.Amount != 250 || operation.Description != "Кофейня" || operation.Date.Format("2006-01-02") != "2024-03-01" {
+			t.Errorf("operation = %+v", operation)
+		}
+	}
+
+	// Повторный импорт того же файла не добавляет операций
+	importer = NewStreamStatementImporter(MT940, strings.NewReader(mt940SameDayCoffee), options, persistence.NewTransactionManager(repo))
+	if err := importer.Import(ctx); err != nil {
+		t.Fatalf("second Import: %v", err)
+	}
+	if got := importer.Summary(); got.Imported != 0 || got.Duplicates != 2 {
+		t.Errorf("second import: imported %d, duplicates %d; want 0 and 2", got.Imported, got.Duplicates)
+	}
+}
+
+func TestMT940Balances(t *testing.T) {
+	t.Run("pages of one statement", func(t *testing.T) {
+		// Промежуточные остатки :62M:/:60M: между страницами одной выписки
+		text := `:20:STMT1
+:25:40817810000000000001
+:28C:1/1
+:60F:C240229RUB1000,00
+:61:2403010301D250,00NMSC//REF1
+:62M:C240301RUB750,00
+:28C:1/2
+:60M:C240301RUB750,00
+:61:2403020302C100,00NMSC//REF2
+:62F:C240302RUB850,00
+`
+		report := &ValidationReport{}
+		statement, err := decodeMT940(strings.NewReader(text), "statement.sta", report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.Valid() {
+			t.Fatalf("problems: %v", report)
+		}
+		if statement.Opening == nil || statement.Opening.Amount != 1000 {
+			t.Errorf("Opening = %+v, want 1000 from :60F:", statement.Opening)
+		}
+		if statement.Closing == nil || statement.Closing.Amount != 850 {
+			t.Errorf("Closing = %+v, want 850 from :62F:", statement.Closing)
+		}
+		if len(statement.Entries) != 2 {
+			t.Errorf("entries = %d, want 2", len(statement.Entries))
+		}
+	})
+
+	t.Run("several statements are rejected", func(t *testing.T) {
+		text := mt940SameDayCoffee + `-
+:20:STMT2
+:25:40817810000000000001
+:28C:2/1
+:60F:C240301RUB500,00
+:61:2403020302D100,00NMSC//REF3
+:62F:C240302RUB400,00
+`
+		report := &ValidationReport{}
+		if _, err := decodeMT940(strings.NewReader(text), "statement.sta", report); err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Problems) != 1 {
+			t.Fatalf("problems = %v, want one", report.Problems)
+		}
+		problem := report.Problems[0]
+		if problem.Line != 14 || problem.Field != ":60F:" || !strings.Contains(problem.Message, "несколько выписок, первая начинается в строке 4") {
+			t.Errorf("problem = %+v", problem)
+		}
+
+		repo := newStatementStore(t)
+		options := StatementImportOptions{BankAccountID: 1, IncomeCategoryID: 1, ExpenseCategoryID: 2}
+		err := NewStreamStatementImporter(MT940, strings.NewReader(text), options, persistence.NewTransactionManager(repo)).Import(context.Background())
+		var aborted *ImportAbortedError
+		if !errors.As(err, &aborted) {
+			t.Fatalf("Import error = %v, want ImportAbortedError", err)
+		}
+		if operations, _ := repo.GetAllOperations(context.Background()); len(operations) != 0 {
+			t.Errorf("store has %d operations after rejected import", len(operations))
+		}
+	})
+}
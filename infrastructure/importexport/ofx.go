@@ -0,0 +1,274 @@
+package importexport
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// OFX выписка банка в формате OFX (SGML 1.x и XML 2.x)
+	OFX FileFormat = "ofx"
+	// QFX выписка банка в формате QFX - OFX с расширениями Intuit
+	QFX FileFormat = "qfx"
+)
+
+func init() {
+	RegisterCodec(&Codec{Format: OFX, Title: "OFX (выписка банка)", Statement: StatementCodec{Decode: decodeOFX}})
+	RegisterCodec(&Codec{Format: QFX, Title: "QFX (выписка банка)", Statement: StatementCodec{Decode: decodeOFX}})
+}
+
+// xmlEncoding находит кодировку в объявлении XML
+var xmlEncoding = regexp.MustCompile(`(?i)<\?xml[^>]*encoding\s*=\s*["']([^"']+)["']`)
+
+// decodeOFX читает выписку OFX. В SGML 1.x у простых элементов нет закрывающих тегов,
+// поэтому значение элемента - текст до следующего тега, а агрегат - элемент без текста.
+// Операции берутся из агрегатов STMTTRN: знак TRNAMT задает тип, DTPOSTED - дату,
+// NAME и MEMO - описание, FITID - ключ для поиска повторов.
+func decodeOFX(r io.Reader, file string, report *ValidationReport) (*Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	charset, err := ofxCharset(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	text, err := decodeCharset(data, charset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	start := strings.Index(strings.ToUpper(text), "<OFX>")
+	if start < 0 {
+		return nil, fmt.Errorf("%s: не найден элемент <OFX>", file)
+	}
+
+	parser := &ofxParser{file: file, report: report, statement: &Statement{}}
+	parser.line = strings.Count(text[:start], "\n") + 1
+	if err := parser.parse(text[start:]); err != nil {
+		return nil, err
+	}
+	return parser.statement, nil
+}
+
+// ofxCharset определяет кодировку по заголовку SGML (ENCODING, CHARSET) или объявлению XML
+func ofxCharset(data []byte) (Charset, error) {
+	head := string(data[:min(len(data), 1024)])
+	if match := xmlEncoding.FindStringSubmatch(head); match != nil {
+		return ParseCharset(match[1])
+	}
+
+	header := make(map[string]string)
+	for _, line := range strings.Split(head, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "<") {
+			break
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			header[strings.ToUpper(strings.TrimSpace(key))] = strings.TrimSpace(value)
+		}
+	}
+	if strings.EqualFold(header["ENCODING"], "UTF-8") {
+		return UTF8, nil
+	}
+	return ParseCharset(header["CHARSET"])
+}
+
+// min возвращает меньшее из двух чисел
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ofxParser разбирает тело OFX и собирает операции выписки
+type ofxParser struct {
+	file      string
+	report    *ValidationReport
+	statement *Statement
+	line      int
+	// stack открытые агрегаты
+	stack []string
+	// entry поля текущего STMTTRN
+	entry     map[string]string
+	entryLine int
+	index     int
+}
+
+// parse проходит по тегам OFX
+func (p *ofxParser) parse(text string) error {
+	for len(text) > 0 {
+		open := strings.IndexByte(text, '<')
+		if open < 0 {
+			break
+		}
+		p.line += strings.Count(text[:open], "\n")
+		text = text[open:]
+
+		end := strings.IndexByte(text, '>')
+		if end < 0 {
+			return fmt.Errorf("%s, строка %d: незакрытый тег", p.file, p.line)
+		}
+		tag := strings.ToUpper(strings.TrimSpace(text[1:end]))
+		text = text[end+1:]
+
+		if tag == "" || tag[0] == '?' || tag[0] == '!' {
+			continue
+		}
+		if tag[0] == '/' {
+			if err := p.close(tag[1:]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		next := strings.IndexByte(text, '<')
+		if next < 0 {
+			next = len(text)
+		}
+		value := strings.TrimSpace(text[:next])
+		if value == "" {
+			p.openAggregate(tag)
+			continue
+		}
+		p.field(tag, html.UnescapeString(value))
+	}
+
+	if p.entry != nil {
+		return fmt.Errorf("%s, строка %d: операция STMTTRN не закрыта", p.file, p.entryLine)
+	}
+	return nil
+}
+
+// openAggregate открывает агрегат
+func (p *ofxParser) openAggregate(tag string) {
+	p.stack = append(p.stack, tag)
+	if tag == "STMTTRN" {
+		p.entry = make(map[string]string)
+		p.entryLine = p.line
+	}
+}
+
+// close закрывает агрегат вместе с вложенными агрегатами без закрывающих тегов.
+// Закрывающий тег простого элемента в XML пропускается.
+func (p *ofxParser) close(tag string) error {
+	for n := len(p.stack) - 1; n >= 0; n-- {
+		if p.stack[n] != tag {
+			continue
+		}
+		for len(p.stack) > n {
+			closed := p.stack[len(p.stack)-1]
+			p.stack = p.stack[:len(p.stack)-1]
+			if closed == "STMTTRN" && p.entry != nil {
+				p.finishEntry()
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// field запоминает значение простого элемента
+func (p *ofxParser) field(tag, value string) {
+	parent := ""
+	if len(p.stack) > 0 {
+		parent = p.stack[len(p.stack)-1]
+	}
+
+	switch {
+	case parent == "STMTTRN" && p.entry != nil:
+		p.entry[tag] = value
+	case tag == "ACCTID" && (parent == "BANKACCTFROM" || parent == "CCACCTFROM"):
+		if p.statement.AccountID == "" {
+			p.statement.AccountID = value
+		} else if p.statement.AccountID != value {
+			p.report.Add(ImportProblem{
+				File:    p.file,
+				Line:    p.line,
+				Field:   "ACCTID",
+				Message: fmt.Sprintf("выписка содержит несколько счетов: %s и %s", p.statement.AccountID, value),
+			})
+		}
+	case tag == "CURDEF" && p.statement.Currency == "":
+		p.statement.Currency = value
+	}
+}
+
+// finishEntry превращает поля STMTTRN в операцию выписки
+func (p *ofxParser) finishEntry() {
+	p.index++
+	fields := p.entry
+	p.entry = nil
+
+	problem := func(field, message string) {
+		p.report.Add(ImportProblem{File: p.file, Line: p.entryLine, Record: p.index, Field: field, Message: message})
+	}
+
+	entry := StatementEntry{
+		ID:   fields["FITID"],
+		Name: fields["NAME"],
+		Memo: fields["MEMO"],
+		Line: p.entryLine,
+	}
+	valid := true
+
+	date, err := parseOFXDate(fields["DTPOSTED"])
+	if err != nil {
+		problem("DTPOSTED", err.Error())
+		valid = false
+	}
+	entry.Date = date
+
+	amount, err := strconv.ParseFloat(strings.Replace(fields["TRNAMT"], ",", ".", 1), 64)
+	if err != nil {
+		problem("TRNAMT", fmt.Sprintf("сумма %q не является числом", fields["TRNAMT"]))
+		valid = false
+	}
+	entry.Amount = amount
+
+	if valid {
+		p.statement.Entries = append(p.statement.Entries, entry)
+	}
+}
+
+// parseOFXDate разбирает дату OFX вида YYYYMMDD[HHMMSS[.XXX]][[смещение:зона]].
+// Без смещения время считается заданным в UTC.
+func parseOFXDate(value string) (time.Time, error) {
+	raw := strings.TrimSpace(value)
+	digits, zone, _ := strings.Cut(raw, "[")
+	if dot := strings.IndexByte(digits, '.'); dot >= 0 {
+		digits = digits[:dot]
+	}
+
+	layouts := map[int]string{8: "20060102", 12: "200601021504", 14: "20060102150405"}
+	layout, ok := layouts[len(digits)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("дата %q не в формате OFX", value)
+	}
+
+	location := time.UTC
+	if zone = strings.TrimSuffix(zone, "]"); zone != "" {
+		offset, name, _ := strings.Cut(zone, ":")
+		hours, err := strconv.ParseFloat(offset, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("смещение часового пояса в дате %q не является числом", value)
+		}
+		if name == "" {
+			name = "UTC" + offset
+		}
+		location = time.FixedZone(name, int(hours*3600))
+	}
+
+	date, err := time.ParseInLocation(layout, digits, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("дата %q не в формате OFX", value)
+	}
+	return date, nil
+}
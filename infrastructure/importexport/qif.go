@@ -0,0 +1,264 @@
+package importexport
+
+import (
+	"KPO1/domain/models"
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// QIF формат Quicken Interchange Format
+const QIF FileFormat = "qif"
+
+// qifTransferCategory категория операций QIF, у которых вместо категории указан счет перевода [Счет]
+const qifTransferCategory = "Переводы"
+
+func init() {
+	RegisterCodec(&Codec{
+		Format:    QIF,
+		Title:     "QIF",
+		Statement: StatementCodec{Decode: decodeQIF, Encode: encodeQIF},
+	})
+}
+
+// qifSection вид раздела QIF
+type qifSection int
+
+const (
+	qifOther qifSection = iota
+	qifTransactions
+	qifCategories
+	qifAccounts
+)
+
+// decodeQIF читает разделы !Type:Bank, !Type:Cash и !Type:CCard в операции, !Type:Cat - в категории,
+// а !Account задает счет для следующих операций. Строка L становится категорией операции,
+// при разбивке без L берется категория первой части S. Остальные разделы пропускаются.
+// Файл не в UTF-8 читается как Windows-1251.
+func decodeQIF(r io.Reader, file string, report *ValidationReport) (*Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	charset := UTF8
+	if !utf8.Valid(data) {
+		charset = Windows1251
+	}
+	text, err := decodeCharset(data, charset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	statement := &Statement{}
+	section := qifOther
+	account := ""
+	fields := make(map[byte]string)
+	recordLine, index := 0, 0
+
+	lines := strings.Split(text, "\n")
+	for n, raw := range lines {
+		line := strings.TrimRight(raw, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if line[0] == '!' {
+			section = parseQIFHeader(line)
+			fields = make(map[byte]string)
+			continue
+		}
+
+		if line[0] != '^' {
+			if len(fields) == 0 {
+				recordLine = n + 1
+			}
+			code, value := line[0], strings.TrimSpace(line[1:])
+			// У разбивки несколько строк S, нужна только первая
+			if _, ok := fields[code]; !ok || code != 'S' {
+				fields[code] = value
+			}
+			continue
+		}
+
+		switch section {
+		case qifAccounts:
+			if name := fields['N']; name != "" {
+				account = name
+			}
+		case qifCategories:
+			if name := fields['N']; name != "" {
+				opType := models.Expense
+				if _, income := fields['I']; income {
+					opType = models.Income
+				}
+				statement.Categories = append(statement.Categories, StatementCategory{Name: name, Type: opType})
+			}
+		case qifTransactions:
+			index++
+			if entry, ok := qifEntry(fields, file, recordLine, index, report); ok {
+				entry.Account = account
+				statement.Entries = append(statement.Entries, entry)
+			}
+		}
+		fields = make(map[byte]string)
+	}
+
+	if len(fields) > 0 && section == qifTransactions {
+		report.Add(ImportProblem{File: file, Line: recordLine, Record: index + 1, Message: "запись не завершена строкой ^"})
+	}
+	return statement, nil
+}
+
+// parseQIFHeader определяет вид раздела по заголовку
+func parseQIFHeader(line string) qifSection {
+	header := strings.ToLower(strings.TrimSpace(line))
+	switch {
+	case header == "!account":
+		return qifAccounts
+	case header == "!type:bank", header == "!type:cash", header == "!type:ccard":
+		return qifTransactions
+	case header == "!type:cat":
+		return qifCategories
+	default:
+		return qifOther
+	}
+}
+
+// qifEntry превращает поля записи раздела операций в операцию выписки
+func qifEntry(fields map[byte]string, file string, line, index int, report *ValidationReport) (StatementEntry, bool) {
+	problem := func(field, message string) {
+		report.Add(ImportProblem{File: file, Line: line, Record: index, Field: field, Message: message})
+	}
+
+	entry := StatementEntry{
+		Name:     fields['P'],
+		Memo:     fields['M'],
+		Category: qifCategory(fields['L']),
+		Line:     line,
+	}
+	if entry.Category == "" {
+		entry.Category = qifCategory(fields['S'])
+	}
+	valid := true
+
+	date, err := parseQIFDate(fields['D'])
+	if err != nil {
+		problem("D", err.Error())
+		valid = false
+	}
+	entry.Date = date
+
+	amountValue, ok := fields['T']
+	if !ok {
+		amountValue = fields['U']
+	}
+	amount, err := parseQIFAmount(amountValue)
+	if err != nil {
+		problem("T", err.Error())
+		valid = false
+	}
+	entry.Amount = amount
+
+	return entry, valid
+}
+
+// qifCategory возвращает название категории из строки L без класса после «/»;
+// перевод на счет [Счет] попадает в категорию переводов
+func qifCategory(value string) string {
+	if slash := strings.IndexByte(value, '/'); slash >= 0 {
+		value = value[:slash]
+	}
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		return qifTransferCategory
+	}
+	return value
+}
+
+// parseQIFDate разбирает дату QIF: 10/05/2023, 10/5'23, 05.10.2023 или 2023-10-05.
+// Дата через косую черту читается как месяц/день/год, как ее пишет Quicken.
+func parseQIFDate(value string) (time.Time, error) {
+	normalized := strings.ReplaceAll(strings.ReplaceAll(strings.TrimSpace(value), "'", "/"), " ", "")
+	for _, layout := range []string{"1/2/2006", "1/2/06", "2.1.2006", "2.1.06", "2006-01-02"} {
+		if date, err := time.Parse(layout, normalized); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("дата %q не в формате QIF", value)
+}
+
+// parseQIFAmount разбирает сумму QIF с разделителями тысяч: -1,234.50, 1 234,50
+func parseQIFAmount(value string) (float64, error) {
+	normalized := strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	if comma := strings.LastIndexByte(normalized, ','); comma >= 0 {
+		if strings.Contains(normalized, ".") || len(normalized)-comma-1 == 3 {
+			// Запятая разделяет тысячи
+			normalized = strings.ReplaceAll(normalized, ",", "")
+		} else {
+			normalized = strings.Replace(normalized, ",", ".", 1)
+		}
+	}
+	amount, err := strconv.ParseFloat(normalized, 64)
+	if err != nil {
+		return 0, fmt.Errorf("сумма %q не является числом", value)
+	}
+	return amount, nil
+}
+
+// encodeQIF записывает категории разделом !Type:Cat, а каждый счет - блоком !Account
+// с операциями в разделе !Type:Bank, упорядоченными по дате
+func encodeQIF(w io.Writer, ledger *Ledger) error {
+	out := bufio.NewWriter(w)
+
+	categoryNames := make(map[int]string, len(ledger.Categories))
+	if len(ledger.Categories) > 0 {
+		fmt.Fprintln(out, "!Type:Cat")
+		for _, category := range ledger.Categories {
+			categoryNames[category.ID] = category.Name
+			flag := "E"
+			if category.Type == models.Income {
+				flag = "I"
+			}
+			fmt.Fprintf(out, "N%s\n%s\n^\n", qifText(category.Name), flag)
+		}
+	}
+
+	byAccount := make(map[int][]*models.Operation)
+	for _, operation := range ledger.Operations {
+		byAccount[operation.BankAccountID] = append(byAccount[operation.BankAccountID], operation)
+	}
+
+	for _, account := range ledger.BankAccounts {
+		fmt.Fprintf(out, "!Account\nN%s\nTBank\n^\n!Type:Bank\n", qifText(account.Name))
+
+		operations := byAccount[account.ID]
+		sort.SliceStable(operations, func(a, b int) bool {
+			if !operations[a].Date.Equal(operations[b].Date) {
+				return operations[a].Date.Before(operations[b].Date)
+			}
+			return operations[a].ID < operations[b].ID
+		})
+		for _, operation := range operations {
+			fmt.Fprintf(out, "D%s\nT%.2f\n", operation.Date.Format("01/02/2006"), balanceDelta(operation))
+			if operation.Description != "" {
+				fmt.Fprintf(out, "P%s\n", qifText(operation.Description))
+			}
+			if name, ok := categoryNames[operation.CategoryID]; ok {
+				fmt.Fprintf(out, "L%s\n", qifText(name))
+			}
+			fmt.Fprintln(out, "^")
+		}
+	}
+
+	return out.Flush()
+}
+
+// qifText убирает переводы строк, которые в QIF разделяют поля
+func qifText(value string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(value)
+}
@@ -0,0 +1,55 @@
+package importexport
+
+import (
+	"KPO1/infrastructure/persistence"
+	"context"
+	"strings"
+	"testing"
+)
+
+// qifSameDayCoffee выписка QIF с двумя одинаковыми покупками за один день
+const qifSameDayCoffee = `!Type:Bank
+D03/01/2024
+T-250.00
+PКофейня
+^
+D03/01/2024
+T-250.00
+PКофейня
+^
+`
+
+func TestQIFImportKeepsIdenticalSameDayOperations(t *testing.T) {
+	ctx := context.Background()
+	repo := newStatementStore(t)
+	options := StatementImportOptions{BankAccountID: 1, IncomeCategoryID: 1, ExpenseCategoryID: 2}
+
+	importer := NewStreamStatementImporter(QIF, strings.NewReader(qifSameDayCoffee), options, persistence.NewTransactionManager(repo))
+	if err := importer.Import(ctx); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if got := importer.Summary(); got.Imported != 2 || got.Duplicates != 0 {
+		t.Errorf("first import: imported %d, duplicates %d; want 2 and 0", got.Imported, got.Duplicates)
+	}
+	operations, err := repo.GetAllOperations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 2 {
+		t.Fatalf("store has %d operations, want 2", len(operations))
+	}
+	for _, operation := range operations {
+		if operation.Amount != 250 || operation.Description != "Кофейня" || operation.Date.Format("2006-01-02") != "2024-03-01" {
+			t.Errorf("operation = %+v", operation)
+		}
+	}
+
+	// Повторный импорт того же файла не добавляет операций
+	importer = NewStreamStatementImporter(QIF, strings.NewReader(qifSameDayCoffee), options, persistence.NewTransactionManager(repo))
+	if err := importer.Import(ctx); err != nil {
+		t.Fatalf("second Import: %v", err)
+	}
+	if got := importer.Summary(); got.Imported != 0 || got.Duplicates != 2 {
+		t.Errorf("second import: imported %d, duplicates %d; want 0 and 2", got.Imported, got.Duplicates)
+	}
+}
@@ -0,0 +1,190 @@
+package importexport
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Record запись, прочитанная кодеком из файла, вместе с ее положением для отчета
+type Record[T any] struct {
+	Item *T
+	// Line строка файла, с которой начинается запись; 0, если неизвестна
+	Line int
+	// Index порядковый номер записи в файле, начиная с 1
+	Index int
+}
+
+// items возвращает сущности прочитанных записей
+func items[T any](records []Record[T]) []*T {
+	result := make([]*T, 0, len(records))
+	for _, record := range records {
+		result = append(result, record.Item)
+	}
+	return result
+}
+
+// readCSVRecords читает записи из CSV с заголовком. Строки, которые не удалось
+// разобрать, попадают в отчет и пропускаются, чтобы собрать все проблемы файла.
+func readCSVRecords[T any](
+	r io.Reader,
+	file string,
+	columns int,
+	parse func(fields []string, problem func(field, message string)) *T,
+	report *ValidationReport,
+) ([]Record[T], error) {
+	reader := csv.NewReader(r)
+	// Число полей проверяется ниже, чтобы короткая строка не прерывала чтение файла
+	reader.FieldsPerRecord = -1
+
+	// Пропускаем заголовок
+	_, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record[T]
+	for index := 1; ; index++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(fields) < columns {
+			report.Add(ImportProblem{
+				File:    file,
+				Line:    line,
+				Record:  index,
+				Message: fmt.Sprintf("ожидается %d полей, получено %d", columns, len(fields)),
+			})
+			continue
+		}
+
+		valid := true
+		item := parse(fields, func(field, message string) {
+			valid = false
+			report.Add(ImportProblem{File: file, Line: line, Record: index, Field: field, Message: message})
+		})
+		if valid {
+			records = append(records, Record[T]{Item: item, Line: line, Index: index})
+		}
+	}
+
+	return records, nil
+}
+
+// readJSONRecords читает массив записей из JSON. Запись с неподходящими значениями
+// попадает в отчет и пропускается; синтаксическая ошибка прерывает чтение файла.
+func readJSONRecords[T any](r io.Reader, file string, report *ValidationReport) ([]Record[T], error) {
+	// Поток читается целиком, чтобы находить строку записи по смещению декодера
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("%s: ожидается массив записей", file)
+	}
+
+	var records []Record[T]
+	for index := 1; decoder.More(); index++ {
+		line := lineAt(data, decoder.InputOffset())
+
+		var item T
+		if err := decoder.Decode(&item); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+
+			problem := ImportProblem{File: file, Line: line, Record: index, Message: err.Error()}
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				problem.Field = fieldName(typeErr.Field)
+				problem.Message = fmt.Sprintf("значение типа %s не подходит для поля", typeErr.Value)
+			}
+			report.Add(problem)
+			continue
+		}
+
+		records = append(records, Record[T]{Item: &item, Line: line, Index: index})
+	}
+
+	return records, nil
+}
+
+// readYAMLRecords читает последовательность записей из YAML. Запись, которую
+// не удалось преобразовать в модель, попадает в отчет и пропускается.
+func readYAMLRecords[T any](r io.Reader, file string, report *ValidationReport) ([]Record[T], error) {
+	var root yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&root); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	sequence := &root
+	if sequence.Kind == yaml.DocumentNode && len(sequence.Content) > 0 {
+		sequence = sequence.Content[0]
+	}
+	if sequence.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%s: ожидается список записей", file)
+	}
+
+	records := make([]Record[T], 0, len(sequence.Content))
+	for n, node := range sequence.Content {
+		var item T
+		if err := node.Decode(&item); err != nil {
+			problem := ImportProblem{File: file, Line: node.Line, Record: n + 1, Message: err.Error()}
+			var typeErr *yaml.TypeError
+			if errors.As(err, &typeErr) {
+				problem.Message = strings.Join(typeErr.Errors, "; ")
+			}
+			report.Add(problem)
+			continue
+		}
+		records = append(records, Record[T]{Item: &item, Line: node.Line, Index: n + 1})
+	}
+
+	return records, nil
+}
+
+// lineAt возвращает номер строки, с которой начинается значение после смещения offset
+func lineAt(data []byte, offset int64) int {
+	pos := int(offset)
+	for pos < len(data) && strings.IndexByte(" \t\r\n,", data[pos]) >= 0 {
+		pos++
+	}
+	return bytes.Count(data[:pos], []byte("\n")) + 1
+}
+
+// fieldName переводит имя поля модели в запись через подчеркивание: BankAccountID -> bank_account_id
+func fieldName(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for n, r := range runes {
+		if n > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[n-1])
+			nextLower := n+1 < len(runes) && unicode.IsLower(runes[n+1])
+			if prevLower || (unicode.IsUpper(runes[n-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,674 @@
+package importexport
+
+import (
+	"KPO1/domain/interfaces"
+	"KPO1/domain/models"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StatementEntry операция из выписки банка
+type StatementEntry struct {
+	// ID идентификатор операции в выписке, например FITID; пустой, если формат его не дает
+	ID   string
+	Date time.Time
+	// Amount сумма со знаком: поступления положительные, списания отрицательные
+	Amount float64
+	// Name получатель или плательщик
+	Name string
+	Memo string
+	// Account название счета в файле для форматов с несколькими счетами; пустое, если счет не указан
+	Account string
+	// Category название категории в файле; пустое, если категорию выбирают правила
+	Category string
+	// Line строка файла, с которой начинается операция; 0, если неизвестна
+	Line int
+}
+
+// Description возвращает описание операции из получателя и назначения платежа
+func (e StatementEntry) Description() string {
+	name, memo := strings.TrimSpace(e.Name), strings.TrimSpace(e.Memo)
+	switch {
+	case name == "":
+		return memo
+	case memo == "" || strings.EqualFold(name, memo):
+		return name
+	default:
+		return name + " - " + memo
+	}
+}
+
+// key возвращает ключ для поиска повторов: ID из выписки, а без него - дату, сумму и описание
+func (e StatementEntry) key() string {
+	if e.ID != "" {
+		return e.ID
+	}
+	return contentKey(e.Date, e.Amount, e.Description())
+}
+
+// operationKey возвращает ключ операции хранилища, сопоставимый с ключами операций выписки
+func operationKey(operation *models.Operation) string {
+	if operation.ExternalID != "" {
+		return operation.ExternalID
+	}
+	return contentKey(operation.Date, balanceDelta(operation), operation.Description)
+}
+
+// contentKey ключ операции без ID в выписке: дата, сумма со знаком и описание
+func contentKey(date time.Time, amount float64, description string) string {
+	return fmt.Sprintf("~%s|%.2f|%s", date.Format("2006-01-02"), amount, description)
+}
+
+// balanceDelta возвращает изменение баланса счета от операции
+func balanceDelta(operation *models.Operation) float64 {
+	if operation.Type == models.Expense {
+		return -operation.Amount
+	}
+	return operation.Amount
+}
+
+// StatementCategory категория, перечисленная в выписке
+type StatementCategory struct {
+	Name string
+	Type models.OperationType
+}
+
+// StatementBalance остаток по счету на дату выписки
+type StatementBalance struct {
+	Date   time.Time
+	Amount float64
+}
+
+// Statement выписка банка по одному или нескольким счетам
+type Statement struct {
+	// AccountID номер счета в банке, если формат его содержит
+	AccountID string
+	Currency  string
+	// Opening и Closing начальный остаток на начало дня и конечный на конец дня;
+	// nil, если формат их не содержит
+	Opening *StatementBalance
+	Closing *StatementBalance
+	// Categories категории из списка категорий выписки; отсутствующие создаются при импорте
+	Categories []StatementCategory
+	Entries    []StatementEntry
+}
+
+// StatementDecoder читает выписку из потока. Операции, которые не удалось разобрать,
+// добавляются в report с именем файла file; ошибка прерывает чтение всей выписки.
+type StatementDecoder func(r io.Reader, file string, report *ValidationReport) (*Statement, error)
+
+// Ledger счета, категории и операции для записи выпиской
+type Ledger struct {
+	BankAccounts []*models.BankAccount
+	Categories   []*models.Category
+	Operations   []*models.Operation
+}
+
+// StatementEncoder записывает счета с их операциями выпиской
+type StatementEncoder func(w io.Writer, ledger *Ledger) error
+
+// StatementCodec чтение и запись выписок банка.
+// Nil-функция означает, что формат не поддерживает импорт или экспорт выписок.
+type StatementCodec struct {
+	Decode StatementDecoder
+	Encode StatementEncoder
+}
+
+// CategoryRule правило выбора категории: первая категория, подстрока Match которой
+// встречается в описании операции без учета регистра и тип которой совпадает с типом операции
+type CategoryRule struct {
+	Match      string `yaml:"match"`
+	CategoryID int    `yaml:"category_id"`
+}
+
+// LoadCategoryRules читает правила категорий из YAML-списка вида:
+//
+//   - match: Пятерочка
+//     category_id: 3
+func LoadCategoryRules(r io.Reader) ([]CategoryRule, error) {
+	var rules []CategoryRule
+	if err := yaml.NewDecoder(r).Decode(&rules); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("ошибка чтения правил категорий: %w", err)
+	}
+	for n, rule := range rules {
+		if strings.TrimSpace(rule.Match) == "" || rule.CategoryID <= 0 {
+			return nil, &models.ValidationError{
+				Field:   "rules",
+				Message: fmt.Sprintf("правило %d: нужны непустая подстрока match и положительный category_id", n+1),
+			}
+		}
+	}
+	return rules, nil
+}
+
+// StatementImportOptions куда и с какими категориями импортируется выписка
+type StatementImportOptions struct {
+	// BankAccountID счет для всех операций выписки; 0 - счета берутся из выписки по названию,
+	// а отсутствующие создаются
+	BankAccountID int
+	// IncomeCategoryID и ExpenseCategoryID категории по умолчанию для поступлений и списаний;
+	// 0 - категории нет, и операция без подходящего правила прерывает импорт
+	IncomeCategoryID  int
+	ExpenseCategoryID int
+	Rules             []CategoryRule
+}
+
+// StatementImportSummary итог импорта выписки
+type StatementImportSummary struct {
+	// Imported операции, сохраненные на счет
+	Imported int
+	// Duplicates операции, уже импортированные ранее, и повторы ID внутри выписки
+	Duplicates int
+	// Skipped операции с нулевой суммой
+	Skipped int
+	// ByRule операции, категория которых выбрана правилом
+	ByRule int
+	// CreatedAccounts и CreatedCategories счета и категории из выписки, которых не было в хранилище
+	CreatedAccounts   int
+	CreatedCategories int
+	// BalanceChange суммарное изменение балансов счетов
+	BalanceChange float64
+	// BalanceChecks сверка остатков выписки с балансом счета по операциям
+	BalanceChecks []StatementBalanceCheck
+}
+
+// StatementBalanceCheck сверка остатка из выписки с балансом счета, рассчитанным по его операциям
+type StatementBalanceCheck struct {
+	// Title название остатка: начальный или конечный
+	Title string
+	Date  time.Time
+	// Statement остаток по выписке
+	Statement float64
+	// Computed баланс по операциям счета на ту же дату
+	Computed float64
+}
+
+// Matches сообщает, что остаток выписки совпадает с балансом по операциям
+func (c StatementBalanceCheck) Matches() bool {
+	return !models.BalancesDiffer(c.Statement, c.Computed)
+}
+
+// String возвращает строковое представление сверки
+func (c StatementBalanceCheck) String() string {
+	return fmt.Sprintf("%s на %s: по выписке %.2f руб., по операциям счета %.2f руб., разница %+.2f руб.",
+		c.Title, c.Date.Format("2006-01-02"), c.Statement, c.Computed, c.Statement-c.Computed)
+}
+
+// StatementImporter импортирует выписку банка в операции выбранного счета или счетов из выписки.
+// Весь импорт выполняется в одной транзакции; операции, уже сохраненные на счет,
+// в том числе находящиеся в корзине, пропускаются по ID из выписки, а без него -
+// по дате, сумме и описанию.
+type StatementImporter struct {
+	format FileFormat
+	// decode чтение выписки вместо кодека формата, например по профилю CSV
+	decode    StatementDecoder
+	file      string
+	open      func() (io.ReadCloser, error)
+	options   StatementImportOptions
+	txManager interfaces.TransactionManager
+	summary   *StatementImportSummary
+}
+
+// NewStatementImporter создает новый импортер выписки из файла path
+func NewStatementImporter(
+	format FileFormat,
+	path string,
+	options StatementImportOptions,
+	txManager interfaces.TransactionManager,
+) *StatementImporter {
+	return &StatementImporter{
+		format: format,
+		file:   filepath.Base(path),
+		open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+		options:   options,
+		txManager: txManager,
+		summary:   &StatementImportSummary{},
+	}
+}
+
+// NewStreamStatementImporter создает новый импортер выписки из потока r.
+// Поток читается один раз.
+func NewStreamStatementImporter(
+	format FileFormat,
+	r io.Reader,
+	options StatementImportOptions,
+	txManager interfaces.TransactionManager,
+) *StatementImporter {
+	consumed := false
+	return &StatementImporter{
+		format: format,
+		file:   fmt.Sprintf("statement.%s", format),
+		open: func() (io.ReadCloser, error) {
+			if consumed {
+				return nil, fmt.Errorf("поток выписки уже прочитан")
+			}
+			consumed = true
+			return io.NopCloser(r), nil
+		},
+		options:   options,
+		txManager: txManager,
+		summary:   &StatementImportSummary{},
+	}
+}
+
+// NewProfileStatementImporter создает новый импортер CSV-выписки из файла path по профилю profile
+func NewProfileStatementImporter(
+	profile *CSVProfile,
+	path string,
+	options StatementImportOptions,
+	txManager interfaces.TransactionManager,
+) *StatementImporter {
+	importer := NewStatementImporter(CSV, path, options, txManager)
+	importer.decode = profile.Decoder()
+	return importer
+}
+
+// NewProfileStreamStatementImporter создает новый импортер CSV-выписки из потока r по профилю profile
+func NewProfileStreamStatementImporter(
+	profile *CSVProfile,
+	r io.Reader,
+	options StatementImportOptions,
+	txManager interfaces.TransactionManager,
+) *StatementImporter {
+	importer := NewStreamStatementImporter(CSV, r, options, txManager)
+	importer.decode = profile.Decoder()
+	return importer
+}
+
+// Summary возвращает итог последнего успешного импорта выписки
+func (i *StatementImporter) Summary() *StatementImportSummary {
+	return i.summary
+}
+
+// Import читает выписку и сохраняет ее операции на счет.
+// При любой ошибке хранилище остается в прежнем состоянии и возвращается *ImportAbortedError.
+func (i *StatementImporter) Import(ctx context.Context) error {
+	statement, err := i.read()
+	if err != nil {
+		return err
+	}
+
+	tx, err := i.txManager.Begin(ctx)
+	if err != nil {
+		return &ImportAbortedError{Stage: "начало транзакции", Err: err}
+	}
+
+	summary := &StatementImportSummary{}
+	if err := i.importEntries(ctx, tx, statement, summary); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (ошибка отката транзакции: %v)", err, rbErr)
+		}
+		return &ImportAbortedError{Stage: "операции", Err: err}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &ImportAbortedError{Stage: "фиксация", Err: err}
+	}
+
+	i.summary = summary
+	return nil
+}
+
+// read читает и разбирает выписку декодером профиля или формата
+func (i *StatementImporter) read() (*Statement, error) {
+	decode := i.decode
+	if decode == nil {
+		codec, err := LookupCodec(i.format)
+		if err != nil {
+			return nil, &ImportAbortedError{Stage: "чтение выписки", Err: err}
+		}
+		if codec.Statement.Decode == nil {
+			return nil, &ImportAbortedError{
+				Stage: "чтение выписки",
+				Err:   fmt.Errorf("формат %s не поддерживает импорт выписок", i.format),
+			}
+		}
+		decode = codec.Statement.Decode
+	}
+
+	r, err := i.open()
+	if err != nil {
+		return nil, &ImportAbortedError{Stage: "чтение выписки", Err: err}
+	}
+	defer r.Close()
+
+	report := &ValidationReport{}
+	statement, err := decode(r, i.file, report)
+	if err != nil {
+		return nil, &ImportAbortedError{Stage: "чтение выписки", Err: err}
+	}
+	if !report.Valid() {
+		report.sort()
+		return nil, &ImportAbortedError{Stage: "проверка выписки", Err: report}
+	}
+	return statement, nil
+}
+
+// importEntries сохраняет новые операции выписки и обновляет балансы счетов
+func (i *StatementImporter) importEntries(ctx context.Context, uow interfaces.UnitOfWork, statement *Statement, summary *StatementImportSummary) error {
+	resolver := &statementResolver{
+		uow:      uow,
+		options:  i.options,
+		summary:  summary,
+		accounts: make(map[string]*models.BankAccount),
+		byID:     make(map[int]*models.BankAccount),
+		known:    make(map[int]map[string]int),
+		types:    make(map[int]models.OperationType),
+		deltas:   make(map[int]float64),
+	}
+	if err := resolver.load(ctx); err != nil {
+		return err
+	}
+
+	for _, category := range statement.Categories {
+		if _, err := resolver.categoryByName(ctx, category.Name, category.Type); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for _, entry := range statement.Entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		account, err := resolver.account(ctx, entry.Account)
+		if err != nil {
+			return fmt.Errorf("%s, строка %d: %w", i.file, entry.Line, err)
+		}
+
+		key := entry.key()
+		known, err := resolver.knownKeys(ctx, account.ID)
+		if err != nil {
+			return err
+		}
+		// ID из выписки однозначно определяет операцию, и его повтор - дубликат, даже в том же файле.
+		// Без ID одинаковые операции одного дня вполне реальны, поэтому пропускается столько копий,
+		// сколько их было в хранилище до импорта, а совпадения внутри файла сохраняются.
+		if known[key] > 0 {
+			if entry.ID == "" {
+				known[key]--
+			}
+			summary.Duplicates++
+			continue
+		}
+		if entry.ID != "" {
+			known[key] = 1
+		}
+
+		if !models.BalancesDiffer(entry.Amount, 0) {
+			summary.Skipped++
+			continue
+		}
+
+		opType := models.Income
+		if entry.Amount < 0 {
+			opType = models.Expense
+		}
+		description := entry.Description()
+		categoryID, err := resolver.category(ctx, opType, entry.Category, description)
+		if err != nil {
+			return fmt.Errorf("%s, строка %d: %w", i.file, entry.Line, err)
+		}
+
+		// Нулевой ID выдается хранилищем из общего счетчика
+		operation := &models.Operation{
+			Type:          opType,
+			BankAccountID: account.ID,
+			CategoryID:    categoryID,
+			Amount:        math.Abs(entry.Amount),
+			Date:          entry.Date,
+			Description:   description,
+			ExternalID:    key,
+			CreatedAt:     now,
+		}
+		if err := uow.Operations().Save(ctx, operation); err != nil {
+			return err
+		}
+
+		summary.Imported++
+		summary.BalanceChange += entry.Amount
+		resolver.deltas[account.ID] += entry.Amount
+	}
+
+	if err := resolver.updateBalances(ctx, now); err != nil {
+		return err
+	}
+	return i.checkBalances(ctx, uow, resolver, statement, summary)
+}
+
+// checkBalances сверяет начальный остаток выписки с операциями счета до ее первого дня,
+// а конечный - с операциями по ее последний день включительно. Расхождение не прерывает импорт.
+func (i *StatementImporter) checkBalances(ctx context.Context, uow interfaces.UnitOfWork, resolver *statementResolver, statement *Statement, summary *StatementImportSummary) error {
+	if statement.Opening == nil && statement.Closing == nil {
+		return nil
+	}
+
+	account, err := resolver.account(ctx, "")
+	if err != nil {
+		return err
+	}
+	operations, err := uow.Operations().GetByBankAccountID(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+
+	// computed возвращает баланс по операциям, дни которых удовлетворяют include
+	computed := func(include func(day string) bool) float64 {
+		balance := 0.0
+		for _, operation := range operations {
+			if include(operation.Date.Format("2006-01-02")) {
+				balance += balanceDelta(operation)
+			}
+		}
+		return balance
+	}
+
+	if opening := statement.Opening; opening != nil {
+		day := opening.Date.Format("2006-01-02")
+		summary.BalanceChecks = append(summary.BalanceChecks, StatementBalanceCheck{
+			Title:     "Начальный остаток",
+			Date:      opening.Date,
+			Statement: opening.Amount,
+			Computed:  computed(func(operationDay string) bool { return operationDay < day }),
+		})
+	}
+	if closing := statement.Closing; closing != nil {
+		day := closing.Date.Format("2006-01-02")
+		summary.BalanceChecks = append(summary.BalanceChecks, StatementBalanceCheck{
+			Title:     "Конечный остаток",
+			Date:      closing.Date,
+			Statement: closing.Amount,
+			Computed:  computed(func(operationDay string) bool { return operationDay <= day }),
+		})
+	}
+	return nil
+}
+
+// statementResolver находит и при необходимости создает счета и категории операций выписки
+type statementResolver struct {
+	uow     interfaces.UnitOfWork
+	options StatementImportOptions
+	summary *StatementImportSummary
+	// accounts счета по названию в нижнем регистре, byID - по ID
+	accounts map[string]*models.BankAccount
+	byID     map[int]*models.BankAccount
+	// categories категории хранилища
+	categories []*models.Category
+	// known число операций счета с каждым ключом, включая операции в корзине
+	known map[int]map[string]int
+	// deleted операции в корзине
+	deleted []*models.Operation
+	// types типы уже проверенных категорий
+	types map[int]models.OperationType
+	// deltas изменения балансов счетов
+	deltas map[int]float64
+}
+
+// load читает счета, категории и операции корзины
+func (r *statementResolver) load(ctx context.Context) error {
+	accounts, err := r.uow.BankAccounts().GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		r.byID[account.ID] = account
+		if _, ok := r.accounts[strings.ToLower(account.Name)]; !ok {
+			r.accounts[strings.ToLower(account.Name)] = account
+		}
+	}
+
+	if r.categories, err = r.uow.Categories().GetAll(ctx); err != nil {
+		return err
+	}
+	r.deleted, err = r.uow.Operations().GetDeleted(ctx)
+	return err
+}
+
+// account возвращает счет операции: выбранный в настройках или счет из выписки по названию
+func (r *statementResolver) account(ctx context.Context, name string) (*models.BankAccount, error) {
+	if r.options.BankAccountID != 0 {
+		if account, ok := r.byID[r.options.BankAccountID]; ok {
+			return account, nil
+		}
+		return nil, models.NewNotFoundError(models.EntityBankAccount, r.options.BankAccountID)
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, &models.ValidationError{Field: "bank_account_id", Message: "выписка не указывает счет, выберите счет для операций"}
+	}
+	if account, ok := r.accounts[strings.ToLower(name)]; ok {
+		return account, nil
+	}
+
+	now := time.Now()
+	account := &models.BankAccount{Name: name, CreatedAt: now, UpdatedAt: now}
+	if err := r.uow.BankAccounts().Save(ctx, account); err != nil {
+		return nil, err
+	}
+	r.accounts[strings.ToLower(name)] = account
+	r.byID[account.ID] = account
+	r.summary.CreatedAccounts++
+	return account, nil
+}
+
+// knownKeys возвращает число операций счета с каждым ключом
+func (r *statementResolver) knownKeys(ctx context.Context, bankAccountID int) (map[string]int, error) {
+	if known, ok := r.known[bankAccountID]; ok {
+		return known, nil
+	}
+
+	live, err := r.uow.Operations().GetByBankAccountID(ctx, bankAccountID)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]int, len(live))
+	for _, operation := range live {
+		known[operationKey(operation)]++
+	}
+	for _, operation := range r.deleted {
+		if operation.BankAccountID == bankAccountID {
+			known[operationKey(operation)]++
+		}
+	}
+	r.known[bankAccountID] = known
+	return known, nil
+}
+
+// category выбирает категорию операции: из выписки, по правилам или по умолчанию
+func (r *statementResolver) category(ctx context.Context, opType models.OperationType, name, description string) (int, error) {
+	if name = strings.TrimSpace(name); name != "" {
+		return r.categoryByName(ctx, name, opType)
+	}
+
+	text := strings.ToLower(description)
+	for _, rule := range r.options.Rules {
+		if !strings.Contains(text, strings.ToLower(rule.Match)) {
+			continue
+		}
+		categoryType, err := r.typeOf(ctx, rule.CategoryID)
+		if err != nil {
+			return 0, err
+		}
+		if categoryType == opType {
+			r.summary.ByRule++
+			return rule.CategoryID, nil
+		}
+	}
+
+	field, categoryID, title := "expense_category_id", r.options.ExpenseCategoryID, "списаний"
+	if opType == models.Income {
+		field, categoryID, title = "income_category_id", r.options.IncomeCategoryID, "поступлений"
+	}
+	if categoryID == 0 {
+		return 0, &models.ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("не задана категория по умолчанию для %s, а правила не подошли к операции %q", title, description),
+		}
+	}
+
+	categoryType, err := r.typeOf(ctx, categoryID)
+	if err != nil {
+		return 0, err
+	}
+	if categoryType != opType {
+		return 0, &models.ValidationError{Field: field, Message: "Тип категории не соответствует типу операции"}
+	}
+	return categoryID, nil
+}
+
+// categoryByName находит категорию по названию и типу или создает ее
+func (r *statementResolver) categoryByName(ctx context.Context, name string, opType models.OperationType) (int, error) {
+	for _, category := range r.categories {
+		if category.Type == opType && strings.EqualFold(category.Name, name) {
+			return category.ID, nil
+		}
+	}
+
+	now := time.Now()
+	category := &models.Category{Name: name, Type: opType, CreatedAt: now, UpdatedAt: now}
+	if err := r.uow.Categories().Save(ctx, category); err != nil {
+		return 0, err
+	}
+	r.categories = append(r.categories, category)
+	r.types[category.ID] = category.Type
+	r.summary.CreatedCategories++
+	return category.ID, nil
+}
+
+// typeOf возвращает тип категории, проверяя, что она существует
+func (r *statementResolver) typeOf(ctx context.Context, categoryID int) (models.OperationType, error) {
+	if categoryType, ok := r.types[categoryID]; ok {
+		return categoryType, nil
+	}
+	category, err := r.uow.Categories().GetByID(ctx, categoryID)
+	if err != nil {
+		return "", err
+	}
+	r.types[categoryID] = category.Type
+	return category.Type, nil
+}
+
+// updateBalances добавляет к балансам счетов суммы импортированных операций
+func (r *statementResolver) updateBalances(ctx context.Context, now time.Time) error {
+	for id, delta := range r.deltas {
+		account := r.byID[id]
+		account.Balance += delta
+		account.UpdatedAt = now
+		if err := r.uow.BankAccounts().Update(ctx, account); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package importexport
+
+import (
+	"KPO1/domain/interfaces"
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// StatementExporter экспортирует счета с их операциями выпиской в одном файле
+type StatementExporter struct {
+	format     FileFormat
+	create     func() (io.WriteCloser, error)
+	repository interfaces.CompositeRepository
+}
+
+// NewStatementExporter создает новый экспортер выписки в файл path
+func NewStatementExporter(format FileFormat, path string, repository interfaces.CompositeRepository) *StatementExporter {
+	return &StatementExporter{
+		format: format,
+		create: func() (io.WriteCloser, error) {
+			return os.Create(path)
+		},
+		repository: repository,
+	}
+}
+
+// NewStreamStatementExporter создает новый экспортер выписки в поток w. Поток экспортер не закрывает.
+func NewStreamStatementExporter(format FileFormat, w io.Writer, repository interfaces.CompositeRepository) *StatementExporter {
+	return &StatementExporter{
+		format: format,
+		create: func() (io.WriteCloser, error) {
+			return nopWriteCloser{w}, nil
+		},
+		repository: repository,
+	}
+}
+
+// Export записывает все счета, категории и операции выпиской
+func (e *StatementExporter) Export(ctx context.Context) error {
+	codec, err := LookupCodec(e.format)
+	if err != nil {
+		return err
+	}
+	if codec.Statement.Encode == nil {
+		return fmt.Errorf("формат %s не поддерживает экспорт выписок", e.format)
+	}
+
+	ledger := &Ledger{}
+	if ledger.BankAccounts, err = e.repository.GetBankAccounts(ctx); err != nil {
+		return fmt.Errorf("ошибка получения счетов: %w", err)
+	}
+	if ledger.Categories, err = e.repository.GetCategories(ctx); err != nil {
+		return fmt.Errorf("ошибка получения категорий: %w", err)
+	}
+	if ledger.Operations, err = e.repository.GetOperations(ctx); err != nil {
+		return fmt.Errorf("ошибка получения операций: %w", err)
+	}
+
+	w, err := e.create()
+	if err != nil {
+		return err
+	}
+	if err := codec.Statement.Encode(w, ledger); err != nil {
+		w.Close()
+		return fmt.Errorf("ошибка экспорта выписки: %w", err)
+	}
+	return w.Close()
+}
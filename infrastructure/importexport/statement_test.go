@@ -0,0 +1,178 @@
+package importexport
+
+import (
+	"KPO1/domain/models"
+	"KPO1/infrastructure/persistence"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newStatementStore создает хранилище в памяти со счетом 1, категорией поступлений 1
+// и категорией списаний 2
+func newStatementStore(t *testing.T) *persistence.MemoryRepository {
+	t.Helper()
+	ctx := context.Background()
+	repo := persistence.NewMemoryRepository()
+	if err := repo.SaveBankAccount(ctx, &models.BankAccount{Name: "Основной"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, category := range []*models.Category{
+		{Type: models.Income, Name: "Поступления"},
+		{Type: models.Expense, Name: "Расходы"},
+	} {
+		if err := repo.SaveCategory(ctx, category); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+// importStatement импортирует выписку statement на счет 1 и возвращает итог импорта
+func importStatement(t *testing.T, repo *persistence.MemoryRepository, statement *Statement) *StatementImportSummary {
+	t.Helper()
+	options := StatementImportOptions{BankAccountID: 1, IncomeCategoryID: 1, ExpenseCategoryID: 2}
+	importer := NewStreamStatementImporter(CSV, strings.NewReader(""), options, persistence.NewTransactionManager(repo))
+	importer.decode = func(io.Reader, string, *ValidationReport) (*Statement, error) {
+		return statement, nil
+	}
+	if err := importer.Import(context.Background()); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	return importer.Summary()
+}
+
+// repeatEntry возвращает n копий операции entry
+func repeatEntry(entry StatementEntry, n int) []StatementEntry {
+	entries := make([]StatementEntry, n)
+	for i := range entries {
+		entries[i] = entry
+	}
+	return entries
+}
+
+func TestStatementImportDeduplicates(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	coffee := StatementEntry{Date: day, Amount: -250, Name: "Кофейня"}
+	salary := StatementEntry{ID: "FIT-1", Date: day, Amount: 50000, Name: "Зарплата"}
+	manual := func(n int) []*models.Operation {
+		operations := make([]*models.Operation, n)
+		for i := range operations {
+			operations[i] = &models.Operation{Type: models.Expense, BankAccountID: 1, CategoryID: 2, Amount: 250, Date: day, Description: "Кофейня"}
+		}
+		return operations
+	}
+
+	for _, test := range []struct {
+		name string
+		// stored операции, внесенные на счет вручную; trashed - номера из них, перемещенные в корзину
+		stored  []*models.Operation
+		trashed []int
+		// previous выписка, импортированная ранее
+		previous   []StatementEntry
+		entries    []StatementEntry
+		imported   int
+		duplicates int
+	}{
+		{
+			name:       "same file imported again",
+			previous:   append(repeatEntry(coffee, 2), salary),
+			entries:    append(repeatEntry(coffee, 2), salary),
+			duplicates: 3,
+		},
+		{
+			name:     "identical rows without ID are kept within a file",
+			entries:  repeatEntry(coffee, 3),
+			imported: 3,
+		},
+		{
+			name:       "three identical rows against one stored copy",
+			stored:     manual(1),
+			entries:    repeatEntry(coffee, 3),
+			imported:   2,
+			duplicates: 1,
+		},
+		{
+			name:       "two identical rows against two stored copies",
+			stored:     manual(2),
+			entries:    repeatEntry(coffee, 2),
+			duplicates: 2,
+		},
+		{
+			name:       "one row against three stored copies",
+			stored:     manual(3),
+			entries:    repeatEntry(coffee, 1),
+			duplicates: 1,
+		},
+		{
+			name:       "stored copy in trash counts",
+			stored:     manual(2),
+			trashed:    []int{0},
+			entries:    repeatEntry(coffee, 3),
+			imported:   1,
+			duplicates: 2,
+		},
+		{
+			name:       "file with more rows than the previous import",
+			previous:   repeatEntry(coffee, 2),
+			entries:    repeatEntry(coffee, 3),
+			imported:   1,
+			duplicates: 2,
+		},
+		{
+			name:       "repeated ID within a file",
+			entries:    repeatEntry(salary, 3),
+			imported:   1,
+			duplicates: 2,
+		},
+		{
+			name:       "ID imported before",
+			previous:   []StatementEntry{salary},
+			entries:    []StatementEntry{salary, {ID: "FIT-2", Date: day, Amount: 50000, Name: "Зарплата"}},
+			imported:   1,
+			duplicates: 1,
+		},
+		{
+			name:     "same content with different IDs",
+			entries:  []StatementEntry{salary, {ID: "FIT-2", Date: day, Amount: 50000, Name: "Зарплата"}},
+			imported: 2,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newStatementStore(t)
+			for _, operation := range test.stored {
+				if err := repo.SaveOperation(ctx, operation); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, i := range test.trashed {
+				if err := repo.DeleteOperation(ctx, test.stored[i].ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if test.previous != nil {
+				importStatement(t, repo, &Statement{Entries: test.previous})
+			}
+			before, err := repo.GetAllOperations(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			summary := importStatement(t, repo, &Statement{Entries: test.entries})
+			if summary.Imported != test.imported || summary.Duplicates != test.duplicates {
+				t.Errorf("imported %d, duplicates %d; want %d and %d",
+					summary.Imported, summary.Duplicates, test.imported, test.duplicates)
+			}
+			after, err := repo.GetAllOperations(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(after) - len(before); got != test.imported {
+				t.Errorf("store gained %d operations, want %d", got, test.imported)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package importexport
+
+import (
+	"fmt"
+	"io"
+)
+
+// ImportStreams потоки с записями сущностей для импорта, например stdin, тело HTTP-запроса
+// или буфер в памяти. Nil-поток означает, что записей этой сущности нет.
+type ImportStreams struct {
+	BankAccounts io.Reader
+	Categories   io.Reader
+	Operations   io.Reader
+}
+
+// ExportStreams потоки для записей сущностей при экспорте, например stdout или тело HTTP-ответа.
+// Сущность с nil-потоком не экспортируется.
+type ExportStreams struct {
+	BankAccounts io.Writer
+	Categories   io.Writer
+	Operations   io.Writer
+}
+
+// reader возвращает поток сущности по имени файла
+func (s ImportStreams) reader(name string) (io.Reader, error) {
+	switch name {
+	case "accounts":
+		return s.BankAccounts, nil
+	case "categories":
+		return s.Categories, nil
+	case "operations":
+		return s.Operations, nil
+	default:
+		return nil, fmt.Errorf("неизвестная сущность: %s", name)
+	}
+}
+
+// create возвращает поток сущности по имени файла; закрытие потока остается за вызывающим
+func (s ExportStreams) create(name string) (io.WriteCloser, error) {
+	var w io.Writer
+	switch name {
+	case "accounts":
+		w = s.BankAccounts
+	case "categories":
+		w = s.Categories
+	case "operations":
+		w = s.Operations
+	default:
+		return nil, fmt.Errorf("неизвестная сущность: %s", name)
+	}
+	if w == nil {
+		return nil, nil
+	}
+	return nopWriteCloser{w}, nil
+}
+
+// nopWriteCloser поток записи, закрытие которого ничего не делает
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,263 @@
+package importexport
+
+import (
+	"KPO1/domain/interfaces"
+	"KPO1/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ImportProblem проблема в записи импортируемого файла
+type ImportProblem struct {
+	// File имя файла, например operations.csv
+	File string
+	// Line строка файла, с которой начинается запись; 0, если неизвестна
+	Line int
+	// Record порядковый номер записи в файле, начиная с 1; 0, если проблема относится к файлу целиком
+	Record int
+	// Field имя поля; пустое, если проблема относится к записи целиком
+	Field   string
+	Message string
+}
+
+// String возвращает описание проблемы с указанием файла, строки и поля
+func (p ImportProblem) String() string {
+	location := p.File
+	if p.Record > 0 {
+		location = fmt.Sprintf("%s, запись %d", p.File, p.Record)
+	}
+	if p.Line > 0 {
+		location = fmt.Sprintf("%s, строка %d (запись %d)", p.File, p.Line, p.Record)
+	}
+	if p.Field != "" {
+		location += ", поле " + p.Field
+	}
+	return location + ": " + p.Message
+}
+
+// ValidationReport отчет о проверке импортируемых файлов.
+// Непустой отчет ImportAll возвращает как причину *ImportAbortedError.
+type ValidationReport struct {
+	Problems []ImportProblem
+}
+
+// Valid сообщает, что проблем не найдено
+func (r *ValidationReport) Valid() bool {
+	return len(r.Problems) == 0
+}
+
+func (r *ValidationReport) Error() string {
+	return fmt.Sprintf("в файлах импорта найдено проблем: %d", len(r.Problems))
+}
+
+// Is сопоставляет отчет с models.ErrValidation
+func (r *ValidationReport) Is(target error) bool {
+	return target == models.ErrValidation
+}
+
+// Add добавляет проблему в отчет; кодеки сообщают так о записях, которые не удалось разобрать
+func (r *ValidationReport) Add(problem ImportProblem) {
+	r.Problems = append(r.Problems, problem)
+}
+
+// sort упорядочивает проблемы по порядку импорта файлов и по записям
+func (r *ValidationReport) sort() {
+	sort.SliceStable(r.Problems, func(a, b int) bool {
+		pa, pb := r.Problems[a], r.Problems[b]
+		if pa.File != pb.File {
+			return fileOrder(pa.File) < fileOrder(pb.File)
+		}
+		return pa.Record < pb.Record
+	})
+}
+
+// fileOrder возвращает место файла в порядке импорта: счета, категории, операции
+func fileOrder(file string) int {
+	for n, prefix := range []string{"accounts.", "categories.", "operations."} {
+		if len(file) >= len(prefix) && file[:len(prefix)] == prefix {
+			return n
+		}
+	}
+	return 3
+}
+
+// importData прочитанные записи всех файлов импорта
+type importData struct {
+	accounts   []Record[models.BankAccount]
+	categories []Record[models.Category]
+	operations []Record[models.Operation]
+}
+
+// check проверяет записи по правилам модели и сервисов: валидность полей,
+// уникальность ID в файле, существование счета и категории операции
+// и соответствие типа операции типу категории.
+// Записи с проблемами исключаются из данных.
+func (i *FileImporter) check(ctx context.Context, uow interfaces.UnitOfWork, data *importData, report *ValidationReport) error {
+	var err error
+	if data.accounts, err = checkRecords(ctx, data.accounts, i.fileName("accounts"), bankAccountID, report, nil); err != nil {
+		return err
+	}
+	if data.categories, err = checkRecords(ctx, data.categories, i.fileName("categories"), categoryID, report, nil); err != nil {
+		return err
+	}
+
+	refs, err := i.loadReferences(ctx, uow, data)
+	if err != nil {
+		return err
+	}
+
+	data.operations, err = checkRecords(ctx, data.operations, i.fileName("operations"), operationID, report, refs.check)
+	return err
+}
+
+// fileName возвращает имя файла сущности в формате импортера
+func (i *FileImporter) fileName(name string) string {
+	return fmt.Sprintf("%s.%s", name, i.format)
+}
+
+// validatable сущность с проверкой полей
+type validatable interface {
+	Validate() error
+}
+
+// checkRecords проверяет поля и уникальность ID записей одного файла,
+// а также дополнительное правило extra, и возвращает записи без проблем
+func checkRecords[T any](
+	ctx context.Context,
+	records []Record[T],
+	file string,
+	idOf func(*T) *int,
+	report *ValidationReport,
+	extra func(item *T) *models.ValidationError,
+) ([]Record[T], error) {
+	seen := make(map[int]int, len(records))
+	valid := records[:0]
+
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		problem := ImportProblem{File: file, Line: record.Line, Record: record.Index}
+
+		if err := any(record.Item).(validatable).Validate(); err != nil {
+			var validation *models.ValidationError
+			if errors.As(err, &validation) {
+				problem.Field = validation.Field
+			}
+			problem.Message = err.Error()
+			report.Add(problem)
+			continue
+		}
+
+		id := *idOf(record.Item)
+		if first, ok := seen[id]; ok {
+			problem.Field = "id"
+			problem.Message = fmt.Sprintf("ID #%d уже встречается в записи %d", id, first)
+			report.Add(problem)
+			continue
+		}
+		seen[id] = record.Index
+
+		if extra != nil {
+			if validation := extra(record.Item); validation != nil {
+				problem.Field = validation.Field
+				problem.Message = validation.Message
+				report.Add(problem)
+				continue
+			}
+		}
+
+		valid = append(valid, record)
+	}
+
+	return valid, nil
+}
+
+// importReferences счета и категории, на которые смогут ссылаться операции после импорта
+type importReferences struct {
+	accounts   map[int]bool
+	categories map[int]models.OperationType
+}
+
+// loadReferences определяет, какие счета и категории будут доступны после импорта.
+// При стратегии skip запись хранилища с тем же ID, в том числе в корзине, остается на месте,
+// при overwrite и remap ссылки операций указывают на запись из файла.
+func (i *FileImporter) loadReferences(ctx context.Context, uow interfaces.UnitOfWork, data *importData) (*importReferences, error) {
+	refs := &importReferences{
+		accounts:   make(map[int]bool),
+		categories: make(map[int]models.OperationType),
+	}
+
+	liveAccounts, err := uow.BankAccounts().GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deletedAccounts, err := uow.BankAccounts().GetDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	liveCategories, err := uow.Categories().GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deletedCategories, err := uow.Categories().GetDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	occupiedAccounts := make(map[int]bool, len(liveAccounts)+len(deletedAccounts))
+	for _, account := range liveAccounts {
+		refs.accounts[account.ID] = true
+		occupiedAccounts[account.ID] = true
+	}
+	for _, account := range deletedAccounts {
+		occupiedAccounts[account.ID] = true
+	}
+	for _, record := range data.accounts {
+		if i.strategy != MergeSkip || !occupiedAccounts[record.Item.ID] {
+			refs.accounts[record.Item.ID] = true
+		}
+	}
+
+	occupiedCategories := make(map[int]bool, len(liveCategories)+len(deletedCategories))
+	for _, category := range liveCategories {
+		refs.categories[category.ID] = category.Type
+		occupiedCategories[category.ID] = true
+	}
+	for _, category := range deletedCategories {
+		occupiedCategories[category.ID] = true
+	}
+	for _, record := range data.categories {
+		if i.strategy != MergeSkip || !occupiedCategories[record.Item.ID] {
+			refs.categories[record.Item.ID] = record.Item.Type
+		}
+	}
+
+	return refs, nil
+}
+
+// check проверяет ссылки операции так же, как сервис операций при создании
+func (r *importReferences) check(operation *models.Operation) *models.ValidationError {
+	if !r.accounts[operation.BankAccountID] {
+		return &models.ValidationError{
+			Field:   "bank_account_id",
+			Message: fmt.Sprintf("счет #%d не найден ни в файле, ни в хранилище", operation.BankAccountID),
+		}
+	}
+
+	categoryType, ok := r.categories[operation.CategoryID]
+	if !ok {
+		return &models.ValidationError{
+			Field:   "category_id",
+			Message: fmt.Sprintf("категория #%d не найдена ни в файле, ни в хранилище", operation.CategoryID),
+		}
+	}
+	if categoryType != operation.Type {
+		return &models.ValidationError{Field: "category_id", Message: "Тип категории не соответствует типу операции"}
+	}
+
+	return nil
+}
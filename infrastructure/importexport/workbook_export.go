@@ -0,0 +1,190 @@
+package importexport
+
+import (
+	"KPO1/domain/interfaces"
+	"KPO1/domain/models"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// ReportSheet строит дополнительный лист книги, например отчет аналитики
+type ReportSheet func(ctx context.Context) (*Sheet, error)
+
+// WorkbookExporter экспортирует счета, категории и операции листами одной книги XLSX
+type WorkbookExporter struct {
+	create     func() (io.WriteCloser, error)
+	repository interfaces.CompositeRepository
+	reports    []ReportSheet
+}
+
+// NewWorkbookExporter создает новый экспортер книги в файл path с листами отчетов reports
+func NewWorkbookExporter(path string, repository interfaces.CompositeRepository, reports ...ReportSheet) *WorkbookExporter {
+	return &WorkbookExporter{
+		create: func() (io.WriteCloser, error) {
+			return os.Create(path)
+		},
+		repository: repository,
+		reports:    reports,
+	}
+}
+
+// NewStreamWorkbookExporter создает новый экспортер книги в поток w. Поток экспортер не закрывает.
+func NewStreamWorkbookExporter(w io.Writer, repository interfaces.CompositeRepository, reports ...ReportSheet) *WorkbookExporter {
+	return &WorkbookExporter{
+		create: func() (io.WriteCloser, error) {
+			return nopWriteCloser{w}, nil
+		},
+		repository: repository,
+		reports:    reports,
+	}
+}
+
+// Export записывает книгу: листы счетов, категорий и операций, затем листы отчетов
+func (e *WorkbookExporter) Export(ctx context.Context) error {
+	ledger := &Ledger{}
+	var err error
+	if ledger.BankAccounts, err = e.repository.GetBankAccounts(ctx); err != nil {
+		return fmt.Errorf("ошибка получения счетов: %w", err)
+	}
+	if ledger.Categories, err = e.repository.GetCategories(ctx); err != nil {
+		return fmt.Errorf("ошибка получения категорий: %w", err)
+	}
+	if ledger.Operations, err = e.repository.GetOperations(ctx); err != nil {
+		return fmt.Errorf("ошибка получения операций: %w", err)
+	}
+
+	sheets := LedgerSheets(ledger)
+	for _, report := range e.reports {
+		sheet, err := report(ctx)
+		if err != nil {
+			return fmt.Errorf("ошибка построения отчета: %w", err)
+		}
+		sheets = append(sheets, sheet)
+	}
+
+	w, err := e.create()
+	if err != nil {
+		return err
+	}
+	if err := WriteXLSX(w, sheets); err != nil {
+		w.Close()
+		return fmt.Errorf("ошибка экспорта книги: %w", err)
+	}
+	return w.Close()
+}
+
+// LedgerSheets возвращает листы счетов, категорий и операций. В операциях вместо ID
+// счета и категории указаны их названия, дата и сумма записаны типизированными ячейками.
+func LedgerSheets(ledger *Ledger) []*Sheet {
+	accounts := &Sheet{Name: "Счета", Columns: []string{"ID", "Название", "Баланс"}}
+	accountNames := make(map[int]string, len(ledger.BankAccounts))
+	for _, account := range ledger.BankAccounts {
+		accountNames[account.ID] = account.Name
+		accounts.AddRow(NumberCell(float64(account.ID)), TextCell(account.Name), MoneyCell(account.Balance))
+	}
+
+	categories := &Sheet{Name: "Категории", Columns: []string{"ID", "Тип", "Название"}}
+	categoryNames := make(map[int]string, len(ledger.Categories))
+	for _, category := range ledger.Categories {
+		categoryNames[category.ID] = category.Name
+		categories.AddRow(NumberCell(float64(category.ID)), TextCell(operationTypeTitle(category.Type)), TextCell(category.Name))
+	}
+
+	operations := &Sheet{Name: "Операции", Columns: []string{"ID", "Дата", "Тип", "Счет", "Категория", "Сумма", "Описание"}}
+	sorted := append([]*models.Operation(nil), ledger.Operations...)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		if !sorted[a].Date.Equal(sorted[b].Date) {
+			return sorted[a].Date.Before(sorted[b].Date)
+		}
+		return sorted[a].ID < sorted[b].ID
+	})
+	for _, operation := range sorted {
+		operations.AddRow(
+			NumberCell(float64(operation.ID)),
+			DateCell(operation.Date),
+			TextCell(operationTypeTitle(operation.Type)),
+			TextCell(accountNames[operation.BankAccountID]),
+			TextCell(categoryNames[operation.CategoryID]),
+			MoneyCell(operation.Amount),
+			TextCell(operation.Description),
+		)
+	}
+
+	return []*Sheet{accounts, categories, operations}
+}
+
+// monthTitles названия месяцев для отчетов
+var monthTitles = [...]string{
+	"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь",
+	"Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь",
+}
+
+// MonthlyDynamicsSheet возвращает лист месячной динамики доходов и расходов за год с итоговой строкой
+func MonthlyDynamicsSheet(year int, dynamics map[time.Month]map[models.OperationType]float64) *Sheet {
+	sheet := &Sheet{
+		Name:    fmt.Sprintf("Динамика %d", year),
+		Columns: []string{"Месяц", "Доходы", "Расходы", "Разница"},
+	}
+	var income, expense float64
+	for month := time.January; month <= time.December; month++ {
+		monthIncome, monthExpense := dynamics[month][models.Income], dynamics[month][models.Expense]
+		income += monthIncome
+		expense += monthExpense
+		sheet.AddRow(TextCell(monthTitles[month-1]), MoneyCell(monthIncome), MoneyCell(monthExpense), MoneyCell(monthIncome-monthExpense))
+	}
+	sheet.AddRow(TextCell("Итого"), MoneyCell(income), MoneyCell(expense), MoneyCell(income-expense))
+	return sheet
+}
+
+// CategorySummarySheet возвращает лист сумм по категориям за период: сначала доходы, затем расходы,
+// внутри типа - по убыванию суммы; доля считается от суммы своего типа
+func CategorySummarySheet(start, end time.Time, summary map[*models.Category]float64) *Sheet {
+	sheet := &Sheet{
+		Name:    "По категориям",
+		Columns: []string{"Категория", "Тип", "Сумма", "Доля, %", "Начало периода", "Конец периода"},
+	}
+
+	categories := make([]*models.Category, 0, len(summary))
+	totals := make(map[models.OperationType]float64)
+	for category, amount := range summary {
+		categories = append(categories, category)
+		totals[category.Type] += amount
+	}
+	sort.Slice(categories, func(a, b int) bool {
+		if categories[a].Type != categories[b].Type {
+			return categories[a].Type == models.Income
+		}
+		if summary[categories[a]] != summary[categories[b]] {
+			return summary[categories[a]] > summary[categories[b]]
+		}
+		return categories[a].Name < categories[b].Name
+	})
+
+	for _, category := range categories {
+		share := 0.0
+		if total := totals[category.Type]; total != 0 {
+			share = summary[category] / total * 100
+		}
+		sheet.AddRow(
+			TextCell(category.Name),
+			TextCell(operationTypeTitle(category.Type)),
+			MoneyCell(summary[category]),
+			MoneyCell(share),
+			DateCell(start),
+			DateCell(end),
+		)
+	}
+	return sheet
+}
+
+// operationTypeTitle возвращает название типа операции или категории
+func operationTypeTitle(opType models.OperationType) string {
+	if opType == models.Income {
+		return "Доход"
+	}
+	return "Расход"
+}
@@ -0,0 +1,324 @@
+package importexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// cellKind тип значения ячейки
+type cellKind int
+
+const (
+	textCell cellKind = iota
+	numberCell
+	moneyCell
+	dateCell
+)
+
+// Cell ячейка листа с типизированным значением
+type Cell struct {
+	kind   cellKind
+	text   string
+	number float64
+	date   time.Time
+}
+
+// TextCell возвращает текстовую ячейку
+func TextCell(text string) Cell {
+	return Cell{kind: textCell, text: text}
+}
+
+// NumberCell возвращает числовую ячейку без форматирования, например для ID
+func NumberCell(number float64) Cell {
+	return Cell{kind: numberCell, number: number}
+}
+
+// MoneyCell возвращает числовую ячейку с форматом суммы
+func MoneyCell(amount float64) Cell {
+	return Cell{kind: moneyCell, number: amount}
+}
+
+// DateCell возвращает ячейку даты, которую Excel хранит числом дней и показывает форматом даты
+func DateCell(date time.Time) Cell {
+	return Cell{kind: dateCell, date: date}
+}
+
+// Sheet лист книги: строка заголовка и строки данных
+type Sheet struct {
+	Name    string
+	Columns []string
+	Rows    [][]Cell
+}
+
+// AddRow добавляет строку данных
+func (s *Sheet) AddRow(cells ...Cell) {
+	s.Rows = append(s.Rows, cells)
+}
+
+// Стили ячеек из xlsxStyles по порядку cellXfs
+const (
+	styleDefault = 0
+	styleDate    = 1
+	styleMoney   = 2
+	styleHeader  = 3
+)
+
+// xlsxStyles формат даты ДД.ММ.ГГГГ, формат суммы с разделителем тысяч и жирный заголовок
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="dd.mm.yyyy"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="4">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+</cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+</styleSheet>`
+
+// WriteXLSX записывает листы книгой Excel (Office Open XML). Строки хранятся прямо в ячейках,
+// без общей таблицы строк; у каждого листа закреплен заголовок и включен автофильтр.
+func WriteXLSX(w io.Writer, sheets []*Sheet) error {
+	if len(sheets) == 0 {
+		return fmt.Errorf("книга должна содержать хотя бы один лист")
+	}
+
+	archive := zip.NewWriter(w)
+	add := func(name, content string) error {
+		file, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(file, content)
+		return err
+	}
+
+	names := sheetNames(sheets)
+	var contentTypes, workbook, relations strings.Builder
+	contentTypes.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+`)
+	workbook.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets>
+`)
+	relations.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+`)
+	for n := range sheets {
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`+"\n", n+1)
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`+"\n", xmlText(names[n]), n+1, n+1)
+		fmt.Fprintf(&relations, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`+"\n", n+1, n+1)
+	}
+	contentTypes.WriteString("</Types>")
+	workbook.WriteString("</sheets>\n<definedNames>\n")
+	for n, sheet := range sheets {
+		if sheetWidth(sheet) == 0 {
+			continue
+		}
+		// Excel ожидает скрытое имя диапазона для автофильтра каждого листа
+		fmt.Fprintf(&workbook, `<definedName name="_xlnm._FilterDatabase" localSheetId="%d" hidden="1">%s</definedName>`+"\n",
+			n, xmlText(fmt.Sprintf("'%s'!$A$1:$%s$%d", strings.ReplaceAll(names[n], "'", "''"), columnName(sheetWidth(sheet)-1), len(sheet.Rows)+1)))
+	}
+	workbook.WriteString("</definedNames>\n</workbook>")
+	fmt.Fprintf(&relations, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`+"\n</Relationships>", len(sheets)+1)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypes.String()},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", relations.String()},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		if err := add(part.name, part.content); err != nil {
+			return err
+		}
+	}
+	for n, sheet := range sheets {
+		if err := add(fmt.Sprintf("xl/worksheets/sheet%d.xml", n+1), worksheetXML(sheet)); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// worksheetXML возвращает разметку листа
+func worksheetXML(sheet *Sheet) string {
+	width := sheetWidth(sheet)
+
+	var out strings.Builder
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>
+`)
+	if width > 0 {
+		out.WriteString("<cols>")
+		for column, chars := range columnWidths(sheet, width) {
+			fmt.Fprintf(&out, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, column+1, column+1, chars)
+		}
+		out.WriteString("</cols>\n")
+	}
+
+	out.WriteString("<sheetData>\n")
+	header := make([]Cell, len(sheet.Columns))
+	for n, title := range sheet.Columns {
+		header[n] = TextCell(title)
+	}
+	writeRow(&out, 1, header, styleHeader)
+	for n, row := range sheet.Rows {
+		writeRow(&out, n+2, row, styleDefault)
+	}
+	out.WriteString("</sheetData>\n")
+
+	if width > 0 {
+		fmt.Fprintf(&out, `<autoFilter ref="A1:%s%d"/>`+"\n", columnName(width-1), len(sheet.Rows)+1)
+	}
+	out.WriteString("</worksheet>")
+	return out.String()
+}
+
+// sheetWidth возвращает число колонок листа
+func sheetWidth(sheet *Sheet) int {
+	width := len(sheet.Columns)
+	for _, row := range sheet.Rows {
+		width = max(width, len(row))
+	}
+	return width
+}
+
+// writeRow записывает строку листа; style применяется к текстовым ячейкам
+func writeRow(out *strings.Builder, number int, cells []Cell, style int) {
+	fmt.Fprintf(out, `<row r="%d">`, number)
+	for column, cell := range cells {
+		ref := columnName(column) + strconv.Itoa(number)
+		switch cell.kind {
+		case numberCell:
+			fmt.Fprintf(out, `<c r="%s"><v>%s</v></c>`, ref, formatNumber(cell.number))
+		case moneyCell:
+			fmt.Fprintf(out, `<c r="%s" s="%d"><v>%s</v></c>`, ref, styleMoney, formatNumber(cell.number))
+		case dateCell:
+			fmt.Fprintf(out, `<c r="%s" s="%d"><v>%s</v></c>`, ref, styleDate, formatNumber(excelSerial(cell.date)))
+		default:
+			if cell.text == "" {
+				continue
+			}
+			fmt.Fprintf(out, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, xmlText(cell.text))
+		}
+	}
+	out.WriteString("</row>\n")
+}
+
+// columnWidths подбирает ширину колонок по самому длинному значению, от 8 до 60 символов
+func columnWidths(sheet *Sheet, width int) []int {
+	widths := make([]int, width)
+	measure := func(column int, chars int) {
+		if chars+2 > widths[column] {
+			widths[column] = chars + 2
+		}
+	}
+	for column, title := range sheet.Columns {
+		measure(column, utf8.RuneCountInString(title))
+	}
+	for _, row := range sheet.Rows {
+		for column, cell := range row {
+			switch cell.kind {
+			case textCell:
+				measure(column, utf8.RuneCountInString(cell.text))
+			case dateCell:
+				measure(column, len("31.12.2006"))
+			default:
+				measure(column, len(formatNumber(math.Round(cell.number)))+4)
+			}
+		}
+	}
+	for column := range widths {
+		widths[column] = max(8, min(widths[column], 60))
+	}
+	return widths
+}
+
+// max возвращает большее из чисел
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// excelSerial возвращает дату числом дней с 30.12.1899, как ее хранит Excel; время - дробная часть
+func excelSerial(date time.Time) float64 {
+	local := time.Date(date.Year(), date.Month(), date.Day(), date.Hour(), date.Minute(), date.Second(), 0, time.UTC)
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	return local.Sub(epoch).Hours() / 24
+}
+
+// formatNumber записывает число без экспоненты
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
+
+// columnName возвращает буквенное имя колонки по номеру с 0: A, B, ..., Z, AA
+func columnName(column int) string {
+	name := ""
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = string(rune('A'+(column-1)%26)) + name
+	}
+	return name
+}
+
+// sheetNames возвращает допустимые и уникальные названия листов: до 31 символа, без []:*?/\
+func sheetNames(sheets []*Sheet) []string {
+	cleaner := strings.NewReplacer("[", "(", "]", ")", ":", " ", "*", " ", "?", " ", "/", "-", `\`, "-")
+	used := make(map[string]bool, len(sheets))
+	names := make([]string, len(sheets))
+	for n, sheet := range sheets {
+		base := strings.TrimSpace(cleaner.Replace(sheet.Name))
+		if base == "" {
+			base = fmt.Sprintf("Лист%d", n+1)
+		}
+		name := truncateRunes(base, 31)
+		for suffix := 2; used[strings.ToLower(name)]; suffix++ {
+			tail := fmt.Sprintf(" (%d)", suffix)
+			name = truncateRunes(base, 31-len(tail)) + tail
+		}
+		used[strings.ToLower(name)] = true
+		names[n] = name
+	}
+	return names
+}
+
+// truncateRunes обрезает строку до limit символов
+func truncateRunes(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit])
+}
+
+// xmlText экранирует текст для XML, заменяя недопустимые символы
+func xmlText(text string) string {
+	var buffer bytes.Buffer
+	xml.EscapeText(&buffer, []byte(text))
+	return buffer.String()
+}
@@ -0,0 +1,157 @@
+package importexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// xlsxWorkbook разметка xl/workbook.xml, нужная тесту
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"sheets>sheet"`
+	DefinedNames []string `xml:"definedNames>definedName"`
+}
+
+// xlsxWorksheet разметка листа, нужная тесту
+type xlsxWorksheet struct {
+	Pane struct {
+		State string `xml:"state,attr"`
+	} `xml:"sheetViews>sheetView>pane"`
+	Rows []struct {
+		R     string `xml:"r,attr"`
+		Cells []struct {
+			R      string `xml:"r,attr"`
+			S      string `xml:"s,attr"`
+			T      string `xml:"t,attr"`
+			V      string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+	AutoFilter struct {
+		Ref string `xml:"ref,attr"`
+	} `xml:"autoFilter"`
+}
+
+// readXLSXParts возвращает содержимое частей книги по именам
+func readXLSXParts(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+	parts := make(map[string][]byte, len(archive.File))
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[file.Name] = content
+	}
+	return parts
+}
+
+func TestWriteXLSX(t *testing.T) {
+	operations := &Sheet{Name: "Операции: 2024/03", Columns: []string{"ID", "Дата", "Сумма", "Описание"}}
+	operations.AddRow(NumberCell(1), DateCell(time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)), MoneyCell(-1250.5), TextCell("Кофе & <чай>"))
+	operations.AddRow(NumberCell(2), DateCell(time.Date(2024, 3, 2, 12, 0, 0, 0, time.Local)), MoneyCell(100), TextCell(""))
+	duplicate := &Sheet{Name: "операции: 2024/03", Columns: []string{"Итого"}}
+
+	var out bytes.Buffer
+	if err := WriteXLSX(&out, []*Sheet{operations, duplicate}); err != nil {
+		t.Fatalf("WriteXLSX: %v", err)
+	}
+	parts := readXLSXParts(t, out.Bytes())
+
+	var names []string
+	for name := range parts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	want := "[Content_Types].xml,_rels/.rels,xl/_rels/workbook.xml.rels,xl/styles.xml,xl/workbook.xml,xl/worksheets/sheet1.xml,xl/worksheets/sheet2.xml"
+	if got := strings.Join(names, ","); got != want {
+		t.Fatalf("parts = %s, want %s", got, want)
+	}
+	for _, name := range names {
+		if err := xml.Unmarshal(parts[name], new(struct{})); err != nil {
+			t.Errorf("%s is not well-formed XML: %v", name, err)
+		}
+	}
+	for _, part := range []string{"/xl/workbook.xml", "/xl/styles.xml", "/xl/worksheets/sheet1.xml", "/xl/worksheets/sheet2.xml"} {
+		if !bytes.Contains(parts["[Content_Types].xml"], []byte(`PartName="`+part+`"`)) {
+			t.Errorf("[Content_Types].xml has no override for %s", part)
+		}
+	}
+
+	var workbook xlsxWorkbook
+	if err := xml.Unmarshal(parts["xl/workbook.xml"], &workbook); err != nil {
+		t.Fatal(err)
+	}
+	if len(workbook.Sheets) != 2 || workbook.Sheets[0].Name != "Операции  2024-03" || workbook.Sheets[1].Name != "операции  2024-03 (2)" {
+		t.Errorf("sheets = %+v, want cleaned and unique names", workbook.Sheets)
+	}
+	if len(workbook.DefinedNames) != 2 || workbook.DefinedNames[0] != "'Операции  2024-03'!$A$1:$D$3" {
+		t.Errorf("defined names = %q", workbook.DefinedNames)
+	}
+
+	var sheet xlsxWorksheet
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatal(err)
+	}
+	if sheet.Pane.State != "frozen" || sheet.AutoFilter.Ref != "A1:D3" {
+		t.Errorf("pane %q, autofilter %q; want frozen header and A1:D3", sheet.Pane.State, sheet.AutoFilter.Ref)
+	}
+	if len(sheet.Rows) != 3 {
+		t.Fatalf("rows = %d, want header and 2 data rows", len(sheet.Rows))
+	}
+
+	header := sheet.Rows[0]
+	if header.R != "1" || len(header.Cells) != 4 || header.Cells[1].Inline != "Дата" || header.Cells[1].S != "3" || header.Cells[1].T != "inlineStr" {
+		t.Errorf("header = %+v", header)
+	}
+
+	first := sheet.Rows[1].Cells
+	if len(first) != 4 {
+		t.Fatalf("first row cells = %+v", first)
+	}
+	for _, check := range []struct {
+		ref, style, kind, value string
+	}{
+		{"A2", "", "", "1"},
+		{"B2", "1", "", "45352"},
+		{"C2", "2", "", "-1250.5"},
+		{"D2", "0", "inlineStr", "Кофе & <чай>"},
+	} {
+		cell := first[strings.IndexByte("ABCD", check.ref[0])]
+		value := cell.V
+		if cell.T == "inlineStr" {
+			value = cell.Inline
+		}
+		if cell.R != check.ref || cell.S != check.style || cell.T != check.kind || value != check.value {
+			t.Errorf("cell %s = %+v, want style %q, type %q, value %q", check.ref, cell, check.style, check.kind, check.value)
+		}
+	}
+
+	// Пустая текстовая ячейка не записывается, время даты - дробная часть числа
+	second := sheet.Rows[2].Cells
+	if len(second) != 3 || second[1].V != "45353.5" {
+		t.Errorf("second row cells = %+v", second)
+	}
+}
+
+func TestWriteXLSXRequiresSheet(t *testing.T) {
+	if err := WriteXLSX(io.Discard, nil); err == nil {
+		t.Error("WriteXLSX without sheets: error = nil")
+	}
+}
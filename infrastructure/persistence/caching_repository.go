@@ -0,0 +1,459 @@
+package persistence
+
+import (
+	"KPO1/domain/interfaces"
+	"KPO1/domain/models"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Убедимся что прокси реализуют интерфейсы репозиториев
+var (
+	_ interfaces.BankAccountRepository = (*CachingBankAccountRepository)(nil)
+	_ interfaces.CategoryRepository    = (*CachingCategoryRepository)(nil)
+	_ interfaces.OperationRepository   = (*CachingOperationRepository)(nil)
+)
+
+// cachedQuery запомненный результат запроса вместе с условием, которому он соответствует
+type cachedQuery[T any] struct {
+	result  []*T
+	ids     map[int]struct{}
+	matches func(item *T) bool
+}
+
+// entityCache кэш сущностей одного типа: записи по ID и результаты запросов.
+// Счетчик поколений не дает записать в кэш данные, прочитанные до изменения.
+type entityCache[T any] struct {
+	genMu   sync.Mutex
+	gen     uint64
+	byID    *lruCache[int, *T]
+	queries *lruCache[string, *cachedQuery[T]]
+	idOf    func(item *T) int
+}
+
+// newEntityCache создает кэш сущностей с заданными размерами
+func newEntityCache[T any](capacity int, idOf func(item *T) int) *entityCache[T] {
+	queryCapacity := capacity / 8
+	if queryCapacity < 16 {
+		queryCapacity = 16
+	}
+	return &entityCache[T]{
+		byID:    newLRUCache[int, *T](capacity),
+		queries: newLRUCache[string, *cachedQuery[T]](queryCapacity),
+		idOf:    idOf,
+	}
+}
+
+// generation возвращает текущее поколение кэша
+func (c *entityCache[T]) generation() uint64 {
+	c.genMu.Lock()
+	defer c.genMu.Unlock()
+	return c.gen
+}
+
+// fill выполняет запись в кэш, только если с момента чтения не было изменений
+func (c *entityCache[T]) fill(gen uint64, store func()) {
+	c.genMu.Lock()
+	defer c.genMu.Unlock()
+	if c.gen == gen {
+		store()
+	}
+}
+
+// getByID возвращает сущность из кэша или загружает ее.
+// Кэш хранит и отдает копии, чтобы изменения у вызывающего не попадали в кэш.
+func (c *entityCache[T]) getByID(ctx context.Context, id int, load func(ctx context.Context, id int) (*T, error)) (*T, error) {
+	if item, ok := c.byID.get(id); ok {
+		return clone(item), nil
+	}
+
+	gen := c.generation()
+	item, err := load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := clone(item)
+	c.fill(gen, func() { c.byID.put(id, cached) })
+	return item, nil
+}
+
+// query возвращает запомненный результат запроса или выполняет его
+func (c *entityCache[T]) query(key string, matches func(item *T) bool, load func() ([]*T, error)) ([]*T, error) {
+	if cached, ok := c.queries.get(key); ok {
+		return cloneAll(cached.result), nil
+	}
+
+	gen := c.generation()
+	result, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &cachedQuery[T]{
+		result:  cloneAll(result),
+		ids:     make(map[int]struct{}, len(result)),
+		matches: matches,
+	}
+	for _, item := range result {
+		entry.ids[c.idOf(item)] = struct{}{}
+	}
+
+	c.fill(gen, func() { c.queries.put(key, entry) })
+	return result, nil
+}
+
+// invalidateItem сбрасывает записи, затронутые сохранением или изменением сущности:
+// саму сущность и запросы, которые ее содержали или должны теперь содержать
+func (c *entityCache[T]) invalidateItem(item *T) {
+	id := c.idOf(item)
+
+	c.genMu.Lock()
+	defer c.genMu.Unlock()
+	c.gen++
+
+	c.byID.remove(id)
+	c.queries.removeIf(func(_ string, q *cachedQuery[T]) bool {
+		_, contains := q.ids[id]
+		return contains || q.matches(item)
+	})
+}
+
+// invalidateID сбрасывает записи, содержащие удаленную сущность
+func (c *entityCache[T]) invalidateID(id int) {
+	c.genMu.Lock()
+	defer c.genMu.Unlock()
+	c.gen++
+
+	c.byID.remove(id)
+	c.queries.removeIf(func(_ string, q *cachedQuery[T]) bool {
+		_, contains := q.ids[id]
+		return contains
+	})
+}
+
+// invalidateAll полностью очищает кэш
+func (c *entityCache[T]) invalidateAll() {
+	c.genMu.Lock()
+	defer c.genMu.Unlock()
+	c.gen++
+
+	c.byID.removeIf(func(int, *T) bool { return true })
+	c.queries.removeIf(func(string, *cachedQuery[T]) bool { return true })
+}
+
+// stats возвращает суммарную статистику кэша
+func (c *entityCache[T]) stats() CacheStats {
+	return c.byID.snapshotStats().add(c.queries.snapshotStats())
+}
+
+// restored сбрасывает записи кэша, в которые должна попасть восстановленная из корзины сущность
+func restored[T any](ctx context.Context, cache *entityCache[T], id int, load func(ctx context.Context, id int) (*T, error)) error {
+	item, err := load(ctx, id)
+	if err != nil {
+		cache.invalidateAll()
+		return nil
+	}
+	cache.invalidateItem(item)
+	return nil
+}
+
+// cloneAll возвращает копии всех сущностей выборки
+func cloneAll[T any](items []*T) []*T {
+	copies := make([]*T, len(items))
+	for i, item := range items {
+		copies[i] = clone(item)
+	}
+	return copies
+}
+
+// matchAll условие запроса, которому соответствует любая сущность
+func matchAll[T any](*T) bool {
+	return true
+}
+
+// inRange проверяет попадание даты в диапазон включительно
+func inRange(date, start, end time.Time) bool {
+	return !date.Before(start) && !date.After(end)
+}
+
+// CachingBankAccountRepository прокси репозитория счетов с кэшированием
+type CachingBankAccountRepository struct {
+	repo  interfaces.BankAccountRepository
+	cache *entityCache[models.BankAccount]
+}
+
+// NewCachingBankAccountRepository создает кэширующий прокси для репозитория счетов
+func NewCachingBankAccountRepository(repo interfaces.BankAccountRepository, capacity int) *CachingBankAccountRepository {
+	return &CachingBankAccountRepository{
+		repo:  repo,
+		cache: newEntityCache(capacity, func(a *models.BankAccount) int { return a.ID }),
+	}
+}
+
+// GetByID получает банковский счет по ID
+func (p *CachingBankAccountRepository) GetByID(ctx context.Context, id int) (*models.BankAccount, error) {
+	return p.cache.getByID(ctx, id, p.repo.GetByID)
+}
+
+// GetAll получает все банковские счета
+func (p *CachingBankAccountRepository) GetAll(ctx context.Context) ([]*models.BankAccount, error) {
+	return p.cache.query("all", matchAll[models.BankAccount], func() ([]*models.BankAccount, error) { return p.repo.GetAll(ctx) })
+}
+
+// Save сохраняет банковский счет
+func (p *CachingBankAccountRepository) Save(ctx context.Context, account *models.BankAccount) error {
+	if err := p.repo.Save(ctx, account); err != nil {
+		return err
+	}
+	p.cache.invalidateItem(account)
+	return nil
+}
+
+// Update обновляет банковский счет
+func (p *CachingBankAccountRepository) Update(ctx context.Context, account *models.BankAccount) error {
+	err := p.repo.Update(ctx, account)
+	p.cache.invalidateItem(account)
+	return err
+}
+
+// Delete перемещает банковский счет в корзину
+func (p *CachingBankAccountRepository) Delete(ctx context.Context, id int) error {
+	err := p.repo.Delete(ctx, id)
+	p.cache.invalidateID(id)
+	return err
+}
+
+// GetDeleted получает банковские счета из корзины; корзина не кэшируется
+func (p *CachingBankAccountRepository) GetDeleted(ctx context.Context) ([]*models.BankAccount, error) {
+	return p.repo.GetDeleted(ctx)
+}
+
+// Restore восстанавливает банковский счет из корзины
+func (p *CachingBankAccountRepository) Restore(ctx context.Context, id int) error {
+	if err := p.repo.Restore(ctx, id); err != nil {
+		return err
+	}
+	return restored(ctx, p.cache, id, p.repo.GetByID)
+}
+
+// Purge окончательно удаляет банковские счета из корзины. Записи корзины не попадают в кэш,
+// поэтому сбрасывать его не нужно.
+func (p *CachingBankAccountRepository) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	return p.repo.Purge(ctx, deletedBefore)
+}
+
+// Stats возвращает статистику попаданий в кэш
+func (p *CachingBankAccountRepository) Stats() CacheStats {
+	return p.cache.stats()
+}
+
+// Invalidate полностью очищает кэш
+func (p *CachingBankAccountRepository) Invalidate() {
+	p.cache.invalidateAll()
+}
+
+// CachingCategoryRepository прокси репозитория категорий с кэшированием
+type CachingCategoryRepository struct {
+	repo  interfaces.CategoryRepository
+	cache *entityCache[models.Category]
+}
+
+// NewCachingCategoryRepository создает кэширующий прокси для репозитория категорий
+func NewCachingCategoryRepository(repo interfaces.CategoryRepository, capacity int) *CachingCategoryRepository {
+	return &CachingCategoryRepository{
+		repo:  repo,
+		cache: newEntityCache(capacity, func(c *models.Category) int { return c.ID }),
+	}
+}
+
+// GetByID получает категорию по ID
+func (p *CachingCategoryRepository) GetByID(ctx context.Context, id int) (*models.Category, error) {
+	return p.cache.getByID(ctx, id, p.repo.GetByID)
+}
+
+// GetAll получает все категории
+func (p *CachingCategoryRepository) GetAll(ctx context.Context) ([]*models.Category, error) {
+	return p.cache.query("all", matchAll[models.Category], func() ([]*models.Category, error) { return p.repo.GetAll(ctx) })
+}
+
+// GetByType получает категории по типу операции
+func (p *CachingCategoryRepository) GetByType(ctx context.Context, opType models.OperationType) ([]*models.Category, error) {
+	return p.cache.query(
+		fmt.Sprintf("type:%s", opType),
+		func(c *models.Category) bool { return c.Type == opType },
+		func() ([]*models.Category, error) { return p.repo.GetByType(ctx, opType) },
+	)
+}
+
+// Save сохраняет категорию
+func (p *CachingCategoryRepository) Save(ctx context.Context, category *models.Category) error {
+	if err := p.repo.Save(ctx, category); err != nil {
+		return err
+	}
+	p.cache.invalidateItem(category)
+	return nil
+}
+
+// Update обновляет категорию
+func (p *CachingCategoryRepository) Update(ctx context.Context, category *models.Category) error {
+	err := p.repo.Update(ctx, category)
+	p.cache.invalidateItem(category)
+	return err
+}
+
+// Delete перемещает категорию в корзину
+func (p *CachingCategoryRepository) Delete(ctx context.Context, id int) error {
+	err := p.repo.Delete(ctx, id)
+	p.cache.invalidateID(id)
+	return err
+}
+
+// GetDeleted получает категории из корзины; корзина не кэшируется
+func (p *CachingCategoryRepository) GetDeleted(ctx context.Context) ([]*models.Category, error) {
+	return p.repo.GetDeleted(ctx)
+}
+
+// Restore восстанавливает категорию из корзины
+func (p *CachingCategoryRepository) Restore(ctx context.Context, id int) error {
+	if err := p.repo.Restore(ctx, id); err != nil {
+		return err
+	}
+	return restored(ctx, p.cache, id, p.repo.GetByID)
+}
+
+// Purge окончательно удаляет категории из корзины. Записи корзины не попадают в кэш,
+// поэтому сбрасывать его не нужно.
+func (p *CachingCategoryRepository) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	return p.repo.Purge(ctx, deletedBefore)
+}
+
+// Stats возвращает статистику попаданий в кэш
+func (p *CachingCategoryRepository) Stats() CacheStats {
+	return p.cache.stats()
+}
+
+// Invalidate полностью очищает кэш
+func (p *CachingCategoryRepository) Invalidate() {
+	p.cache.invalidateAll()
+}
+
+// CachingOperationRepository прокси репозитория операций с кэшированием
+type CachingOperationRepository struct {
+	repo  interfaces.OperationRepository
+	cache *entityCache[models.Operation]
+}
+
+// NewCachingOperationRepository создает кэширующий прокси для репозитория операций
+func NewCachingOperationRepository(repo interfaces.OperationRepository, capacity int) *CachingOperationRepository {
+	return &CachingOperationRepository{
+		repo:  repo,
+		cache: newEntityCache(capacity, func(o *models.Operation) int { return o.ID }),
+	}
+}
+
+// GetByID получает операцию по ID
+func (p *CachingOperationRepository) GetByID(ctx context.Context, id int) (*models.Operation, error) {
+	return p.cache.getByID(ctx, id, p.repo.GetByID)
+}
+
+// GetAll получает все операции
+func (p *CachingOperationRepository) GetAll(ctx context.Context) ([]*models.Operation, error) {
+	return p.cache.query("all", matchAll[models.Operation], func() ([]*models.Operation, error) { return p.repo.GetAll(ctx) })
+}
+
+// GetByBankAccountID получает операции по ID банковского счета
+func (p *CachingOperationRepository) GetByBankAccountID(ctx context.Context, bankAccountID int) ([]*models.Operation, error) {
+	return p.cache.query(
+		fmt.Sprintf("account:%d", bankAccountID),
+		func(o *models.Operation) bool { return o.BankAccountID == bankAccountID },
+		func() ([]*models.Operation, error) { return p.repo.GetByBankAccountID(ctx, bankAccountID) },
+	)
+}
+
+// GetByCategoryID получает операции по ID категории
+func (p *CachingOperationRepository) GetByCategoryID(ctx context.Context, categoryID int) ([]*models.Operation, error) {
+	return p.cache.query(
+		fmt.Sprintf("category:%d", categoryID),
+		func(o *models.Operation) bool { return o.CategoryID == categoryID },
+		func() ([]*models.Operation, error) { return p.repo.GetByCategoryID(ctx, categoryID) },
+	)
+}
+
+// GetByDateRange получает операции в указанном диапазоне дат
+func (p *CachingOperationRepository) GetByDateRange(ctx context.Context, start, end time.Time) ([]*models.Operation, error) {
+	return p.cache.query(
+		fmt.Sprintf("range:%d:%d", start.UnixNano(), end.UnixNano()),
+		func(o *models.Operation) bool { return inRange(o.Date, start, end) },
+		func() ([]*models.Operation, error) { return p.repo.GetByDateRange(ctx, start, end) },
+	)
+}
+
+// GetByTypeAndDateRange получает операции определенного типа в указанном диапазоне дат
+func (p *CachingOperationRepository) GetByTypeAndDateRange(ctx context.Context, opType models.OperationType, start, end time.Time) ([]*models.Operation, error) {
+	return p.cache.query(
+		fmt.Sprintf("type-range:%s:%d:%d", opType, start.UnixNano(), end.UnixNano()),
+		func(o *models.Operation) bool { return o.Type == opType && inRange(o.Date, start, end) },
+		func() ([]*models.Operation, error) { return p.repo.GetByTypeAndDateRange(ctx, opType, start, end) },
+	)
+}
+
+// Find возвращает страницу операций, подходящих под запрос.
+// Страницы не кэшируются: курсоры и смещения делают повторные запросы редкими.
+func (p *CachingOperationRepository) Find(ctx context.Context, query models.OperationQuery) (*models.OperationPage, error) {
+	return p.repo.Find(ctx, query)
+}
+
+// Save сохраняет операцию
+func (p *CachingOperationRepository) Save(ctx context.Context, operation *models.Operation) error {
+	if err := p.repo.Save(ctx, operation); err != nil {
+		return err
+	}
+	p.cache.invalidateItem(operation)
+	return nil
+}
+
+// Update обновляет операцию
+func (p *CachingOperationRepository) Update(ctx context.Context, operation *models.Operation) error {
+	err := p.repo.Update(ctx, operation)
+	p.cache.invalidateItem(operation)
+	return err
+}
+
+// Delete перемещает операцию в корзину
+func (p *CachingOperationRepository) Delete(ctx context.Context, id int) error {
+	err := p.repo.Delete(ctx, id)
+	p.cache.invalidateID(id)
+	return err
+}
+
+// GetDeleted получает операции из корзины; корзина не кэшируется
+func (p *CachingOperationRepository) GetDeleted(ctx context.Context) ([]*models.Operation, error) {
+	return p.repo.GetDeleted(ctx)
+}
+
+// Restore восстанавливает операцию из корзины
+func (p *CachingOperationRepository) Restore(ctx context.Context, id int) error {
+	if err := p.repo.Restore(ctx, id); err != nil {
+		return err
+	}
+	return restored(ctx, p.cache, id, p.repo.GetByID)
+}
+
+// Purge окончательно удаляет операции из корзины. Записи корзины не попадают в кэш,
+// поэтому сбрасывать его не нужно.
+func (p *CachingOperationRepository) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	return p.repo.Purge(ctx, deletedBefore)
+}
+
+// Stats возвращает статистику попаданий в кэш
+func (p *CachingOperationRepository) Stats() CacheStats {
+	return p.cache.stats()
+}
+
+// Invalidate полностью очищает кэш
+func (p *CachingOperationRepository) Invalidate() {
+	p.cache.invalidateAll()
+}
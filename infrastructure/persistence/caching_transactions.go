@@ -0,0 +1,237 @@
+package persistence
+
+import (
+	"KPO1/domain/interfaces"
+	"KPO1/domain/models"
+	"context"
+)
+
+// changeLog сущности, измененные в транзакции
+type changeLog[T any] struct {
+	saved   []*T
+	deleted []int
+}
+
+// flush сбрасывает из кэша записи, затронутые изменениями
+func (l *changeLog[T]) flush(cache *entityCache[T]) {
+	for _, item := range l.saved {
+		cache.invalidateItem(item)
+	}
+	for _, id := range l.deleted {
+		cache.invalidateID(id)
+	}
+}
+
+// CachingTransactionManager менеджер транзакций, который после фиксации
+// сбрасывает из кэширующих прокси записи, измененные в транзакции
+type CachingTransactionManager struct {
+	txManager    interfaces.TransactionManager
+	bankAccounts *CachingBankAccountRepository
+	categories   *CachingCategoryRepository
+	operations   *CachingOperationRepository
+}
+
+// NewCachingTransactionManager создает менеджер транзакций, согласованный с кэшами репозиториев
+func NewCachingTransactionManager(
+	txManager interfaces.TransactionManager,
+	bankAccounts *CachingBankAccountRepository,
+	categories *CachingCategoryRepository,
+	operations *CachingOperationRepository,
+) *CachingTransactionManager {
+	return &CachingTransactionManager{
+		txManager:    txManager,
+		bankAccounts: bankAccounts,
+		categories:   categories,
+		operations:   operations,
+	}
+}
+
+// Begin начинает транзакцию с учетом изменяемых в ней сущностей
+func (m *CachingTransactionManager) Begin(ctx context.Context) (interfaces.Transaction, error) {
+	tx, err := m.txManager.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &cachingTransaction{Transaction: tx, manager: m}
+	t.bankAccountRepo = &trackedBankAccountRepository{BankAccountRepository: tx.BankAccounts(), log: &t.bankAccounts}
+	t.categoryRepo = &trackedCategoryRepository{CategoryRepository: tx.Categories(), log: &t.categories}
+	t.operationRepo = &trackedOperationRepository{OperationRepository: tx.Operations(), log: &t.operations}
+	return t, nil
+}
+
+// cachingTransaction транзакция, запоминающая измененные сущности
+type cachingTransaction struct {
+	interfaces.Transaction
+	manager *CachingTransactionManager
+
+	bankAccounts changeLog[models.BankAccount]
+	categories   changeLog[models.Category]
+	operations   changeLog[models.Operation]
+
+	bankAccountRepo *trackedBankAccountRepository
+	categoryRepo    *trackedCategoryRepository
+	operationRepo   *trackedOperationRepository
+}
+
+// BankAccounts возвращает репозиторий счетов в рамках транзакции
+func (t *cachingTransaction) BankAccounts() interfaces.BankAccountRepository {
+	return t.bankAccountRepo
+}
+
+// Categories возвращает репозиторий категорий в рамках транзакции
+func (t *cachingTransaction) Categories() interfaces.CategoryRepository {
+	return t.categoryRepo
+}
+
+// Operations возвращает репозиторий операций в рамках транзакции
+func (t *cachingTransaction) Operations() interfaces.OperationRepository {
+	return t.operationRepo
+}
+
+// Commit фиксирует транзакцию и сбрасывает устаревшие записи кэша
+func (t *cachingTransaction) Commit() error {
+	err := t.Transaction.Commit()
+	t.bankAccounts.flush(t.manager.bankAccounts.cache)
+	t.categories.flush(t.manager.categories.cache)
+	t.operations.flush(t.manager.operations.cache)
+	return err
+}
+
+// trackedBankAccountRepository репозиторий счетов, запоминающий изменения
+type trackedBankAccountRepository struct {
+	interfaces.BankAccountRepository
+	log *changeLog[models.BankAccount]
+}
+
+// Save сохраняет банковский счет
+func (r *trackedBankAccountRepository) Save(ctx context.Context, account *models.BankAccount) error {
+	if err := r.BankAccountRepository.Save(ctx, account); err != nil {
+		return err
+	}
+	r.log.saved = append(r.log.saved, clone(account))
+	return nil
+}
+
+// Update обновляет банковский счет
+func (r *trackedBankAccountRepository) Update(ctx context.Context, account *models.BankAccount) error {
+	if err := r.BankAccountRepository.Update(ctx, account); err != nil {
+		return err
+	}
+	r.log.saved = append(r.log.saved, clone(account))
+	return nil
+}
+
+// Delete перемещает банковский счет в корзину
+func (r *trackedBankAccountRepository) Delete(ctx context.Context, id int) error {
+	if err := r.BankAccountRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.log.deleted = append(r.log.deleted, id)
+	return nil
+}
+
+// Restore восстанавливает банковский счет из корзины
+func (r *trackedBankAccountRepository) Restore(ctx context.Context, id int) error {
+	if err := r.BankAccountRepository.Restore(ctx, id); err != nil {
+		return err
+	}
+	item, err := r.BankAccountRepository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	r.log.saved = append(r.log.saved, item)
+	return nil
+}
+
+// trackedCategoryRepository репозиторий категорий, запоминающий изменения
+type trackedCategoryRepository struct {
+	interfaces.CategoryRepository
+	log *changeLog[models.Category]
+}
+
+// Save сохраняет категорию
+func (r *trackedCategoryRepository) Save(ctx context.Context, category *models.Category) error {
+	if err := r.CategoryRepository.Save(ctx, category); err != nil {
+		return err
+	}
+	r.log.saved = append(r.log.saved, clone(category))
+	return nil
+}
+
+// Update обновляет категорию
+func (r *trackedCategoryRepository) Update(ctx context.Context, category *models.Category) error {
+	if err := r.CategoryRepository.Update(ctx, category); err != nil {
+		return err
+	}
+	r.log.saved = append(r.log.saved, clone(category))
+	return nil
+}
+
+// Delete перемещает категорию в корзину
+func (r *trackedCategoryRepository) Delete(ctx context.Context, id int) error {
+	if err := r.CategoryRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.log.deleted = append(r.log.deleted, id)
+	return nil
+}
+
+// Restore восстанавливает категорию из корзины
+func (r *trackedCategoryRepository) Restore(ctx context.Context, id int) error {
+	if err := r.CategoryRepository.Restore(ctx, id); err != nil {
+		return err
+	}
+	item, err := r.CategoryRepository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	r.log.saved = append(r.log.saved, item)
+	return nil
+}
+
+// trackedOperationRepository репозиторий операций, запоминающий изменения
+type trackedOperationRepository struct {
+	interfaces.OperationRepository
+	log *changeLog[models.Operation]
+}
+
+// Save сохраняет операцию
+func (r *trackedOperationRepository) Save(ctx context.Context, operation *models.Operation) error {
+	if err := r.OperationRepository.Save(ctx, operation); err != nil {
+		return err
+	}
+	r.log.saved = append(r.log.saved, clone(operation))
+	return nil
+}
+
+// Update обновляет операцию
+func (r *trackedOperationRepository) Update(ctx context.Context, operation *models.Operation) error {
+	if err := r.OperationRepository.Update(ctx, operation); err != nil {
+		return err
+	}
+	r.log.saved = append(r.log.saved, clone(operation))
+	return nil
+}
+
+// Delete перемещает операцию в корзину
+func (r *trackedOperationRepository) Delete(ctx context.Context, id int) error {
+	if err := r.OperationRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.log.deleted = append(r.log.deleted, id)
+	return nil
+}
+
+// Restore восстанавливает операцию из корзины
+func (r *trackedOperationRepository) Restore(ctx context.Context, id int) error {
+	if err := r.OperationRepository.Restore(ctx, id); err != nil {
+		return err
+	}
+	item, err := r.OperationRepository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	r.log.saved = append(r.log.saved, item)
+	return nil
+}
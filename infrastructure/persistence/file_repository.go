@@ -0,0 +1,343 @@
+package persistence
+
+import (
+	"KPO1/domain/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// snapshotFileName имя файла со снимком данных в директории хранилища
+const snapshotFileName = "ledger.json"
+
+// defaultCompactThreshold количество записей журнала, после которого он сворачивается в снимок
+const defaultCompactThreshold = 500
+
+// FileRepository реализация хранилища данных в файлах.
+// Данные обслуживаются из памяти, каждая транзакция дописывается
+// в журнал, а журнал периодически сворачивается в снимок.
+type FileRepository struct {
+	*MemoryRepository
+	dataDir          string
+	fileMu           sync.Mutex
+	journal          *journal
+	seq              uint64
+	compactThreshold int
+	recovery         RecoveryReport
+	// compactErr ошибка последней неудачной свертки журнала; свертка повторяется
+	// при следующей фиксации и при закрытии хранилища
+	compactErr error
+}
+
+// NewFileRepository создает файловое хранилище и восстанавливает в нем данные из директории
+func NewFileRepository(dataDir string) (*FileRepository, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию данных: %w", err)
+	}
+
+	r := &FileRepository{
+		MemoryRepository: NewMemoryRepository(),
+		dataDir:          dataDir,
+		compactThreshold: defaultCompactThreshold,
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// DataDir возвращает директорию, в которой хранятся данные
+func (r *FileRepository) DataDir() string {
+	return r.dataDir
+}
+
+// Recovery возвращает отчет о восстановлении данных из журнала при запуске
+func (r *FileRepository) Recovery() RecoveryReport {
+	return r.recovery
+}
+
+// CompactError возвращает ошибку последней свертки журнала в снимок или nil, если она удалась.
+// Неудачная свертка не отменяет зафиксированную транзакцию: изменения уже в журнале.
+func (r *FileRepository) CompactError() error {
+	r.fileMu.Lock()
+	defer r.fileMu.Unlock()
+
+	return r.compactErr
+}
+
+// SetCompactThreshold задает количество записей журнала, после которого создается снимок
+func (r *FileRepository) SetCompactThreshold(threshold int) {
+	r.fileMu.Lock()
+	defer r.fileMu.Unlock()
+
+	if threshold > 0 {
+		r.compactThreshold = threshold
+	}
+}
+
+// SaveBankAccount сохраняет банковский счет
+func (r *FileRepository) SaveBankAccount(ctx context.Context, account *models.BankAccount) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.SaveBankAccount(ctx, account) })
+}
+
+// UpdateBankAccount обновляет банковский счет
+func (r *FileRepository) UpdateBankAccount(ctx context.Context, account *models.BankAccount) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.UpdateBankAccount(ctx, account) })
+}
+
+// DeleteBankAccount перемещает банковский счет в корзину
+func (r *FileRepository) DeleteBankAccount(ctx context.Context, id int) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.DeleteBankAccount(ctx, id) })
+}
+
+// RestoreBankAccount восстанавливает банковский счет из корзины
+func (r *FileRepository) RestoreBankAccount(ctx context.Context, id int) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.RestoreBankAccount(ctx, id) })
+}
+
+// PurgeBankAccounts окончательно удаляет банковские счета, перемещенные в корзину раньше deletedBefore
+func (r *FileRepository) PurgeBankAccounts(ctx context.Context, deletedBefore time.Time) (purged int, err error) {
+	err = runInTx(ctx, r, func(tx StoreTx) error {
+		purged, err = tx.PurgeBankAccounts(ctx, deletedBefore)
+		return err
+	})
+	return purged, err
+}
+
+// SaveCategory сохраняет категорию
+func (r *FileRepository) SaveCategory(ctx context.Context, category *models.Category) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.SaveCategory(ctx, category) })
+}
+
+// UpdateCategory обновляет категорию
+func (r *FileRepository) UpdateCategory(ctx context.Context, category *models.Category) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.UpdateCategory(ctx, category) })
+}
+
+// DeleteCategory перемещает категорию в корзину
+func (r *FileRepository) DeleteCategory(ctx context.Context, id int) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.DeleteCategory(ctx, id) })
+}
+
+// RestoreCategory восстанавливает категорию из корзины
+func (r *FileRepository) RestoreCategory(ctx context.Context, id int) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.RestoreCategory(ctx, id) })
+}
+
+// PurgeCategories окончательно удаляет категории, перемещенные в корзину раньше deletedBefore
+func (r *FileRepository) PurgeCategories(ctx context.Context, deletedBefore time.Time) (purged int, err error) {
+	err = runInTx(ctx, r, func(tx StoreTx) error {
+		purged, err = tx.PurgeCategories(ctx, deletedBefore)
+		return err
+	})
+	return purged, err
+}
+
+// SaveOperation сохраняет операцию
+func (r *FileRepository) SaveOperation(ctx context.Context, operation *models.Operation) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.SaveOperation(ctx, operation) })
+}
+
+// UpdateOperation обновляет операцию
+func (r *FileRepository) UpdateOperation(ctx context.Context, operation *models.Operation) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.UpdateOperation(ctx, operation) })
+}
+
+// DeleteOperation перемещает операцию в корзину
+func (r *FileRepository) DeleteOperation(ctx context.Context, id int) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.DeleteOperation(ctx, id) })
+}
+
+// RestoreOperation восстанавливает операцию из корзины
+func (r *FileRepository) RestoreOperation(ctx context.Context, id int) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.RestoreOperation(ctx, id) })
+}
+
+// PurgeOperations окончательно удаляет операции, перемещенные в корзину раньше deletedBefore
+func (r *FileRepository) PurgeOperations(ctx context.Context, deletedBefore time.Time) (purged int, err error) {
+	err = runInTx(ctx, r, func(tx StoreTx) error {
+		purged, err = tx.PurgeOperations(ctx, deletedBefore)
+		return err
+	})
+	return purged, err
+}
+
+// BeginTx начинает транзакцию, изменения которой при фиксации
+// записываются в журнал одной записью
+func (r *FileRepository) BeginTx(ctx context.Context) (StoreTx, error) {
+	tx, err := r.MemoryRepository.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &fileTx{memoryTx: tx, repo: r}, nil
+}
+
+// fileTx транзакция файлового хранилища
+type fileTx struct {
+	*memoryTx
+	repo *FileRepository
+}
+
+// Commit записывает изменения транзакции в журнал и применяет их в памяти.
+// Если запись в журнал не удалась, транзакция откатывается. После записи в журнал
+// транзакция зафиксирована, поэтому ошибка последующей свертки в снимок не возвращается,
+// а запоминается для CompactError.
+func (tx *fileTx) Commit() error {
+	if tx.done {
+		return errTxDone
+	}
+	if tx.changes.empty() {
+		return tx.memoryTx.Commit()
+	}
+
+	r := tx.repo
+	r.fileMu.Lock()
+	defer r.fileMu.Unlock()
+
+	if r.journal == nil {
+		tx.Rollback()
+		return fmt.Errorf("хранилище закрыто")
+	}
+
+	entry := &journalEntry{Seq: r.seq + 1, Op: opCommit, Changes: tx.changes}
+	if err := r.journal.append(entry); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("ошибка записи в журнал: %w", err)
+	}
+	r.seq++
+
+	if err := tx.memoryTx.Commit(); err != nil {
+		return err
+	}
+
+	if r.journal.entries >= r.compactThreshold {
+		r.compactErr = r.compact()
+	}
+
+	return nil
+}
+
+// Compact сворачивает журнал в снимок и очищает его
+func (r *FileRepository) Compact() error {
+	r.fileMu.Lock()
+	defer r.fileMu.Unlock()
+
+	r.compactErr = r.compact()
+	return r.compactErr
+}
+
+// Close сворачивает журнал в снимок и закрывает файлы хранилища
+func (r *FileRepository) Close() error {
+	r.fileMu.Lock()
+	defer r.fileMu.Unlock()
+
+	if r.journal == nil {
+		return nil
+	}
+
+	err := r.compact()
+	r.compactErr = err
+	if closeErr := r.journal.close(); err == nil {
+		err = closeErr
+	}
+	r.journal = nil
+	return err
+}
+
+// load читает снимок из директории данных и применяет к нему журнал
+func (r *FileRepository) load() error {
+	var snap memorySnapshot
+
+	file, err := os.Open(filepath.Join(r.dataDir, snapshotFileName))
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return fmt.Errorf("ошибка открытия файла данных: %w", err)
+	default:
+		decodeErr := json.NewDecoder(file).Decode(&snap)
+		file.Close()
+		if decodeErr != nil {
+			return fmt.Errorf("ошибка чтения файла данных: %w", decodeErr)
+		}
+		r.MemoryRepository.restore(&snap)
+	}
+
+	j, lastSeq, report, err := openJournal(filepath.Join(r.dataDir, journalFileName), r.MemoryRepository, snap.JournalSequence)
+	if err != nil {
+		return err
+	}
+
+	r.journal = j
+	r.seq = lastSeq
+	r.recovery = report
+	return nil
+}
+
+// compact атомарно записывает снимок с номером последней записи журнала и очищает журнал.
+// Если сбой произойдет между этими шагами, записи журнала не старше снимка будут пропущены.
+func (r *FileRepository) compact() error {
+	snap := r.MemoryRepository.snapshot()
+	snap.JournalSequence = r.seq
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации данных: %w", err)
+	}
+
+	if err := writeFileAtomic(filepath.Join(r.dataDir, snapshotFileName), data); err != nil {
+		return fmt.Errorf("ошибка сохранения данных: %w", err)
+	}
+
+	if err := r.journal.reset(); err != nil {
+		return fmt.Errorf("ошибка очистки журнала: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic записывает файл так, что читатель видит либо старое, либо новое содержимое
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	// Синхронизируем директорию, чтобы переименование пережило сбой питания
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
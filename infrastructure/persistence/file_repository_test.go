@@ -0,0 +1,212 @@
+package persistence
+
+import (
+	"KPO1/domain/models"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openFileRepository открывает файловое хранилище в dir и закрывает его по окончании теста
+func openFileRepository(t *testing.T, dir string) *FileRepository {
+	t.Helper()
+	repo, err := NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("NewFileRepository: %v", err)
+	}
+	t.Cleanup(func() {
+		if repo.journal != nil {
+			repo.journal.close()
+			repo.journal = nil
+		}
+	})
+	return repo
+}
+
+// saveAccounts сохраняет счета с названиями names, каждый отдельной транзакцией
+func saveAccounts(t *testing.T, repo *FileRepository, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := repo.SaveBankAccount(context.Background(), &models.BankAccount{Name: name}); err != nil {
+			t.Fatalf("SaveBankAccount(%q): %v", name, err)
+		}
+	}
+}
+
+// accountNames возвращает названия счетов хранилища, упорядоченные по ID
+func accountNames(t *testing.T, repo *FileRepository) string {
+	t.Helper()
+	accounts, err := repo.GetAllBankAccounts(context.Background())
+	if err != nil {
+		t.Fatalf("GetAllBankAccounts: %v", err)
+	}
+	names := make([]string, len(accounts))
+	for i, account := range accounts {
+		names[i] = account.Name
+	}
+	return strings.Join(names, ",")
+}
+
+// readJournal возвращает содержимое журнала в dir
+func readJournal(t *testing.T, dir string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, journalFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// writeJournal заменяет содержимое журнала в dir
+func writeJournal(t *testing.T, dir string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, journalFileName), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileRepositoryReplaysJournal(t *testing.T) {
+	dir := t.TempDir()
+	saveAccounts(t, openFileRepository(t, dir), "Основной", "Карта")
+
+	repo := openFileRepository(t, dir)
+	if got := repo.Recovery(); got.ReplayedEntries != 2 || got.DiscardedBytes != 0 {
+		t.Errorf("Recovery() = %+v, want 2 replayed entries and nothing discarded", got)
+	}
+	if got := accountNames(t, repo); got != "Основной,Карта" {
+		t.Errorf("accounts = %q", got)
+	}
+}
+
+func TestFileRepositoryDiscardsTornTail(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		tail func(last string) string
+	}{
+		{"line without newline", func(last string) string { return last[:len(last)/2] }},
+		{"checksum mismatch in last line", func(last string) string { return strings.Replace(last, "Карта", "Карто", 1) }},
+		{"no checksum", func(string) string { return "{\"seq\":3}\n" }},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			saveAccounts(t, openFileRepository(t, dir), "Основной", "Карта")
+
+			lines := strings.SplitAfter(string(readJournal(t, dir)), "\n")
+			first, last := lines[0], lines[1]
+			tail := test.tail(last)
+			writeJournal(t, dir, []byte(first+tail))
+
+			repo := openFileRepository(t, dir)
+			if got := repo.Recovery(); got.ReplayedEntries != 1 || got.DiscardedBytes != int64(len(tail)) {
+				t.Errorf("Recovery() = %+v, want 1 replayed entry and %d bytes discarded", got, len(tail))
+			}
+			if got := accountNames(t, repo); got != "Основной" {
+				t.Errorf("accounts = %q, want only the first one", got)
+			}
+			if got := string(readJournal(t, dir)); got != first {
+				t.Errorf("journal was not truncated to the last whole entry: %q", got)
+			}
+
+			// Новые записи дописываются после последней целой записи
+			saveAccounts(t, repo, "Вклад")
+			if got := accountNames(t, openFileRepository(t, dir)); got != "Основной,Вклад" {
+				t.Errorf("accounts after reopen = %q", got)
+			}
+		})
+	}
+}
+
+func TestFileRepositoryRejectsCorruptedMiddle(t *testing.T) {
+	dir := t.TempDir()
+	saveAccounts(t, openFileRepository(t, dir), "Основной", "Карта")
+
+	journal := string(readJournal(t, dir))
+	writeJournal(t, dir, []byte(strings.Replace(journal, "Основной", "Основное", 1)))
+
+	if _, err := NewFileRepository(dir); err == nil || !strings.Contains(err.Error(), "строке 1") {
+		t.Fatalf("NewFileRepository() error = %v, want corruption in line 1", err)
+	}
+}
+
+func TestFileRepositorySkipsEntriesInSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	repo := openFileRepository(t, dir)
+	saveAccounts(t, repo, "Основной", "Карта")
+	journal := readJournal(t, dir)
+	if err := repo.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	saveAccounts(t, repo, "Вклад")
+
+	// Сбой между записью снимка и очисткой журнала: в журнале остались записи из снимка
+	writeJournal(t, dir, append(journal, readJournal(t, dir)...))
+
+	reopened := openFileRepository(t, dir)
+	if got := reopened.Recovery(); got.ReplayedEntries != 1 {
+		t.Errorf("Recovery() = %+v, want only the entry after the snapshot replayed", got)
+	}
+	if got := accountNames(t, reopened); got != "Основной,Карта,Вклад" {
+		t.Errorf("accounts = %q", got)
+	}
+}
+
+func TestFileRepositoryCompactsAtThreshold(t *testing.T) {
+	dir := t.TempDir()
+	repo := openFileRepository(t, dir)
+	repo.SetCompactThreshold(3)
+
+	saveAccounts(t, repo, "Основной", "Карта")
+	if _, err := os.Stat(filepath.Join(dir, snapshotFileName)); !os.IsNotExist(err) {
+		t.Fatalf("snapshot written before threshold: %v", err)
+	}
+
+	saveAccounts(t, repo, "Вклад")
+	if _, err := os.Stat(filepath.Join(dir, snapshotFileName)); err != nil {
+		t.Fatalf("snapshot not written at threshold: %v", err)
+	}
+	if got := readJournal(t, dir); len(got) != 0 {
+		t.Errorf("journal not reset after compaction: %q", got)
+	}
+
+	saveAccounts(t, repo, "Наличные")
+	reopened := openFileRepository(t, dir)
+	if got := reopened.Recovery(); got.ReplayedEntries != 1 {
+		t.Errorf("Recovery() = %+v, want 1 replayed entry", got)
+	}
+	if got := accountNames(t, reopened); got != "Основной,Карта,Вклад,Наличные" {
+		t.Errorf("accounts = %q", got)
+	}
+}
+
+func TestFileRepositoryCommitSurvivesFailedCompaction(t *testing.T) {
+	dir := t.TempDir()
+	repo := openFileRepository(t, dir)
+	repo.SetCompactThreshold(1)
+
+	// Каталог на месте снимка не дает его переименовать
+	snapshot := filepath.Join(dir, snapshotFileName)
+	if err := os.Mkdir(snapshot, 0755); err != nil {
+		t.Fatal(err)
+	}
+	saveAccounts(t, repo, "Основной")
+	if repo.CompactError() == nil {
+		t.Fatal("CompactError() = nil, want snapshot failure")
+	}
+	if got := accountNames(t, repo); got != "Основной" {
+		t.Errorf("accounts = %q, want the committed account", got)
+	}
+
+	// Следующая фиксация повторяет свертку
+	if err := os.Remove(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	saveAccounts(t, repo, "Карта")
+	if err := repo.CompactError(); err != nil {
+		t.Errorf("CompactError() = %v after retry", err)
+	}
+	if got := accountNames(t, openFileRepository(t, dir)); got != "Основной,Карта" {
+		t.Errorf("accounts after reopen = %q", got)
+	}
+}
@@ -0,0 +1,227 @@
+package persistence
+
+import (
+	"KPO1/domain/models"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strconv"
+)
+
+// journalFileName имя файла журнала изменений в директории хранилища
+const journalFileName = "journal.log"
+
+// journalOp тип изменения, записанного в журнал
+type journalOp string
+
+const (
+	opSaveBankAccount   journalOp = "save_bank_account"
+	opUpdateBankAccount journalOp = "update_bank_account"
+	opDeleteBankAccount journalOp = "delete_bank_account"
+	opSaveCategory      journalOp = "save_category"
+	opUpdateCategory    journalOp = "update_category"
+	opDeleteCategory    journalOp = "delete_category"
+	opSaveOperation     journalOp = "save_operation"
+	opUpdateOperation   journalOp = "update_operation"
+	opDeleteOperation   journalOp = "delete_operation"
+	opCommit            journalOp = "commit"
+)
+
+// journalEntry запись журнала об одном изменении хранилища или о зафиксированной транзакции.
+// Транзакция записывается одной строкой, поэтому при сбое она теряется целиком.
+type journalEntry struct {
+	Seq         uint64              `json:"seq"`
+	Op          journalOp           `json:"op"`
+	ID          int                 `json:"id,omitempty"`
+	BankAccount *models.BankAccount `json:"bank_account,omitempty"`
+	Category    *models.Category    `json:"category,omitempty"`
+	Operation   *models.Operation   `json:"operation,omitempty"`
+	Changes     *memoryChanges      `json:"changes,omitempty"`
+}
+
+// apply применяет запись журнала к репозиторию в памяти
+func (e *journalEntry) apply(repo *MemoryRepository) error {
+	changes := &memoryChanges{
+		BankAccounts: make(map[int]*models.BankAccount),
+		Categories:   make(map[int]*models.Category),
+		Operations:   make(map[int]*models.Operation),
+	}
+
+	switch e.Op {
+	case opCommit:
+		if e.Changes == nil {
+			return fmt.Errorf("запись журнала %d без изменений", e.Seq)
+		}
+		changes = e.Changes
+	case opSaveBankAccount, opUpdateBankAccount:
+		changes.BankAccounts[e.BankAccount.ID] = e.BankAccount
+	case opDeleteBankAccount:
+		changes.BankAccounts[e.ID] = nil
+	case opSaveCategory, opUpdateCategory:
+		changes.Categories[e.Category.ID] = e.Category
+	case opDeleteCategory:
+		changes.Categories[e.ID] = nil
+	case opSaveOperation, opUpdateOperation:
+		changes.Operations[e.Operation.ID] = e.Operation
+	case opDeleteOperation:
+		changes.Operations[e.ID] = nil
+	default:
+		return fmt.Errorf("неизвестная запись журнала: %s", e.Op)
+	}
+
+	repo.applyChanges(changes)
+	return nil
+}
+
+// RecoveryReport описывает восстановление данных из журнала при запуске
+type RecoveryReport struct {
+	// ReplayedEntries количество примененных записей журнала
+	ReplayedEntries int
+	// DiscardedBytes размер отброшенного недописанного хвоста журнала
+	DiscardedBytes int64
+}
+
+// journal журнал изменений, в который записи только дописываются.
+// Каждая строка имеет вид "<crc32> <json>", что позволяет
+// обнаружить запись, оборванную при сбое.
+type journal struct {
+	file    *os.File
+	entries int
+}
+
+// openJournal открывает журнал и применяет к репозиторию записи новее afterSeq.
+// Оборванная последняя запись отбрасывается и отражается в отчете,
+// повреждение в середине журнала считается ошибкой.
+func openJournal(path string, repo *MemoryRepository, afterSeq uint64) (*journal, uint64, RecoveryReport, error) {
+	var report RecoveryReport
+	lastSeq := afterSeq
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, 0, report, fmt.Errorf("ошибка открытия журнала: %w", err)
+	}
+
+	repo.beginBulkLoad()
+	defer repo.endBulkLoad()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	line := 0
+	for {
+		raw, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			file.Close()
+			return nil, 0, report, fmt.Errorf("ошибка чтения журнала: %w", readErr)
+		}
+		if len(raw) == 0 {
+			break
+		}
+		line++
+
+		entry, parseErr := parseJournalLine(raw)
+		if parseErr != nil || readErr == io.EOF {
+			// Последняя строка без перевода строки или с неверной суммой - недописанная запись
+			if _, peekErr := reader.Peek(1); peekErr == io.EOF {
+				report.DiscardedBytes = int64(len(raw))
+				break
+			}
+			file.Close()
+			return nil, 0, report, fmt.Errorf("журнал поврежден в строке %d: %v", line, parseErr)
+		}
+		offset += int64(len(raw))
+
+		if entry.Seq <= afterSeq {
+			continue
+		}
+
+		if err := entry.apply(repo); err != nil {
+			file.Close()
+			return nil, 0, report, fmt.Errorf("ошибка применения записи журнала %d: %w", entry.Seq, err)
+		}
+		lastSeq = entry.Seq
+		report.ReplayedEntries++
+	}
+
+	// Отрезаем недописанный хвост, чтобы новые записи шли после последней целой
+	if err := file.Truncate(offset); err != nil {
+		file.Close()
+		return nil, 0, report, fmt.Errorf("ошибка восстановления журнала: %w", err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, 0, report, fmt.Errorf("ошибка восстановления журнала: %w", err)
+	}
+
+	return &journal{file: file, entries: report.ReplayedEntries}, lastSeq, report, nil
+}
+
+// parseJournalLine разбирает строку журнала и проверяет ее контрольную сумму
+func parseJournalLine(raw []byte) (*journalEntry, error) {
+	raw = bytes.TrimRight(raw, "\n")
+	sep := bytes.IndexByte(raw, ' ')
+	if sep < 0 {
+		return nil, fmt.Errorf("нет контрольной суммы")
+	}
+
+	sum, err := strconv.ParseUint(string(raw[:sep]), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("неверная контрольная сумма: %w", err)
+	}
+
+	payload := raw[sep+1:]
+	if crc32.ChecksumIEEE(payload) != uint32(sum) {
+		return nil, fmt.Errorf("контрольная сумма не совпадает")
+	}
+
+	var entry journalEntry
+	if err := json.Unmarshal(payload, &entry); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// append дописывает записи в журнал одной операцией записи и сбрасывает их на диск
+func (j *journal) append(entries ...*journalEntry) error {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		payload, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&buf, "%08x ", crc32.ChecksumIEEE(payload))
+		buf.Write(payload)
+		buf.WriteByte('\n')
+	}
+
+	if _, err := j.file.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if err := j.file.Sync(); err != nil {
+		return err
+	}
+
+	j.entries += len(entries)
+	return nil
+}
+
+// reset очищает журнал после того, как его записи вошли в снимок
+func (j *journal) reset() error {
+	if err := j.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	j.entries = 0
+	return j.file.Sync()
+}
+
+// close закрывает файл журнала
+func (j *journal) close() error {
+	return j.file.Close()
+}
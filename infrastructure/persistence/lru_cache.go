@@ -0,0 +1,135 @@
+package persistence
+
+import (
+	"container/list"
+	"sync"
+)
+
+// CacheStats представляет статистику работы кэша
+type CacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	Evictions     uint64
+	Invalidations uint64
+	Entries       int
+}
+
+// HitRatio возвращает долю попаданий в кэш среди всех обращений
+func (s CacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// add складывает статистику двух кэшей
+func (s CacheStats) add(other CacheStats) CacheStats {
+	return CacheStats{
+		Hits:          s.Hits + other.Hits,
+		Misses:        s.Misses + other.Misses,
+		Evictions:     s.Evictions + other.Evictions,
+		Invalidations: s.Invalidations + other.Invalidations,
+		Entries:       s.Entries + other.Entries,
+	}
+}
+
+// lruEntry элемент списка вытеснения
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// lruCache потокобезопасный кэш ограниченного размера с вытеснением давно не использованных записей
+type lruCache[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[K]*list.Element
+	order    *list.List
+	stats    CacheStats
+}
+
+// newLRUCache создает кэш на capacity записей
+func newLRUCache[K comparable, V any](capacity int) *lruCache[K, V] {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &lruCache[K, V]{
+		capacity: capacity,
+		items:    make(map[K]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get возвращает значение по ключу и отмечает его как недавно использованное
+func (c *lruCache[K, V]) get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.order.MoveToFront(elem)
+		c.stats.Hits++
+		return elem.Value.(*lruEntry[K, V]).value, true
+	}
+
+	c.stats.Misses++
+	var zero V
+	return zero, false
+}
+
+// put добавляет значение, вытесняя самую старую запись при переполнении
+func (c *lruCache[K, V]) put(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*lruEntry[K, V]).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry[K, V]).key)
+		c.stats.Evictions++
+	}
+}
+
+// remove удаляет запись по ключу
+func (c *lruCache[K, V]) remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.order.Remove(elem)
+		delete(c.items, key)
+		c.stats.Invalidations++
+	}
+}
+
+// removeIf удаляет все записи, для которых условие истинно
+func (c *lruCache[K, V]) removeIf(cond func(key K, value V) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, elem := range c.items {
+		if cond(key, elem.Value.(*lruEntry[K, V]).value) {
+			c.order.Remove(elem)
+			delete(c.items, key)
+			c.stats.Invalidations++
+		}
+	}
+}
+
+// snapshotStats возвращает текущую статистику кэша
+func (c *lruCache[K, V]) snapshotStats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = len(c.items)
+	return stats
+}
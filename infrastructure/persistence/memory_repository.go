@@ -2,17 +2,23 @@ package persistence
 
 import (
 	"KPO1/domain/models"
-	"errors"
+	"context"
+	"sort"
 	"sync"
 	"time"
 )
 
-// MemoryRepository реализация хранилища данных в памяти
+// MemoryRepository реализация хранилища данных в памяти.
+// Хранилище отдает и сохраняет копии сущностей, поэтому изменение
+// полученного объекта не затрагивает данные до вызова Update.
+// Удаленные записи остаются в картах с отметкой DeletedAt до очистки корзины.
 type MemoryRepository struct {
 	bankAccounts    map[int]*models.BankAccount
 	categories      map[int]*models.Category
 	operations      map[int]*models.Operation
+	opIndex         *operationIndex
 	mu              sync.RWMutex
+	txLock          chan struct{}
 	nextBankAccID   int
 	nextCategoryID  int
 	nextOperationID int
@@ -24,279 +30,560 @@ func NewMemoryRepository() *MemoryRepository {
 		bankAccounts:    make(map[int]*models.BankAccount),
 		categories:      make(map[int]*models.Category),
 		operations:      make(map[int]*models.Operation),
+		opIndex:         newOperationIndex(),
+		txLock:          make(chan struct{}, 1),
 		nextBankAccID:   1,
 		nextCategoryID:  1,
 		nextOperationID: 1,
 	}
 }
 
-// GetBankAccountByID возвращает банковский счет по его ID
-func (r *MemoryRepository) GetBankAccountByID(id int) (*models.BankAccount, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+// BeginTx начинает транзакцию. Транзакции выполняются по одной,
+// их изменения становятся видны другим только после фиксации.
+func (r *MemoryRepository) BeginTx(ctx context.Context) (StoreTx, error) {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
 
-	account, exists := r.bankAccounts[id]
-	if !exists {
-		return nil, errors.New("банковский счет не найден")
+// NextID выдает следующий свободный ID сущности. Выданный ID больше не выдается,
+// даже если запись с ним так и не была сохранена.
+func (r *MemoryRepository) NextID(ctx context.Context, entity models.EntityKind) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
 	}
-	return account, nil
+	return r.reserveID(entity, 0)
 }
 
-// GetAllBankAccounts возвращает все банковские счета
-func (r *MemoryRepository) GetAllBankAccounts() ([]*models.BankAccount, error) {
+// nextIDs возвращает следующие свободные ID для счетов, категорий и операций
+func (r *MemoryRepository) nextIDs() (bankAccountID, categoryID, operationID int) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	accounts := make([]*models.BankAccount, 0, len(r.bankAccounts))
-	for _, account := range r.bankAccounts {
-		accounts = append(accounts, account)
-	}
-	return accounts, nil
+	return r.nextBankAccID, r.nextCategoryID, r.nextOperationID
 }
 
-// SaveBankAccount сохраняет банковский счет
-func (r *MemoryRepository) SaveBankAccount(account *models.BankAccount) error {
+// reserveID выдает ID из счетчика сущности, если он не задан, и сдвигает счетчик за него
+func (r *MemoryRepository) reserveID(entity models.EntityKind, id int) (int, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if account.ID == 0 {
-		account.ID = r.nextBankAccID
-		r.nextBankAccID++
-	} else if account.ID >= r.nextBankAccID {
-		r.nextBankAccID = account.ID + 1
+	next, err := idCounter(entity, &r.nextBankAccID, &r.nextCategoryID, &r.nextOperationID)
+	if err != nil {
+		return 0, err
 	}
-
-	r.bankAccounts[account.ID] = account
-	return nil
+	return allocateID(id, next), nil
 }
 
-// UpdateBankAccount обновляет банковский счет
-func (r *MemoryRepository) UpdateBankAccount(account *models.BankAccount) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// GetBankAccountByID возвращает банковский счет по его ID
+func (r *MemoryRepository) GetBankAccountByID(ctx context.Context, id int) (*models.BankAccount, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.mu.RUnlock()
 
-	if _, exists := r.bankAccounts[account.ID]; !exists {
-		return errors.New("банковский счет не найден")
+	account, exists := r.bankAccounts[id]
+	if !exists || account.IsDeleted() {
+		return nil, models.NewNotFoundError(models.EntityBankAccount, id)
 	}
+	return clone(account), nil
+}
 
-	r.bankAccounts[account.ID] = account
-	return nil
+// GetAllBankAccounts возвращает все банковские счета в порядке возрастания ID
+func (r *MemoryRepository) GetAllBankAccounts(ctx context.Context) ([]*models.BankAccount, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.mu.RUnlock()
+
+	return selectItems(ctx, r.bankAccounts, live(matchAll[models.BankAccount]), byBankAccountID)
 }
 
-// DeleteBankAccount удаляет банковский счет
-func (r *MemoryRepository) DeleteBankAccount(id int) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// SaveBankAccount сохраняет банковский счет
+func (r *MemoryRepository) SaveBankAccount(ctx context.Context, account *models.BankAccount) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.SaveBankAccount(ctx, account) })
+}
+
+// UpdateBankAccount обновляет банковский счет
+func (r *MemoryRepository) UpdateBankAccount(ctx context.Context, account *models.BankAccount) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.UpdateBankAccount(ctx, account) })
+}
 
-	if _, exists := r.bankAccounts[id]; !exists {
-		return errors.New("банковский счет не найден")
+// DeleteBankAccount перемещает банковский счет в корзину
+func (r *MemoryRepository) DeleteBankAccount(ctx context.Context, id int) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.DeleteBankAccount(ctx, id) })
+}
+
+// GetDeletedBankAccounts возвращает банковские счета из корзины
+func (r *MemoryRepository) GetDeletedBankAccounts(ctx context.Context) ([]*models.BankAccount, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
 	}
+	defer r.mu.RUnlock()
 
-	delete(r.bankAccounts, id)
-	return nil
+	return selectItems(ctx, r.bankAccounts, deleted[models.BankAccount], byBankAccountID)
+}
+
+// RestoreBankAccount восстанавливает банковский счет из корзины
+func (r *MemoryRepository) RestoreBankAccount(ctx context.Context, id int) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.RestoreBankAccount(ctx, id) })
+}
+
+// PurgeBankAccounts окончательно удаляет банковские счета, перемещенные в корзину раньше deletedBefore
+func (r *MemoryRepository) PurgeBankAccounts(ctx context.Context, deletedBefore time.Time) (purged int, err error) {
+	err = runInTx(ctx, r, func(tx StoreTx) error {
+		purged, err = tx.PurgeBankAccounts(ctx, deletedBefore)
+		return err
+	})
+	return purged, err
 }
 
 // GetCategoryByID возвращает категорию по её ID
-func (r *MemoryRepository) GetCategoryByID(id int) (*models.Category, error) {
-	r.mu.RLock()
+func (r *MemoryRepository) GetCategoryByID(ctx context.Context, id int) (*models.Category, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
 	defer r.mu.RUnlock()
 
 	category, exists := r.categories[id]
-	if !exists {
-		return nil, errors.New("категория не найдена")
+	if !exists || category.IsDeleted() {
+		return nil, models.NewNotFoundError(models.EntityCategory, id)
 	}
-	return category, nil
+	return clone(category), nil
 }
 
-// GetAllCategories возвращает все категории
-func (r *MemoryRepository) GetAllCategories() ([]*models.Category, error) {
-	r.mu.RLock()
+// GetAllCategories возвращает все категории в порядке возрастания ID
+func (r *MemoryRepository) GetAllCategories(ctx context.Context) ([]*models.Category, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
 	defer r.mu.RUnlock()
 
-	categories := make([]*models.Category, 0, len(r.categories))
-	for _, category := range r.categories {
-		categories = append(categories, category)
-	}
-	return categories, nil
+	return selectItems(ctx, r.categories, live(matchAll[models.Category]), byCategoryID)
 }
 
 // GetCategoriesByType возвращает категории определенного типа
-func (r *MemoryRepository) GetCategoriesByType(opType models.OperationType) ([]*models.Category, error) {
-	r.mu.RLock()
+func (r *MemoryRepository) GetCategoriesByType(ctx context.Context, opType models.OperationType) ([]*models.Category, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
 	defer r.mu.RUnlock()
 
-	categories := make([]*models.Category, 0)
-	for _, category := range r.categories {
-		if category.Type == opType {
-			categories = append(categories, category)
-		}
-	}
-	return categories, nil
+	match := func(category *models.Category) bool { return category.Type == opType }
+	return selectItems(ctx, r.categories, live(match), byCategoryID)
 }
 
 // SaveCategory сохраняет категорию
-func (r *MemoryRepository) SaveCategory(category *models.Category) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if category.ID == 0 {
-		category.ID = r.nextCategoryID
-		r.nextCategoryID++
-	} else if category.ID >= r.nextCategoryID {
-		r.nextCategoryID = category.ID + 1
-	}
-
-	r.categories[category.ID] = category
-	return nil
+func (r *MemoryRepository) SaveCategory(ctx context.Context, category *models.Category) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.SaveCategory(ctx, category) })
 }
 
 // UpdateCategory обновляет категорию
-func (r *MemoryRepository) UpdateCategory(category *models.Category) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+func (r *MemoryRepository) UpdateCategory(ctx context.Context, category *models.Category) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.UpdateCategory(ctx, category) })
+}
+
+// DeleteCategory перемещает категорию в корзину
+func (r *MemoryRepository) DeleteCategory(ctx context.Context, id int) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.DeleteCategory(ctx, id) })
+}
 
-	if _, exists := r.categories[category.ID]; !exists {
-		return errors.New("категория не найдена")
+// GetDeletedCategories возвращает категории из корзины
+func (r *MemoryRepository) GetDeletedCategories(ctx context.Context) ([]*models.Category, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
 	}
+	defer r.mu.RUnlock()
 
-	r.categories[category.ID] = category
-	return nil
+	return selectItems(ctx, r.categories, deleted[models.Category], byCategoryID)
 }
 
-// DeleteCategory удаляет категорию
-func (r *MemoryRepository) DeleteCategory(id int) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if _, exists := r.categories[id]; !exists {
-		return errors.New("категория не найдена")
-	}
+// RestoreCategory восстанавливает категорию из корзины
+func (r *MemoryRepository) RestoreCategory(ctx context.Context, id int) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.RestoreCategory(ctx, id) })
+}
 
-	delete(r.categories, id)
-	return nil
+// PurgeCategories окончательно удаляет категории, перемещенные в корзину раньше deletedBefore
+func (r *MemoryRepository) PurgeCategories(ctx context.Context, deletedBefore time.Time) (purged int, err error) {
+	err = runInTx(ctx, r, func(tx StoreTx) error {
+		purged, err = tx.PurgeCategories(ctx, deletedBefore)
+		return err
+	})
+	return purged, err
 }
 
 // GetOperationByID возвращает операцию по её ID
-func (r *MemoryRepository) GetOperationByID(id int) (*models.Operation, error) {
-	r.mu.RLock()
+func (r *MemoryRepository) GetOperationByID(ctx context.Context, id int) (*models.Operation, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
 	defer r.mu.RUnlock()
 
 	operation, exists := r.operations[id]
-	if !exists {
-		return nil, errors.New("операция не найдена")
+	if !exists || operation.IsDeleted() {
+		return nil, models.NewNotFoundError(models.EntityOperation, id)
 	}
-	return operation, nil
+	return clone(operation), nil
 }
 
-// GetAllOperations возвращает все операции
-func (r *MemoryRepository) GetAllOperations() ([]*models.Operation, error) {
-	r.mu.RLock()
+// GetAllOperations возвращает все операции в порядке возрастания ID
+func (r *MemoryRepository) GetAllOperations(ctx context.Context) ([]*models.Operation, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
 	defer r.mu.RUnlock()
 
-	operations := make([]*models.Operation, 0, len(r.operations))
-	for _, operation := range r.operations {
-		operations = append(operations, operation)
-	}
-	return operations, nil
+	return selectItems(ctx, r.operations, live(matchAll[models.Operation]), byOperationID)
 }
 
 // GetOperationsByBankAccountID возвращает операции по ID банковского счета
-func (r *MemoryRepository) GetOperationsByBankAccountID(bankAccountID int) ([]*models.Operation, error) {
-	r.mu.RLock()
+func (r *MemoryRepository) GetOperationsByBankAccountID(ctx context.Context, bankAccountID int) ([]*models.Operation, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
 	defer r.mu.RUnlock()
 
-	operations := make([]*models.Operation, 0)
-	for _, operation := range r.operations {
-		if operation.BankAccountID == bankAccountID {
-			operations = append(operations, operation)
-		}
-	}
-	return operations, nil
+	return r.operationsByIDs(ctx, r.opIndex.byAccount[bankAccountID])
 }
 
 // GetOperationsByCategoryID возвращает операции по ID категории
-func (r *MemoryRepository) GetOperationsByCategoryID(categoryID int) ([]*models.Operation, error) {
-	r.mu.RLock()
+func (r *MemoryRepository) GetOperationsByCategoryID(ctx context.Context, categoryID int) ([]*models.Operation, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
 	defer r.mu.RUnlock()
 
-	operations := make([]*models.Operation, 0)
-	for _, operation := range r.operations {
-		if operation.CategoryID == categoryID {
-			operations = append(operations, operation)
-		}
-	}
-	return operations, nil
+	return r.operationsByIDs(ctx, r.opIndex.byCategory[categoryID])
 }
 
 // GetOperationsByDateRange возвращает операции в указанном диапазоне дат
-func (r *MemoryRepository) GetOperationsByDateRange(start, end time.Time) ([]*models.Operation, error) {
-	r.mu.RLock()
+func (r *MemoryRepository) GetOperationsByDateRange(ctx context.Context, start, end time.Time) ([]*models.Operation, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
 	defer r.mu.RUnlock()
 
 	operations := make([]*models.Operation, 0)
-	for _, operation := range r.operations {
-		if (operation.Date.Equal(start) || operation.Date.After(start)) &&
-			(operation.Date.Equal(end) || operation.Date.Before(end)) {
-			operations = append(operations, operation)
-		}
-	}
-	return operations, nil
+	err := r.opIndex.scanDateRange(ctx, start, end, func(id int) {
+		operations = append(operations, clone(r.operations[id]))
+	})
+	return operations, err
 }
 
 // GetOperationsByTypeAndDateRange возвращает операции определенного типа в указанном диапазоне дат
-func (r *MemoryRepository) GetOperationsByTypeAndDateRange(opType models.OperationType, start, end time.Time) ([]*models.Operation, error) {
-	r.mu.RLock()
+func (r *MemoryRepository) GetOperationsByTypeAndDateRange(ctx context.Context, opType models.OperationType, start, end time.Time) ([]*models.Operation, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
 	defer r.mu.RUnlock()
 
 	operations := make([]*models.Operation, 0)
-	for _, operation := range r.operations {
-		if operation.Type == opType &&
-			(operation.Date.Equal(start) || operation.Date.After(start)) &&
-			(operation.Date.Equal(end) || operation.Date.Before(end)) {
-			operations = append(operations, operation)
+	err := r.opIndex.scanDateRange(ctx, start, end, func(id int) {
+		if operation := r.operations[id]; operation.Type == opType {
+			operations = append(operations, clone(operation))
+		}
+	})
+	return operations, err
+}
+
+// FindOperations возвращает страницу операций, подходящих под запрос.
+// Кандидаты берутся из самого узкого индекса, подходящего к фильтру.
+func (r *MemoryRepository) FindOperations(ctx context.Context, query models.OperationQuery) (*models.OperationPage, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.mu.RUnlock()
+
+	candidates, err := r.operationCandidates(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	page, err := pageOperations(ctx, query, candidates)
+	if err != nil {
+		return nil, err
+	}
+	page.Operations = cloneAll(page.Operations)
+	return page, nil
+}
+
+// operationCandidates возвращает операции, среди которых находятся все подходящие под запрос
+func (r *MemoryRepository) operationCandidates(ctx context.Context, query models.OperationQuery) ([]*models.Operation, error) {
+	var ids map[int]struct{}
+	switch {
+	case query.BankAccountID != 0:
+		ids = r.opIndex.byAccount[query.BankAccountID]
+	case query.CategoryID != 0:
+		ids = r.opIndex.byCategory[query.CategoryID]
+	case !query.From.IsZero() || !query.To.IsZero():
+		end := query.To
+		if end.IsZero() {
+			end = maxTime
+		}
+		candidates := make([]*models.Operation, 0)
+		err := r.opIndex.scanDateRange(ctx, query.From, end, func(id int) {
+			candidates = append(candidates, r.operations[id])
+		})
+		return candidates, err
+	default:
+		ids = r.opIndex.all()
+	}
+
+	candidates := make([]*models.Operation, 0, len(ids))
+	i := 0
+	for id := range ids {
+		if err := checkCanceled(ctx, i); err != nil {
+			return nil, err
 		}
+		candidates = append(candidates, r.operations[id])
+		i++
 	}
+	return candidates, nil
+}
+
+// operationsByIDs возвращает копии операций из набора ID в порядке возрастания ID
+func (r *MemoryRepository) operationsByIDs(ctx context.Context, ids map[int]struct{}) ([]*models.Operation, error) {
+	operations := make([]*models.Operation, 0, len(ids))
+	for id := range ids {
+		if err := checkCanceled(ctx, len(operations)); err != nil {
+			return nil, err
+		}
+		operations = append(operations, clone(r.operations[id]))
+	}
+	sort.Slice(operations, func(i, j int) bool { return operations[i].ID < operations[j].ID })
 	return operations, nil
 }
 
 // SaveOperation сохраняет операцию
-func (r *MemoryRepository) SaveOperation(operation *models.Operation) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+func (r *MemoryRepository) SaveOperation(ctx context.Context, operation *models.Operation) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.SaveOperation(ctx, operation) })
+}
 
-	if operation.ID == 0 {
-		operation.ID = r.nextOperationID
-		r.nextOperationID++
-	} else if operation.ID >= r.nextOperationID {
-		r.nextOperationID = operation.ID + 1
+// UpdateOperation обновляет операцию
+func (r *MemoryRepository) UpdateOperation(ctx context.Context, operation *models.Operation) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.UpdateOperation(ctx, operation) })
+}
+
+// DeleteOperation перемещает операцию в корзину
+func (r *MemoryRepository) DeleteOperation(ctx context.Context, id int) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.DeleteOperation(ctx, id) })
+}
+
+// GetDeletedOperations возвращает операции из корзины
+func (r *MemoryRepository) GetDeletedOperations(ctx context.Context) ([]*models.Operation, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
 	}
+	defer r.mu.RUnlock()
+
+	return selectItems(ctx, r.operations, deleted[models.Operation], byOperationID)
+}
+
+// RestoreOperation восстанавливает операцию из корзины
+func (r *MemoryRepository) RestoreOperation(ctx context.Context, id int) error {
+	return runInTx(ctx, r, func(tx StoreTx) error { return tx.RestoreOperation(ctx, id) })
+}
 
+// PurgeOperations окончательно удаляет операции, перемещенные в корзину раньше deletedBefore
+func (r *MemoryRepository) PurgeOperations(ctx context.Context, deletedBefore time.Time) (purged int, err error) {
+	err = runInTx(ctx, r, func(tx StoreTx) error {
+		purged, err = tx.PurgeOperations(ctx, deletedBefore)
+		return err
+	})
+	return purged, err
+}
+
+// putBankAccount записывает счет в хранилище; вызывается под блокировкой mu
+func (r *MemoryRepository) putBankAccount(account *models.BankAccount) {
+	r.bankAccounts[account.ID] = account
+	if account.ID >= r.nextBankAccID {
+		r.nextBankAccID = account.ID + 1
+	}
+}
+
+// putCategory записывает категорию в хранилище; вызывается под блокировкой mu
+func (r *MemoryRepository) putCategory(category *models.Category) {
+	r.categories[category.ID] = category
+	if category.ID >= r.nextCategoryID {
+		r.nextCategoryID = category.ID + 1
+	}
+}
+
+// putOperation записывает операцию в хранилище и индексы; вызывается под блокировкой mu.
+// Операции в корзине в индексы не попадают.
+func (r *MemoryRepository) putOperation(operation *models.Operation) {
 	r.operations[operation.ID] = operation
-	return nil
+	if operation.IsDeleted() {
+		r.opIndex.remove(operation.ID)
+	} else {
+		r.opIndex.add(operation)
+	}
+	if operation.ID >= r.nextOperationID {
+		r.nextOperationID = operation.ID + 1
+	}
 }
 
-// UpdateOperation обновляет операцию
-func (r *MemoryRepository) UpdateOperation(operation *models.Operation) error {
+// beginBulkLoad начинает массовую загрузку операций, например повтор журнала при запуске:
+// индекс по дате строится один раз в endBulkLoad, а не вставкой каждой операции
+func (r *MemoryRepository) beginBulkLoad() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.opIndex.beginBulk()
+}
+
+// endBulkLoad завершает массовую загрузку операций
+func (r *MemoryRepository) endBulkLoad() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.opIndex.finishBulk()
+}
+
+// removeOperation удаляет операцию из хранилища и индексов; вызывается под блокировкой mu
+func (r *MemoryRepository) removeOperation(id int) {
+	delete(r.operations, id)
+	r.opIndex.remove(id)
+}
 
-	if _, exists := r.operations[operation.ID]; !exists {
-		return errors.New("операция не найдена")
+// memorySnapshot представляет сериализуемое состояние репозитория в памяти
+type memorySnapshot struct {
+	NextBankAccountID int                   `json:"next_bank_account_id"`
+	NextCategoryID    int                   `json:"next_category_id"`
+	NextOperationID   int                   `json:"next_operation_id"`
+	BankAccounts      []*models.BankAccount `json:"bank_accounts"`
+	Categories        []*models.Category    `json:"categories"`
+	Operations        []*models.Operation   `json:"operations"`
+	JournalSequence   uint64                `json:"journal_sequence,omitempty"`
+}
+
+// snapshot возвращает текущее состояние репозитория, упорядоченное по ID
+func (r *MemoryRepository) snapshot() *memorySnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snap := &memorySnapshot{
+		NextBankAccountID: r.nextBankAccID,
+		NextCategoryID:    r.nextCategoryID,
+		NextOperationID:   r.nextOperationID,
+		BankAccounts:      make([]*models.BankAccount, 0, len(r.bankAccounts)),
+		Categories:        make([]*models.Category, 0, len(r.categories)),
+		Operations:        make([]*models.Operation, 0, len(r.operations)),
 	}
 
-	r.operations[operation.ID] = operation
-	return nil
+	for _, account := range r.bankAccounts {
+		snap.BankAccounts = append(snap.BankAccounts, account)
+	}
+	for _, category := range r.categories {
+		snap.Categories = append(snap.Categories, category)
+	}
+	for _, operation := range r.operations {
+		snap.Operations = append(snap.Operations, operation)
+	}
+
+	sort.Slice(snap.BankAccounts, func(i, j int) bool { return snap.BankAccounts[i].ID < snap.BankAccounts[j].ID })
+	sort.Slice(snap.Categories, func(i, j int) bool { return snap.Categories[i].ID < snap.Categories[j].ID })
+	sort.Slice(snap.Operations, func(i, j int) bool { return snap.Operations[i].ID < snap.Operations[j].ID })
+
+	return snap
 }
 
-// DeleteOperation удаляет операцию
-func (r *MemoryRepository) DeleteOperation(id int) error {
+// restore заменяет состояние репозитория содержимым снимка
+func (r *MemoryRepository) restore(snap *memorySnapshot) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.operations[id]; !exists {
-		return errors.New("операция не найдена")
+	r.bankAccounts = make(map[int]*models.BankAccount, len(snap.BankAccounts))
+	r.categories = make(map[int]*models.Category, len(snap.Categories))
+	r.operations = make(map[int]*models.Operation, len(snap.Operations))
+	r.opIndex = newOperationIndex()
+	r.nextBankAccID = 1
+	r.nextCategoryID = 1
+	r.nextOperationID = 1
+
+	for _, account := range snap.BankAccounts {
+		r.putBankAccount(account)
+	}
+	for _, category := range snap.Categories {
+		r.putCategory(category)
+	}
+	for _, operation := range snap.Operations {
+		r.operations[operation.ID] = operation
+		if operation.ID >= r.nextOperationID {
+			r.nextOperationID = operation.ID + 1
+		}
 	}
+	live := make([]*models.Operation, 0, len(snap.Operations))
+	for _, operation := range snap.Operations {
+		if !operation.IsDeleted() {
+			live = append(live, operation)
+		}
+	}
+	r.opIndex.rebuild(live)
 
-	delete(r.operations, id)
+	// Счетчики из снимка могут опережать максимальный ID, если последние записи были удалены
+	if snap.NextBankAccountID > r.nextBankAccID {
+		r.nextBankAccID = snap.NextBankAccountID
+	}
+	if snap.NextCategoryID > r.nextCategoryID {
+		r.nextCategoryID = snap.NextCategoryID
+	}
+	if snap.NextOperationID > r.nextOperationID {
+		r.nextOperationID = snap.NextOperationID
+	}
+}
+
+// softDeletable сущность, которую можно переместить в корзину
+type softDeletable interface {
+	IsDeleted() bool
+}
+
+// deleted сообщает, что запись находится в корзине
+func deleted[T any](item *T) bool {
+	entity, ok := any(item).(softDeletable)
+	return ok && entity.IsDeleted()
+}
+
+// selectItems возвращает копии записей, удовлетворяющих условию, в заданном порядке.
+// Во время обхода периодически проверяет отмену контекста.
+func selectItems[T any](ctx context.Context, items map[int]*T, match func(*T) bool, less func(a, b *T) bool) ([]*T, error) {
+	result := make([]*T, 0)
+	i := 0
+	for _, item := range items {
+		if err := checkCanceled(ctx, i); err != nil {
+			return nil, err
+		}
+		if match(item) {
+			result = append(result, clone(item))
+		}
+		i++
+	}
+	sort.Slice(result, func(i, j int) bool { return less(result[i], result[j]) })
+	return result, nil
+}
+
+// ctxCheckInterval через сколько записей длинные циклы проверяют отмену контекста
+const ctxCheckInterval = 256
+
+// checkCanceled возвращает ошибку контекста на каждой ctxCheckInterval-й итерации цикла,
+// если контекст отменен; на остальных итерациях проверка не выполняется
+func checkCanceled(ctx context.Context, iteration int) error {
+	if iteration%ctxCheckInterval != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// rlock захватывает хранилище для чтения, если контекст еще не отменен
+func (r *MemoryRepository) rlock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.RLock()
 	return nil
 }
+
+// clone возвращает копию сущности
+func clone[T any](item *T) *T {
+	copied := *item
+	return &copied
+}
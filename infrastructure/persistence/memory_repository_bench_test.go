@@ -0,0 +1,115 @@
+package persistence
+
+import (
+	"KPO1/application/analytics"
+	"KPO1/domain/interfaces"
+	"KPO1/domain/models"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// benchOperations число операций в хранилище для бенчмарков
+const benchOperations = 1_000_000
+
+var (
+	benchSnapshotOnce sync.Once
+	benchSnapshot     *memorySnapshot
+)
+
+// seedSnapshot возвращает снимок с benchOperations операциями, равномерно распределенными
+// по 2015-2024 годам, двум счетам и четырем категориям
+func seedSnapshot() *memorySnapshot {
+	benchSnapshotOnce.Do(func() {
+		start := time.Date(2015, 1, 1, 0, 0, 0, 0, time.Local)
+		span := time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local).Sub(start)
+		step := span / benchOperations
+
+		snap := &memorySnapshot{
+			BankAccounts: []*models.BankAccount{{ID: 1, Name: "Основной"}, {ID: 2, Name: "Карта"}},
+			Categories: []*models.Category{
+				{ID: 1, Type: models.Income, Name: "Зарплата"},
+				{ID: 2, Type: models.Income, Name: "Кэшбэк"},
+				{ID: 3, Type: models.Expense, Name: "Кафе"},
+				{ID: 4, Type: models.Expense, Name: "Здоровье"},
+			},
+			Operations: make([]*models.Operation, 0, benchOperations),
+		}
+		for i := 0; i < benchOperations; i++ {
+			category := snap.Categories[i%len(snap.Categories)]
+			snap.Operations = append(snap.Operations, &models.Operation{
+				ID:            i + 1,
+				Type:          category.Type,
+				BankAccountID: 1 + i%2,
+				CategoryID:    category.ID,
+				Amount:        float64(100 + i%900),
+				// Даты перемешаны относительно ID, как после импорта нескольких выписок
+				Date: start.Add(time.Duration((i*7919)%benchOperations) * step),
+			})
+		}
+		benchSnapshot = snap
+	})
+	return benchSnapshot
+}
+
+// scanOperationRepository возвращает операции периода полным перебором хранилища,
+// как MemoryRepository до появления индекса по дате
+type scanOperationRepository struct {
+	interfaces.OperationRepository
+	repo *MemoryRepository
+}
+
+// GetByDateRange перебирает все операции хранилища
+func (s scanOperationRepository) GetByDateRange(ctx context.Context, start, end time.Time) ([]*models.Operation, error) {
+	if err := s.repo.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.repo.mu.RUnlock()
+
+	operations := make([]*models.Operation, 0)
+	for _, operation := range s.repo.operations {
+		if !operation.IsDeleted() && !operation.Date.Before(start) && !operation.Date.After(end) {
+			operations = append(operations, clone(operation))
+		}
+	}
+	return operations, nil
+}
+
+// BenchmarkGetMonthlyDynamics сравнивает месячную динамику за год (десятая часть операций)
+// по индексу дат за O(log n + k) и полным перебором за O(n):
+//
+//	go test -run '^$' -bench GetMonthlyDynamics -benchmem ./infrastructure/persistence
+func BenchmarkGetMonthlyDynamics(b *testing.B) {
+	repo := NewMemoryRepository()
+	repo.restore(seedSnapshot())
+	ctx := context.Background()
+
+	for _, bench := range []struct {
+		name       string
+		operations interfaces.OperationRepository
+	}{
+		{"index", NewOperationRepository(repo)},
+		{"scan", scanOperationRepository{OperationRepository: NewOperationRepository(repo), repo: repo}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			service := analytics.NewAnalyticsService(bench.operations, NewCategoryRepository(repo))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := service.GetMonthlyDynamics(ctx, 2020); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRestoreOperations измеряет загрузку снимка с benchOperations операциями:
+// индекс по дате строится одной сортировкой, а не вставкой каждой операции
+func BenchmarkRestoreOperations(b *testing.B) {
+	snap := seedSnapshot()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewMemoryRepository().restore(snap)
+	}
+}
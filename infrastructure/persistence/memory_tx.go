@@ -0,0 +1,655 @@
+package persistence
+
+import (
+	"KPO1/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// errTxDone возвращается при обращении к завершенной транзакции
+var errTxDone = errors.New("транзакция уже завершена")
+
+// memoryChanges набор изменений транзакции. Значение nil означает удаление записи.
+// Набор сериализуется в журнал целиком, чтобы транзакция восстанавливалась атомарно.
+type memoryChanges struct {
+	BankAccounts      map[int]*models.BankAccount `json:"bank_accounts,omitempty"`
+	Categories        map[int]*models.Category    `json:"categories,omitempty"`
+	Operations        map[int]*models.Operation   `json:"operations,omitempty"`
+	NextBankAccountID int                         `json:"next_bank_account_id"`
+	NextCategoryID    int                         `json:"next_category_id"`
+	NextOperationID   int                         `json:"next_operation_id"`
+}
+
+// empty сообщает, что транзакция ничего не изменила
+func (c *memoryChanges) empty() bool {
+	return len(c.BankAccounts) == 0 && len(c.Categories) == 0 && len(c.Operations) == 0
+}
+
+// applyChanges применяет набор изменений к хранилищу под блокировкой
+func (r *MemoryRepository) applyChanges(changes *memoryChanges) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, account := range changes.BankAccounts {
+		if account == nil {
+			delete(r.bankAccounts, id)
+		} else {
+			r.putBankAccount(account)
+		}
+	}
+	for id, category := range changes.Categories {
+		if category == nil {
+			delete(r.categories, id)
+		} else {
+			r.putCategory(category)
+		}
+	}
+	for id, operation := range changes.Operations {
+		if operation == nil {
+			r.removeOperation(id)
+		} else {
+			r.putOperation(operation)
+		}
+	}
+
+	if changes.NextBankAccountID > r.nextBankAccID {
+		r.nextBankAccID = changes.NextBankAccountID
+	}
+	if changes.NextCategoryID > r.nextCategoryID {
+		r.nextCategoryID = changes.NextCategoryID
+	}
+	if changes.NextOperationID > r.nextOperationID {
+		r.nextOperationID = changes.NextOperationID
+	}
+}
+
+// memoryTx транзакция хранилища в памяти. Изменения копятся в наборе
+// поверх зафиксированных данных и применяются разом при фиксации.
+// Пока транзакция открыта, другие транзакции ждут ее завершения.
+type memoryTx struct {
+	repo    *MemoryRepository
+	changes *memoryChanges
+	done    bool
+}
+
+// begin захватывает хранилище для записи и открывает транзакцию.
+// Если хранилище занято другой транзакцией, ждет ее завершения или отмены контекста.
+func (r *MemoryRepository) begin(ctx context.Context) (*memoryTx, error) {
+	select {
+	case r.txLock <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	bankAccountID, categoryID, operationID := r.nextIDs()
+	return &memoryTx{
+		repo: r,
+		changes: &memoryChanges{
+			BankAccounts:      make(map[int]*models.BankAccount),
+			Categories:        make(map[int]*models.Category),
+			Operations:        make(map[int]*models.Operation),
+			NextBankAccountID: bankAccountID,
+			NextCategoryID:    categoryID,
+			NextOperationID:   operationID,
+		},
+	}, nil
+}
+
+// Commit фиксирует изменения транзакции
+func (tx *memoryTx) Commit() error {
+	if tx.done {
+		return errTxDone
+	}
+
+	tx.repo.applyChanges(tx.changes)
+	tx.finish()
+	return nil
+}
+
+// Rollback отменяет изменения транзакции. После фиксации ничего не делает.
+func (tx *memoryTx) Rollback() error {
+	if !tx.done {
+		tx.finish()
+	}
+	return nil
+}
+
+// finish завершает транзакцию и освобождает хранилище
+func (tx *memoryTx) finish() {
+	tx.done = true
+	<-tx.repo.txLock
+}
+
+// check проверяет, что транзакция не завершена и контекст не отменен
+func (tx *memoryTx) check(ctx context.Context) error {
+	if tx.done {
+		return errTxDone
+	}
+	return ctx.Err()
+}
+
+// NextID выдает следующий свободный ID сущности из общего счетчика хранилища
+func (tx *memoryTx) NextID(ctx context.Context, entity models.EntityKind) (int, error) {
+	if err := tx.check(ctx); err != nil {
+		return 0, err
+	}
+	return tx.allocate(entity, 0)
+}
+
+// allocate резервирует ID в хранилище и сдвигает счетчик в наборе изменений,
+// чтобы он попал в журнал вместе с записью. ID, выданный в откаченной транзакции, не переиспользуется.
+func (tx *memoryTx) allocate(entity models.EntityKind, id int) (int, error) {
+	id, err := tx.repo.reserveID(entity, id)
+	if err != nil {
+		return 0, err
+	}
+
+	next, err := idCounter(entity, &tx.changes.NextBankAccountID, &tx.changes.NextCategoryID, &tx.changes.NextOperationID)
+	if err != nil {
+		return 0, err
+	}
+	return allocateID(id, next), nil
+}
+
+// GetBankAccountByID возвращает банковский счет по его ID
+func (tx *memoryTx) GetBankAccountByID(ctx context.Context, id int) (*models.BankAccount, error) {
+	return overlayGet(ctx, tx.changes.BankAccounts, id, tx.repo.GetBankAccountByID, models.EntityBankAccount)
+}
+
+// GetAllBankAccounts возвращает все банковские счета
+func (tx *memoryTx) GetAllBankAccounts(ctx context.Context) ([]*models.BankAccount, error) {
+	base, err := tx.repo.GetAllBankAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mergeOverlay(base, tx.changes.BankAccounts, bankAccountKey, live(matchAll[models.BankAccount]), byBankAccountID), nil
+}
+
+// SaveBankAccount сохраняет банковский счет
+func (tx *memoryTx) SaveBankAccount(ctx context.Context, account *models.BankAccount) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+
+	id, err := tx.allocate(models.EntityBankAccount, account.ID)
+	if err != nil {
+		return err
+	}
+	account.ID = id
+
+	if current, err := tx.GetBankAccountByID(ctx, account.ID); err == nil {
+		account.Version = current.Version + 1
+	} else if account.Version == 0 {
+		account.Version = 1
+	}
+	tx.changes.BankAccounts[account.ID] = clone(account)
+	return nil
+}
+
+// UpdateBankAccount обновляет банковский счет
+func (tx *memoryTx) UpdateBankAccount(ctx context.Context, account *models.BankAccount) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+	current, err := tx.GetBankAccountByID(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+	if err := advanceVersion(models.EntityBankAccount, account.ID, current.Version, &account.Version); err != nil {
+		return err
+	}
+
+	tx.changes.BankAccounts[account.ID] = clone(account)
+	return nil
+}
+
+// DeleteBankAccount перемещает банковский счет в корзину
+func (tx *memoryTx) DeleteBankAccount(ctx context.Context, id int) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+	account, err := tx.GetBankAccountByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	account.DeletedAt = &now
+	account.Version++
+	tx.changes.BankAccounts[id] = account
+	return nil
+}
+
+// GetDeletedBankAccounts возвращает банковские счета из корзины
+func (tx *memoryTx) GetDeletedBankAccounts(ctx context.Context) ([]*models.BankAccount, error) {
+	base, err := tx.repo.GetDeletedBankAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mergeOverlay(base, tx.changes.BankAccounts, bankAccountKey, deleted[models.BankAccount], byBankAccountID), nil
+}
+
+// RestoreBankAccount восстанавливает банковский счет из корзины
+func (tx *memoryTx) RestoreBankAccount(ctx context.Context, id int) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+	account := lookupAny(tx.repo, tx.changes.BankAccounts, tx.repo.bankAccounts, id)
+	if account == nil || !account.IsDeleted() {
+		return models.NewNotInTrashError(models.EntityBankAccount, id)
+	}
+
+	account = clone(account)
+	account.DeletedAt = nil
+	account.Version++
+	tx.changes.BankAccounts[id] = account
+	return nil
+}
+
+// PurgeBankAccounts окончательно удаляет банковские счета, перемещенные в корзину раньше deletedBefore
+func (tx *memoryTx) PurgeBankAccounts(ctx context.Context, deletedBefore time.Time) (int, error) {
+	if err := tx.check(ctx); err != nil {
+		return 0, err
+	}
+	trash, err := tx.GetDeletedBankAccounts(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, account := range trash {
+		if account.DeletedAt.Before(deletedBefore) {
+			tx.changes.BankAccounts[account.ID] = nil
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// GetCategoryByID возвращает категорию по её ID
+func (tx *memoryTx) GetCategoryByID(ctx context.Context, id int) (*models.Category, error) {
+	return overlayGet(ctx, tx.changes.Categories, id, tx.repo.GetCategoryByID, models.EntityCategory)
+}
+
+// GetAllCategories возвращает все категории
+func (tx *memoryTx) GetAllCategories(ctx context.Context) ([]*models.Category, error) {
+	base, err := tx.repo.GetAllCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mergeOverlay(base, tx.changes.Categories, categoryKey, live(matchAll[models.Category]), byCategoryID), nil
+}
+
+// GetCategoriesByType возвращает категории определенного типа
+func (tx *memoryTx) GetCategoriesByType(ctx context.Context, opType models.OperationType) ([]*models.Category, error) {
+	base, err := tx.repo.GetCategoriesByType(ctx, opType)
+	if err != nil {
+		return nil, err
+	}
+	match := func(category *models.Category) bool { return category.Type == opType }
+	return mergeOverlay(base, tx.changes.Categories, categoryKey, live(match), byCategoryID), nil
+}
+
+// SaveCategory сохраняет категорию
+func (tx *memoryTx) SaveCategory(ctx context.Context, category *models.Category) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+
+	id, err := tx.allocate(models.EntityCategory, category.ID)
+	if err != nil {
+		return err
+	}
+	category.ID = id
+
+	if current, err := tx.GetCategoryByID(ctx, category.ID); err == nil {
+		category.Version = current.Version + 1
+	} else if category.Version == 0 {
+		category.Version = 1
+	}
+	tx.changes.Categories[category.ID] = clone(category)
+	return nil
+}
+
+// UpdateCategory обновляет категорию
+func (tx *memoryTx) UpdateCategory(ctx context.Context, category *models.Category) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+	current, err := tx.GetCategoryByID(ctx, category.ID)
+	if err != nil {
+		return err
+	}
+	if err := advanceVersion(models.EntityCategory, category.ID, current.Version, &category.Version); err != nil {
+		return err
+	}
+
+	tx.changes.Categories[category.ID] = clone(category)
+	return nil
+}
+
+// DeleteCategory перемещает категорию в корзину
+func (tx *memoryTx) DeleteCategory(ctx context.Context, id int) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+	category, err := tx.GetCategoryByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	category.DeletedAt = &now
+	category.Version++
+	tx.changes.Categories[id] = category
+	return nil
+}
+
+// GetDeletedCategories возвращает категории из корзины
+func (tx *memoryTx) GetDeletedCategories(ctx context.Context) ([]*models.Category, error) {
+	base, err := tx.repo.GetDeletedCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mergeOverlay(base, tx.changes.Categories, categoryKey, deleted[models.Category], byCategoryID), nil
+}
+
+// RestoreCategory восстанавливает категорию из корзины
+func (tx *memoryTx) RestoreCategory(ctx context.Context, id int) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+	category := lookupAny(tx.repo, tx.changes.Categories, tx.repo.categories, id)
+	if category == nil || !category.IsDeleted() {
+		return models.NewNotInTrashError(models.EntityCategory, id)
+	}
+
+	category = clone(category)
+	category.DeletedAt = nil
+	category.Version++
+	tx.changes.Categories[id] = category
+	return nil
+}
+
+// PurgeCategories окончательно удаляет категории, перемещенные в корзину раньше deletedBefore
+func (tx *memoryTx) PurgeCategories(ctx context.Context, deletedBefore time.Time) (int, error) {
+	if err := tx.check(ctx); err != nil {
+		return 0, err
+	}
+	trash, err := tx.GetDeletedCategories(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, category := range trash {
+		if category.DeletedAt.Before(deletedBefore) {
+			tx.changes.Categories[category.ID] = nil
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// GetOperationByID возвращает операцию по её ID
+func (tx *memoryTx) GetOperationByID(ctx context.Context, id int) (*models.Operation, error) {
+	return overlayGet(ctx, tx.changes.Operations, id, tx.repo.GetOperationByID, models.EntityOperation)
+}
+
+// GetAllOperations возвращает все операции
+func (tx *memoryTx) GetAllOperations(ctx context.Context) ([]*models.Operation, error) {
+	base, err := tx.repo.GetAllOperations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mergeOverlay(base, tx.changes.Operations, operationKey, live(matchAll[models.Operation]), byOperationID), nil
+}
+
+// GetOperationsByBankAccountID возвращает операции по ID банковского счета
+func (tx *memoryTx) GetOperationsByBankAccountID(ctx context.Context, bankAccountID int) ([]*models.Operation, error) {
+	base, err := tx.repo.GetOperationsByBankAccountID(ctx, bankAccountID)
+	if err != nil {
+		return nil, err
+	}
+	match := func(operation *models.Operation) bool { return operation.BankAccountID == bankAccountID }
+	return mergeOverlay(base, tx.changes.Operations, operationKey, live(match), byOperationID), nil
+}
+
+// GetOperationsByCategoryID возвращает операции по ID категории
+func (tx *memoryTx) GetOperationsByCategoryID(ctx context.Context, categoryID int) ([]*models.Operation, error) {
+	base, err := tx.repo.GetOperationsByCategoryID(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	match := func(operation *models.Operation) bool { return operation.CategoryID == categoryID }
+	return mergeOverlay(base, tx.changes.Operations, operationKey, live(match), byOperationID), nil
+}
+
+// GetOperationsByDateRange возвращает операции в указанном диапазоне дат
+func (tx *memoryTx) GetOperationsByDateRange(ctx context.Context, start, end time.Time) ([]*models.Operation, error) {
+	base, err := tx.repo.GetOperationsByDateRange(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	match := func(operation *models.Operation) bool { return inRange(operation.Date, start, end) }
+	return mergeOverlay(base, tx.changes.Operations, operationKey, live(match), byOperationDate), nil
+}
+
+// GetOperationsByTypeAndDateRange возвращает операции определенного типа в указанном диапазоне дат
+func (tx *memoryTx) GetOperationsByTypeAndDateRange(ctx context.Context, opType models.OperationType, start, end time.Time) ([]*models.Operation, error) {
+	base, err := tx.repo.GetOperationsByTypeAndDateRange(ctx, opType, start, end)
+	if err != nil {
+		return nil, err
+	}
+	match := func(operation *models.Operation) bool {
+		return operation.Type == opType && inRange(operation.Date, start, end)
+	}
+	return mergeOverlay(base, tx.changes.Operations, operationKey, live(match), byOperationDate), nil
+}
+
+// FindOperations возвращает страницу операций, подходящих под запрос, с учетом изменений транзакции
+func (tx *memoryTx) FindOperations(ctx context.Context, query models.OperationQuery) (*models.OperationPage, error) {
+	operations, err := tx.GetAllOperations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pageOperations(ctx, query, operations)
+}
+
+// SaveOperation сохраняет операцию
+func (tx *memoryTx) SaveOperation(ctx context.Context, operation *models.Operation) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+
+	id, err := tx.allocate(models.EntityOperation, operation.ID)
+	if err != nil {
+		return err
+	}
+	operation.ID = id
+
+	if current, err := tx.GetOperationByID(ctx, operation.ID); err == nil {
+		operation.Version = current.Version + 1
+	} else if operation.Version == 0 {
+		operation.Version = 1
+	}
+	tx.changes.Operations[operation.ID] = clone(operation)
+	return nil
+}
+
+// UpdateOperation обновляет операцию
+func (tx *memoryTx) UpdateOperation(ctx context.Context, operation *models.Operation) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+	current, err := tx.GetOperationByID(ctx, operation.ID)
+	if err != nil {
+		return err
+	}
+	if err := advanceVersion(models.EntityOperation, operation.ID, current.Version, &operation.Version); err != nil {
+		return err
+	}
+
+	tx.changes.Operations[operation.ID] = clone(operation)
+	return nil
+}
+
+// DeleteOperation перемещает операцию в корзину
+func (tx *memoryTx) DeleteOperation(ctx context.Context, id int) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+	operation, err := tx.GetOperationByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	operation.DeletedAt = &now
+	operation.Version++
+	tx.changes.Operations[id] = operation
+	return nil
+}
+
+// GetDeletedOperations возвращает операции из корзины
+func (tx *memoryTx) GetDeletedOperations(ctx context.Context) ([]*models.Operation, error) {
+	base, err := tx.repo.GetDeletedOperations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mergeOverlay(base, tx.changes.Operations, operationKey, deleted[models.Operation], byOperationID), nil
+}
+
+// RestoreOperation восстанавливает операцию из корзины
+func (tx *memoryTx) RestoreOperation(ctx context.Context, id int) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+	operation := lookupAny(tx.repo, tx.changes.Operations, tx.repo.operations, id)
+	if operation == nil || !operation.IsDeleted() {
+		return models.NewNotInTrashError(models.EntityOperation, id)
+	}
+
+	operation = clone(operation)
+	operation.DeletedAt = nil
+	operation.Version++
+	tx.changes.Operations[id] = operation
+	return nil
+}
+
+// PurgeOperations окончательно удаляет операции, перемещенные в корзину раньше deletedBefore
+func (tx *memoryTx) PurgeOperations(ctx context.Context, deletedBefore time.Time) (int, error) {
+	if err := tx.check(ctx); err != nil {
+		return 0, err
+	}
+	trash, err := tx.GetDeletedOperations(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, operation := range trash {
+		if operation.DeletedAt.Before(deletedBefore) {
+			tx.changes.Operations[operation.ID] = nil
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// allocateID выдает новый ID, если он не задан, и сдвигает счетчик за заданный ID
+func allocateID(id int, next *int) int {
+	if id == 0 {
+		id = *next
+	}
+	if id >= *next {
+		*next = id + 1
+	}
+	return id
+}
+
+// idCounter выбирает счетчик ID для сущности из счетчиков счетов, категорий и операций
+func idCounter(entity models.EntityKind, bankAccounts, categories, operations *int) (*int, error) {
+	switch entity {
+	case models.EntityBankAccount:
+		return bankAccounts, nil
+	case models.EntityCategory:
+		return categories, nil
+	case models.EntityOperation:
+		return operations, nil
+	default:
+		return nil, fmt.Errorf("неизвестный тип сущности: %s", entity)
+	}
+}
+
+// advanceVersion проверяет, что изменение сделано по текущей версии сущности, и увеличивает версию
+func advanceVersion(entity models.EntityKind, id, current int, version *int) error {
+	if *version != current {
+		return &models.ConflictError{Entity: entity, ID: id, ExpectedVersion: *version, ActualVersion: current}
+	}
+	*version = current + 1
+	return nil
+}
+
+// lookupAny ищет запись среди изменений транзакции, затем в хранилище, включая корзину.
+// Пока транзакция открыта, другие транзакции не меняют хранилище, поэтому запись можно читать без копирования.
+func lookupAny[T any](r *MemoryRepository, overlay, base map[int]*T, id int) *T {
+	if item, ok := overlay[id]; ok {
+		return item
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return base[id]
+}
+
+// live добавляет к условию запроса исключение записей из корзины
+func live[T any](match func(*T) bool) func(*T) bool {
+	return func(item *T) bool { return !deleted(item) && match(item) }
+}
+
+// overlayGet ищет запись сначала среди изменений транзакции, затем в хранилище
+func overlayGet[T any](ctx context.Context, overlay map[int]*T, id int, base func(ctx context.Context, id int) (*T, error), entity models.EntityKind) (*T, error) {
+	if item, ok := overlay[id]; ok {
+		if item == nil || deleted(item) {
+			return nil, models.NewNotFoundError(entity, id)
+		}
+		return clone(item), nil
+	}
+	return base(ctx, id)
+}
+
+// mergeOverlay накладывает изменения транзакции на выборку из хранилища.
+// Измененные записи попадают в результат, только если удовлетворяют условию match.
+func mergeOverlay[T any](base []*T, overlay map[int]*T, id func(*T) int, match func(*T) bool, less func(a, b *T) bool) []*T {
+	result := make([]*T, 0, len(base)+len(overlay))
+	for _, item := range base {
+		if _, changed := overlay[id(item)]; !changed {
+			result = append(result, item)
+		}
+	}
+	for _, item := range overlay {
+		if item != nil && match(item) {
+			result = append(result, clone(item))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return less(result[i], result[j]) })
+	return result
+}
+
+// Ключи и порядок сущностей для наложения изменений транзакции
+func bankAccountKey(account *models.BankAccount) int { return account.ID }
+func categoryKey(category *models.Category) int      { return category.ID }
+func operationKey(operation *models.Operation) int   { return operation.ID }
+
+func byBankAccountID(a, b *models.BankAccount) bool { return a.ID < b.ID }
+func byCategoryID(a, b *models.Category) bool       { return a.ID < b.ID }
+func byOperationID(a, b *models.Operation) bool     { return a.ID < b.ID }
+
+// byOperationDate упорядочивает операции по дате, затем по ID, как индекс по дате
+func byOperationDate(a, b *models.Operation) bool {
+	return dateIndexEntry{date: a.Date, id: a.ID}.before(dateIndexEntry{date: b.Date, id: b.ID})
+}
@@ -0,0 +1,43 @@
+-- Начальная схема хранилища учета финансов
+
+CREATE TABLE IF NOT EXISTS bank_accounts (
+    id         INTEGER PRIMARY KEY,
+    name       TEXT    NOT NULL,
+    balance    REAL    NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL DEFAULT 0,
+    updated_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id         INTEGER PRIMARY KEY,
+    type       TEXT    NOT NULL,
+    name       TEXT    NOT NULL,
+    created_at INTEGER NOT NULL DEFAULT 0,
+    updated_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_categories_type ON categories (type);
+
+CREATE TABLE IF NOT EXISTS operations (
+    id              INTEGER PRIMARY KEY,
+    type            TEXT    NOT NULL,
+    bank_account_id INTEGER NOT NULL,
+    category_id     INTEGER NOT NULL,
+    amount          REAL    NOT NULL,
+    date            INTEGER NOT NULL,
+    description     TEXT    NOT NULL DEFAULT '',
+    created_at      INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_operations_bank_account_id ON operations (bank_account_id);
+CREATE INDEX IF NOT EXISTS idx_operations_category_id ON operations (category_id);
+CREATE INDEX IF NOT EXISTS idx_operations_date ON operations (date);
+
+CREATE TABLE IF NOT EXISTS id_sequences (
+    entity  TEXT    PRIMARY KEY,
+    next_id INTEGER NOT NULL
+);
+
+INSERT INTO id_sequences (entity, next_id) VALUES ('bank_account', 1);
+INSERT INTO id_sequences (entity, next_id) VALUES ('category', 1);
+INSERT INTO id_sequences (entity, next_id) VALUES ('operation', 1);
@@ -0,0 +1,5 @@
+-- Версии сущностей для оптимистичной блокировки
+
+ALTER TABLE bank_accounts ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE categories ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE operations ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,9 @@
+-- Корзина: удаленные записи помечаются временем удаления, 0 - запись не удалена
+
+ALTER TABLE bank_accounts ADD COLUMN deleted_at INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE categories ADD COLUMN deleted_at INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE operations ADD COLUMN deleted_at INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_bank_accounts_deleted_at ON bank_accounts (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_categories_deleted_at ON categories (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_operations_deleted_at ON operations (deleted_at);
@@ -0,0 +1,5 @@
+-- Идентификатор операции в выписке банка для поиска повторов при импорте выписок
+
+ALTER TABLE operations ADD COLUMN external_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_operations_external_id ON operations (bank_account_id, external_id);
@@ -0,0 +1,171 @@
+package persistence
+
+import (
+	"KPO1/domain/models"
+	"context"
+	"sort"
+	"time"
+)
+
+// operationIndexKey значения полей операции, по которым она проиндексирована.
+// Они хранятся отдельно, так как поля самой операции могут измениться до вызова Update.
+type operationIndexKey struct {
+	bankAccountID int
+	categoryID    int
+	date          time.Time
+}
+
+// dateIndexEntry элемент индекса операций, упорядоченного по дате
+type dateIndexEntry struct {
+	date time.Time
+	id   int
+}
+
+// before задает порядок элементов индекса: по дате, затем по ID
+func (e dateIndexEntry) before(other dateIndexEntry) bool {
+	if e.date.Equal(other.date) {
+		return e.id < other.id
+	}
+	return e.date.Before(other.date)
+}
+
+// operationIndex вторичные индексы операций по счету, категории и дате.
+// Не потокобезопасен: используется под блокировкой MemoryRepository.
+type operationIndex struct {
+	byAccount  map[int]map[int]struct{}
+	byCategory map[int]map[int]struct{}
+	byDate     []dateIndexEntry
+	keys       map[int]operationIndexKey
+	// bulk массовая загрузка: byDate не ведется до finishBulk
+	bulk bool
+}
+
+// newOperationIndex создает пустой индекс операций
+func newOperationIndex() *operationIndex {
+	return &operationIndex{
+		byAccount:  make(map[int]map[int]struct{}),
+		byCategory: make(map[int]map[int]struct{}),
+		keys:       make(map[int]operationIndexKey),
+	}
+}
+
+// add индексирует операцию, предварительно убирая ее прежние ключи
+func (idx *operationIndex) add(operation *models.Operation) {
+	idx.remove(operation.ID)
+
+	key := operationIndexKey{
+		bankAccountID: operation.BankAccountID,
+		categoryID:    operation.CategoryID,
+		date:          operation.Date,
+	}
+	idx.keys[operation.ID] = key
+
+	addToSet(idx.byAccount, key.bankAccountID, operation.ID)
+	addToSet(idx.byCategory, key.categoryID, operation.ID)
+
+	if idx.bulk {
+		return
+	}
+	entry := dateIndexEntry{date: key.date, id: operation.ID}
+	pos := sort.Search(len(idx.byDate), func(i int) bool { return !idx.byDate[i].before(entry) })
+	idx.byDate = append(idx.byDate, dateIndexEntry{})
+	copy(idx.byDate[pos+1:], idx.byDate[pos:])
+	idx.byDate[pos] = entry
+}
+
+// remove убирает операцию из всех индексов
+func (idx *operationIndex) remove(id int) {
+	key, ok := idx.keys[id]
+	if !ok {
+		return
+	}
+	delete(idx.keys, id)
+
+	removeFromSet(idx.byAccount, key.bankAccountID, id)
+	removeFromSet(idx.byCategory, key.categoryID, id)
+
+	if idx.bulk {
+		return
+	}
+	entry := dateIndexEntry{date: key.date, id: id}
+	pos := sort.Search(len(idx.byDate), func(i int) bool { return !idx.byDate[i].before(entry) })
+	if pos < len(idx.byDate) && idx.byDate[pos].id == id {
+		idx.byDate = append(idx.byDate[:pos], idx.byDate[pos+1:]...)
+	}
+}
+
+// rebuild заново строит индексы по набору операций
+func (idx *operationIndex) rebuild(operations []*models.Operation) {
+	*idx = *newOperationIndex()
+	idx.beginBulk()
+	for _, operation := range operations {
+		idx.add(operation)
+	}
+	idx.finishBulk()
+}
+
+// beginBulk начинает массовую загрузку. Вставка в упорядоченный по дате срез стоит O(n),
+// и загрузка n операций по одной заняла бы O(n²), поэтому до finishBulk индекс по дате
+// не ведется, а затем строится одной сортировкой за O(n log n). Запросы по дате
+// до finishBulk не выполняются.
+func (idx *operationIndex) beginBulk() {
+	idx.bulk = true
+	idx.byDate = nil
+}
+
+// finishBulk завершает массовую загрузку и строит индекс по дате
+func (idx *operationIndex) finishBulk() {
+	if !idx.bulk {
+		return
+	}
+	idx.bulk = false
+	idx.byDate = make([]dateIndexEntry, 0, len(idx.keys))
+	for id, key := range idx.keys {
+		idx.byDate = append(idx.byDate, dateIndexEntry{date: key.date, id: id})
+	}
+	sort.Slice(idx.byDate, func(i, j int) bool { return idx.byDate[i].before(idx.byDate[j]) })
+}
+
+// all возвращает ID всех проиндексированных операций
+func (idx *operationIndex) all() map[int]struct{} {
+	ids := make(map[int]struct{}, len(idx.keys))
+	for id := range idx.keys {
+		ids[id] = struct{}{}
+	}
+	return ids
+}
+
+// scanDateRange вызывает visit для ID операций с датой в диапазоне [start, end]
+// в порядке возрастания даты за O(log n + k). Обход прерывается, если контекст отменен.
+func (idx *operationIndex) scanDateRange(ctx context.Context, start, end time.Time, visit func(id int)) error {
+	first := sort.Search(len(idx.byDate), func(i int) bool { return !idx.byDate[i].date.Before(start) })
+	for pos := first; pos < len(idx.byDate) && !idx.byDate[pos].date.After(end); pos++ {
+		if err := checkCanceled(ctx, pos-first); err != nil {
+			return err
+		}
+		visit(idx.byDate[pos].id)
+	}
+	return nil
+}
+
+// addToSet добавляет ID в множество по ключу
+func addToSet(sets map[int]map[int]struct{}, key, id int) {
+	set, ok := sets[key]
+	if !ok {
+		set = make(map[int]struct{})
+		sets[key] = set
+	}
+	set[id] = struct{}{}
+}
+
+// removeFromSet удаляет ID из множества по ключу и удаляет пустое множество
+func removeFromSet(sets map[int]map[int]struct{}, key, id int) {
+	set, ok := sets[key]
+	if !ok {
+		return
+	}
+	delete(set, id)
+	if len(set) == 0 {
+		delete(sets, key)
+	}
+}
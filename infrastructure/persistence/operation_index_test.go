@@ -0,0 +1,198 @@
+package persistence
+
+import (
+	"KPO1/domain/models"
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+// indexStart первый день, на который приходятся операции теста индекса
+var indexStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+
+// randomOperation возвращает операцию с ID id на одном из трех счетов и четырех категорий
+// в один из 30 дней; одинаковые даты у разных операций проверяют порядок по ID
+func randomOperation(rnd *rand.Rand, id int) *models.Operation {
+	return &models.Operation{
+		ID:            id,
+		Type:          models.Expense,
+		BankAccountID: 1 + rnd.Intn(3),
+		CategoryID:    1 + rnd.Intn(4),
+		Amount:        float64(1 + rnd.Intn(1000)),
+		Date:          indexStart.AddDate(0, 0, rnd.Intn(30)).Add(time.Duration(rnd.Intn(3)) * time.Hour),
+	}
+}
+
+// checkIndex сравнивает выборки по индексам с полным перебором операций хранилища
+func checkIndex(t *testing.T, repo *MemoryRepository, step string) {
+	t.Helper()
+	ctx := context.Background()
+	operations := NewOperationRepository(repo)
+
+	all, err := repo.GetAllOperations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scan := func(match func(*models.Operation) bool) []int {
+		var ids []int
+		for _, operation := range all {
+			if match(operation) {
+				ids = append(ids, operation.ID)
+			}
+		}
+		return ids
+	}
+	sortedIDs := func(found []*models.Operation) []int {
+		ids := operationIDs(found)
+		sort.Ints(ids)
+		return ids
+	}
+
+	for account := 1; account <= 4; account++ {
+		found, err := operations.GetByBankAccountID(ctx, account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := scan(func(operation *models.Operation) bool { return operation.BankAccountID == account })
+		if got := sortedIDs(found); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: GetByBankAccountID(%d) = %v, scan %v", step, account, got, want)
+		}
+	}
+	for category := 1; category <= 5; category++ {
+		found, err := operations.GetByCategoryID(ctx, category)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := scan(func(operation *models.Operation) bool { return operation.CategoryID == category })
+		if got := sortedIDs(found); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: GetByCategoryID(%d) = %v, scan %v", step, category, got, want)
+		}
+	}
+
+	for _, days := range [][2]int{{0, 29}, {3, 3}, {10, 17}, {-5, 0}, {29, 40}, {31, 40}} {
+		start := indexStart.AddDate(0, 0, days[0])
+		// Конец диапазона включает операции, совпадающие с ним по времени
+		end := indexStart.AddDate(0, 0, days[1]).Add(2 * time.Hour)
+		found, err := operations.GetByDateRange(ctx, start, end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := scan(func(operation *models.Operation) bool {
+			return !operation.Date.Before(start) && !operation.Date.After(end)
+		})
+		if got := sortedIDs(found); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: GetByDateRange(day %d, day %d) = %v, scan %v", step, days[0], days[1], got, want)
+		}
+		if !sort.SliceIsSorted(found, func(i, j int) bool {
+			return dateIndexEntry{found[i].Date, found[i].ID}.before(dateIndexEntry{found[j].Date, found[j].ID})
+		}) {
+			t.Errorf("%s: GetByDateRange(day %d, day %d) is not ordered by date and ID", step, days[0], days[1])
+		}
+	}
+}
+
+func TestOperationIndexMatchesScan(t *testing.T) {
+	ctx := context.Background()
+	rnd := rand.New(rand.NewSource(1))
+
+	// Загрузка снимка, часть операций которого в корзине
+	snap := &memorySnapshot{}
+	deletedAt := indexStart
+	for id := 1; id <= 200; id++ {
+		operation := randomOperation(rnd, id)
+		if id%10 == 0 {
+			operation.DeletedAt = &deletedAt
+		}
+		snap.Operations = append(snap.Operations, operation)
+	}
+	repo := NewMemoryRepository()
+	repo.restore(snap)
+	checkIndex(t, repo, "restore")
+
+	// Повтор журнала: изменения применяются при отключенном индексе по дате
+	repo.beginBulkLoad()
+	changes := &memoryChanges{
+		BankAccounts: make(map[int]*models.BankAccount),
+		Categories:   make(map[int]*models.Category),
+		Operations:   make(map[int]*models.Operation),
+	}
+	for id := 150; id <= 250; id++ {
+		changes.Operations[id] = randomOperation(rnd, id)
+	}
+	for id := 1; id <= 20; id++ {
+		changes.Operations[id] = nil
+	}
+	repo.applyChanges(changes)
+	repo.endBulkLoad()
+	checkIndex(t, repo, "bulk load")
+
+	for n := 0; n < 50; n++ {
+		operation := randomOperation(rnd, 0)
+		if err := repo.SaveOperation(ctx, operation); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkIndex(t, repo, "add")
+
+	all, err := repo.GetAllOperations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n, operation := range all {
+		if n%3 != 0 {
+			continue
+		}
+		moved := randomOperation(rnd, operation.ID)
+		operation.BankAccountID, operation.CategoryID, operation.Date = moved.BankAccountID, moved.CategoryID, moved.Date
+		if err := repo.UpdateOperation(ctx, operation); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkIndex(t, repo, "update")
+
+	for n, operation := range all {
+		if n%4 == 1 {
+			if err := repo.DeleteOperation(ctx, operation.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	checkIndex(t, repo, "delete")
+
+	deleted, err := repo.GetDeletedOperations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n, operation := range deleted {
+		if n%2 == 0 {
+			if err := repo.RestoreOperation(ctx, operation.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	checkIndex(t, repo, "restore from trash")
+
+	if _, err := repo.PurgeOperations(ctx, time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	checkIndex(t, repo, "purge")
+
+	// Откат транзакции не меняет индексы
+	tx, err := repo.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.SaveOperation(ctx, randomOperation(rnd, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.DeleteOperation(ctx, all[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	checkIndex(t, repo, "rollback")
+}
@@ -0,0 +1,51 @@
+package persistence
+
+import (
+	"KPO1/domain/models"
+	"context"
+	"sort"
+	"time"
+)
+
+// maxTime верхняя граница периода без даты окончания
+var maxTime = time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC)
+
+// pageOperations выполняет запрос над набором операций в памяти:
+// отбирает подходящие под фильтр, сортирует и вырезает страницу.
+// Операции из набора не копируются.
+func pageOperations(ctx context.Context, query models.OperationQuery, candidates []*models.Operation) (*models.OperationPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	after, _ := query.CursorKey()
+
+	matched := make([]*models.Operation, 0, len(candidates))
+	for i, operation := range candidates {
+		if err := checkCanceled(ctx, i); err != nil {
+			return nil, err
+		}
+		if query.Matches(operation) {
+			matched = append(matched, operation)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return query.Compare(matched[i], matched[j]) < 0 })
+
+	page := &models.OperationPage{Total: len(matched)}
+
+	start := query.Offset
+	if after != nil {
+		start = sort.Search(len(matched), func(i int) bool { return query.Compare(matched[i], after) > 0 })
+	}
+	if start > len(matched) {
+		start = len(matched)
+	}
+
+	end := len(matched)
+	if query.Limit > 0 && start+query.Limit < end {
+		end = start + query.Limit
+		page.NextCursor = query.NextCursor(matched[end-1])
+	}
+
+	page.Operations = matched[start:end]
+	return page, nil
+}
@@ -3,135 +3,235 @@ package persistence
 import (
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"context"
 	"time"
 )
 
+// DataStore представляет хранилище данных, к которому адаптируются репозитории.
+// Update принимает сущность с версией, прочитанной ранее, отклоняет устаревшую
+// версию ошибкой *models.ConflictError и увеличивает версию при успехе.
+// Delete перемещает запись в корзину, Purge удаляет записи из корзины окончательно.
+// Методы возвращают ошибку контекста, если он отменен до завершения операции.
+type DataStore interface {
+	interfaces.IDAllocator
+
+	GetBankAccountByID(ctx context.Context, id int) (*models.BankAccount, error)
+	GetAllBankAccounts(ctx context.Context) ([]*models.BankAccount, error)
+	SaveBankAccount(ctx context.Context, account *models.BankAccount) error
+	UpdateBankAccount(ctx context.Context, account *models.BankAccount) error
+	DeleteBankAccount(ctx context.Context, id int) error
+	GetDeletedBankAccounts(ctx context.Context) ([]*models.BankAccount, error)
+	RestoreBankAccount(ctx context.Context, id int) error
+	PurgeBankAccounts(ctx context.Context, deletedBefore time.Time) (int, error)
+
+	GetCategoryByID(ctx context.Context, id int) (*models.Category, error)
+	GetAllCategories(ctx context.Context) ([]*models.Category, error)
+	GetCategoriesByType(ctx context.Context, opType models.OperationType) ([]*models.Category, error)
+	SaveCategory(ctx context.Context, category *models.Category) error
+	UpdateCategory(ctx context.Context, category *models.Category) error
+	DeleteCategory(ctx context.Context, id int) error
+	GetDeletedCategories(ctx context.Context) ([]*models.Category, error)
+	RestoreCategory(ctx context.Context, id int) error
+	PurgeCategories(ctx context.Context, deletedBefore time.Time) (int, error)
+
+	GetOperationByID(ctx context.Context, id int) (*models.Operation, error)
+	GetAllOperations(ctx context.Context) ([]*models.Operation, error)
+	GetOperationsByBankAccountID(ctx context.Context, bankAccountID int) ([]*models.Operation, error)
+	GetOperationsByCategoryID(ctx context.Context, categoryID int) ([]*models.Operation, error)
+	GetOperationsByDateRange(ctx context.Context, start, end time.Time) ([]*models.Operation, error)
+	GetOperationsByTypeAndDateRange(ctx context.Context, opType models.OperationType, start, end time.Time) ([]*models.Operation, error)
+	FindOperations(ctx context.Context, query models.OperationQuery) (*models.OperationPage, error)
+	SaveOperation(ctx context.Context, operation *models.Operation) error
+	UpdateOperation(ctx context.Context, operation *models.Operation) error
+	DeleteOperation(ctx context.Context, id int) error
+	GetDeletedOperations(ctx context.Context) ([]*models.Operation, error)
+	RestoreOperation(ctx context.Context, id int) error
+	PurgeOperations(ctx context.Context, deletedBefore time.Time) (int, error)
+}
+
+// Убедимся что хранилища реализуют интерфейс DataStore
+var (
+	_ DataStore = (*MemoryRepository)(nil)
+	_ DataStore = (*FileRepository)(nil)
+	_ DataStore = (*SQLRepository)(nil)
+)
+
 // BankAccountRepositoryAdapter адаптер репозитория для банковских счетов
 type BankAccountRepositoryAdapter struct {
-	repo *MemoryRepository
+	repo DataStore
 }
 
 // NewBankAccountRepository создает новый репозиторий для банковских счетов
-func NewBankAccountRepository(repo *MemoryRepository) interfaces.BankAccountRepository {
+func NewBankAccountRepository(repo DataStore) interfaces.BankAccountRepository {
 	return &BankAccountRepositoryAdapter{repo: repo}
 }
 
 // GetByID получает банковский счет по ID
-func (a *BankAccountRepositoryAdapter) GetByID(id int) (*models.BankAccount, error) {
-	return a.repo.GetBankAccountByID(id)
+func (a *BankAccountRepositoryAdapter) GetByID(ctx context.Context, id int) (*models.BankAccount, error) {
+	return a.repo.GetBankAccountByID(ctx, id)
 }
 
 // GetAll получает все банковские счета
-func (a *BankAccountRepositoryAdapter) GetAll() ([]*models.BankAccount, error) {
-	return a.repo.GetAllBankAccounts()
+func (a *BankAccountRepositoryAdapter) GetAll(ctx context.Context) ([]*models.BankAccount, error) {
+	return a.repo.GetAllBankAccounts(ctx)
 }
 
 // Save сохраняет банковский счет
-func (a *BankAccountRepositoryAdapter) Save(account *models.BankAccount) error {
-	return a.repo.SaveBankAccount(account)
+func (a *BankAccountRepositoryAdapter) Save(ctx context.Context, account *models.BankAccount) error {
+	return a.repo.SaveBankAccount(ctx, account)
 }
 
 // Update обновляет банковский счет
-func (a *BankAccountRepositoryAdapter) Update(account *models.BankAccount) error {
-	return a.repo.UpdateBankAccount(account)
+func (a *BankAccountRepositoryAdapter) Update(ctx context.Context, account *models.BankAccount) error {
+	return a.repo.UpdateBankAccount(ctx, account)
+}
+
+// Delete перемещает банковский счет в корзину
+func (a *BankAccountRepositoryAdapter) Delete(ctx context.Context, id int) error {
+	return a.repo.DeleteBankAccount(ctx, id)
+}
+
+// GetDeleted получает банковские счета из корзины
+func (a *BankAccountRepositoryAdapter) GetDeleted(ctx context.Context) ([]*models.BankAccount, error) {
+	return a.repo.GetDeletedBankAccounts(ctx)
 }
 
-// Delete удаляет банковский счет
-func (a *BankAccountRepositoryAdapter) Delete(id int) error {
-	return a.repo.DeleteBankAccount(id)
+// Restore восстанавливает банковский счет из корзины
+func (a *BankAccountRepositoryAdapter) Restore(ctx context.Context, id int) error {
+	return a.repo.RestoreBankAccount(ctx, id)
+}
+
+// Purge окончательно удаляет банковские счета, перемещенные в корзину раньше deletedBefore
+func (a *BankAccountRepositoryAdapter) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	return a.repo.PurgeBankAccounts(ctx, deletedBefore)
 }
 
 // CategoryRepositoryAdapter адаптер репозитория для категорий
 type CategoryRepositoryAdapter struct {
-	repo *MemoryRepository
+	repo DataStore
 }
 
 // NewCategoryRepository создает новый репозиторий для категорий
-func NewCategoryRepository(repo *MemoryRepository) interfaces.CategoryRepository {
+func NewCategoryRepository(repo DataStore) interfaces.CategoryRepository {
 	return &CategoryRepositoryAdapter{repo: repo}
 }
 
 // GetByID получает категорию по ID
-func (a *CategoryRepositoryAdapter) GetByID(id int) (*models.Category, error) {
-	return a.repo.GetCategoryByID(id)
+func (a *CategoryRepositoryAdapter) GetByID(ctx context.Context, id int) (*models.Category, error) {
+	return a.repo.GetCategoryByID(ctx, id)
 }
 
 // GetAll получает все категории
-func (a *CategoryRepositoryAdapter) GetAll() ([]*models.Category, error) {
-	return a.repo.GetAllCategories()
+func (a *CategoryRepositoryAdapter) GetAll(ctx context.Context) ([]*models.Category, error) {
+	return a.repo.GetAllCategories(ctx)
 }
 
 // Save сохраняет категорию
-func (a *CategoryRepositoryAdapter) Save(category *models.Category) error {
-	return a.repo.SaveCategory(category)
+func (a *CategoryRepositoryAdapter) Save(ctx context.Context, category *models.Category) error {
+	return a.repo.SaveCategory(ctx, category)
 }
 
 // Update обновляет категорию
-func (a *CategoryRepositoryAdapter) Update(category *models.Category) error {
-	return a.repo.UpdateCategory(category)
+func (a *CategoryRepositoryAdapter) Update(ctx context.Context, category *models.Category) error {
+	return a.repo.UpdateCategory(ctx, category)
+}
+
+// Delete перемещает категорию в корзину
+func (a *CategoryRepositoryAdapter) Delete(ctx context.Context, id int) error {
+	return a.repo.DeleteCategory(ctx, id)
+}
+
+// GetDeleted получает категории из корзины
+func (a *CategoryRepositoryAdapter) GetDeleted(ctx context.Context) ([]*models.Category, error) {
+	return a.repo.GetDeletedCategories(ctx)
+}
+
+// Restore восстанавливает категорию из корзины
+func (a *CategoryRepositoryAdapter) Restore(ctx context.Context, id int) error {
+	return a.repo.RestoreCategory(ctx, id)
 }
 
-// Delete удаляет категорию
-func (a *CategoryRepositoryAdapter) Delete(id int) error {
-	return a.repo.DeleteCategory(id)
+// Purge окончательно удаляет категории, перемещенные в корзину раньше deletedBefore
+func (a *CategoryRepositoryAdapter) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	return a.repo.PurgeCategories(ctx, deletedBefore)
 }
 
 // GetByType получает категории по типу операции
-func (a *CategoryRepositoryAdapter) GetByType(opType models.OperationType) ([]*models.Category, error) {
-	return a.repo.GetCategoriesByType(opType)
+func (a *CategoryRepositoryAdapter) GetByType(ctx context.Context, opType models.OperationType) ([]*models.Category, error) {
+	return a.repo.GetCategoriesByType(ctx, opType)
 }
 
 // OperationRepositoryAdapter адаптер репозитория для операций
 type OperationRepositoryAdapter struct {
-	repo *MemoryRepository
+	repo DataStore
 }
 
 // NewOperationRepository создает новый репозиторий для операций
-func NewOperationRepository(repo *MemoryRepository) interfaces.OperationRepository {
+func NewOperationRepository(repo DataStore) interfaces.OperationRepository {
 	return &OperationRepositoryAdapter{repo: repo}
 }
 
 // GetByID получает операцию по ID
-func (a *OperationRepositoryAdapter) GetByID(id int) (*models.Operation, error) {
-	return a.repo.GetOperationByID(id)
+func (a *OperationRepositoryAdapter) GetByID(ctx context.Context, id int) (*models.Operation, error) {
+	return a.repo.GetOperationByID(ctx, id)
 }
 
 // GetAll получает все операции
-func (a *OperationRepositoryAdapter) GetAll() ([]*models.Operation, error) {
-	return a.repo.GetAllOperations()
+func (a *OperationRepositoryAdapter) GetAll(ctx context.Context) ([]*models.Operation, error) {
+	return a.repo.GetAllOperations(ctx)
 }
 
 // Save сохраняет операцию
-func (a *OperationRepositoryAdapter) Save(operation *models.Operation) error {
-	return a.repo.SaveOperation(operation)
+func (a *OperationRepositoryAdapter) Save(ctx context.Context, operation *models.Operation) error {
+	return a.repo.SaveOperation(ctx, operation)
 }
 
 // Update обновляет операцию
-func (a *OperationRepositoryAdapter) Update(operation *models.Operation) error {
-	return a.repo.UpdateOperation(operation)
+func (a *OperationRepositoryAdapter) Update(ctx context.Context, operation *models.Operation) error {
+	return a.repo.UpdateOperation(ctx, operation)
 }
 
-// Delete удаляет операцию
-func (a *OperationRepositoryAdapter) Delete(id int) error {
-	return a.repo.DeleteOperation(id)
+// Delete перемещает операцию в корзину
+func (a *OperationRepositoryAdapter) Delete(ctx context.Context, id int) error {
+	return a.repo.DeleteOperation(ctx, id)
+}
+
+// GetDeleted получает операции из корзины
+func (a *OperationRepositoryAdapter) GetDeleted(ctx context.Context) ([]*models.Operation, error) {
+	return a.repo.GetDeletedOperations(ctx)
+}
+
+// Restore восстанавливает операцию из корзины
+func (a *OperationRepositoryAdapter) Restore(ctx context.Context, id int) error {
+	return a.repo.RestoreOperation(ctx, id)
+}
+
+// Purge окончательно удаляет операции, перемещенные в корзину раньше deletedBefore
+func (a *OperationRepositoryAdapter) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	return a.repo.PurgeOperations(ctx, deletedBefore)
 }
 
 // GetByBankAccountID получает операции по ID банковского счета
-func (a *OperationRepositoryAdapter) GetByBankAccountID(bankAccountID int) ([]*models.Operation, error) {
-	return a.repo.GetOperationsByBankAccountID(bankAccountID)
+func (a *OperationRepositoryAdapter) GetByBankAccountID(ctx context.Context, bankAccountID int) ([]*models.Operation, error) {
+	return a.repo.GetOperationsByBankAccountID(ctx, bankAccountID)
 }
 
 // GetByCategoryID получает операции по ID категории
-func (a *OperationRepositoryAdapter) GetByCategoryID(categoryID int) ([]*models.Operation, error) {
-	return a.repo.GetOperationsByCategoryID(categoryID)
+func (a *OperationRepositoryAdapter) GetByCategoryID(ctx context.Context, categoryID int) ([]*models.Operation, error) {
+	return a.repo.GetOperationsByCategoryID(ctx, categoryID)
 }
 
 // GetByDateRange получает операции в указанном диапазоне дат
-func (a *OperationRepositoryAdapter) GetByDateRange(start, end time.Time) ([]*models.Operation, error) {
-	return a.repo.GetOperationsByDateRange(start, end)
+func (a *OperationRepositoryAdapter) GetByDateRange(ctx context.Context, start, end time.Time) ([]*models.Operation, error) {
+	return a.repo.GetOperationsByDateRange(ctx, start, end)
 }
 
 // GetByTypeAndDateRange получает операции определенного типа в указанном диапазоне дат
-func (a *OperationRepositoryAdapter) GetByTypeAndDateRange(opType models.OperationType, start, end time.Time) ([]*models.Operation, error) {
-	return a.repo.GetOperationsByTypeAndDateRange(opType, start, end)
+func (a *OperationRepositoryAdapter) GetByTypeAndDateRange(ctx context.Context, opType models.OperationType, start, end time.Time) ([]*models.Operation, error) {
+	return a.repo.GetOperationsByTypeAndDateRange(ctx, opType, start, end)
+}
+
+// Find возвращает страницу операций, подходящих под запрос
+func (a *OperationRepositoryAdapter) Find(ctx context.Context, query models.OperationQuery) (*models.OperationPage, error) {
+	return a.repo.FindOperations(ctx, query)
 }
@@ -0,0 +1,796 @@
+package persistence
+
+import (
+	"KPO1/domain/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Имена последовательностей ID в таблице id_sequences
+const (
+	bankAccountSequence = "bank_account"
+	categorySequence    = "category"
+	operationSequence   = "operation"
+)
+
+// SQLRepository реализация хранилища данных поверх database/sql.
+// Запросы используют плейсхолдеры "?" и рассчитаны на SQLite.
+type SQLRepository struct {
+	sqlStore
+}
+
+// sqlQuerier общий интерфейс для sql.DB и sql.Tx
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// sqlStore операции хранилища, выполняемые либо напрямую в базе данных,
+// либо внутри открытой транзакции
+type sqlStore struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+// sqlTx транзакция хранилища в базе данных
+type sqlTx struct {
+	sqlStore
+}
+
+// NewSQLRepository открывает базу данных и применяет к ней миграции схемы
+func NewSQLRepository(driverName, dataSourceName string) (*SQLRepository, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия базы данных: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ошибка подключения к базе данных: %w", err)
+	}
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLRepository{sqlStore{db: db}}, nil
+}
+
+// Close закрывает соединение с базой данных
+func (r *SQLRepository) Close() error {
+	return r.db.Close()
+}
+
+// BeginTx начинает транзакцию базы данных
+func (r *SQLRepository) BeginTx(ctx context.Context) (StoreTx, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{sqlStore{db: r.db, tx: tx}}, nil
+}
+
+// Commit фиксирует транзакцию
+func (t *sqlTx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback откатывает транзакцию. После фиксации ничего не делает.
+func (t *sqlTx) Rollback() error {
+	if err := t.tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return err
+	}
+	return nil
+}
+
+// q возвращает транзакцию, если она открыта, иначе базу данных
+func (r *sqlStore) q() sqlQuerier {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.db
+}
+
+// NextID выдает следующий свободный ID сущности из последовательности в базе.
+// Вне транзакции ID резервируется сразу и больше не выдается.
+func (r *sqlStore) NextID(ctx context.Context, entity models.EntityKind) (int, error) {
+	sequence, err := sequenceOf(entity)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		id, err = reserveID(ctx, tx, sequence, 0)
+		return err
+	})
+	return id, err
+}
+
+// bankAccountColumns список колонок счета в порядке сканирования
+const bankAccountColumns = `id, name, balance, created_at, updated_at, version, deleted_at`
+
+// GetBankAccountByID возвращает банковский счет по его ID
+func (r *sqlStore) GetBankAccountByID(ctx context.Context, id int) (*models.BankAccount, error) {
+	row := r.q().QueryRowContext(ctx, `SELECT `+bankAccountColumns+` FROM bank_accounts WHERE id = ? AND deleted_at = 0`, id)
+
+	account, err := scanBankAccount(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewNotFoundError(models.EntityBankAccount, id)
+	}
+	return account, err
+}
+
+// GetAllBankAccounts возвращает все банковские счета
+func (r *sqlStore) GetAllBankAccounts(ctx context.Context) ([]*models.BankAccount, error) {
+	return r.queryBankAccounts(ctx, `SELECT `+bankAccountColumns+` FROM bank_accounts WHERE deleted_at = 0 ORDER BY id`)
+}
+
+// SaveBankAccount сохраняет банковский счет
+func (r *sqlStore) SaveBankAccount(ctx context.Context, account *models.BankAccount) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		id, err := reserveID(ctx, tx, bankAccountSequence, account.ID)
+		if err != nil {
+			return err
+		}
+		version, err := savedVersion(ctx, tx, "bank_accounts", id, account.Version)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO bank_accounts (`+bankAccountColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET
+				name = excluded.name,
+				balance = excluded.balance,
+				created_at = excluded.created_at,
+				updated_at = excluded.updated_at,
+				version = excluded.version,
+				deleted_at = excluded.deleted_at`,
+			id, account.Name, account.Balance, toDBTime(account.CreatedAt), toDBTime(account.UpdatedAt), version,
+			toDBDeletedAt(account.DeletedAt))
+		if err != nil {
+			return err
+		}
+
+		account.ID, account.Version = id, version
+		return nil
+	})
+}
+
+// UpdateBankAccount обновляет банковский счет, если его версия не изменилась
+func (r *sqlStore) UpdateBankAccount(ctx context.Context, account *models.BankAccount) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `UPDATE bank_accounts SET name = ?, balance = ?, created_at = ?, updated_at = ?, version = version + 1
+			WHERE id = ? AND version = ? AND deleted_at = 0`,
+			account.Name, account.Balance, toDBTime(account.CreatedAt), toDBTime(account.UpdatedAt), account.ID, account.Version)
+		if err := checkVersion(ctx, tx, result, err, "bank_accounts", models.EntityBankAccount, account.ID, account.Version); err != nil {
+			return err
+		}
+
+		account.Version++
+		return nil
+	})
+}
+
+// DeleteBankAccount перемещает банковский счет в корзину
+func (r *sqlStore) DeleteBankAccount(ctx context.Context, id int) error {
+	result, err := r.q().ExecContext(ctx, `UPDATE bank_accounts SET deleted_at = ?, version = version + 1 WHERE id = ? AND deleted_at = 0`,
+		time.Now().UnixNano(), id)
+	return checkAffected(result, err, models.NewNotFoundError(models.EntityBankAccount, id))
+}
+
+// GetDeletedBankAccounts возвращает банковские счета из корзины
+func (r *sqlStore) GetDeletedBankAccounts(ctx context.Context) ([]*models.BankAccount, error) {
+	return r.queryBankAccounts(ctx, `SELECT `+bankAccountColumns+` FROM bank_accounts WHERE deleted_at <> 0 ORDER BY id`)
+}
+
+// RestoreBankAccount восстанавливает банковский счет из корзины
+func (r *sqlStore) RestoreBankAccount(ctx context.Context, id int) error {
+	result, err := r.q().ExecContext(ctx, `UPDATE bank_accounts SET deleted_at = 0, version = version + 1 WHERE id = ? AND deleted_at <> 0`, id)
+	return checkAffected(result, err, models.NewNotInTrashError(models.EntityBankAccount, id))
+}
+
+// PurgeBankAccounts окончательно удаляет банковские счета, перемещенные в корзину раньше deletedBefore
+func (r *sqlStore) PurgeBankAccounts(ctx context.Context, deletedBefore time.Time) (int, error) {
+	result, err := r.q().ExecContext(ctx, `DELETE FROM bank_accounts WHERE deleted_at <> 0 AND deleted_at < ?`, deletedBefore.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	return int(purged), err
+}
+
+// categoryColumns список колонок категории в порядке сканирования
+const categoryColumns = `id, type, name, created_at, updated_at, version, deleted_at`
+
+// GetCategoryByID возвращает категорию по её ID
+func (r *sqlStore) GetCategoryByID(ctx context.Context, id int) (*models.Category, error) {
+	row := r.q().QueryRowContext(ctx, `SELECT `+categoryColumns+` FROM categories WHERE id = ? AND deleted_at = 0`, id)
+
+	category, err := scanCategory(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewNotFoundError(models.EntityCategory, id)
+	}
+	return category, err
+}
+
+// GetAllCategories возвращает все категории
+func (r *sqlStore) GetAllCategories(ctx context.Context) ([]*models.Category, error) {
+	return r.queryCategories(ctx, `SELECT `+categoryColumns+` FROM categories WHERE deleted_at = 0 ORDER BY id`)
+}
+
+// GetCategoriesByType возвращает категории определенного типа
+func (r *sqlStore) GetCategoriesByType(ctx context.Context, opType models.OperationType) ([]*models.Category, error) {
+	return r.queryCategories(ctx, `SELECT `+categoryColumns+` FROM categories WHERE type = ? AND deleted_at = 0 ORDER BY id`, string(opType))
+}
+
+// SaveCategory сохраняет категорию
+func (r *sqlStore) SaveCategory(ctx context.Context, category *models.Category) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		id, err := reserveID(ctx, tx, categorySequence, category.ID)
+		if err != nil {
+			return err
+		}
+		version, err := savedVersion(ctx, tx, "categories", id, category.Version)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO categories (`+categoryColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET
+				type = excluded.type,
+				name = excluded.name,
+				created_at = excluded.created_at,
+				updated_at = excluded.updated_at,
+				version = excluded.version,
+				deleted_at = excluded.deleted_at`,
+			id, string(category.Type), category.Name, toDBTime(category.CreatedAt), toDBTime(category.UpdatedAt), version,
+			toDBDeletedAt(category.DeletedAt))
+		if err != nil {
+			return err
+		}
+
+		category.ID, category.Version = id, version
+		return nil
+	})
+}
+
+// UpdateCategory обновляет категорию, если ее версия не изменилась
+func (r *sqlStore) UpdateCategory(ctx context.Context, category *models.Category) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `UPDATE categories SET type = ?, name = ?, created_at = ?, updated_at = ?, version = version + 1
+			WHERE id = ? AND version = ? AND deleted_at = 0`,
+			string(category.Type), category.Name, toDBTime(category.CreatedAt), toDBTime(category.UpdatedAt), category.ID, category.Version)
+		if err := checkVersion(ctx, tx, result, err, "categories", models.EntityCategory, category.ID, category.Version); err != nil {
+			return err
+		}
+
+		category.Version++
+		return nil
+	})
+}
+
+// DeleteCategory перемещает категорию в корзину
+func (r *sqlStore) DeleteCategory(ctx context.Context, id int) error {
+	result, err := r.q().ExecContext(ctx, `UPDATE categories SET deleted_at = ?, version = version + 1 WHERE id = ? AND deleted_at = 0`,
+		time.Now().UnixNano(), id)
+	return checkAffected(result, err, models.NewNotFoundError(models.EntityCategory, id))
+}
+
+// GetDeletedCategories возвращает категории из корзины
+func (r *sqlStore) GetDeletedCategories(ctx context.Context) ([]*models.Category, error) {
+	return r.queryCategories(ctx, `SELECT `+categoryColumns+` FROM categories WHERE deleted_at <> 0 ORDER BY id`)
+}
+
+// RestoreCategory восстанавливает категорию из корзины
+func (r *sqlStore) RestoreCategory(ctx context.Context, id int) error {
+	result, err := r.q().ExecContext(ctx, `UPDATE categories SET deleted_at = 0, version = version + 1 WHERE id = ? AND deleted_at <> 0`, id)
+	return checkAffected(result, err, models.NewNotInTrashError(models.EntityCategory, id))
+}
+
+// PurgeCategories окончательно удаляет категории, перемещенные в корзину раньше deletedBefore
+func (r *sqlStore) PurgeCategories(ctx context.Context, deletedBefore time.Time) (int, error) {
+	result, err := r.q().ExecContext(ctx, `DELETE FROM categories WHERE deleted_at <> 0 AND deleted_at < ?`, deletedBefore.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	return int(purged), err
+}
+
+// operationColumns список колонок операции в порядке сканирования
+const operationColumns = `id, type, bank_account_id, category_id, amount, date, description, created_at, version, deleted_at, external_id`
+
+// GetOperationByID возвращает операцию по её ID
+func (r *sqlStore) GetOperationByID(ctx context.Context, id int) (*models.Operation, error) {
+	row := r.q().QueryRowContext(ctx, `SELECT `+operationColumns+` FROM operations WHERE id = ? AND deleted_at = 0`, id)
+
+	operation, err := scanOperation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewNotFoundError(models.EntityOperation, id)
+	}
+	return operation, err
+}
+
+// GetAllOperations возвращает все операции
+func (r *sqlStore) GetAllOperations(ctx context.Context) ([]*models.Operation, error) {
+	return r.queryOperations(ctx, `SELECT `+operationColumns+` FROM operations WHERE deleted_at = 0 ORDER BY id`)
+}
+
+// GetOperationsByBankAccountID возвращает операции по ID банковского счета
+func (r *sqlStore) GetOperationsByBankAccountID(ctx context.Context, bankAccountID int) ([]*models.Operation, error) {
+	return r.queryOperations(ctx, `SELECT `+operationColumns+` FROM operations WHERE bank_account_id = ? AND deleted_at = 0 ORDER BY id`, bankAccountID)
+}
+
+// GetOperationsByCategoryID возвращает операции по ID категории
+func (r *sqlStore) GetOperationsByCategoryID(ctx context.Context, categoryID int) ([]*models.Operation, error) {
+	return r.queryOperations(ctx, `SELECT `+operationColumns+` FROM operations WHERE category_id = ? AND deleted_at = 0 ORDER BY id`, categoryID)
+}
+
+// GetOperationsByDateRange возвращает операции в указанном диапазоне дат
+func (r *sqlStore) GetOperationsByDateRange(ctx context.Context, start, end time.Time) ([]*models.Operation, error) {
+	return r.queryOperations(ctx, `SELECT `+operationColumns+` FROM operations WHERE date >= ? AND date <= ? AND deleted_at = 0 ORDER BY date, id`,
+		toDBTime(start), toDBTime(end))
+}
+
+// GetOperationsByTypeAndDateRange возвращает операции определенного типа в указанном диапазоне дат
+func (r *sqlStore) GetOperationsByTypeAndDateRange(ctx context.Context, opType models.OperationType, start, end time.Time) ([]*models.Operation, error) {
+	return r.queryOperations(ctx, `SELECT `+operationColumns+` FROM operations
+		WHERE type = ? AND date >= ? AND date <= ? AND deleted_at = 0 ORDER BY date, id`,
+		string(opType), toDBTime(start), toDBTime(end))
+}
+
+// FindOperations возвращает страницу операций, подходящих под запрос.
+// Фильтр, сортировка и страница выполняются в базе данных, кроме поиска по описанию:
+// LOWER в SQLite меняет регистр только латиницы, поэтому такой запрос дорабатывается в Go.
+func (r *sqlStore) FindOperations(ctx context.Context, query models.OperationQuery) (*models.OperationPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	where, args := operationFilter(query)
+	if query.Description != "" {
+		operations, err := r.queryOperations(ctx, `SELECT `+operationColumns+` FROM operations WHERE `+where, args...)
+		if err != nil {
+			return nil, err
+		}
+		return pageOperations(ctx, query, operations)
+	}
+
+	page := &models.OperationPage{}
+	if err := r.q().QueryRowContext(ctx, `SELECT COUNT(*) FROM operations WHERE `+where, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	column := string(query.SortField())
+	direction, after := ` ASC`, `>`
+	if query.Descending {
+		direction, after = ` DESC`, `<`
+	}
+
+	if key, _ := query.CursorKey(); key != nil {
+		value := operationSortValue(query.SortField(), key)
+		where += ` AND (` + column + ` ` + after + ` ? OR (` + column + ` = ? AND id ` + after + ` ?))`
+		args = append(args, value, value, key.ID)
+	}
+
+	sqlQuery := `SELECT ` + operationColumns + ` FROM operations WHERE ` + where +
+		` ORDER BY ` + column + direction + `, id` + direction
+	switch {
+	case query.Limit > 0:
+		// Читаем на одну операцию больше, чтобы узнать, есть ли следующая страница
+		sqlQuery += ` LIMIT ?`
+		args = append(args, query.Limit+1)
+	case query.Offset > 0:
+		sqlQuery += ` LIMIT -1`
+	}
+	if query.Offset > 0 {
+		sqlQuery += ` OFFSET ?`
+		args = append(args, query.Offset)
+	}
+
+	operations, err := r.queryOperations(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if query.Limit > 0 && len(operations) > query.Limit {
+		operations = operations[:query.Limit]
+		page.NextCursor = query.NextCursor(operations[len(operations)-1])
+	}
+	page.Operations = operations
+	return page, nil
+}
+
+// SaveOperation сохраняет операцию
+func (r *sqlStore) SaveOperation(ctx context.Context, operation *models.Operation) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		id, err := reserveID(ctx, tx, operationSequence, operation.ID)
+		if err != nil {
+			return err
+		}
+		version, err := savedVersion(ctx, tx, "operations", id, operation.Version)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO operations (`+operationColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET
+				type = excluded.type,
+				bank_account_id = excluded.bank_account_id,
+				category_id = excluded.category_id,
+				amount = excluded.amount,
+				date = excluded.date,
+				description = excluded.description,
+				created_at = excluded.created_at,
+				version = excluded.version,
+				deleted_at = excluded.deleted_at,
+				external_id = excluded.external_id`,
+			id, string(operation.Type), operation.BankAccountID, operation.CategoryID,
+			operation.Amount, toDBTime(operation.Date), operation.Description, toDBTime(operation.CreatedAt), version,
+			toDBDeletedAt(operation.DeletedAt), operation.ExternalID)
+		if err != nil {
+			return err
+		}
+
+		operation.ID, operation.Version = id, version
+		return nil
+	})
+}
+
+// UpdateOperation обновляет операцию, если ее версия не изменилась
+func (r *sqlStore) UpdateOperation(ctx context.Context, operation *models.Operation) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `UPDATE operations SET type = ?, bank_account_id = ?, category_id = ?, amount = ?,
+				date = ?, description = ?, created_at = ?, external_id = ?, version = version + 1
+				WHERE id = ? AND version = ? AND deleted_at = 0`,
+			string(operation.Type), operation.BankAccountID, operation.CategoryID, operation.Amount,
+			toDBTime(operation.Date), operation.Description, toDBTime(operation.CreatedAt), operation.ExternalID,
+			operation.ID, operation.Version)
+		if err := checkVersion(ctx, tx, result, err, "operations", models.EntityOperation, operation.ID, operation.Version); err != nil {
+			return err
+		}
+
+		operation.Version++
+		return nil
+	})
+}
+
+// DeleteOperation перемещает операцию в корзину
+func (r *sqlStore) DeleteOperation(ctx context.Context, id int) error {
+	result, err := r.q().ExecContext(ctx, `UPDATE operations SET deleted_at = ?, version = version + 1 WHERE id = ? AND deleted_at = 0`,
+		time.Now().UnixNano(), id)
+	return checkAffected(result, err, models.NewNotFoundError(models.EntityOperation, id))
+}
+
+// GetDeletedOperations возвращает операции из корзины
+func (r *sqlStore) GetDeletedOperations(ctx context.Context) ([]*models.Operation, error) {
+	return r.queryOperations(ctx, `SELECT `+operationColumns+` FROM operations WHERE deleted_at <> 0 ORDER BY id`)
+}
+
+// RestoreOperation восстанавливает операцию из корзины
+func (r *sqlStore) RestoreOperation(ctx context.Context, id int) error {
+	result, err := r.q().ExecContext(ctx, `UPDATE operations SET deleted_at = 0, version = version + 1 WHERE id = ? AND deleted_at <> 0`, id)
+	return checkAffected(result, err, models.NewNotInTrashError(models.EntityOperation, id))
+}
+
+// PurgeOperations окончательно удаляет операции, перемещенные в корзину раньше deletedBefore
+func (r *sqlStore) PurgeOperations(ctx context.Context, deletedBefore time.Time) (int, error) {
+	result, err := r.q().ExecContext(ctx, `DELETE FROM operations WHERE deleted_at <> 0 AND deleted_at < ?`, deletedBefore.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	return int(purged), err
+}
+
+// inTx выполняет функцию в транзакции базы данных, используя уже открытую транзакцию
+func (r *sqlStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// queryBankAccounts выполняет запрос и читает из него банковские счета
+func (r *sqlStore) queryBankAccounts(ctx context.Context, query string, args ...interface{}) ([]*models.BankAccount, error) {
+	rows, err := r.q().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := make([]*models.BankAccount, 0)
+	for rows.Next() {
+		account, err := scanBankAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// queryCategories выполняет запрос и читает из него категории
+func (r *sqlStore) queryCategories(ctx context.Context, query string, args ...interface{}) ([]*models.Category, error) {
+	rows, err := r.q().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make([]*models.Category, 0)
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+// queryOperations выполняет запрос и читает из него операции
+func (r *sqlStore) queryOperations(ctx context.Context, query string, args ...interface{}) ([]*models.Operation, error) {
+	rows, err := r.q().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	operations := make([]*models.Operation, 0)
+	for rows.Next() {
+		operation, err := scanOperation(rows)
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, operation)
+	}
+	return operations, rows.Err()
+}
+
+// operationFilter возвращает условие WHERE для фильтра запроса операций, кроме поиска по описанию
+func operationFilter(query models.OperationQuery) (string, []interface{}) {
+	conditions := []string{`deleted_at = 0`}
+	args := make([]interface{}, 0)
+
+	if query.BankAccountID != 0 {
+		conditions = append(conditions, `bank_account_id = ?`)
+		args = append(args, query.BankAccountID)
+	}
+	if query.CategoryID != 0 {
+		conditions = append(conditions, `category_id = ?`)
+		args = append(args, query.CategoryID)
+	}
+	if query.Type != "" {
+		conditions = append(conditions, `type = ?`)
+		args = append(args, string(query.Type))
+	}
+	if query.MinAmount != nil {
+		conditions = append(conditions, `amount >= ?`)
+		args = append(args, *query.MinAmount)
+	}
+	if query.MaxAmount != nil {
+		conditions = append(conditions, `amount <= ?`)
+		args = append(args, *query.MaxAmount)
+	}
+	if !query.From.IsZero() {
+		conditions = append(conditions, `date >= ?`)
+		args = append(args, toDBTime(query.From))
+	}
+	if !query.To.IsZero() {
+		conditions = append(conditions, `date <= ?`)
+		args = append(args, toDBTime(query.To))
+	}
+
+	return strings.Join(conditions, ` AND `), args
+}
+
+// operationSortValue возвращает значение поля сортировки операции в том виде, в котором оно хранится в базе
+func operationSortValue(field models.OperationSortField, operation *models.Operation) interface{} {
+	switch field {
+	case models.SortOperationsByDate:
+		return toDBTime(operation.Date)
+	case models.SortOperationsByAmount:
+		return operation.Amount
+	case models.SortOperationsByType:
+		return string(operation.Type)
+	case models.SortOperationsByBankAccount:
+		return operation.BankAccountID
+	case models.SortOperationsByCategory:
+		return operation.CategoryID
+	case models.SortOperationsByDescription:
+		return operation.Description
+	case models.SortOperationsByCreatedAt:
+		return toDBTime(operation.CreatedAt)
+	default:
+		return operation.ID
+	}
+}
+
+// sequenceOf возвращает имя последовательности ID для сущности
+func sequenceOf(entity models.EntityKind) (string, error) {
+	switch entity {
+	case models.EntityBankAccount:
+		return bankAccountSequence, nil
+	case models.EntityCategory:
+		return categorySequence, nil
+	case models.EntityOperation:
+		return operationSequence, nil
+	default:
+		return "", fmt.Errorf("неизвестный тип сущности: %s", entity)
+	}
+}
+
+// reserveID выдает новый ID из последовательности или сдвигает ее за уже заданный ID
+func reserveID(ctx context.Context, tx *sql.Tx, sequence string, id int) (int, error) {
+	if id == 0 {
+		if err := tx.QueryRowContext(ctx, `SELECT next_id FROM id_sequences WHERE entity = ?`, sequence).Scan(&id); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err := tx.ExecContext(ctx, `UPDATE id_sequences SET next_id = ? WHERE entity = ? AND next_id <= ?`, id+1, sequence, id)
+	return id, err
+}
+
+// savedVersion возвращает версию сохраняемой записи: следующую за текущей,
+// если запись с таким ID уже есть, иначе заданную или первую
+func savedVersion(ctx context.Context, tx *sql.Tx, table string, id, version int) (int, error) {
+	var current int
+	err := tx.QueryRowContext(ctx, `SELECT version FROM `+table+` WHERE id = ?`, id).Scan(&current)
+	switch {
+	case err == nil:
+		return current + 1, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return 0, err
+	case version == 0:
+		return 1, nil
+	default:
+		return version, nil
+	}
+}
+
+// checkVersion различает отсутствие записи и конфликт версий, если обновление не изменило ни одной строки
+func checkVersion(ctx context.Context, tx *sql.Tx, result sql.Result, err error, table string, entity models.EntityKind, id, version int) error {
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected > 0 {
+		return err
+	}
+
+	var current int
+	err = tx.QueryRowContext(ctx, `SELECT version FROM `+table+` WHERE id = ? AND deleted_at = 0`, id).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.NewNotFoundError(entity, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	return &models.ConflictError{Entity: entity, ID: id, ExpectedVersion: version, ActualVersion: current}
+}
+
+// rowScanner общий интерфейс для sql.Row и sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanBankAccount читает банковский счет из строки результата
+func scanBankAccount(row rowScanner) (*models.BankAccount, error) {
+	var account models.BankAccount
+	var createdAt, updatedAt, deletedAt int64
+	err := row.Scan(&account.ID, &account.Name, &account.Balance, &createdAt, &updatedAt, &account.Version, &deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	account.CreatedAt = fromDBTime(createdAt)
+	account.UpdatedAt = fromDBTime(updatedAt)
+	account.DeletedAt = fromDBDeletedAt(deletedAt)
+	return &account, nil
+}
+
+// scanCategory читает категорию из строки результата
+func scanCategory(row rowScanner) (*models.Category, error) {
+	var category models.Category
+	var opType string
+	var createdAt, updatedAt, deletedAt int64
+	err := row.Scan(&category.ID, &opType, &category.Name, &createdAt, &updatedAt, &category.Version, &deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	category.Type = models.OperationType(opType)
+	category.CreatedAt = fromDBTime(createdAt)
+	category.UpdatedAt = fromDBTime(updatedAt)
+	category.DeletedAt = fromDBDeletedAt(deletedAt)
+	return &category, nil
+}
+
+// scanOperation читает операцию из строки результата
+func scanOperation(row rowScanner) (*models.Operation, error) {
+	var operation models.Operation
+	var opType string
+	var date, createdAt, deletedAt int64
+	err := row.Scan(&operation.ID, &opType, &operation.BankAccountID, &operation.CategoryID,
+		&operation.Amount, &date, &operation.Description, &createdAt, &operation.Version, &deletedAt, &operation.ExternalID)
+	if err != nil {
+		return nil, err
+	}
+	operation.Type = models.OperationType(opType)
+	operation.Date = fromDBTime(date)
+	operation.CreatedAt = fromDBTime(createdAt)
+	operation.DeletedAt = fromDBDeletedAt(deletedAt)
+	return &operation, nil
+}
+
+// checkAffected возвращает ошибку notFound, если запрос не изменил ни одной строки
+func checkAffected(result sql.Result, err error, notFound error) error {
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// toDBTime переводит время в наносекунды Unix; нулевое время хранится как 0
+func toDBTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// fromDBTime восстанавливает время из наносекунд Unix
+func fromDBTime(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// toDBDeletedAt переводит время удаления в наносекунды Unix; 0 означает, что запись не удалена
+func toDBDeletedAt(deletedAt *time.Time) int64 {
+	if deletedAt == nil {
+		return 0
+	}
+	return deletedAt.UnixNano()
+}
+
+// fromDBDeletedAt восстанавливает время удаления; для неудаленной записи возвращает nil
+func fromDBDeletedAt(ns int64) *time.Time {
+	if ns == 0 {
+		return nil
+	}
+	t := time.Unix(0, ns)
+	return &t
+}
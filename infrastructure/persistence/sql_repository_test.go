@@ -0,0 +1,299 @@
+package persistence
+
+import (
+	"KPO1/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteDSN возвращает строку подключения к файлу SQLite во временной директории теста
+func sqliteDSN(t *testing.T) string {
+	t.Helper()
+	return "file:" + filepath.Join(t.TempDir(), "ledger.db") + "?_pragma=busy_timeout(5000)&_txlock=immediate"
+}
+
+// openSQLRepository открывает SQL-хранилище и закрывает его по окончании теста
+func openSQLRepository(t *testing.T, dsn string) *SQLRepository {
+	t.Helper()
+	repo, err := NewSQLRepository("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("NewSQLRepository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+// operationIDs возвращает ID операций в порядке следования
+func operationIDs(operations []*models.Operation) []int {
+	ids := make([]int, len(operations))
+	for i, operation := range operations {
+		ids[i] = operation.ID
+	}
+	return ids
+}
+
+func TestSQLRepositoryMigrations(t *testing.T) {
+	dsn := sqliteDSN(t)
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := openSQLRepository(t, dsn)
+	var applied int
+	if err := repo.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(migrations) {
+		t.Fatalf("applied %d migrations on a fresh database, want %d", applied, len(migrations))
+	}
+	account := &models.BankAccount{Name: "Основной", Balance: 100}
+	if err := repo.SaveBankAccount(context.Background(), account); err != nil {
+		t.Fatal(err)
+	}
+	repo.Close()
+
+	// Повторное открытие не применяет миграции заново и сохраняет данные
+	reopened := openSQLRepository(t, dsn)
+	if err := reopened.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(migrations) {
+		t.Errorf("applied %d migrations after re-open, want %d", applied, len(migrations))
+	}
+	got, err := reopened.GetBankAccountByID(context.Background(), account.ID)
+	if err != nil {
+		t.Fatalf("GetBankAccountByID after re-open: %v", err)
+	}
+	if got.Name != "Основной" || got.Balance != 100 || got.Version != 1 {
+		t.Errorf("account after re-open = %+v", got)
+	}
+}
+
+func TestSQLRepositoryCRUDAndConflict(t *testing.T) {
+	ctx := context.Background()
+	repo := openSQLRepository(t, sqliteDSN(t))
+
+	account := &models.BankAccount{Name: "Основной"}
+	if err := repo.SaveBankAccount(ctx, account); err != nil {
+		t.Fatal(err)
+	}
+	if account.ID != 1 || account.Version != 1 {
+		t.Fatalf("saved account = %+v, want ID 1 and version 1", account)
+	}
+
+	stale := *account
+	account.Name = "Зарплатный"
+	if err := repo.UpdateBankAccount(ctx, account); err != nil {
+		t.Fatalf("UpdateBankAccount: %v", err)
+	}
+	if account.Version != 2 {
+		t.Errorf("version after update = %d, want 2", account.Version)
+	}
+
+	stale.Name = "Устаревший"
+	err := repo.UpdateBankAccount(ctx, &stale)
+	var conflict *models.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("update with stale version: error = %v, want ConflictError", err)
+	}
+	if conflict.ExpectedVersion != 1 || conflict.ActualVersion != 2 {
+		t.Errorf("conflict = %+v, want expected 1 and actual 2", conflict)
+	}
+	if got, _ := repo.GetBankAccountByID(ctx, account.ID); got.Name != "Зарплатный" {
+		t.Errorf("name after conflict = %q, stale update must not be applied", got.Name)
+	}
+
+	category := &models.Category{Type: models.Expense, Name: "Кафе"}
+	if err := repo.SaveCategory(ctx, category); err != nil {
+		t.Fatal(err)
+	}
+	operation := &models.Operation{
+		Type:          models.Expense,
+		BankAccountID: account.ID,
+		CategoryID:    category.ID,
+		Amount:        350,
+		Date:          time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC),
+		Description:   "Кофе",
+		ExternalID:    "FIT-1",
+	}
+	if err := repo.SaveOperation(ctx, operation); err != nil {
+		t.Fatal(err)
+	}
+
+	operation.Amount = 400
+	operation.ExternalID = "FIT-2"
+	if err := repo.UpdateOperation(ctx, operation); err != nil {
+		t.Fatalf("UpdateOperation: %v", err)
+	}
+	got, err := repo.GetOperationByID(ctx, operation.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Amount != 400 || got.ExternalID != "FIT-2" || got.Version != 2 {
+		t.Errorf("operation after update = %+v, want amount 400, external ID FIT-2 and version 2", got)
+	}
+	if !got.Date.Equal(operation.Date) {
+		t.Errorf("date = %v, want %v", got.Date, operation.Date)
+	}
+
+	var notFound *models.NotFoundError
+	if _, err := repo.GetOperationByID(ctx, 999); !errors.As(err, &notFound) {
+		t.Errorf("GetOperationByID(999) error = %v, want NotFoundError", err)
+	}
+}
+
+func TestSQLRepositorySoftDelete(t *testing.T) {
+	ctx := context.Background()
+	repo := openSQLRepository(t, sqliteDSN(t))
+
+	for _, amount := range []float64{100, 200} {
+		operation := &models.Operation{Type: models.Income, BankAccountID: 1, CategoryID: 1, Amount: amount, Date: time.Now()}
+		if err := repo.SaveOperation(ctx, operation); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repo.DeleteOperation(ctx, 1); err != nil {
+		t.Fatalf("DeleteOperation: %v", err)
+	}
+	var notFound *models.NotFoundError
+	if _, err := repo.GetOperationByID(ctx, 1); !errors.As(err, &notFound) {
+		t.Errorf("deleted operation is still visible: %v", err)
+	}
+	if err := repo.DeleteOperation(ctx, 1); !errors.As(err, &notFound) {
+		t.Errorf("second delete error = %v, want NotFoundError", err)
+	}
+	live, _ := repo.GetAllOperations(ctx)
+	deleted, _ := repo.GetDeletedOperations(ctx)
+	if fmt.Sprint(operationIDs(live)) != "[2]" || fmt.Sprint(operationIDs(deleted)) != "[1]" {
+		t.Fatalf("live %v, deleted %v after delete", operationIDs(live), operationIDs(deleted))
+	}
+
+	if err := repo.RestoreOperation(ctx, 1); err != nil {
+		t.Fatalf("RestoreOperation: %v", err)
+	}
+	if err := repo.RestoreOperation(ctx, 1); !errors.As(err, &notFound) || !notFound.InTrash {
+		t.Errorf("restore of a live operation error = %v, want not-in-trash NotFoundError", err)
+	}
+	live, _ = repo.GetAllOperations(ctx)
+	if fmt.Sprint(operationIDs(live)) != "[1 2]" {
+		t.Fatalf("live %v after restore", operationIDs(live))
+	}
+
+	if err := repo.DeleteOperation(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if purged, err := repo.PurgeOperations(ctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Errorf("purge of older operations = %d, %v; want nothing purged", purged, err)
+	}
+	if purged, err := repo.PurgeOperations(ctx, time.Now().Add(time.Second)); err != nil || purged != 1 {
+		t.Errorf("PurgeOperations = %d, %v; want 1", purged, err)
+	}
+	deleted, _ = repo.GetDeletedOperations(ctx)
+	live, _ = repo.GetAllOperations(ctx)
+	if len(deleted) != 0 || fmt.Sprint(operationIDs(live)) != "[1]" {
+		t.Errorf("live %v, deleted %v after purge", operationIDs(live), operationIDs(deleted))
+	}
+}
+
+func TestSQLRepositoryFindOperations(t *testing.T) {
+	ctx := context.Background()
+	repo := openSQLRepository(t, sqliteDSN(t))
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC) }
+	for _, operation := range []*models.Operation{
+		{Type: models.Expense, BankAccountID: 1, CategoryID: 2, Amount: 300, Date: day(1), Description: "Кофе у дома"},
+		{Type: models.Expense, BankAccountID: 1, CategoryID: 2, Amount: 150, Date: day(2), Description: "КОФЕ в офисе"},
+		{Type: models.Income, BankAccountID: 1, CategoryID: 1, Amount: 5000, Date: day(3), Description: "Зарплата"},
+		{Type: models.Expense, BankAccountID: 2, CategoryID: 2, Amount: 300, Date: day(4), Description: "кофе с собой"},
+		{Type: models.Expense, BankAccountID: 1, CategoryID: 3, Amount: 300, Date: day(5), Description: "Аптека"},
+		{Type: models.Expense, BankAccountID: 1, CategoryID: 2, Amount: 90, Date: day(6), Description: "Кофе"},
+		{Type: models.Expense, BankAccountID: 1, CategoryID: 2, Amount: 300, Date: day(7), Description: "Кофейня"},
+	} {
+		if err := repo.SaveOperation(ctx, operation); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.DeleteOperation(ctx, 7); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name  string
+		query models.OperationQuery
+		total int
+		pages string
+	}{
+		{
+			name:  "account and type sorted by amount descending",
+			query: models.OperationQuery{BankAccountID: 1, Type: models.Expense, SortBy: models.SortOperationsByAmount, Descending: true, Limit: 2},
+			total: 4,
+			pages: "[[5 1] [2 6]]",
+		},
+		{
+			name:  "date range sorted by date",
+			query: models.OperationQuery{From: day(2), To: day(5), SortBy: models.SortOperationsByDate, Limit: 3},
+			total: 4,
+			pages: "[[2 3 4] [5]]",
+		},
+		{
+			name:  "description ignores case of Cyrillic",
+			query: models.OperationQuery{Description: "кофе", SortBy: models.SortOperationsByAmount, Limit: 2},
+			total: 4,
+			pages: "[[6 2] [1 4]]",
+		},
+		{
+			name:  "description with account filter",
+			query: models.OperationQuery{Description: "КОФЕ", BankAccountID: 1, Descending: true, Limit: 1},
+			total: 3,
+			pages: "[[6] [2] [1]]",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var pages [][]int
+			query := test.query
+			for {
+				page, err := repo.FindOperations(ctx, query)
+				if err != nil {
+					t.Fatalf("FindOperations: %v", err)
+				}
+				if page.Total != test.total {
+					t.Errorf("Total = %d, want %d", page.Total, test.total)
+				}
+				pages = append(pages, operationIDs(page.Operations))
+				if page.NextCursor == "" || len(pages) > 10 {
+					break
+				}
+				query.Cursor = page.NextCursor
+			}
+			if got := fmt.Sprint(pages); got != test.pages {
+				t.Errorf("pages = %s, want %s", got, test.pages)
+			}
+		})
+	}
+
+	// Курсор не сдвигается, если между страницами удалили уже показанную операцию
+	query := models.OperationQuery{BankAccountID: 1, SortBy: models.SortOperationsByDate, Limit: 2}
+	first, err := repo.FindOperations(ctx, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteOperation(ctx, first.Operations[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	query.Cursor = first.NextCursor
+	second, err := repo.FindOperations(ctx, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(operationIDs(second.Operations)); got != "[3 5]" {
+		t.Errorf("page after delete = %s, want [3 5]", got)
+	}
+}
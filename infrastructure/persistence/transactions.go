@@ -0,0 +1,94 @@
+package persistence
+
+import (
+	"KPO1/domain/interfaces"
+	"context"
+	"fmt"
+)
+
+// TransactionalStore представляет хранилище данных с поддержкой транзакций.
+// BeginTx ждет начала транзакции не дольше, чем живет контекст.
+type TransactionalStore interface {
+	DataStore
+	BeginTx(ctx context.Context) (StoreTx, error)
+}
+
+// StoreTx представляет транзакцию хранилища. Чтение внутри транзакции
+// видит ее собственные незафиксированные изменения.
+type StoreTx interface {
+	DataStore
+	Commit() error
+	Rollback() error
+}
+
+// Убедимся что хранилища поддерживают транзакции
+var (
+	_ TransactionalStore = (*MemoryRepository)(nil)
+	_ TransactionalStore = (*FileRepository)(nil)
+	_ TransactionalStore = (*SQLRepository)(nil)
+)
+
+// runInTx выполняет fn в транзакции хранилища: фиксирует ее при успехе и откатывает при ошибке
+func runInTx(ctx context.Context, store TransactionalStore, fn func(tx StoreTx) error) error {
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (ошибка отката транзакции: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// storeTransaction транзакция над репозиториями, адаптированными к транзакции хранилища
+type storeTransaction struct {
+	StoreTx
+	bankAccounts interfaces.BankAccountRepository
+	categories   interfaces.CategoryRepository
+	operations   interfaces.OperationRepository
+}
+
+// BankAccounts возвращает репозиторий счетов в рамках транзакции
+func (t *storeTransaction) BankAccounts() interfaces.BankAccountRepository {
+	return t.bankAccounts
+}
+
+// Categories возвращает репозиторий категорий в рамках транзакции
+func (t *storeTransaction) Categories() interfaces.CategoryRepository {
+	return t.categories
+}
+
+// Operations возвращает репозиторий операций в рамках транзакции
+func (t *storeTransaction) Operations() interfaces.OperationRepository {
+	return t.operations
+}
+
+// StoreTransactionManager создает транзакции над хранилищем данных
+type StoreTransactionManager struct {
+	store TransactionalStore
+}
+
+// NewTransactionManager создает менеджер транзакций для хранилища
+func NewTransactionManager(store TransactionalStore) *StoreTransactionManager {
+	return &StoreTransactionManager{store: store}
+}
+
+// Begin начинает транзакцию
+func (m *StoreTransactionManager) Begin(ctx context.Context) (interfaces.Transaction, error) {
+	tx, err := m.store.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+
+	return &storeTransaction{
+		StoreTx:      tx,
+		bankAccounts: NewBankAccountRepository(tx),
+		categories:   NewCategoryRepository(tx),
+		operations:   NewOperationRepository(tx),
+	}, nil
+}
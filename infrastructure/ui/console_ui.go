@@ -2,8 +2,10 @@ package ui
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
 )
 
@@ -19,9 +21,11 @@ func (c *ConsoleUI) SetMenu(menu *MainMenu) {
 	c.menu = menu
 }
 
-func (c *ConsoleUI) Run() error {
+// Run выполняет основной цикл меню, пока не будет выбран выход или отменен ctx.
+// Ctrl+C во время выполнения пункта меню прерывает только этот пункт.
+func (c *ConsoleUI) Run(ctx context.Context) error {
 	reader := bufio.NewReader(os.Stdin)
-	for {
+	for ctx.Err() == nil {
 		fmt.Println("\n--- Главное меню ---")
 		c.menu.Display()
 		fmt.Print("Выберите опцию: ")
@@ -34,10 +38,18 @@ func (c *ConsoleUI) Run() error {
 			fmt.Println("Выход из приложения.")
 			break
 		}
-		err = c.menu.HandleInput(input, reader)
+		err = c.handleInput(ctx, input, reader)
 		if err != nil {
 			fmt.Printf("Ошибка: %v\n", err)
 		}
 	}
 	return nil
 }
+
+// handleInput выполняет пункт меню в контексте, который отменяется по Ctrl+C
+func (c *ConsoleUI) handleInput(ctx context.Context, input string, reader *bufio.Reader) error {
+	actionCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	return c.menu.HandleInput(actionCtx, input, reader)
+}
@@ -2,11 +2,17 @@ package ui
 
 import (
 	"KPO1/application/commands"
+	"KPO1/application/facade"
 	"KPO1/di"
 	"KPO1/domain/interfaces"
 	"KPO1/domain/models"
+	"KPO1/infrastructure/importexport"
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -30,28 +36,32 @@ func (m *MainMenu) Display() {
 	fmt.Println("3. Управление операциями")
 	fmt.Println("4. Аналитика")
 	fmt.Println("5. Импорт/Экспорт данных")
+	fmt.Println("6. Корзина")
 	fmt.Println("0. Выход")
 }
 
-func (m *MainMenu) HandleInput(input string, reader *bufio.Reader) error {
+// HandleInput выполняет выбранный пункт главного меню. Отмена ctx прерывает выполняемую команду.
+func (m *MainMenu) HandleInput(ctx context.Context, input string, reader *bufio.Reader) error {
 	switch input {
 	case "1":
-		return m.bankAccountsMenu(reader)
+		return m.bankAccountsMenu(ctx, reader)
 	case "2":
-		return m.categoriesMenu(reader)
+		return m.categoriesMenu(ctx, reader)
 	case "3":
-		return m.operationsMenu(reader)
+		return m.operationsMenu(ctx, reader)
 	case "4":
-		return m.analyticsMenu(reader)
+		return m.analyticsMenu(ctx, reader)
 	case "5":
-		return m.importExportMenu(reader)
+		return m.importExportMenu(ctx, reader)
+	case "6":
+		return m.trashMenu(ctx, reader)
 	default:
 		fmt.Println("Неверный выбор. Повторите попытку.")
 	}
 	return nil
 }
 
-func (m *MainMenu) bankAccountsMenu(reader *bufio.Reader) error {
+func (m *MainMenu) bankAccountsMenu(ctx context.Context, reader *bufio.Reader) error {
 	fmt.Println("\n--- Управление банковскими счетами ---")
 	fmt.Println("1. Создать счет")
 	fmt.Println("2. Получить счет по ID")
@@ -59,6 +69,7 @@ func (m *MainMenu) bankAccountsMenu(reader *bufio.Reader) error {
 	fmt.Println("4. Обновить счет")
 	fmt.Println("5. Удалить счет")
 	fmt.Println("6. Пересчитать баланс")
+	fmt.Println("7. Сверить балансы с операциями")
 	fmt.Println("0. Назад")
 	fmt.Print("Выберите опцию: ")
 	input, _ := reader.ReadString('\n')
@@ -76,11 +87,11 @@ func (m *MainMenu) bankAccountsMenu(reader *bufio.Reader) error {
 			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
+		if err := cmd.Execute(ctx); err == nil {
 			account := <-resultCh
 			fmt.Printf("Создан счет: %+v\n", account)
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "2":
 		fmt.Print("Введите ID счета: ")
@@ -95,11 +106,11 @@ func (m *MainMenu) bankAccountsMenu(reader *bufio.Reader) error {
 			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
+		if err := cmd.Execute(ctx); err == nil {
 			account := <-resultCh
 			fmt.Printf("Счет: %+v\n", account)
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "3":
 		resultCh := make(chan []*models.BankAccount, 1)
@@ -109,20 +120,26 @@ func (m *MainMenu) bankAccountsMenu(reader *bufio.Reader) error {
 			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
+		if err := cmd.Execute(ctx); err == nil {
 			accounts := <-resultCh
 			fmt.Println("Список счетов:")
 			for _, acc := range accounts {
 				fmt.Printf("%+v\n", acc)
 			}
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "4":
 		fmt.Print("Введите ID счета для обновления: ")
 		idStr, _ := reader.ReadString('\n')
 		idStr = strings.TrimSpace(idStr)
 		id, _ := strconv.Atoi(idStr)
+		current, err := m.container.GetBankAccountFacade().GetBankAccount(ctx, id)
+		if err != nil {
+			printError(err)
+			return nil
+		}
+		fmt.Printf("Текущий счет: %+v\n", current)
 		fmt.Print("Введите новое название счета: ")
 		name, _ := reader.ReadString('\n')
 		name = strings.TrimSpace(name)
@@ -131,15 +148,16 @@ func (m *MainMenu) bankAccountsMenu(reader *bufio.Reader) error {
 		cmd := commands.NewUpdateBankAccountCommand(
 			m.container.GetBankAccountFacade(),
 			id,
+			current.Version,
 			name,
 			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
+		if err := cmd.Execute(ctx); err == nil {
 			account := <-resultCh
 			fmt.Printf("Обновленный счет: %+v\n", account)
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printUpdateError(<-errorCh)
 		}
 	case "5":
 		fmt.Print("Введите ID счета для удаления: ")
@@ -152,22 +170,24 @@ func (m *MainMenu) bankAccountsMenu(reader *bufio.Reader) error {
 			id,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
-			fmt.Println("Счет успешно удален.")
+		if err := cmd.Execute(ctx); err == nil {
+			fmt.Println("Счет перемещен в корзину.")
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "6":
 		fmt.Print("Введите ID счета для пересчета баланса: ")
 		idStr, _ := reader.ReadString('\n')
 		idStr = strings.TrimSpace(idStr)
 		id, _ := strconv.Atoi(idStr)
-		account, err := m.container.GetBankAccountFacade().RecalculateBalance(id)
+		account, err := m.container.GetBankAccountFacade().RecalculateBalance(ctx, id)
 		if err == nil {
 			fmt.Printf("Пересчитанный счет: %+v\n", account)
 		} else {
-			fmt.Printf("Ошибка: %v\n", err)
+			printError(err)
 		}
+	case "7":
+		m.reconcileBalances(ctx, reader)
 	case "0":
 		return nil
 	default:
@@ -176,7 +196,7 @@ func (m *MainMenu) bankAccountsMenu(reader *bufio.Reader) error {
 	return nil
 }
 
-func (m *MainMenu) categoriesMenu(reader *bufio.Reader) error {
+func (m *MainMenu) categoriesMenu(ctx context.Context, reader *bufio.Reader) error {
 	fmt.Println("\n--- Управление категориями ---")
 	fmt.Println("1. Создать категорию")
 	fmt.Println("2. Получить категорию по ID")
@@ -211,11 +231,11 @@ func (m *MainMenu) categoriesMenu(reader *bufio.Reader) error {
 			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
+		if err := cmd.Execute(ctx); err == nil {
 			category := <-resultCh
 			fmt.Printf("Создана категория: %+v\n", category)
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "2":
 		fmt.Print("Введите ID категории: ")
@@ -230,11 +250,11 @@ func (m *MainMenu) categoriesMenu(reader *bufio.Reader) error {
 			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
+		if err := cmd.Execute(ctx); err == nil {
 			category := <-resultCh
 			fmt.Printf("Категория: %+v\n", category)
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "3":
 		resultCh := make(chan []*models.Category, 1)
@@ -244,14 +264,14 @@ func (m *MainMenu) categoriesMenu(reader *bufio.Reader) error {
 			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
+		if err := cmd.Execute(ctx); err == nil {
 			categories := <-resultCh
 			fmt.Println("Список категорий:")
 			for _, cat := range categories {
 				fmt.Printf("%+v\n", cat)
 			}
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "4":
 		fmt.Print("Введите тип категории (1 - доход, 2 - расход): ")
@@ -271,20 +291,26 @@ func (m *MainMenu) categoriesMenu(reader *bufio.Reader) error {
 			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
+		if err := cmd.Execute(ctx); err == nil {
 			categories := <-resultCh
 			fmt.Println("Список категорий:")
 			for _, cat := range categories {
 				fmt.Printf("%+v\n", cat)
 			}
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "5":
 		fmt.Print("Введите ID категории для обновления: ")
 		idStr, _ := reader.ReadString('\n')
 		idStr = strings.TrimSpace(idStr)
 		id, _ := strconv.Atoi(idStr)
+		current, err := m.container.GetCategoryFacade().GetCategory(ctx, id)
+		if err != nil {
+			printError(err)
+			return nil
+		}
+		fmt.Printf("Текущая категория: %+v\n", current)
 		fmt.Print("Введите новое название категории: ")
 		name, _ := reader.ReadString('\n')
 		name = strings.TrimSpace(name)
@@ -302,16 +328,17 @@ func (m *MainMenu) categoriesMenu(reader *bufio.Reader) error {
 		cmd := commands.NewUpdateCategoryCommand(
 			m.container.GetCategoryFacade(),
 			id,
+			current.Version,
 			name,
 			opType,
 			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
+		if err := cmd.Execute(ctx); err == nil {
 			category := <-resultCh
 			fmt.Printf("Обновленная категория: %+v\n", category)
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printUpdateError(<-errorCh)
 		}
 	case "6":
 		fmt.Print("Введите ID категории для удаления: ")
@@ -324,10 +351,10 @@ func (m *MainMenu) categoriesMenu(reader *bufio.Reader) error {
 			id,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
-			fmt.Println("Категория успешно удалена.")
+		if err := cmd.Execute(ctx); err == nil {
+			fmt.Println("Категория перемещена в корзину.")
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "0":
 		return nil
@@ -337,7 +364,7 @@ func (m *MainMenu) categoriesMenu(reader *bufio.Reader) error {
 	return nil
 }
 
-func (m *MainMenu) operationsMenu(reader *bufio.Reader) error {
+func (m *MainMenu) operationsMenu(ctx context.Context, reader *bufio.Reader) error {
 	fmt.Println("\n--- Управление операциями ---")
 	fmt.Println("1. Создать операцию")
 	fmt.Println("2. Получить операцию по ID")
@@ -346,6 +373,7 @@ func (m *MainMenu) operationsMenu(reader *bufio.Reader) error {
 	fmt.Println("5. Список операций по категории")
 	fmt.Println("6. Обновить операцию")
 	fmt.Println("7. Удалить операцию")
+	fmt.Println("8. Поиск операций")
 	fmt.Println("0. Назад")
 	fmt.Print("Выберите опцию: ")
 	input, _ := reader.ReadString('\n')
@@ -397,11 +425,11 @@ func (m *MainMenu) operationsMenu(reader *bufio.Reader) error {
 			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
+		if err := cmd.Execute(ctx); err == nil {
 			operation := <-resultCh
 			fmt.Printf("Создана операция: %+v\n", operation)
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "2":
 		fmt.Print("Введите ID операции: ")
@@ -416,11 +444,11 @@ func (m *MainMenu) operationsMenu(reader *bufio.Reader) error {
 			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
+		if err := cmd.Execute(ctx); err == nil {
 			operation := <-resultCh
 			fmt.Printf("Операция: %+v\n", operation)
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "3":
 		resultCh := make(chan []*models.Operation, 1)
@@ -430,14 +458,14 @@ func (m *MainMenu) operationsMenu(reader *bufio.Reader) error {
 			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
+		if err := cmd.Execute(ctx); err == nil {
 			operations := <-resultCh
 			fmt.Println("Список операций:")
 			for _, op := range operations {
 				fmt.Printf("%+v\n", op)
 			}
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "4":
 		fmt.Print("Введите ID счета: ")
@@ -452,14 +480,14 @@ func (m *MainMenu) operationsMenu(reader *bufio.Reader) error {
 			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
+		if err := cmd.Execute(ctx); err == nil {
 			operations := <-resultCh
 			fmt.Println("Операции по счету:")
 			for _, op := range operations {
 				fmt.Printf("%+v\n", op)
 			}
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "5":
 		fmt.Print("Введите ID категории: ")
@@ -474,20 +502,26 @@ func (m *MainMenu) operationsMenu(reader *bufio.Reader) error {
 			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
+		if err := cmd.Execute(ctx); err == nil {
 			operations := <-resultCh
 			fmt.Println("Операции по категории:")
 			for _, op := range operations {
 				fmt.Printf("%+v\n", op)
 			}
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "6":
 		fmt.Print("Введите ID операции для обновления: ")
 		idStr, _ := reader.ReadString('\n')
 		idStr = strings.TrimSpace(idStr)
 		id, _ := strconv.Atoi(idStr)
+		current, err := m.container.GetOperationFacade().GetOperationDetails(ctx, id)
+		if err != nil {
+			printError(err)
+			return nil
+		}
+		fmt.Printf("Текущая операция: %+v\n", current)
 		fmt.Print("Введите новый ID счета: ")
 		bankStr, _ := reader.ReadString('\n')
 		bankStr = strings.TrimSpace(bankStr)
@@ -525,6 +559,7 @@ func (m *MainMenu) operationsMenu(reader *bufio.Reader) error {
 		cmd := commands.NewUpdateOperationCommand(
 			m.container.GetOperationFacade(),
 			id,
+			current.Version,
 			bankID,
 			categoryID,
 			amount,
@@ -534,11 +569,11 @@ func (m *MainMenu) operationsMenu(reader *bufio.Reader) error {
 			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
+		if err := cmd.Execute(ctx); err == nil {
 			operation := <-resultCh
 			fmt.Printf("Обновленная операция: %+v\n", operation)
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printUpdateError(<-errorCh)
 		}
 	case "7":
 		fmt.Print("Введите ID операции для удаления: ")
@@ -551,11 +586,13 @@ func (m *MainMenu) operationsMenu(reader *bufio.Reader) error {
 			id,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
-			fmt.Println("Операция успешно удалена.")
+		if err := cmd.Execute(ctx); err == nil {
+			fmt.Println("Операция перемещена в корзину.")
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
+	case "8":
+		m.searchOperations(ctx, reader)
 	case "0":
 		return nil
 	default:
@@ -564,7 +601,73 @@ func (m *MainMenu) operationsMenu(reader *bufio.Reader) error {
 	return nil
 }
 
-func (m *MainMenu) analyticsMenu(reader *bufio.Reader) error {
+// searchOperations запрашивает фильтр и сортировку и постранично выводит найденные операции
+func (m *MainMenu) searchOperations(ctx context.Context, reader *bufio.Reader) {
+	readLine := func(prompt string) string {
+		fmt.Print(prompt)
+		line, _ := reader.ReadString('\n')
+		return strings.TrimSpace(line)
+	}
+	readAmount := func(prompt string) *float64 {
+		amount, err := strconv.ParseFloat(readLine(prompt), 64)
+		if err != nil {
+			return nil
+		}
+		return &amount
+	}
+	readDate := func(prompt string) time.Time {
+		date, _ := time.Parse("2006-01-02", readLine(prompt))
+		return date
+	}
+
+	fmt.Println("Пустой ввод - без условия.")
+	var query models.OperationQuery
+	query.BankAccountID, _ = strconv.Atoi(readLine("ID счета: "))
+	query.CategoryID, _ = strconv.Atoi(readLine("ID категории: "))
+	switch readLine("Тип (1 - доход, 2 - расход): ") {
+	case "1":
+		query.Type = models.Income
+	case "2":
+		query.Type = models.Expense
+	}
+	query.MinAmount = readAmount("Минимальная сумма: ")
+	query.MaxAmount = readAmount("Максимальная сумма: ")
+	query.From = readDate("Дата начала (YYYY-MM-DD): ")
+	query.To = readDate("Дата окончания (YYYY-MM-DD): ")
+	query.Description = readLine("Текст в описании: ")
+
+	fields := models.OperationSortFields()
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = string(field)
+	}
+	query.SortBy = models.OperationSortField(readLine(fmt.Sprintf("Сортировка (%s): ", strings.Join(names, ", "))))
+	query.Descending = readLine("По убыванию? (д/н): ") == "д"
+	query.Limit = 10
+
+	for {
+		resultCh := make(chan *models.OperationPage, 1)
+		errorCh := make(chan error, 1)
+		cmd := commands.NewFindOperationsCommand(m.container.GetOperationFacade(), query, resultCh, errorCh)
+		if err := cmd.Execute(ctx); err != nil {
+			printError(<-errorCh)
+			return
+		}
+
+		page := <-resultCh
+		fmt.Printf("Найдено операций: %d\n", page.Total)
+		for _, op := range page.Operations {
+			fmt.Println(op)
+		}
+
+		if page.NextCursor == "" || readLine("Следующая страница? (д/н): ") != "д" {
+			return
+		}
+		query.Cursor = page.NextCursor
+	}
+}
+
+func (m *MainMenu) analyticsMenu(ctx context.Context, reader *bufio.Reader) error {
 	fmt.Println("\nМеню аналитики:")
 	fmt.Println("1. Разница доходов и расходов за период")
 	fmt.Println("2. Группировка по категориям")
@@ -592,11 +695,11 @@ func (m *MainMenu) analyticsMenu(reader *bufio.Reader) error {
 		// Оборачиваем команду в декоратор для измерения времени
 		decoratedCmd := m.wrapWithTimeDecorator(cmd)
 
-		if err := decoratedCmd.Execute(); err == nil {
+		if err := decoratedCmd.Execute(ctx); err == nil {
 			diff := <-resultCh
 			fmt.Printf("Разница доходов и расходов за период: %.2f руб.\n", diff)
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "2":
 		start, end := readDateRange(reader)
@@ -613,14 +716,14 @@ func (m *MainMenu) analyticsMenu(reader *bufio.Reader) error {
 		// Оборачиваем команду в декоратор для измерения времени
 		decoratedCmd := m.wrapWithTimeDecorator(cmd)
 
-		if err := decoratedCmd.Execute(); err == nil {
+		if err := decoratedCmd.Execute(ctx); err == nil {
 			expenses := <-resultCh
 			fmt.Println("Расходы по категориям:")
 			for cat, amt := range expenses {
 				fmt.Printf("%s: %.2f\n", cat, amt)
 			}
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "3":
 		fmt.Print("Введите год: ")
@@ -639,14 +742,14 @@ func (m *MainMenu) analyticsMenu(reader *bufio.Reader) error {
 		// Оборачиваем команду в декоратор для измерения времени
 		decoratedCmd := m.wrapWithTimeDecorator(cmd)
 
-		if err := decoratedCmd.Execute(); err == nil {
+		if err := decoratedCmd.Execute(ctx); err == nil {
 			dynamics := <-resultCh
 			fmt.Println("Месячная динамика:")
 			for month, data := range dynamics {
 				fmt.Printf("%s - Доход: %.2f, Расход: %.2f\n", month, data[models.Income], data[models.Expense])
 			}
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "0":
 		return nil
@@ -656,120 +759,595 @@ func (m *MainMenu) analyticsMenu(reader *bufio.Reader) error {
 	return nil
 }
 
-func (m *MainMenu) importExportMenu(reader *bufio.Reader) error {
+func (m *MainMenu) importExportMenu(ctx context.Context, reader *bufio.Reader) error {
 	fmt.Println("\n--- Импорт/Экспорт данных ---")
-	fmt.Println("1. Экспорт в CSV")
-	fmt.Println("2. Экспорт в JSON")
-	fmt.Println("3. Экспорт в YAML")
-	fmt.Println("4. Импорт из CSV")
-	fmt.Println("5. Импорт из JSON")
-	fmt.Println("6. Импорт из YAML")
+	fmt.Println("1. Экспорт")
+	fmt.Println("2. Импорт")
+	fmt.Println("3. Импорт выписки банка")
+	fmt.Println("4. Экспорт выписки")
+	fmt.Println("5. Профили CSV банков")
+	fmt.Println("6. Экспорт в Excel (XLSX)")
+	fmt.Println("7. Создать резервную копию")
+	fmt.Println("8. Проверить резервную копию")
+	fmt.Println("9. Восстановить из резервной копии")
 	fmt.Println("0. Назад")
 	fmt.Print("Выберите опцию: ")
 	input, _ := reader.ReadString('\n')
 	input = strings.TrimSpace(input)
 	switch input {
 	case "1":
-		fmt.Print("Введите путь для экспорта CSV: ")
+		codec := readCodec(reader, "экспорта", (*importexport.Codec).CanExport)
+		if codec == nil {
+			return nil
+		}
+		fmt.Printf("Введите путь для экспорта %s: ", codec.Title)
 		path, _ := reader.ReadString('\n')
 		path = strings.TrimSpace(path)
 		errorCh := make(chan error, 1)
-		cmd := commands.NewExportCSVCommand(
+		cmd := commands.NewExportCommand(
+			codec.Format,
 			m.container.GetBankAccountRepository(),
 			m.container.GetCategoryRepository(),
 			m.container.GetOperationRepository(),
 			path,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
-			fmt.Println("Экспорт CSV выполнен успешно.")
+		if err := cmd.Execute(ctx); err == nil {
+			fmt.Printf("Экспорт %s выполнен успешно.\n", codec.Title)
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "2":
-		fmt.Print("Введите путь для экспорта JSON: ")
+		codec := readCodec(reader, "импорта", (*importexport.Codec).CanImport)
+		if codec == nil {
+			return nil
+		}
+		fmt.Printf("Введите путь для импорта %s: ", codec.Title)
 		path, _ := reader.ReadString('\n')
 		path = strings.TrimSpace(path)
+		strategy, err := readMergeStrategy(reader)
+		if err != nil {
+			printError(err)
+			return nil
+		}
+		if !m.validateImport(ctx, codec.Format, path, strategy) {
+			return nil
+		}
+		resultCh := make(chan *importexport.ImportSummary, 1)
 		errorCh := make(chan error, 1)
-		cmd := commands.NewExportJSONCommand(
-			m.container.GetBankAccountRepository(),
-			m.container.GetCategoryRepository(),
-			m.container.GetOperationRepository(),
+		cmd := commands.NewImportCommand(
+			codec.Format,
+			m.container.GetTransactionManager(),
 			path,
+			strategy,
+			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
-			fmt.Println("Экспорт JSON выполнен успешно.")
+		if err := cmd.Execute(ctx); err == nil {
+			fmt.Printf("Импорт %s выполнен успешно.\n", codec.Title)
+			printImportSummary(<-resultCh)
+			m.reconcileBalances(ctx, reader)
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "3":
-		fmt.Print("Введите путь для экспорта YAML: ")
+		m.importStatement(ctx, reader)
+	case "4":
+		codec := readCodec(reader, "выписок", (*importexport.Codec).CanExportStatement)
+		if codec == nil {
+			return nil
+		}
+		fmt.Printf("Введите путь к файлу выписки %s: ", codec.Title)
 		path, _ := reader.ReadString('\n')
 		path = strings.TrimSpace(path)
 		errorCh := make(chan error, 1)
-		cmd := commands.NewExportYAMLCommand(
+		cmd := commands.NewExportStatementCommand(
+			codec.Format,
 			m.container.GetBankAccountRepository(),
 			m.container.GetCategoryRepository(),
 			m.container.GetOperationRepository(),
 			path,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
-			fmt.Println("Экспорт YAML выполнен успешно.")
+		if err := cmd.Execute(ctx); err == nil {
+			fmt.Printf("Экспорт выписки %s выполнен успешно.\n", codec.Title)
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
+		}
+	case "5":
+		m.csvProfilesMenu(ctx, reader)
+	case "6":
+		m.exportWorkbook(ctx, reader)
+	case "7":
+		m.backup(ctx, reader)
+	case "8":
+		fmt.Print("Введите путь к резервной копии: ")
+		path, _ := reader.ReadString('\n')
+		m.verifyBackup(ctx, strings.TrimSpace(path))
+	case "9":
+		m.restoreBackup(ctx, reader)
+	case "0":
+		return nil
+	default:
+		fmt.Println("Неверный выбор.")
+	}
+	return nil
+}
+
+// importStatement импортирует выписку банка в операции выбранного счета или счетов из выписки
+func (m *MainMenu) importStatement(ctx context.Context, reader *bufio.Reader) {
+	codec := readCodec(reader, "выписок", (*importexport.Codec).CanImportStatement)
+	if codec == nil {
+		return
+	}
+	fmt.Printf("Введите путь к файлу выписки %s: ", codec.Title)
+	path, _ := reader.ReadString('\n')
+	path = strings.TrimSpace(path)
+
+	options, err := readStatementOptions(reader)
+	if err != nil {
+		printError(err)
+		return
+	}
+
+	resultCh := make(chan *importexport.StatementImportSummary, 1)
+	errorCh := make(chan error, 1)
+	cmd := commands.NewImportStatementCommand(
+		codec.Format,
+		m.container.GetTransactionManager(),
+		path,
+		options,
+		resultCh,
+		errorCh,
+	)
+	if err := cmd.Execute(ctx); err != nil {
+		printError(<-errorCh)
+		return
+	}
+
+	fmt.Println("Импорт выписки выполнен успешно.")
+	printStatementSummary(<-resultCh)
+}
+
+// printStatementSummary выводит итог импорта выписки и сверку ее остатков
+func printStatementSummary(summary *importexport.StatementImportSummary) {
+	fmt.Printf("Операций импортировано: %d (категория по правилам: %d), повторов пропущено: %d, с нулевой суммой: %d\n",
+		summary.Imported, summary.ByRule, summary.Duplicates, summary.Skipped)
+	if summary.CreatedAccounts > 0 || summary.CreatedCategories > 0 {
+		fmt.Printf("Создано счетов: %d, категорий: %d\n", summary.CreatedAccounts, summary.CreatedCategories)
+	}
+	fmt.Printf("Баланс изменен на %+.2f руб.\n", summary.BalanceChange)
+	for _, check := range summary.BalanceChecks {
+		status := "совпадает"
+		if !check.Matches() {
+			status = "РАСХОЖДЕНИЕ"
+		}
+		fmt.Printf("%s - %s\n", check, status)
+	}
+}
+
+// exportWorkbook экспортирует счета, категории, операции и по желанию отчеты аналитики в книгу XLSX
+func (m *MainMenu) exportWorkbook(ctx context.Context, reader *bufio.Reader) {
+	fmt.Print("Введите путь к файлу книги (.xlsx): ")
+	path, _ := reader.ReadString('\n')
+	path = strings.TrimSpace(path)
+
+	var reports commands.WorkbookReports
+	fmt.Print("Год месячной динамики (Enter - без листа динамики): ")
+	yearStr, _ := reader.ReadString('\n')
+	reports.Year, _ = strconv.Atoi(strings.TrimSpace(yearStr))
+	fmt.Print("Добавить лист сумм по категориям за период? (y/N): ")
+	answer, _ := reader.ReadString('\n')
+	if strings.EqualFold(strings.TrimSpace(answer), "y") {
+		reports.Start, reports.End = readDateRange(reader)
+	}
+
+	errorCh := make(chan error, 1)
+	cmd := commands.NewExportWorkbookCommand(
+		m.container.GetBankAccountRepository(),
+		m.container.GetCategoryRepository(),
+		m.container.GetOperationRepository(),
+		m.container.GetAnalyticsFacade(),
+		reports,
+		path,
+		errorCh,
+	)
+	if err := cmd.Execute(ctx); err != nil {
+		printError(<-errorCh)
+		return
+	}
+	fmt.Println("Экспорт в Excel выполнен успешно.")
+}
+
+// backup сохраняет все данные в один архив резервной копии
+func (m *MainMenu) backup(ctx context.Context, reader *bufio.Reader) {
+	codec := readCodec(reader, "файлов копии", (*importexport.Codec).CanExport)
+	if codec == nil {
+		return
+	}
+	fmt.Print("Введите путь к архиву (.zip, .tar.gz или .tgz): ")
+	path, _ := reader.ReadString('\n')
+	path = strings.TrimSpace(path)
+
+	resultCh := make(chan *importexport.BundleManifest, 1)
+	errorCh := make(chan error, 1)
+	cmd := commands.NewBackupCommand(
+		codec.Format,
+		m.container.GetBankAccountRepository(),
+		m.container.GetCategoryRepository(),
+		m.container.GetOperationRepository(),
+		path,
+		resultCh,
+		errorCh,
+	)
+	if err := cmd.Execute(ctx); err != nil {
+		printError(<-errorCh)
+		return
+	}
+	fmt.Println("Резервная копия создана.")
+	printBundleManifest(<-resultCh)
+}
+
+// verifyBackup проверяет резервную копию path и выводит ее манифест
+func (m *MainMenu) verifyBackup(ctx context.Context, path string) bool {
+	resultCh := make(chan *importexport.BundleManifest, 1)
+	errorCh := make(chan error, 1)
+	cmd := commands.NewVerifyBackupCommand(path, resultCh, errorCh)
+	if err := cmd.Execute(ctx); err != nil {
+		printError(<-errorCh)
+		fmt.Println("Резервная копия повреждена или не читается.")
+		return false
+	}
+	fmt.Println("Резервная копия цела.")
+	printBundleManifest(<-resultCh)
+	return true
+}
+
+// restoreBackup проверяет резервную копию и импортирует ее по выбранной стратегии слияния
+func (m *MainMenu) restoreBackup(ctx context.Context, reader *bufio.Reader) {
+	fmt.Print("Введите путь к резервной копии: ")
+	path, _ := reader.ReadString('\n')
+	path = strings.TrimSpace(path)
+	if !m.verifyBackup(ctx, path) {
+		return
+	}
+	strategy, err := readMergeStrategy(reader)
+	if err != nil {
+		printError(err)
+		return
+	}
+
+	resultCh := make(chan *importexport.ImportSummary, 1)
+	errorCh := make(chan error, 1)
+	cmd := commands.NewRestoreBackupCommand(
+		m.container.GetTransactionManager(),
+		path,
+		strategy,
+		resultCh,
+		errorCh,
+	)
+	if err := cmd.Execute(ctx); err != nil {
+		printError(<-errorCh)
+		return
+	}
+	fmt.Println("Восстановление из резервной копии выполнено успешно.")
+	printImportSummary(<-resultCh)
+	m.reconcileBalances(ctx, reader)
+}
+
+// printBundleManifest выводит версию, формат, время создания и состав резервной копии
+func printBundleManifest(manifest *importexport.BundleManifest) {
+	fmt.Printf("Версия схемы: %d, формат: %s, создана: %s\n",
+		manifest.Version, manifest.Format, manifest.ExportedAt.Local().Format("02.01.2006 15:04:05"))
+	for _, file := range manifest.Files {
+		fmt.Printf("  %s: записей %d, %d байт, SHA-256 %s\n", file.Name, file.Count, file.Size, file.SHA256)
+	}
+}
+
+// csvProfilesMenu управляет профилями CSV банков и импортирует выписки по ним
+func (m *MainMenu) csvProfilesMenu(ctx context.Context, reader *bufio.Reader) {
+	store := m.container.GetCSVProfileStore()
+
+	fmt.Println("\n--- Профили CSV банков ---")
+	fmt.Println("1. Список профилей")
+	fmt.Println("2. Создать или изменить профиль")
+	fmt.Println("3. Удалить профиль")
+	fmt.Println("4. Импорт CSV-выписки по профилю")
+	fmt.Println("0. Назад")
+	fmt.Print("Выберите опцию: ")
+	input, _ := reader.ReadString('\n')
+	switch strings.TrimSpace(input) {
+	case "1":
+		profiles, err := store.List()
+		if err != nil {
+			printError(err)
+			return
+		}
+		if len(profiles) == 0 {
+			fmt.Println("Профилей нет.")
+		}
+		for _, profile := range profiles {
+			fmt.Println(describeCSVProfile(profile))
+		}
+	case "2":
+		profile := readCSVProfile(reader)
+		if err := store.Save(profile); err != nil {
+			printError(err)
+			return
+		}
+		fmt.Printf("Профиль %q сохранен.\n", profile.Name)
+	case "3":
+		fmt.Print("Введите название профиля: ")
+		name, _ := reader.ReadString('\n')
+		if err := store.Delete(strings.TrimSpace(name)); err != nil {
+			printError(err)
+			return
 		}
+		fmt.Println("Профиль удален.")
 	case "4":
-		fmt.Print("Введите путь для импорта CSV: ")
+		fmt.Print("Введите название профиля: ")
+		name, _ := reader.ReadString('\n')
+		profile, err := store.Get(strings.TrimSpace(name))
+		if err != nil {
+			printError(err)
+			return
+		}
+		fmt.Print("Введите путь к CSV-файлу выписки: ")
 		path, _ := reader.ReadString('\n')
 		path = strings.TrimSpace(path)
+		options, err := readStatementOptions(reader)
+		if err != nil {
+			printError(err)
+			return
+		}
+
+		resultCh := make(chan *importexport.StatementImportSummary, 1)
 		errorCh := make(chan error, 1)
-		cmd := commands.NewImportCSVCommand(
-			m.container.GetBankAccountRepository(),
-			m.container.GetCategoryRepository(),
-			m.container.GetOperationRepository(),
+		cmd := commands.NewImportProfileStatementCommand(
+			profile,
+			m.container.GetTransactionManager(),
 			path,
+			options,
+			resultCh,
 			errorCh,
 		)
-		if err := cmd.Execute(); err == nil {
-			fmt.Println("Импорт CSV выполнен успешно.")
-		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+		if err := cmd.Execute(ctx); err != nil {
+			printError(<-errorCh)
+			return
 		}
-	case "5":
-		fmt.Print("Введите путь для импорта JSON: ")
-		path, _ := reader.ReadString('\n')
-		path = strings.TrimSpace(path)
+		fmt.Println("Импорт выписки выполнен успешно.")
+		printStatementSummary(<-resultCh)
+	case "0":
+	default:
+		fmt.Println("Неверный выбор.")
+	}
+}
+
+// describeCSVProfile возвращает краткое описание профиля
+func describeCSVProfile(profile *importexport.CSVProfile) string {
+	orDefault := func(value, fallback string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	}
+
+	columns := []string{"дата " + profile.Columns.Date}
+	for _, column := range []struct{ title, value string }{
+		{"сумма", profile.Columns.Amount},
+		{"списание", profile.Columns.Debit},
+		{"поступление", profile.Columns.Credit},
+		{"направление", profile.Columns.Direction},
+		{"получатель", profile.Columns.Payee},
+		{"назначение", profile.Columns.Description},
+		{"категория", profile.Columns.Category},
+		{"ID", profile.Columns.ID},
+	} {
+		if column.value != "" {
+			columns = append(columns, column.title+" "+column.value)
+		}
+	}
+	return fmt.Sprintf("%s: разделитель %q, кодировка %s, дата %s, знак %s; колонки: %s",
+		profile.Name, orDefault(profile.Delimiter, ","), orDefault(profile.Encoding, "utf-8"),
+		orDefault(profile.DateFormat, "02.01.2006"), orDefault(string(profile.Sign), string(importexport.SignPositiveIncome)),
+		strings.Join(columns, ", "))
+}
+
+// readCSVProfile запрашивает параметры профиля CSV; пустой ввод оставляет значение по умолчанию
+func readCSVProfile(reader *bufio.Reader) *importexport.CSVProfile {
+	read := func(prompt string) string {
+		fmt.Print(prompt)
+		value, _ := reader.ReadString('\n')
+		return strings.TrimSpace(value)
+	}
+
+	profile := &importexport.CSVProfile{}
+	profile.Name = read("Введите название профиля: ")
+	profile.Delimiter = read("Введите разделитель полей (Enter - «,», tab - табуляция): ")
+	profile.Encoding = read("Введите кодировку (Enter - utf-8, windows-1251): ")
+	profile.SkipRows, _ = strconv.Atoi(read("Введите число строк перед заголовком (Enter - 0): "))
+	profile.NoHeader = strings.EqualFold(read("В файле есть строка заголовка? (Y/n): "), "n")
+	fmt.Println("Колонки задаются названием из заголовка или номером, начиная с 1.")
+	profile.Columns.Date = read("Колонка даты: ")
+	profile.DateFormat = read("Формат даты в нотации Go (Enter - 02.01.2006): ")
+	profile.DecimalSeparator = read("Десятичный разделитель (Enter - «.»): ")
+	profile.ThousandsSeparator = read("Разделитель тысяч (Enter - нет): ")
+
+	fmt.Println("Правила знака:")
+	fmt.Println("1. Сумма со знаком, поступления положительные")
+	fmt.Println("2. Сумма со знаком, списания положительные")
+	fmt.Println("3. Сумма без знака, направление в отдельной колонке")
+	fmt.Println("4. Списание и поступление в разных колонках")
+	switch read("Выберите правило: ") {
+	case "2":
+		profile.Sign = importexport.SignPositiveExpense
+	case "3":
+		profile.Sign = importexport.SignDirectionColumn
+	case "4":
+		profile.Sign = importexport.SignDebitCredit
+	default:
+		profile.Sign = importexport.SignPositiveIncome
+	}
+	switch profile.Sign {
+	case importexport.SignDebitCredit:
+		profile.Columns.Debit = read("Колонка суммы списания: ")
+		profile.Columns.Credit = read("Колонка суммы поступления: ")
+	case importexport.SignDirectionColumn:
+		profile.Columns.Amount = read("Колонка суммы: ")
+		profile.Columns.Direction = read("Колонка направления: ")
+		profile.DebitValues = splitValues(read("Значения списаний через запятую: "))
+		profile.CreditValues = splitValues(read("Значения поступлений через запятую: "))
+	default:
+		profile.Columns.Amount = read("Колонка суммы: ")
+	}
+
+	profile.Columns.Payee = read("Колонка получателя или плательщика (Enter - нет): ")
+	profile.Columns.Description = read("Колонка назначения платежа (Enter - нет): ")
+	profile.Columns.Category = read("Колонка категории (Enter - нет): ")
+	profile.Columns.ID = read("Колонка ID операции в банке (Enter - нет): ")
+	return profile
+}
+
+// splitValues делит список значений через запятую
+func splitValues(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// readStatementOptions запрашивает счет, категории по умолчанию и файл правил категорий
+func readStatementOptions(reader *bufio.Reader) (importexport.StatementImportOptions, error) {
+	var options importexport.StatementImportOptions
+
+	fmt.Print("Введите ID счета для операций выписки (Enter - счета из выписки по названию): ")
+	idStr, _ := reader.ReadString('\n')
+	options.BankAccountID, _ = strconv.Atoi(strings.TrimSpace(idStr))
+
+	fmt.Print("Введите ID категории поступлений по умолчанию (Enter - без категории): ")
+	idStr, _ = reader.ReadString('\n')
+	options.IncomeCategoryID, _ = strconv.Atoi(strings.TrimSpace(idStr))
+
+	fmt.Print("Введите ID категории списаний по умолчанию (Enter - без категории): ")
+	idStr, _ = reader.ReadString('\n')
+	options.ExpenseCategoryID, _ = strconv.Atoi(strings.TrimSpace(idStr))
+
+	fmt.Print("Введите путь к YAML-файлу правил категорий (Enter - без правил): ")
+	rulesPath, _ := reader.ReadString('\n')
+	rulesPath = strings.TrimSpace(rulesPath)
+	if rulesPath == "" {
+		return options, nil
+	}
+
+	file, err := os.Open(rulesPath)
+	if err != nil {
+		return options, err
+	}
+	defer file.Close()
+	options.Rules, err = importexport.LoadCategoryRules(file)
+	return options, err
+}
+
+// readCodec предлагает выбрать один из зарегистрированных форматов, подходящих по supports
+func readCodec(reader *bufio.Reader, action string, supports func(*importexport.Codec) bool) *importexport.Codec {
+	var codecs []*importexport.Codec
+	for _, codec := range importexport.Codecs() {
+		if supports(codec) {
+			codecs = append(codecs, codec)
+		}
+	}
+
+	fmt.Printf("Форматы %s:\n", action)
+	for n, codec := range codecs {
+		fmt.Printf("%d. %s\n", n+1, codec.Title)
+	}
+	fmt.Print("Выберите формат: ")
+	input, _ := reader.ReadString('\n')
+	n, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || n < 1 || n > len(codecs) {
+		fmt.Println("Неверный выбор.")
+		return nil
+	}
+	return codecs[n-1]
+}
+
+func (m *MainMenu) trashMenu(ctx context.Context, reader *bufio.Reader) error {
+	fmt.Println("\n--- Корзина ---")
+	fmt.Println("1. Показать содержимое корзины")
+	fmt.Println("2. Восстановить счет")
+	fmt.Println("3. Восстановить категорию")
+	fmt.Println("4. Восстановить операцию")
+	fmt.Println("5. Очистить корзину")
+	fmt.Println("0. Назад")
+	fmt.Print("Выберите опцию: ")
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+
+	bankAccountFacade := m.container.GetBankAccountFacade()
+	categoryFacade := m.container.GetCategoryFacade()
+	operationFacade := m.container.GetOperationFacade()
+
+	switch input {
+	case "1":
+		resultCh := make(chan *commands.TrashContents, 1)
 		errorCh := make(chan error, 1)
-		cmd := commands.NewImportJSONCommand(
-			m.container.GetBankAccountRepository(),
-			m.container.GetCategoryRepository(),
-			m.container.GetOperationRepository(),
-			path,
-			errorCh,
-		)
-		if err := cmd.Execute(); err == nil {
-			fmt.Println("Импорт JSON выполнен успешно.")
+		cmd := commands.NewListTrashCommand(bankAccountFacade, categoryFacade, operationFacade, resultCh, errorCh)
+		if err := cmd.Execute(ctx); err != nil {
+			printError(<-errorCh)
+			return nil
+		}
+		contents := <-resultCh
+		if len(contents.BankAccounts)+len(contents.Categories)+len(contents.Operations) == 0 {
+			fmt.Println("Корзина пуста.")
+			return nil
+		}
+		for _, account := range contents.BankAccounts {
+			fmt.Printf("Счет #%d %q, удален %s\n", account.ID, account.Name, account.DeletedAt.Format("2006-01-02 15:04"))
+		}
+		for _, category := range contents.Categories {
+			fmt.Printf("Категория #%d %q, удалена %s\n", category.ID, category.Name, category.DeletedAt.Format("2006-01-02 15:04"))
+		}
+		for _, operation := range contents.Operations {
+			fmt.Printf("Операция #%d на %.2f от %s, удалена %s\n", operation.ID, operation.Amount,
+				operation.Date.Format("2006-01-02"), operation.DeletedAt.Format("2006-01-02 15:04"))
+		}
+	case "2", "3", "4":
+		entity := map[string]commands.TrashEntity{
+			"2": commands.TrashBankAccount,
+			"3": commands.TrashCategory,
+			"4": commands.TrashOperation,
+		}[input]
+		fmt.Print("Введите ID для восстановления: ")
+		idStr, _ := reader.ReadString('\n')
+		idStr = strings.TrimSpace(idStr)
+		id, _ := strconv.Atoi(idStr)
+		errorCh := make(chan error, 1)
+		cmd := commands.NewRestoreFromTrashCommand(bankAccountFacade, categoryFacade, operationFacade, entity, id, errorCh)
+		if err := cmd.Execute(ctx); err == nil {
+			fmt.Println("Восстановлено из корзины.")
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
-	case "6":
-		fmt.Print("Введите путь для импорта YAML: ")
-		path, _ := reader.ReadString('\n')
-		path = strings.TrimSpace(path)
+	case "5":
+		fmt.Print("Удалить записи, находящиеся в корзине дольше скольких дней (0 - все): ")
+		daysStr, _ := reader.ReadString('\n')
+		daysStr = strings.TrimSpace(daysStr)
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 0 {
+			fmt.Println("Неверное количество дней.")
+			return nil
+		}
+		resultCh := make(chan int, 1)
 		errorCh := make(chan error, 1)
-		cmd := commands.NewImportYAMLCommand(
-			m.container.GetBankAccountRepository(),
-			m.container.GetCategoryRepository(),
-			m.container.GetOperationRepository(),
-			path,
-			errorCh,
-		)
-		if err := cmd.Execute(); err == nil {
-			fmt.Println("Импорт YAML выполнен успешно.")
+		cmd := commands.NewPurgeTrashCommand(bankAccountFacade, categoryFacade, operationFacade,
+			time.Duration(days)*24*time.Hour, resultCh, errorCh)
+		if err := cmd.Execute(ctx); err == nil {
+			fmt.Printf("Окончательно удалено записей: %d\n", <-resultCh)
 		} else {
-			fmt.Printf("Ошибка: %v\n", <-errorCh)
+			printError(<-errorCh)
 		}
 	case "0":
 		return nil
@@ -799,7 +1377,160 @@ func readDateRange(reader *bufio.Reader) (time.Time, time.Time) {
 	return start, end
 }
 
+// readMergeStrategy запрашивает, что делать с записями, ID которых уже заняты
+func readMergeStrategy(reader *bufio.Reader) (importexport.MergeStrategy, error) {
+	fmt.Print("При совпадении ID: overwrite - заменить, skip - пропустить, remap - сохранить под новым ID [skip]: ")
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return importexport.MergeSkip, nil
+	}
+	return importexport.ParseMergeStrategy(input)
+}
+
+// validateImport проверяет файлы импорта до сохранения и выводит найденные проблемы.
+// Возвращает true, если импорт можно выполнять.
+func (m *MainMenu) validateImport(ctx context.Context, format importexport.FileFormat, path string, strategy importexport.MergeStrategy) bool {
+	resultCh := make(chan *importexport.ValidationReport, 1)
+	errorCh := make(chan error, 1)
+	cmd := commands.NewValidateImportCommand(
+		format,
+		m.container.GetTransactionManager(),
+		path,
+		strategy,
+		resultCh,
+		errorCh,
+	)
+	if err := cmd.Execute(ctx); err != nil {
+		printError(<-errorCh)
+		return false
+	}
+
+	report := <-resultCh
+	if report.Valid() {
+		return true
+	}
+	printValidationReport(report)
+	fmt.Println("Импорт отменен, данные не изменены. Исправьте файлы и повторите импорт.")
+	return false
+}
+
+// printValidationReport выводит проблемы, найденные при проверке файлов импорта
+func printValidationReport(report *importexport.ValidationReport) {
+	fmt.Printf("Найдено проблем в файлах импорта: %d\n", len(report.Problems))
+	for _, problem := range report.Problems {
+		fmt.Printf("  %s\n", problem)
+	}
+}
+
+// printImportSummary выводит итог слияния импортированных записей
+func printImportSummary(summary *importexport.ImportSummary) {
+	printEntityImportSummary("Счета", summary.BankAccounts)
+	printEntityImportSummary("Категории", summary.Categories)
+	printEntityImportSummary("Операции", summary.Operations)
+	if summary.RewrittenReferences > 0 {
+		fmt.Printf("Переписано ссылок операций на счета и категории: %d\n", summary.RewrittenReferences)
+	}
+}
+
+// printEntityImportSummary выводит итог импорта записей одного типа и перенесенные ID по возрастанию
+func printEntityImportSummary(title string, summary importexport.EntityImportSummary) {
+	fmt.Printf("%s: создано %d, заменено %d, пропущено %d, перенесено на новые ID %d\n",
+		title, summary.Created, summary.Overwritten, summary.Skipped, len(summary.Remapped))
+
+	oldIDs := make([]int, 0, len(summary.Remapped))
+	for oldID := range summary.Remapped {
+		oldIDs = append(oldIDs, oldID)
+	}
+	sort.Ints(oldIDs)
+	for _, oldID := range oldIDs {
+		fmt.Printf("  #%d -> #%d\n", oldID, summary.Remapped[oldID])
+	}
+}
+
+// reconcileBalances сверяет балансы счетов с суммой их операций и для каждого расхождения
+// предлагает доверять файлу, записав начальный остаток, или пересчитать баланс по операциям
+func (m *MainMenu) reconcileBalances(ctx context.Context, reader *bufio.Reader) {
+	reconciliationFacade := m.container.GetReconciliationFacade()
+
+	resultCh := make(chan []*models.BalanceDiscrepancy, 1)
+	errorCh := make(chan error, 1)
+	cmd := commands.NewReconcileBalancesCommand(reconciliationFacade, resultCh, errorCh)
+	if err := cmd.Execute(ctx); err != nil {
+		printError(<-errorCh)
+		return
+	}
+
+	discrepancies := <-resultCh
+	if len(discrepancies) == 0 {
+		fmt.Println("Балансы всех счетов совпадают с суммой операций.")
+		return
+	}
+
+	fmt.Printf("Балансы не совпадают с суммой операций у счетов: %d\n", len(discrepancies))
+	for _, discrepancy := range discrepancies {
+		fmt.Println(discrepancy)
+		fmt.Println("1. Доверять файлу: записать разницу операцией начального остатка")
+		fmt.Println("2. Пересчитать баланс по операциям")
+		fmt.Println("0. Оставить как есть")
+		fmt.Print("Выберите опцию: ")
+		input, _ := reader.ReadString('\n')
+		input = strings.TrimSpace(input)
+
+		switch input {
+		case "1":
+			resultCh := make(chan *models.Operation, 1)
+			errorCh := make(chan error, 1)
+			cmd := commands.NewTrustStoredBalanceCommand(reconciliationFacade, discrepancy.BankAccountID, resultCh, errorCh)
+			if err := cmd.Execute(ctx); err == nil {
+				fmt.Printf("Записана операция начального остатка: %s\n", <-resultCh)
+			} else {
+				printError(<-errorCh)
+			}
+		case "2":
+			resultCh := make(chan *models.BankAccount, 1)
+			errorCh := make(chan error, 1)
+			cmd := commands.NewTrustOperationsCommand(reconciliationFacade, discrepancy.BankAccountID, resultCh, errorCh)
+			if err := cmd.Execute(ctx); err == nil {
+				fmt.Printf("Пересчитанный счет: %s\n", <-resultCh)
+			} else {
+				printError(<-errorCh)
+			}
+		default:
+			fmt.Println("Баланс счета оставлен без изменений.")
+		}
+	}
+}
+
 // Обертываем команду в декоратор для измерения времени выполнения
 func (m *MainMenu) wrapWithTimeDecorator(cmd interfaces.Command) interfaces.Command {
 	return commands.NewTimeMeasurementDecorator(cmd)
 }
+
+// printError выводит ошибку и подсказку, зависящую от ее вида
+func printError(err error) {
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("Действие прервано.")
+		return
+	}
+	fmt.Printf("Ошибка: %v\n", err)
+	var report *importexport.ValidationReport
+	if errors.As(err, &report) {
+		printValidationReport(report)
+		return
+	}
+	if field, ok := facade.InvalidField(err); ok && field != "" {
+		fmt.Printf("Проверьте поле: %s\n", field)
+	}
+	if facade.IsInUse(err) {
+		fmt.Println("Сначала переместите в корзину связанные операции.")
+	}
+}
+
+// printUpdateError выводит ошибку обновления и подсказку при конфликте версий
+func printUpdateError(err error) {
+	printError(err)
+	if facade.IsConflict(err) {
+		fmt.Println("Данные изменились после чтения. Повторите обновление, чтобы работать с актуальной версией.")
+	}
+}